@@ -1,19 +1,28 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
+	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/ankiconnect"
 	"codeberg.org/snonux/totalrecall/internal/archive"
 	"codeberg.org/snonux/totalrecall/internal/cli"
 	appconfig "codeberg.org/snonux/totalrecall/internal/config"
 	"codeberg.org/snonux/totalrecall/internal/gui"
 	"codeberg.org/snonux/totalrecall/internal/models"
 	"codeberg.org/snonux/totalrecall/internal/processor"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // runDeps holds injectable implementations for composition-root wiring (DIP).
@@ -58,14 +67,56 @@ func main() {
 }
 
 func runCommand(cmd *cobra.Command, args []string, flags *cli.Flags, deps runDeps) error {
-	// Handle --archive flag
-	if flags.Archive {
-		home, _ := os.UserHomeDir()
-		cardsDir := filepath.Join(home, ".local", "state", "totalrecall", "cards")
-		if err := deps.Archiver.ArchiveCards(cardsDir); err != nil {
-			return fmt.Errorf("failed to archive cards: %w", err)
+	archiver, err := archiverFor(deps.Archiver)
+	if err != nil {
+		return err
+	}
+	deps.Archiver = archiver
+
+	// Handle archive flags
+	if flags.Archive || flags.ListArchives || flags.RestoreArchive != "" || flags.PruneArchives > 0 {
+		return manageArchives(flags, deps.Archiver)
+	}
+
+	// Handle --dump-templates flag
+	if flags.DumpTemplates != "" {
+		return dumpTemplates(flags.DumpTemplates)
+	}
+
+	// Handle --inspect-apkg flag
+	if flags.InspectAPKG != "" {
+		return inspectAPKG(flags.InspectAPKG, flags.JSON)
+	}
+
+	// Handle --import-apkg flag
+	if flags.ImportAPKG != "" {
+		return importAPKG(flags)
+	}
+
+	// Handle --migrate-cards flag
+	if flags.MigrateCards {
+		return migrateCards(flags.OutputDir)
+	}
+
+	// Handle trash bin flags
+	if flags.ListTrash || flags.RestoreTrash != "" || flags.PurgeTrash {
+		return manageTrash(flags)
+	}
+
+	// Handle --list-exports flag
+	if flags.ListExports {
+		return listExports(flags.OutputDir, flags.JSON)
+	}
+
+	// Handle --list-revisions and --restore-revision flags
+	if flags.ListRevisions || flags.RestoreRevision > 0 {
+		if len(args) == 0 {
+			return fmt.Errorf("--list-revisions and --restore-revision need a word argument")
 		}
-		return nil
+		if flags.RestoreRevision > 0 {
+			return restoreRevision(flags.OutputDir, args[0], flags.RevisionAsset, flags.RestoreRevision)
+		}
+		return listRevisions(flags.OutputDir, args[0])
 	}
 
 	// Handle --list-models flag
@@ -83,7 +134,10 @@ func runCommand(cmd *cobra.Command, args []string, flags *cli.Flags, deps runDep
 
 	// Resolve all Viper config values once here so the processor never touches
 	// the global Viper singleton directly (Dependency Inversion Principle).
-	proc := newProcessor(flags)
+	proc, err := newProcessor(flags)
+	if err != nil {
+		return err
+	}
 
 	// Handle failed-asset retry mode before normal input processing.
 	if flags.RetryFailedAssets {
@@ -100,8 +154,9 @@ func runCommand(cmd *cobra.Command, args []string, flags *cli.Flags, deps runDep
 		if err := proc.ProcessSingleWord(args[0]); err != nil {
 			return err
 		}
-	} else {
-		// No input provided - launch GUI mode by default
+	} else if !flags.AnkiConnect && !flags.GenerateAnki {
+		// No input provided - launch GUI mode by default. --anki and
+		// --anki-connect without input export the existing cards instead.
 		return runGUIMode(proc, flags, deps)
 	}
 
@@ -109,17 +164,384 @@ func runCommand(cmd *cobra.Command, args []string, flags *cli.Flags, deps runDep
 	if flags.GenerateAnki {
 		fmt.Printf("\nGenerating Anki import file...\n")
 		outputPath, err := proc.GenerateAnkiFile()
-		if err != nil {
+		switch {
+		case errors.Is(err, processor.ErrNothingToExport):
+			fmt.Println("No new or changed cards to export.")
+		case err != nil:
 			fmt.Fprintf(os.Stderr, "Warning: Failed to generate Anki file: %v\n", err)
-		} else {
+		default:
 			fmt.Printf("Anki package created: %s\n", outputPath)
 		}
 	}
 
+	if flags.AnkiConnect {
+		if err := pushToAnkiConnect(proc, flags.DeckName); err != nil {
+			return err
+		}
+	}
+
 	fmt.Printf("\nDone! Materials saved to: %s\n", flags.OutputDir)
 	return nil
 }
 
+// pushToAnkiConnect adds the cards to a running Anki and prints one line per
+// card. Cards that failed do not fail the command; Anki being unreachable
+// does.
+func pushToAnkiConnect(proc *processor.Processor, deckName string) error {
+	fmt.Printf("\nAdding cards to Anki deck %q via AnkiConnect...\n", deckName)
+	results, err := proc.PushToAnkiConnect(context.Background())
+	if errors.Is(err, processor.ErrNothingToExport) {
+		fmt.Println("No new or changed cards to export.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export to AnkiConnect: %w", err)
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "  %-8s %s: %v\n", result.Status, result.Word, result.Err)
+			continue
+		}
+		fmt.Printf("  %-8s %s\n", result.Status, result.Word)
+	}
+	fmt.Printf("AnkiConnect: %s\n", ankiconnect.Summary(results))
+	return nil
+}
+
+// dumpTemplates writes the built-in card templates to dir and explains where
+// edited copies go.
+func dumpTemplates(dir string) error {
+	written, skipped, err := anki.DumpTemplates(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d template file(s) to %s\n", len(written), dir)
+	for _, name := range skipped {
+		fmt.Printf("  Kept existing %s\n", name)
+	}
+	if overrideDir, err := anki.TemplateDir(); err == nil {
+		fmt.Printf("Copy the files you change to %s to use them in exports.\n", overrideDir)
+	}
+	return nil
+}
+
+// inspectAPKG prints the inspection report of an .apkg file. Errors in the
+// package fail the command so scripts can check packages before importing
+// them; warnings do not.
+func inspectAPKG(path string, asJSON bool) error {
+	report, err := anki.InspectAPKG(path)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	if n := report.Errors(); n > 0 {
+		return fmt.Errorf("%s has %d error(s)", path, n)
+	}
+	return nil
+}
+
+// importAPKG creates card directories in the output directory from the notes
+// of an APKG file and prints what happened to each note. Notes that could not
+// be imported fail the command once all others are done.
+func importAPKG(flags *cli.Flags) error {
+	fields, err := anki.ParseFieldMapping(flags.ImportFields)
+	if err != nil {
+		return fmt.Errorf("invalid --import-fields: %w", err)
+	}
+	if err := os.MkdirAll(flags.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	result, err := anki.ImportAPKG(flags.ImportAPKG, store.New(flags.OutputDir), anki.ImportOptions{Fields: fields})
+	if err != nil {
+		return err
+	}
+
+	if flags.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Importing %s into: %s\n", flags.ImportAPKG, flags.OutputDir)
+		for _, note := range result.Notes {
+			label := note.Word
+			if label == "" {
+				label = fmt.Sprintf("note %d", note.NoteID)
+			}
+			line := fmt.Sprintf("  %-8s %s", note.Status, label)
+			if len(note.Media) > 0 {
+				line += fmt.Sprintf(" (%s)", strings.Join(note.Media, ", "))
+			}
+			if note.Message != "" {
+				line += ": " + note.Message
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("Imported %d note(s), skipped %d, %d failed.\n",
+			result.Count(anki.ImportStatusImported), result.Count(anki.ImportStatusSkipped), result.Count(anki.ImportStatusFailed))
+		if result.Count(anki.ImportStatusImported) > 0 {
+			fmt.Println("Run totalrecall --retry-failed-assets to generate the audio and images the imported cards lack.")
+		}
+	}
+
+	if n := result.Count(anki.ImportStatusFailed); n > 0 {
+		return fmt.Errorf("failed to import %d note(s)", n)
+	}
+	return nil
+}
+
+// migrateCards upgrades every card directory under outputDir to the current
+// card.json manifest format and prints a summary. Individual failures are
+// listed but only fail the command once every other card has been migrated.
+func migrateCards(outputDir string) error {
+	fmt.Printf("Migrating cards in: %s\n", outputDir)
+	report := store.New(outputDir).MigrateManifests()
+
+	for _, err := range report.Errors {
+		fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+	}
+	fmt.Printf("Migrated %d card(s), %d already up to date, %d failed.\n", report.Migrated, report.Current, report.Failed)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("failed to migrate %d card(s)", report.Failed)
+	}
+	return nil
+}
+
+// listRevisions prints every stored asset revision of word, marking the
+// selected one with an asterisk.
+func listRevisions(outputDir, word string) error {
+	cardDir := store.FindCardDirectory(outputDir, word)
+	if cardDir == "" {
+		return fmt.Errorf("no card found for %q in %s", word, outputDir)
+	}
+
+	m := store.LoadManifest(cardDir)
+	slots := m.RevisionSlots()
+	if len(slots) == 0 {
+		fmt.Printf("No stored revisions for %s.\n", word)
+		return nil
+	}
+
+	for _, slot := range slots {
+		fmt.Printf("%s:\n", slot)
+		selected := m.SelectedRevision(slot)
+		for _, rev := range m.RevisionsOf(slot) {
+			marker := " "
+			if rev.Number == selected {
+				marker = "*"
+			}
+			fmt.Printf("  %s %d  %s  %s\n", marker, rev.Number, rev.Asset.CreatedAt.Local().Format("2006-01-02 15:04"), revisionSummary(rev.Asset))
+		}
+	}
+	return nil
+}
+
+// revisionSummary describes how an asset revision was generated.
+func revisionSummary(asset store.Asset) string {
+	parts := []string{asset.File}
+	for _, detail := range []string{asset.Provider, asset.Model, asset.Voice} {
+		if detail != "" {
+			parts = append(parts, detail)
+		}
+	}
+	summary := strings.Join(parts, ", ")
+	if asset.Prompt != "" {
+		summary += fmt.Sprintf(" - %q", asset.Prompt)
+	}
+	return summary
+}
+
+// restoreRevision makes revision number of slot the selected asset of word.
+func restoreRevision(outputDir, word, slot string, number int) error {
+	cardDir := store.FindCardDirectory(outputDir, word)
+	if cardDir == "" {
+		return fmt.Errorf("no card found for %q in %s", word, outputDir)
+	}
+
+	rev, err := store.RestoreRevision(cardDir, slot, number)
+	if err != nil {
+		return fmt.Errorf("failed to restore revision: %w", err)
+	}
+	fmt.Printf("Restored %s revision %d of %s as %s\n", slot, rev.Number, word, rev.Asset.File)
+	return nil
+}
+
+// archiverFor applies the configured archive format (--archive-format or
+// archive.format in the config file) to the default archiver. Injected
+// archivers are used as they are.
+func archiverFor(archiver archive.Archiver) (archive.Archiver, error) {
+	defaultArchiver, ok := archiver.(archive.DefaultArchiver)
+	if !ok {
+		return archiver, nil
+	}
+
+	name := strings.TrimSpace(viper.GetString("archive.format"))
+	if name == "" {
+		return archiver, nil
+	}
+	format, err := archive.ParseFormat(name)
+	if err != nil {
+		return nil, err
+	}
+	defaultArchiver.Format = format
+	return defaultArchiver, nil
+}
+
+// manageArchives archives, lists, restores or prunes the archives of the
+// output directory. Archiving may be combined with pruning.
+func manageArchives(flags *cli.Flags, archiver archive.Archiver) error {
+	cardsDir := flags.OutputDir
+
+	switch {
+	case flags.Archive:
+		if err := archiver.ArchiveCards(cardsDir); err != nil {
+			return fmt.Errorf("failed to archive cards: %w", err)
+		}
+
+	case flags.RestoreArchive != "":
+		result, err := archiver.Restore(cardsDir, flags.RestoreArchive, archive.RestoreOptions{Merge: flags.RestoreMerge})
+		if err != nil {
+			return fmt.Errorf("failed to restore archive: %w", err)
+		}
+		fmt.Printf("Restored %d card(s) from %s into %s\n", len(result.Restored), result.Archive.Name, cardsDir)
+		if len(result.Skipped) > 0 {
+			fmt.Printf("Skipped %d card(s) that already exist: %s\n", len(result.Skipped), strings.Join(result.Skipped, ", "))
+		}
+
+	case flags.ListArchives:
+		archives, err := archiver.List(cardsDir)
+		if err != nil {
+			return err
+		}
+		if len(archives) == 0 {
+			fmt.Printf("No archives in %s\n", archive.ArchiveDir(cardsDir))
+		}
+		for _, arc := range archives {
+			fmt.Printf("%s  %-7s  %3d card(s)  %s\n", arc.CreatedAt.Format("2006-01-02 15:04"), arc.Format, len(arc.Words), arc.Name)
+			if len(arc.Words) > 0 {
+				fmt.Printf("    %s\n", strings.Join(arc.Words, ", "))
+			}
+		}
+	}
+
+	if flags.PruneArchives > 0 {
+		pruned, err := archiver.Prune(cardsDir, flags.PruneArchives)
+		for _, arc := range pruned {
+			fmt.Printf("Deleted archive %s\n", arc.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune archives: %w", err)
+		}
+	}
+	return nil
+}
+
+// manageTrash lists, restores or purges cards in the trash bin of the output
+// directory.
+func manageTrash(flags *cli.Flags) error {
+	cs := store.New(flags.OutputDir)
+
+	switch {
+	case flags.RestoreTrash != "":
+		entry, err := cs.FindTrashEntry(flags.RestoreTrash)
+		if err != nil {
+			return err
+		}
+		cardDir, err := cs.RestoreFromTrash(entry)
+		if errors.Is(err, store.ErrTrashConflict) {
+			return fmt.Errorf("%w; delete the current card of %q first", err, entry.Word)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %s to %s\n", entry.Word, cardDir)
+		return nil
+
+	case flags.PurgeTrash:
+		if flags.TrashRetentionDays < 0 {
+			return fmt.Errorf("--trash-retention must not be negative")
+		}
+		retention := time.Duration(flags.TrashRetentionDays) * 24 * time.Hour
+		purged, err := cs.PurgeTrash(time.Now().Add(-retention))
+		for _, entry := range purged {
+			fmt.Printf("Purged %s (%s)\n", entry.Name, entry.Word)
+		}
+		fmt.Printf("Purged %d card(s) deleted more than %d day(s) ago.\n", len(purged), flags.TrashRetentionDays)
+		return err
+	}
+
+	entries, err := cs.ListTrash()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The trash bin is empty.")
+		return nil
+	}
+	for _, entry := range entries {
+		word := entry.Word
+		if word == "" {
+			word = "(unknown word)"
+		}
+		fmt.Printf("%s  %-20s  %s\n", entry.DeletedAt.Format("2006-01-02 15:04"), word, entry.Name)
+	}
+	return nil
+}
+
+// listExports prints the export ledger of the output directory: every export
+// with its destination, filter and cards, oldest first.
+func listExports(outputDir string, asJSON bool) error {
+	ledger, err := store.New(outputDir).ExportLedger()
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(ledger)
+	}
+	if len(ledger.Exports) == 0 {
+		fmt.Printf("No exports recorded in %s.\n", outputDir)
+		return nil
+	}
+	for _, export := range ledger.Exports {
+		details := []string{export.Format, fmt.Sprintf("%d card(s)", len(export.Cards))}
+		if export.Deck != "" {
+			details = append(details, fmt.Sprintf("deck %q", export.Deck))
+		}
+		if export.Filter != "" {
+			details = append(details, export.Filter)
+		}
+		fmt.Printf("%s  %s (%s)\n", export.ExportedAt.Local().Format("2006-01-02 15:04"), export.Target(), strings.Join(details, ", "))
+
+		words := make([]string, 0, len(export.Cards))
+		for _, card := range export.Cards {
+			words = append(words, card.Word)
+		}
+		if len(words) > 0 {
+			fmt.Printf("  %s\n", strings.Join(words, ", "))
+		}
+	}
+	return nil
+}
+
 // runGUIMode launches the GUI application from the cmd/totalrecall package so
 // that the GUI factory is invoked from the composition root rather than from
 // the processor package, reducing the processor→gui import coupling.
@@ -139,6 +561,8 @@ func runGUIMode(proc *processor.Processor, flags *cli.Flags, deps runDeps) error
 		guiConfig.GoogleAPIKey = cli.GetGoogleAPIKey()
 	}
 
+	guiConfig.Archiver = deps.Archiver
+
 	app := deps.NewGUI(guiConfig)
 	app.Run()
 
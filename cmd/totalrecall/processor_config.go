@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/spf13/viper"
 
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/cli"
 	"codeberg.org/snonux/totalrecall/internal/processor"
 )
@@ -13,7 +17,43 @@ import (
 // returns a fully-resolved processor.Config. Centralising all Viper access
 // here means the processor package is free of any Viper dependency, which
 // improves testability and removes tight coupling to the global config singleton.
-func newProcessorConfig() *processor.Config {
+func newProcessorConfig() (*processor.Config, error) {
+	deckOptions, err := newDeckOptions()
+	if err != nil {
+		return nil, err
+	}
+	directions, err := internal.ParseCardDirections(viper.GetString("anki.card_directions"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid anki.card_directions: %w", err)
+	}
+	packageFormat, err := anki.ParsePackageFormat(viper.GetString("anki.apkg_format"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid anki.apkg_format: %w", err)
+	}
+	grouping, err := anki.ParseDeckGrouping(viper.GetString("anki.subdecks"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid anki.subdecks: %w", err)
+	}
+	fallbacks := viper.GetStringSlice("audio.fallback_providers")
+	if _, err := audio.ProviderChain("", fallbacks); err != nil {
+		return nil, fmt.Errorf("invalid audio.fallback_providers: %w", err)
+	}
+	postProcess := audio.PostProcessConfig{
+		LoudnessLUFS: viper.GetFloat64("audio.loudness_target"),
+		SilenceDB:    viper.GetFloat64("audio.silence_threshold"),
+		FadeMs:       viper.GetInt("audio.fade_ms"),
+	}
+	if err := postProcess.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid audio post-processing: %w", err)
+	}
+	slowSpeed := 0.0
+	if viper.GetBool("audio.slow_variant") {
+		slowSpeed = viper.GetFloat64("audio.slow_speed")
+		if err := audio.ValidateSlowSpeed(slowSpeed); err != nil {
+			return nil, fmt.Errorf("invalid audio.slow_speed: %w", err)
+		}
+	}
+
 	return &processor.Config{
 		// Translation & phonetic
 		TranslationProvider:    strings.TrimSpace(viper.GetString("translation.provider")),
@@ -33,6 +73,15 @@ func newProcessorConfig() *processor.Config {
 		OpenAISpeedSet:       viper.IsSet("audio.openai_speed"),
 		OpenAIInstruction:    viper.GetString("audio.openai_instruction"),
 		OpenAIInstructionSet: viper.IsSet("audio.openai_instruction"),
+		LocalTTSEngine:       strings.TrimSpace(viper.GetString("audio.local_engine")),
+		LocalTTSBinary:       strings.TrimSpace(viper.GetString("audio.local_binary")),
+		LocalTTSModel:        strings.TrimSpace(viper.GetString("audio.local_model")),
+		LocalTTSVoice:        strings.TrimSpace(viper.GetString("audio.local_voice")),
+		AudioFallback:        fallbacks,
+		AudioCacheDir:        strings.TrimSpace(viper.GetString("audio.cache_dir")),
+		AudioCacheSizeMB:     viper.GetInt("audio.cache_size_mb"),
+		AudioPostProcess:     postProcess,
+		SlowAudioSpeed:       slowSpeed,
 
 		// Image
 		ImageProvider:               strings.ToLower(strings.TrimSpace(viper.GetString("image.provider"))),
@@ -48,10 +97,63 @@ func newProcessorConfig() *processor.Config {
 		ImageNanoBananaModelSet:     viper.IsSet("image.nanobanana_model"),
 		ImageNanoBananaTextModel:    strings.TrimSpace(viper.GetString("image.nanobanana_text_model")),
 		ImageNanoBananaTextModelSet: viper.IsSet("image.nanobanana_text_model"),
+
+		// Anki
+		AnkiConnectURL: strings.TrimSpace(viper.GetString("anki.connect_url")),
+		DeckOptions:    &deckOptions,
+		CardDirections: directions,
+		PackageFormat:  packageFormat,
+		DeckGrouping:   grouping,
+	}, nil
+}
+
+// newDeckOptions reads the deck options of APKG exports. The flag defaults
+// are the anki package defaults, so unset keys keep those. learning_steps
+// may be a string ("1m 10m") or a YAML list in the config file.
+func newDeckOptions() (anki.DeckOptions, error) {
+	steps, err := anki.ParseLearningSteps(strings.Join(viper.GetStringSlice("anki.learning_steps"), " "))
+	if err != nil {
+		return anki.DeckOptions{}, fmt.Errorf("invalid anki.learning_steps: %w", err)
+	}
+
+	options := anki.DeckOptions{
+		NewPerDay:      viper.GetInt("anki.new_per_day"),
+		ReviewsPerDay:  viper.GetInt("anki.reviews_per_day"),
+		LearningSteps:  steps,
+		BurySiblings:   viper.GetBool("anki.bury_siblings"),
+		ReverseSameDay: viper.GetBool("anki.reverse_same_day"),
 	}
+	if err := options.Validate(); err != nil {
+		return anki.DeckOptions{}, fmt.Errorf("invalid deck options: %w", err)
+	}
+	return options, nil
 }
 
 // newProcessor builds a processor from CLI flags and the Viper-backed config.
-func newProcessor(flags *cli.Flags) *processor.Processor {
-	return processor.NewProcessor(flags, newProcessorConfig())
+func newProcessor(flags *cli.Flags) (*processor.Processor, error) {
+	config, err := newProcessorConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.ExportFilter, err = newExportFilter(flags); err != nil {
+		return nil, err
+	}
+	return processor.NewProcessor(flags, config), nil
+}
+
+// newExportFilter builds the card selection of exports from the
+// --since-last-export, --changed-since and --only-tag flags.
+func newExportFilter(flags *cli.Flags) (anki.ExportFilter, error) {
+	filter := anki.ExportFilter{
+		SinceLastExport: flags.SinceLastExport,
+		Tag:             strings.TrimSpace(flags.OnlyTag),
+	}
+	if flags.ChangedSince != "" {
+		since, err := anki.ParseExportDate(flags.ChangedSince)
+		if err != nil {
+			return anki.ExportFilter{}, fmt.Errorf("invalid --changed-since: %w", err)
+		}
+		filter.ChangedSince = since
+	}
+	return filter, nil
 }
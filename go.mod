@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	fyne.io/fyne/v2 v2.6.1
 	github.com/dweymouth/fyne-tooltip v0.3.3
+	github.com/klauspost/compress v1.18.0
 	github.com/magefile/mage v1.15.0
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/sashabaranov/go-openai v1.40.5
@@ -13,6 +14,7 @@ require (
 	github.com/spf13/pflag v1.0.6
 	github.com/spf13/viper v1.20.1
 	google.golang.org/genai v1.52.1
+	google.golang.org/protobuf v1.36.1
 )
 
 require (
@@ -74,6 +76,5 @@ require (
 	golang.org/x/text v0.23.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241223144023-3abc09e42ca8 // indirect
 	google.golang.org/grpc v1.67.3 // indirect
-	google.golang.org/protobuf v1.36.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -7,8 +7,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
+
+	"codeberg.org/snonux/totalrecall/internal"
 )
 
 // APKGGenerator creates Anki package files (.apkg)
@@ -17,12 +20,21 @@ type APKGGenerator struct {
 	deckID       int64
 	modelID      int64
 	modelIDBgBg  int64 // Separate model for bg-bg cards
+	modelIDCloze int64 // Cloze model for sentence cards
+	deckConfID   int64 // Deck options preset of the deck
+	deckOptions  DeckOptions
+	directions   internal.CardDirections // Directions of cards without their own
+	format       PackageFormat
+	grouping     DeckGrouping
 	cards        []Card
+	packagePath  string         // file written by the last GenerateAPKG
 	mediaFiles   map[string]int // maps original filename to media number
 	mediaCounter int
 
-	schemer   *SQLiteSchemer
-	packager  *ZipPackager
+	schemer  *SQLiteSchemer
+	packager *ZipPackager
+	// templates is loaded on first use because user overrides may be
+	// invalid, which is reported as an export error.
 	templates *CardTemplate
 }
 
@@ -37,12 +49,16 @@ func NewAPKGGenerator(deckName string) *APKGGenerator {
 		deckID:       deckID,
 		modelID:      stableID(deckName + "/model/en-bg"),
 		modelIDBgBg:  stableID(deckName + "/model/bg-bg"),
+		modelIDCloze: stableID(deckName + "/model/sentence"),
+		deckConfID:   stableID(deckName + "/deck-options"),
+		deckOptions:  DefaultDeckOptions(),
+		format:       DefaultPackageFormat,
+		grouping:     DeckGroupingNone,
 		cards:        make([]Card, 0),
 		mediaFiles:   make(map[string]int),
 		mediaCounter: 0,
 		schemer:      NewSQLiteSchemer(),
 		packager:     NewZipPackager(),
-		templates:    MustCardTemplate(),
 	}
 }
 
@@ -60,6 +76,68 @@ func stableID(seed string) int64 {
 	return lo + v%(hi-lo+1)
 }
 
+// SetDeckOptions replaces the scheduling options written into the deck
+// options preset of the package.
+func (g *APKGGenerator) SetDeckOptions(options DeckOptions) {
+	g.deckOptions = options
+}
+
+// SetCardDirections sets the directions of the cards that do not choose
+// their own; the zero value means internal.DefaultCardDirections.
+func (g *APKGGenerator) SetCardDirections(directions internal.CardDirections) {
+	g.directions = directions
+}
+
+// SetPackageFormat selects the layout of the package; the empty format
+// means DefaultPackageFormat.
+func (g *APKGGenerator) SetPackageFormat(format PackageFormat) {
+	if format == "" {
+		format = DefaultPackageFormat
+	}
+	g.format = format
+}
+
+// SetDeckGrouping selects the subdecks the cards are put into; the empty
+// grouping means DeckGroupingNone.
+func (g *APKGGenerator) SetDeckGrouping(grouping DeckGrouping) {
+	if grouping == "" {
+		grouping = DeckGroupingNone
+	}
+	g.grouping = grouping
+}
+
+// deckOf returns the full name of the deck card goes into.
+func (g *APKGGenerator) deckOf(card Card) string {
+	return g.grouping.DeckFor(g.deckName, card)
+}
+
+// deckIDOf returns the ID of the deck named name. Subdeck IDs are derived
+// from their full name like the ID of the deck itself, so re-exports keep
+// them.
+func (g *APKGGenerator) deckIDOf(name string) int64 {
+	if name == g.deckName {
+		return g.deckID
+	}
+	return stableID(name)
+}
+
+// deckNames returns the deck followed by the subdecks the cards go into in
+// alphabetical order, including the intermediate levels Anki needs.
+func (g *APKGGenerator) deckNames() []string {
+	seen := map[string]bool{g.deckName: true}
+	names := []string{g.deckName}
+	for _, card := range g.cards {
+		for _, name := range deckAncestors(g.deckOf(card)) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
 // AddCard adds a card to the generator
 func (g *APKGGenerator) AddCard(card Card) {
 	g.cards = append(g.cards, card)
@@ -67,6 +145,10 @@ func (g *APKGGenerator) AddCard(card Card) {
 
 // GenerateAPKG creates an .apkg file
 func (g *APKGGenerator) GenerateAPKG(outputPath string) error {
+	if err := g.deckOptions.Validate(); err != nil {
+		return fmt.Errorf("invalid deck options: %w", err)
+	}
+
 	// Create temporary directory for building the package
 	tempDir, err := os.MkdirTemp("", "anki_export_*")
 	if err != nil {
@@ -92,6 +174,12 @@ func (g *APKGGenerator) GenerateAPKG(outputPath string) error {
 		return fmt.Errorf("failed to create database: %w", err)
 	}
 
+	if g.format == PackageFormatAnki21b {
+		if err := g.convertToAnki21b(tempDir); err != nil {
+			return fmt.Errorf("failed to create %s package: %w", PackageFormatAnki21b, err)
+		}
+	}
+
 	// Create the .apkg zip file with a timestamped name
 	timestamp := time.Now().Format("2006-01-02-15:04:05")
 	safeDeckName := strings.ReplaceAll(g.deckName, " ", "_")
@@ -105,10 +193,25 @@ func (g *APKGGenerator) GenerateAPKG(outputPath string) error {
 		return fmt.Errorf("failed to create zip package: %w", err)
 	}
 
+	g.packagePath = finalPath
 	return nil
 }
 
+// PackagePath returns the file written by the last GenerateAPKG call: the
+// package is named after the deck, the time and the card count rather than
+// the requested output path.
+func (g *APKGGenerator) PackagePath() string {
+	return g.packagePath
+}
+
 func (g *APKGGenerator) createDatabase(dbPath string) error {
+	if g.templates == nil {
+		tmpl, err := NewCardTemplate()
+		if err != nil {
+			return err
+		}
+		g.templates = tmpl
+	}
 	return g.schemer.CreateDatabase(dbPath, g, g.templates)
 }
 
@@ -119,52 +222,28 @@ func (g *APKGGenerator) createZipPackage(tempDir, outputPath string) error {
 // copyMediaFiles copies media files and assigns them numbers
 func (g *APKGGenerator) copyMediaFiles(tempDir string) error {
 	for _, card := range g.cards {
-		// Copy audio file (front audio for bg-bg, only audio for en-bg)
-		if card.AudioFile != "" && fileExists(card.AudioFile) {
-			cardDirID := filepath.Base(filepath.Dir(card.AudioFile))
-			originalFilename := filepath.Base(card.AudioFile)
-			uniqueFilename := fmt.Sprintf("%s_%s", cardDirID, originalFilename)
-
-			if _, exists := g.mediaFiles[uniqueFilename]; !exists {
-				targetPath := filepath.Join(tempDir, fmt.Sprintf("%d", g.mediaCounter))
-				if err := copyFile(card.AudioFile, targetPath); err != nil {
-					return fmt.Errorf("failed to copy audio file %s: %w", card.AudioFile, err)
-				}
-				g.mediaFiles[uniqueFilename] = g.mediaCounter
-				g.mediaCounter++
-			}
+		files := []struct{ path, label string }{
+			{card.AudioFile, "audio"}, // front audio for bg-bg, only audio for en-bg
+			{card.AudioFileBack, "back audio"},
+			{card.AudioFileSlow, "slow audio"},
+			{card.AudioFileBackSlow, "slow back audio"},
+			{card.ImageFile, "image"},
 		}
-
-		// Copy back audio file (only for bg-bg cards)
-		if card.AudioFileBack != "" && fileExists(card.AudioFileBack) {
-			cardDirID := filepath.Base(filepath.Dir(card.AudioFileBack))
-			originalFilename := filepath.Base(card.AudioFileBack)
-			uniqueFilename := fmt.Sprintf("%s_%s", cardDirID, originalFilename)
-
-			if _, exists := g.mediaFiles[uniqueFilename]; !exists {
-				targetPath := filepath.Join(tempDir, fmt.Sprintf("%d", g.mediaCounter))
-				if err := copyFile(card.AudioFileBack, targetPath); err != nil {
-					return fmt.Errorf("failed to copy back audio file %s: %w", card.AudioFileBack, err)
-				}
-				g.mediaFiles[uniqueFilename] = g.mediaCounter
-				g.mediaCounter++
+		for _, file := range files {
+			if file.path == "" || !fileExists(file.path) {
+				continue
 			}
-		}
 
-		// Copy image file
-		if card.ImageFile != "" && fileExists(card.ImageFile) {
-			cardDirID := filepath.Base(filepath.Dir(card.ImageFile))
-			originalFilename := filepath.Base(card.ImageFile)
-			uniqueFilename := fmt.Sprintf("%s_%s", cardDirID, originalFilename)
-
-			if _, exists := g.mediaFiles[uniqueFilename]; !exists {
-				targetPath := filepath.Join(tempDir, fmt.Sprintf("%d", g.mediaCounter))
-				if err := copyFile(card.ImageFile, targetPath); err != nil {
-					return fmt.Errorf("failed to copy image file %s: %w", card.ImageFile, err)
-				}
-				g.mediaFiles[uniqueFilename] = g.mediaCounter
-				g.mediaCounter++
+			uniqueFilename := MediaFileName(file.path)
+			if _, exists := g.mediaFiles[uniqueFilename]; exists {
+				continue
+			}
+			targetPath := filepath.Join(tempDir, fmt.Sprintf("%d", g.mediaCounter))
+			if err := copyFile(file.path, targetPath); err != nil {
+				return fmt.Errorf("failed to copy %s file %s: %w", file.label, file.path, err)
 			}
+			g.mediaFiles[uniqueFilename] = g.mediaCounter
+			g.mediaCounter++
 		}
 	}
 
@@ -3,9 +3,16 @@ package anki
 import (
 	"archive/zip"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal"
 )
 
 func TestNewAPKGGenerator(t *testing.T) {
@@ -99,6 +106,8 @@ func TestGenerateAPKG(t *testing.T) {
 	}
 
 	gen := NewAPKGGenerator("Test Bulgarian Deck")
+	// The anki21b layout is covered by TestGenerateAPKGAnki21b.
+	gen.SetPackageFormat(PackageFormatLegacy)
 
 	// Add a test card
 	gen.AddCard(Card{
@@ -216,6 +225,434 @@ func TestCreateDatabase(t *testing.T) {
 	}
 }
 
+// TestCreateDatabaseWritesTags checks that card tags reach the note and the
+// collection's tag registry.
+func TestCreateDatabaseWritesTags(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat", Tags: []string{"lesson-1", "animals"}})
+	gen.AddCard(Card{Bulgarian: "куче", Translation: "dog"})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var tags string
+	if err := db.QueryRow("SELECT tags FROM notes WHERE sfld = ?", "котка").Scan(&tags); err != nil {
+		t.Fatalf("query note tags: %v", err)
+	}
+	if tags != " animals lesson-1 " {
+		t.Errorf("note tags = %q; want %q", tags, " animals lesson-1 ")
+	}
+	if err := db.QueryRow("SELECT tags FROM notes WHERE sfld = ?", "куче").Scan(&tags); err != nil {
+		t.Fatalf("query note tags: %v", err)
+	}
+	if tags != "" {
+		t.Errorf("untagged note tags = %q; want empty", tags)
+	}
+
+	var registry string
+	if err := db.QueryRow("SELECT tags FROM col").Scan(&registry); err != nil {
+		t.Fatalf("query col tags: %v", err)
+	}
+	if registry != `{"animals":0,"lesson-1":0}` {
+		t.Errorf("col tags = %s", registry)
+	}
+}
+
+// TestCreateDatabaseWritesPronunciationFields checks that every note has as
+// many fields as its note type declares and that IPA, transliteration and the
+// example land in their own fields rather than in Notes.
+func TestCreateDatabaseWritesPronunciationFields(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat", IPA: "[ˈkɔtkɐ]", Transliteration: "kotka",
+		Example: "Котката спи.", ExampleTranslation: "The cat is sleeping."})
+	gen.AddCard(Card{Bulgarian: "куче", Translation: "животно", CardType: "bg-bg", IPA: "[ˈkutʃɛ]", Transliteration: "kuche"})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var modelsJSON string
+	if err := db.QueryRow("SELECT models FROM col").Scan(&modelsJSON); err != nil {
+		t.Fatalf("query models: %v", err)
+	}
+	var models map[string]struct {
+		Flds []struct{ Name string } `json:"flds"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		t.Fatalf("decode models: %v", err)
+	}
+
+	tests := []struct {
+		word string
+		want map[string]string
+	}{
+		{"котка", map[string]string{"Notes": "", "IPA": "[ˈkɔtkɐ]", "Transliteration": "kotka",
+			"Example": "Котката спи.", "ExampleTranslation": "The cat is sleeping."}},
+		{"куче", map[string]string{"Notes": "", "IPA": "[ˈkutʃɛ]", "Transliteration": "kuche", "Example": ""}},
+	}
+	for _, tt := range tests {
+		var mid int64
+		var flds string
+		if err := db.QueryRow("SELECT mid, flds FROM notes WHERE sfld = ?", tt.word).Scan(&mid, &flds); err != nil {
+			t.Fatalf("query note %s: %v", tt.word, err)
+		}
+		model := models[fmt.Sprintf("%d", mid)]
+		values := strings.Split(flds, "\x1f")
+		if len(values) != len(model.Flds) {
+			t.Fatalf("%s: %d field values for %d note type fields", tt.word, len(values), len(model.Flds))
+		}
+		for i, field := range model.Flds {
+			if want, ok := tt.want[field.Name]; ok && values[i] != want {
+				t.Errorf("%s: field %s = %q; want %q", tt.word, field.Name, values[i], want)
+			}
+		}
+	}
+}
+
+// TestCreateDatabaseWritesClozeCards checks that a sentence card becomes a
+// note of the cloze note type with one card per cloze number.
+func TestCreateDatabaseWritesClozeCards(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat"})
+	gen.AddCard(Card{Bulgarian: "Аз обичам ябълки.", Translation: "I love apples.", CardType: "sentence",
+		Cloze: "{{c1::Аз}} обичам {{c3::ябълки}}."})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var modelsJSON string
+	if err := db.QueryRow("SELECT models FROM col").Scan(&modelsJSON); err != nil {
+		t.Fatalf("query models: %v", err)
+	}
+	var models map[string]struct {
+		Name string `json:"name"`
+		Type int    `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+		t.Fatalf("decode models: %v", err)
+	}
+
+	var noteID, mid int64
+	var flds string
+	if err := db.QueryRow("SELECT id, mid, flds FROM notes WHERE sfld = ?", "Аз обичам ябълки.").Scan(&noteID, &mid, &flds); err != nil {
+		t.Fatalf("query sentence note: %v", err)
+	}
+	model := models[fmt.Sprintf("%d", mid)]
+	if model.Name != SentenceNoteTypeName || model.Type != 1 {
+		t.Errorf("sentence note model = %q (type %d); want %q (type 1)", model.Name, model.Type, SentenceNoteTypeName)
+	}
+	values := strings.Split(flds, "\x1f")
+	if values[0] != "{{c1::Аз}} обичам {{c3::ябълки}}." || values[1] != "Аз обичам ябълки." {
+		t.Errorf("sentence note fields = %q", values)
+	}
+
+	rows, err := db.Query("SELECT ord FROM cards WHERE nid = ? ORDER BY ord", noteID)
+	if err != nil {
+		t.Fatalf("query cards: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var ords []int
+	for rows.Next() {
+		var ord int
+		if err := rows.Scan(&ord); err != nil {
+			t.Fatalf("scan card: %v", err)
+		}
+		ords = append(ords, ord)
+	}
+	if !slices.Equal(ords, []int{0, 2}) {
+		t.Errorf("cloze card ords = %v; want [0 2]", ords)
+	}
+}
+
+// TestCreateDatabaseWritesCardDirections checks that the export-wide
+// directions apply to cards without their own and that the cards rows and
+// direction fields follow the effective directions.
+func TestCreateDatabaseWritesCardDirections(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.SetCardDirections(internal.CardDirections{Forward: true})
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat"})
+	gen.AddCard(Card{Bulgarian: "куче", Translation: "животно", CardType: "bg-bg",
+		Directions: internal.CardDirections{Reverse: true, TypeIn: true}})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tests := []struct {
+		word     string
+		cardType string
+		wantOrds []int
+		wantFlds []string // NoForward, NoReverse, TypeIn
+	}{
+		{"котка", "en-bg", []int{0}, []string{"", "y", ""}},
+		{"куче", "bg-bg", []int{1, 2}, []string{"y", "", "y"}},
+	}
+	for _, tt := range tests {
+		var noteID int64
+		var flds string
+		if err := db.QueryRow("SELECT id, flds FROM notes WHERE sfld = ?", tt.word).Scan(&noteID, &flds); err != nil {
+			t.Fatalf("query note %s: %v", tt.word, err)
+		}
+		values := strings.Split(flds, "\x1f")
+		first := slices.Index(noteTypeFieldNames(tt.cardType), "NoForward")
+		if got := values[first : first+3]; !slices.Equal(got, tt.wantFlds) {
+			t.Errorf("%s: direction fields = %q; want %q", tt.word, got, tt.wantFlds)
+		}
+
+		rows, err := db.Query("SELECT ord FROM cards WHERE nid = ? ORDER BY ord", noteID)
+		if err != nil {
+			t.Fatalf("query cards: %v", err)
+		}
+		var ords []int
+		for rows.Next() {
+			var ord int
+			if err := rows.Scan(&ord); err != nil {
+				t.Fatalf("scan card: %v", err)
+			}
+			ords = append(ords, ord)
+		}
+		_ = rows.Close()
+		if !slices.Equal(ords, tt.wantOrds) {
+			t.Errorf("%s: card ords = %v; want %v", tt.word, ords, tt.wantOrds)
+		}
+	}
+}
+
+// TestCreateDatabaseWritesSubdecks checks that cards land in the subdeck of
+// their section, whose ID is derived from its full name.
+func TestCreateDatabaseWritesSubdecks(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.SetDeckGrouping(DeckGroupingSection)
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat", Section: "Lesson 3::Animals"})
+	gen.AddCard(Card{Bulgarian: "хляб", Translation: "bread"})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var decksJSON string
+	if err := db.QueryRow("SELECT decks FROM col").Scan(&decksJSON); err != nil {
+		t.Fatalf("query col: %v", err)
+	}
+	var decks map[string]struct {
+		Name string `json:"name"`
+		Conf int64  `json:"conf"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		t.Fatalf("decode decks: %v", err)
+	}
+	for _, name := range []string{"Test Deck", "Test Deck::Lesson 3", "Test Deck::Lesson 3::Animals"} {
+		deck, ok := decks[fmt.Sprintf("%d", stableID(name))]
+		if !ok || deck.Name != name {
+			t.Errorf("decks has no %q with ID stableID(name): %s", name, decksJSON)
+			continue
+		}
+		if deck.Conf != gen.deckConfID {
+			t.Errorf("%s: conf = %d; want the deck's preset %d", name, deck.Conf, gen.deckConfID)
+		}
+	}
+
+	want := map[string]int64{
+		"котка": stableID("Test Deck::Lesson 3::Animals"),
+		"хляб":  gen.deckID,
+	}
+	for word, wantDeck := range want {
+		var did int64
+		if err := db.QueryRow("SELECT c.did FROM cards c JOIN notes n ON c.nid = n.id WHERE n.sfld = ? LIMIT 1", word).Scan(&did); err != nil {
+			t.Fatalf("query card %s: %v", word, err)
+		}
+		if did != wantDeck {
+			t.Errorf("%s: did = %d; want %d", word, did, wantDeck)
+		}
+	}
+}
+
+// TestCreateDatabaseWritesDeckOptions checks that the deck points to its own
+// options preset and that the preset carries the configured scheduling.
+func TestCreateDatabaseWritesDeckOptions(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.anki2")
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.SetDeckOptions(DeckOptions{
+		NewPerDay:      7,
+		ReviewsPerDay:  250,
+		LearningSteps:  []time.Duration{30 * time.Second, time.Hour},
+		BurySiblings:   false,
+		ReverseSameDay: true,
+	})
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat"})
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatalf("createDatabase() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var decksJSON, dconfJSON string
+	if err := db.QueryRow("SELECT decks, dconf FROM col").Scan(&decksJSON, &dconfJSON); err != nil {
+		t.Fatalf("query col: %v", err)
+	}
+	var decks map[string]struct {
+		Conf int64 `json:"conf"`
+	}
+	if err := json.Unmarshal([]byte(decksJSON), &decks); err != nil {
+		t.Fatalf("decode decks: %v", err)
+	}
+	type queueConf struct {
+		PerDay int       `json:"perDay"`
+		Delays []float64 `json:"delays"`
+		Bury   bool      `json:"bury"`
+	}
+	var dconf map[string]struct {
+		Name string    `json:"name"`
+		New  queueConf `json:"new"`
+		Rev  queueConf `json:"rev"`
+	}
+	if err := json.Unmarshal([]byte(dconfJSON), &dconf); err != nil {
+		t.Fatalf("decode dconf: %v", err)
+	}
+
+	confID := decks[fmt.Sprintf("%d", gen.deckID)].Conf
+	if confID == 1 {
+		t.Fatal("deck uses the Default options preset")
+	}
+	conf, ok := dconf[fmt.Sprintf("%d", confID)]
+	if !ok {
+		t.Fatalf("dconf has no preset %d", confID)
+	}
+	if conf.Name != "Test Deck" {
+		t.Errorf("preset name = %q; want the deck name", conf.Name)
+	}
+	if conf.New.PerDay != 7 || conf.Rev.PerDay != 250 {
+		t.Errorf("limits = %d new, %d reviews; want 7, 250", conf.New.PerDay, conf.Rev.PerDay)
+	}
+	if !slices.Equal(conf.New.Delays, []float64{0.5, 60}) {
+		t.Errorf("learning steps = %v; want [0.5 60]", conf.New.Delays)
+	}
+	if conf.New.Bury || conf.Rev.Bury {
+		t.Errorf("bury new = %v, review = %v; want both off", conf.New.Bury, conf.Rev.Bury)
+	}
+	if dconf["1"].New.PerDay != DefaultDeckOptions().NewPerDay {
+		t.Errorf("Default preset was changed: %+v", dconf["1"])
+	}
+}
+
+func TestGenerateAPKGRejectsInvalidDeckOptions(t *testing.T) {
+	gen := NewAPKGGenerator("Test Deck")
+	options := DefaultDeckOptions()
+	options.NewPerDay = -1
+	gen.SetDeckOptions(options)
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat"})
+
+	err := gen.GenerateAPKG(filepath.Join(t.TempDir(), "test.apkg"))
+	if err == nil || !strings.Contains(err.Error(), "new cards per day") {
+		t.Errorf("GenerateAPKG() error = %v; want invalid deck options", err)
+	}
+}
+
+// noteRow is the identity and mod time of an exported note and its cards.
+type noteRow struct {
+	noteID, noteMod int64
+	guid            string
+	cardIDs         string
+	cardMods        string
+}
+
+func readNoteRow(t *testing.T, dbPath, word string) noteRow {
+	t.Helper()
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var row noteRow
+	if err := db.QueryRow("SELECT id, mod, guid FROM notes WHERE sfld = ?", word).Scan(&row.noteID, &row.noteMod, &row.guid); err != nil {
+		t.Fatalf("query note %s: %v", word, err)
+	}
+	if err := db.QueryRow("SELECT group_concat(id), group_concat(mod) FROM (SELECT id, mod FROM cards WHERE nid = ? ORDER BY ord)", row.noteID).Scan(&row.cardIDs, &row.cardMods); err != nil {
+		t.Fatalf("query cards of %s: %v", word, err)
+	}
+	return row
+}
+
+// TestReExportKeepsIDsAndModTimes exports the same cards twice: unchanged
+// cards must produce identical rows, an edited card only a newer mod time.
+func TestReExportKeepsIDsAndModTimes(t *testing.T) {
+	edited := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	export := func(catModified time.Time) string {
+		dbPath := filepath.Join(t.TempDir(), "collection.anki2")
+		gen := NewAPKGGenerator("Test Deck")
+		gen.AddCard(Card{Bulgarian: "котка", Translation: "cat", Modified: catModified})
+		gen.AddCard(Card{Bulgarian: "куче", Translation: "dog", Modified: edited})
+		if err := gen.createDatabase(dbPath); err != nil {
+			t.Fatalf("createDatabase() error = %v", err)
+		}
+		return dbPath
+	}
+
+	first := export(edited)
+	second := export(edited)
+	third := export(edited.Add(time.Hour))
+
+	for _, word := range []string{"котка", "куче"} {
+		if a, b := readNoteRow(t, first, word), readNoteRow(t, second, word); a != b {
+			t.Errorf("%s: re-export changed %+v to %+v", word, a, b)
+		}
+	}
+
+	before, after := readNoteRow(t, first, "котка"), readNoteRow(t, third, "котка")
+	if after.noteMod != edited.Add(time.Hour).Unix() || before.noteMod != edited.Unix() {
+		t.Errorf("note mod = %d then %d; want the card's Modified time", before.noteMod, after.noteMod)
+	}
+	if before.noteID != after.noteID || before.guid != after.guid || before.cardIDs != after.cardIDs {
+		t.Errorf("editing a card changed its identity: %+v vs %+v", before, after)
+	}
+	if dog := readNoteRow(t, third, "куче"); dog != readNoteRow(t, first, "куче") {
+		t.Errorf("editing котка changed the untouched куче note: %+v", dog)
+	}
+}
+
 func TestMarshalJSONReturnsErrorForUnsupportedValue(t *testing.T) {
 	if _, err := marshalJSON("bad", make(chan int)); err == nil {
 		t.Fatal("marshalJSON() error = nil, want error")
@@ -3,14 +3,24 @@ package anki
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // ResolveAudioPaths returns the matching audio files for a logical base name.
-// It prefers multi-voice outputs (audio_<voice>.<ext>) over a stale single file
-// and uses metadata hints before falling back to common formats.
+// Files recorded in the card manifest win. For cards without recorded audio
+// it prefers multi-voice outputs (audio_<voice>.<ext>) over a stale single
+// file and uses metadata hints before falling back to common formats. Slow
+// clips (audio_slow.<ext>) only match their own base name.
 func ResolveAudioPaths(wordDir, baseName, preferredFormat string) []string {
+	kind := store.AssetKind(baseName)
+	if paths := store.LoadManifest(wordDir).AssetPaths(wordDir, kind); len(paths) > 0 {
+		return paths
+	}
+
 	formats := audioFormatsToTry(wordDir, preferredFormat)
 
 	// Prefer voice-specific files so multi-voice output wins over any stale
@@ -18,7 +28,13 @@ func ResolveAudioPaths(wordDir, baseName, preferredFormat string) []string {
 	for _, format := range formats {
 		globPattern := filepath.Join(wordDir, baseName+"_*."+format)
 		matches, err := filepath.Glob(globPattern)
-		if err == nil && len(matches) > 0 {
+		if err != nil {
+			continue
+		}
+		matches = slices.DeleteFunc(matches, func(path string) bool {
+			return store.AssetKindForFile(path) != kind
+		})
+		if len(matches) > 0 {
 			sort.Strings(matches)
 			return matches
 		}
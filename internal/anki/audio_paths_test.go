@@ -30,3 +30,29 @@ func TestResolveAudioFilePrefersVoiceSpecificFilesOverStaleSingleFile(t *testing
 		t.Fatalf("ResolveAudioFile() = %q, want voice-specific wav file", got)
 	}
 }
+
+func TestResolveAudioFileKeepsSlowClipsApart(t *testing.T) {
+	wordDir := t.TempDir()
+	for _, name := range []string{"audio.mp3", "audio_slow.mp3", "audio_front.mp3", "audio_front_slow.mp3"} {
+		if err := os.WriteFile(filepath.Join(wordDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := map[string]string{
+		"audio":            "audio.mp3",
+		"audio_slow":       "audio_slow.mp3",
+		"audio_front":      "audio_front.mp3",
+		"audio_front_slow": "audio_front_slow.mp3",
+		"audio_back_slow":  "",
+	}
+	for baseName, name := range tests {
+		want := ""
+		if name != "" {
+			want = filepath.Join(wordDir, name)
+		}
+		if got := ResolveAudioFile(wordDir, baseName, "mp3"); got != want {
+			t.Errorf("ResolveAudioFile(%q) = %q, want %q", baseName, got, want)
+		}
+	}
+}
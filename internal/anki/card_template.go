@@ -2,81 +2,237 @@ package anki
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	appconfig "codeberg.org/snonux/totalrecall/internal/config"
 )
 
 //go:embed templates/*.html templates/*.css
 var embeddedCardAssets embed.FS
 
+// Template file names, shared by the embedded assets, the override directory
+// and DumpTemplates.
+const (
+	enBgFrontFile        = "en_bg_front.html"
+	enBgBackFile         = "en_bg_back.html"
+	enBgReverseFrontFile = "en_bg_reverse_front.html"
+	enBgReverseBackFile  = "en_bg_reverse_back.html"
+	enBgTypeFrontFile    = "en_bg_type_front.html"
+	enBgTypeBackFile     = "en_bg_type_back.html"
+	bgBgFrontFile        = "bg_bg_front.html"
+	bgBgBackFile         = "bg_bg_back.html"
+	bgBgReverseFrontFile = "bg_bg_reverse_front.html"
+	bgBgReverseBackFile  = "bg_bg_reverse_back.html"
+	bgBgTypeFrontFile    = "bg_bg_type_front.html"
+	bgBgTypeBackFile     = "bg_bg_type_back.html"
+	sentenceFrontFile    = "sentence_front.html"
+	sentenceBackFile     = "sentence_back.html"
+	cssFile              = "card.css"
+)
+
 // CardTemplate loads Anki card HTML/CSS from embedded files and builds note-type JSON for the collection.
 type CardTemplate struct {
 	enBgFront, enBgBack, enBgReverseFront, enBgReverseBack string
+	enBgTypeFront, enBgTypeBack                            string
 	bgBgFront, bgBgBack, bgBgReverseFront, bgBgReverseBack string
+	bgBgTypeFront, bgBgTypeBack                            string
+	sentenceFront, sentenceBack                            string
 	css                                                    string
 }
 
-// NewCardTemplate reads embedded template and stylesheet files.
+// TemplateDir is where NewCardTemplate looks for template overrides: the
+// templates directory inside the config directory.
+func TemplateDir() (string, error) {
+	dir, err := appconfig.ConfigDir()
+	return filepath.Join(dir, "templates"), err
+}
+
+// NewCardTemplate reads the card templates and stylesheet, preferring files
+// in TemplateDir over the embedded ones. Each file is overridden on its own,
+// so changing the CSS does not mean copying every template.
 func NewCardTemplate() (*CardTemplate, error) {
+	dir, err := TemplateDir()
+	if err != nil {
+		// Without a home directory there is no override directory either.
+		return LoadCardTemplate("")
+	}
+	return LoadCardTemplate(dir)
+}
+
+// LoadCardTemplate reads the card templates and stylesheet from overrideDir,
+// falling back to the embedded version of every file that is not there. An
+// empty overrideDir uses the embedded files only. Overridden templates are
+// validated against the note type fields.
+func LoadCardTemplate(overrideDir string) (*CardTemplate, error) {
 	read := func(name string) (string, error) {
+		if overrideDir != "" {
+			b, err := os.ReadFile(filepath.Join(overrideDir, name))
+			if err == nil {
+				return string(b), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("read template override %s: %w", name, err)
+			}
+		}
 		b, err := embeddedCardAssets.ReadFile("templates/" + name)
 		if err != nil {
 			return "", fmt.Errorf("read template %s: %w", name, err)
 		}
 		return string(b), nil
 	}
+
 	c := &CardTemplate{}
-	var err error
-	if c.enBgFront, err = read("en_bg_front.html"); err != nil {
-		return nil, err
-	}
-	if c.enBgBack, err = read("en_bg_back.html"); err != nil {
-		return nil, err
-	}
-	if c.enBgReverseFront, err = read("en_bg_reverse_front.html"); err != nil {
-		return nil, err
-	}
-	if c.enBgReverseBack, err = read("en_bg_reverse_back.html"); err != nil {
-		return nil, err
+	files := []struct {
+		name   string
+		target *string
+	}{
+		{enBgFrontFile, &c.enBgFront},
+		{enBgBackFile, &c.enBgBack},
+		{enBgReverseFrontFile, &c.enBgReverseFront},
+		{enBgReverseBackFile, &c.enBgReverseBack},
+		{enBgTypeFrontFile, &c.enBgTypeFront},
+		{enBgTypeBackFile, &c.enBgTypeBack},
+		{bgBgFrontFile, &c.bgBgFront},
+		{bgBgBackFile, &c.bgBgBack},
+		{bgBgReverseFrontFile, &c.bgBgReverseFront},
+		{bgBgReverseBackFile, &c.bgBgReverseBack},
+		{bgBgTypeFrontFile, &c.bgBgTypeFront},
+		{bgBgTypeBackFile, &c.bgBgTypeBack},
+		{sentenceFrontFile, &c.sentenceFront},
+		{sentenceBackFile, &c.sentenceBack},
+		{cssFile, &c.css},
 	}
-	if c.bgBgFront, err = read("bg_bg_front.html"); err != nil {
-		return nil, err
+	for _, file := range files {
+		text, err := read(file.name)
+		if err != nil {
+			return nil, err
+		}
+		*file.target = text
 	}
-	if c.bgBgBack, err = read("bg_bg_back.html"); err != nil {
-		return nil, err
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid card templates in %s: %w", overrideDir, err)
 	}
-	if c.bgBgReverseFront, err = read("bg_bg_reverse_front.html"); err != nil {
-		return nil, err
+	return c, nil
+}
+
+// templateFieldRef matches a {{...}} field reference of an Anki template.
+var templateFieldRef = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// builtinTemplateFields are the special fields Anki provides to every
+// template in addition to the note fields.
+var builtinTemplateFields = map[string]bool{
+	"FrontSide": true, "Tags": true, "Type": true, "Deck": true, "Subdeck": true,
+	"Card": true, "CardFlag": true, "CardID": true,
+}
+
+// Validate checks that every field a template references with {{Field}},
+// {{#Field}}, {{^Field}}, {{/Field}} or a filter such as {{type:Field}}
+// exists in the template's note type. Anki would otherwise refuse the note
+// type on import or render an empty card.
+func (c *CardTemplate) Validate() error {
+	templates := []struct {
+		file   string
+		text   string
+		fields []string
+	}{
+		{enBgFrontFile, c.enBgFront, enBgFieldNames},
+		{enBgBackFile, c.enBgBack, enBgFieldNames},
+		{enBgReverseFrontFile, c.enBgReverseFront, enBgFieldNames},
+		{enBgReverseBackFile, c.enBgReverseBack, enBgFieldNames},
+		{enBgTypeFrontFile, c.enBgTypeFront, enBgFieldNames},
+		{enBgTypeBackFile, c.enBgTypeBack, enBgFieldNames},
+		{bgBgFrontFile, c.bgBgFront, bgBgFieldNames},
+		{bgBgBackFile, c.bgBgBack, bgBgFieldNames},
+		{bgBgReverseFrontFile, c.bgBgReverseFront, bgBgFieldNames},
+		{bgBgReverseBackFile, c.bgBgReverseBack, bgBgFieldNames},
+		{bgBgTypeFrontFile, c.bgBgTypeFront, bgBgFieldNames},
+		{bgBgTypeBackFile, c.bgBgTypeBack, bgBgFieldNames},
+		{sentenceFrontFile, c.sentenceFront, sentenceFieldNames},
+		{sentenceBackFile, c.sentenceBack, sentenceFieldNames},
 	}
-	if c.bgBgReverseBack, err = read("bg_bg_reverse_back.html"); err != nil {
-		return nil, err
+
+	var errs []error
+	for _, t := range templates {
+		for _, field := range referencedFields(t.text) {
+			if !builtinTemplateFields[field] && !slices.Contains(t.fields, field) {
+				errs = append(errs, fmt.Errorf("%s references unknown field {{%s}} (fields: %s)",
+					t.file, field, strings.Join(t.fields, ", ")))
+			}
+		}
 	}
-	if c.css, err = read("card.css"); err != nil {
-		return nil, err
+	return errors.Join(errs...)
+}
+
+// referencedFields returns the field names referenced in a template, in
+// order of first use.
+func referencedFields(text string) []string {
+	var fields []string
+	for _, match := range templateFieldRef.FindAllStringSubmatch(text, -1) {
+		ref := strings.TrimSpace(match[1])
+		ref = strings.TrimLeft(ref, "#^/")
+		// Filters precede the field name: {{type:Field}}, {{tts bg_BG:Field}}
+		if i := strings.LastIndex(ref, ":"); i >= 0 {
+			ref = ref[i+1:]
+		}
+		if ref = strings.TrimSpace(ref); ref != "" && !slices.Contains(fields, ref) {
+			fields = append(fields, ref)
+		}
 	}
-	return c, nil
+	return fields
 }
 
-// MustCardTemplate returns NewCardTemplate() or panics; suitable for process startup when assets must exist.
-func MustCardTemplate() *CardTemplate {
-	t, err := NewCardTemplate()
+// DumpTemplates writes the embedded templates and stylesheet to dir as a
+// starting point for overrides. Files that already exist are left alone and
+// reported as skipped.
+func DumpTemplates(dir string) (written, skipped []string, err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	entries, err := embeddedCardAssets.ReadDir("templates")
 	if err != nil {
-		panic(err)
+		return nil, nil, fmt.Errorf("failed to list embedded templates: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		target := filepath.Join(dir, name)
+		if _, err := os.Stat(target); err == nil {
+			skipped = append(skipped, name)
+			continue
+		}
+
+		data, err := embeddedCardAssets.ReadFile("templates/" + name)
+		if err != nil {
+			return written, skipped, fmt.Errorf("failed to read embedded %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return written, skipped, fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		written = append(written, name)
 	}
-	return t
+	return written, skipped, nil
 }
 
 // EnBgNoteTypeConfig builds the English–Bulgarian note type map for Anki's models JSON.
 func (c *CardTemplate) EnBgNoteTypeConfig(modelID, deckID int64) map[string]interface{} {
 	return map[string]interface{}{
 		"id":    modelID,
-		"name":  "Vocabulary from TotalRecall (Basic + Reverse)",
+		"name":  EnBgNoteTypeName,
 		"type":  0,
 		"mod":   time.Now().Unix(),
 		"usn":   -1,
 		"sortf": 0,
 		"did":   deckID,
-		"req":   [][]interface{}{[]interface{}{0, "all", []int{0}}, []interface{}{1, "all", []int{1}}},
+		"req":   wordNoteRequirements(enBgFieldNames),
 		"vers":  []int{},
 		"tags":  []string{},
 		"latexPre": `\documentclass[12pt]{article}
@@ -87,53 +243,7 @@ func (c *CardTemplate) EnBgNoteTypeConfig(modelID, deckID int64) map[string]inte
 \setlength{\parindent}{0in}
 \begin{document}`,
 		"latexPost": `\end{document}`,
-		"flds": []map[string]interface{}{
-			{
-				"name":   "English",
-				"ord":    0,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Bulgarian",
-				"ord":    1,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Image",
-				"ord":    2,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Audio",
-				"ord":    3,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Notes",
-				"ord":    4,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   16,
-				"media":  []string{},
-			},
-		},
+		"flds":      fieldConfigs(enBgFieldNames),
 		"tmpls": []map[string]interface{}{
 			{
 				"name":  "Forward",
@@ -153,6 +263,15 @@ func (c *CardTemplate) EnBgNoteTypeConfig(modelID, deckID int64) map[string]inte
 				"bqfmt": "",
 				"bafmt": "",
 			},
+			{
+				"name":  "Type answer",
+				"ord":   2,
+				"qfmt":  c.enBgTypeFront,
+				"afmt":  c.enBgTypeBack,
+				"did":   nil,
+				"bqfmt": "",
+				"bafmt": "",
+			},
 		},
 		"css": c.css,
 	}
@@ -162,13 +281,13 @@ func (c *CardTemplate) EnBgNoteTypeConfig(modelID, deckID int64) map[string]inte
 func (c *CardTemplate) BgBgNoteTypeConfig(modelIDBgBg, deckID int64) map[string]interface{} {
 	return map[string]interface{}{
 		"id":    modelIDBgBg,
-		"name":  "Bulgarian-Bulgarian from TotalRecall",
+		"name":  BgBgNoteTypeName,
 		"type":  0,
 		"mod":   time.Now().Unix(),
 		"usn":   -1,
 		"sortf": 0,
 		"did":   deckID,
-		"req":   [][]interface{}{[]interface{}{0, "all", []int{0}}, []interface{}{1, "all", []int{1}}},
+		"req":   wordNoteRequirements(bgBgFieldNames),
 		"vers":  []int{},
 		"tags":  []string{},
 		"latexPre": `\documentclass[12pt]{article}
@@ -179,62 +298,7 @@ func (c *CardTemplate) BgBgNoteTypeConfig(modelIDBgBg, deckID int64) map[string]
 \setlength{\parindent}{0in}
 \begin{document}`,
 		"latexPost": `\end{document}`,
-		"flds": []map[string]interface{}{
-			{
-				"name":   "BulgarianFront",
-				"ord":    0,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "BulgarianBack",
-				"ord":    1,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Image",
-				"ord":    2,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "AudioFront",
-				"ord":    3,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "AudioBack",
-				"ord":    4,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   20,
-				"media":  []string{},
-			},
-			{
-				"name":   "Notes",
-				"ord":    5,
-				"sticky": false,
-				"rtl":    false,
-				"font":   "Arial",
-				"size":   16,
-				"media":  []string{},
-			},
-		},
+		"flds":      fieldConfigs(bgBgFieldNames),
 		"tmpls": []map[string]interface{}{
 			{
 				"name":  "Forward",
@@ -254,7 +318,92 @@ func (c *CardTemplate) BgBgNoteTypeConfig(modelIDBgBg, deckID int64) map[string]
 				"bqfmt": "",
 				"bafmt": "",
 			},
+			{
+				"name":  "Type answer",
+				"ord":   2,
+				"qfmt":  c.bgBgTypeFront,
+				"afmt":  c.bgBgTypeBack,
+				"did":   nil,
+				"bqfmt": "",
+				"bafmt": "",
+			},
 		},
 		"css": c.css,
 	}
 }
+
+// wordNoteRequirements builds the legacy "req" list of a word note type:
+// the forward and reverse cards need the first two fields, the type-in card
+// needs the TypeIn flag. Current Anki versions derive this from the
+// templates themselves, which also honour NoForward and NoReverse.
+func wordNoteRequirements(fields []string) [][]interface{} {
+	return [][]interface{}{
+		{0, "all", []int{0}},
+		{1, "all", []int{1}},
+		{2, "all", []int{slices.Index(fields, "TypeIn")}},
+	}
+}
+
+// SentenceNoteTypeConfig builds the cloze note type of sentence cards for
+// Anki's models JSON. Cloze note types have a single template; Anki renders
+// one card per cloze number from it.
+func (c *CardTemplate) SentenceNoteTypeConfig(modelID, deckID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id":    modelID,
+		"name":  SentenceNoteTypeName,
+		"type":  1,
+		"mod":   time.Now().Unix(),
+		"usn":   -1,
+		"sortf": 1,
+		"did":   deckID,
+		"req":   [][]interface{}{[]interface{}{0, "all", []int{0}}},
+		"vers":  []int{},
+		"tags":  []string{},
+		"latexPre": `\documentclass[12pt]{article}
+\special{papersize=3in,5in}
+\usepackage[utf8]{inputenc}
+\usepackage{amssymb,amsmath}
+\pagestyle{empty}
+\setlength{\parindent}{0in}
+\begin{document}`,
+		"latexPost": `\end{document}`,
+		"flds":      fieldConfigs(sentenceFieldNames),
+		"tmpls": []map[string]interface{}{
+			{
+				"name":  "Cloze",
+				"ord":   0,
+				"qfmt":  c.sentenceFront,
+				"afmt":  c.sentenceBack,
+				"did":   nil,
+				"bqfmt": "",
+				"bafmt": "",
+			},
+		},
+		"css": c.css,
+	}
+}
+
+// fieldConfigs builds the flds entries of a note type. The word, translation
+// and media fields use the large font, the supplementary fields a smaller one.
+func fieldConfigs(names []string) []map[string]interface{} {
+	small := map[string]bool{"Notes": true, "IPA": true, "Transliteration": true, "Example": true, "ExampleTranslation": true,
+		"NoForward": true, "NoReverse": true, "TypeIn": true}
+
+	fields := make([]map[string]interface{}, 0, len(names))
+	for ord, name := range names {
+		size := 20
+		if small[name] {
+			size = 16
+		}
+		fields = append(fields, map[string]interface{}{
+			"name":   name,
+			"ord":    ord,
+			"sticky": false,
+			"rtl":    false,
+			"font":   "Arial",
+			"size":   size,
+			"media":  []string{},
+		})
+	}
+	return fields
+}
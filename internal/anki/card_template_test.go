@@ -0,0 +1,88 @@
+package anki
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedTemplatesAreValid(t *testing.T) {
+	tmpl, err := LoadCardTemplate("")
+	if err != nil {
+		t.Fatalf("LoadCardTemplate() error = %v", err)
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+}
+
+// TestLoadCardTemplateOverridesSingleFiles overrides the stylesheet only and
+// expects every template to still come from the embedded files.
+func TestLoadCardTemplateOverridesSingleFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, cssFile), []byte(".card { color: red; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := LoadCardTemplate(dir)
+	if err != nil {
+		t.Fatalf("LoadCardTemplate() error = %v", err)
+	}
+	if tmpl.css != ".card { color: red; }" {
+		t.Errorf("css = %q; want the override", tmpl.css)
+	}
+	embedded, _ := embeddedCardAssets.ReadFile("templates/" + enBgBackFile)
+	if tmpl.enBgBack != string(embedded) {
+		t.Errorf("en-bg back template was not taken from the embedded files")
+	}
+}
+
+func TestLoadCardTemplateRejectsUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	back := "{{FrontSide}}<hr id=answer>{{Bulgarian}} {{#Plural}}{{Plural}}{{/Plural}} {{type:Bulgarian}}"
+	if err := os.WriteFile(filepath.Join(dir, enBgBackFile), []byte(back), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadCardTemplate(dir)
+	if err == nil {
+		t.Fatal("LoadCardTemplate() error = nil; want unknown field error")
+	}
+	if !strings.Contains(err.Error(), enBgBackFile) || !strings.Contains(err.Error(), "{{Plural}}") {
+		t.Errorf("error = %v; want file and field named", err)
+	}
+}
+
+func TestReferencedFields(t *testing.T) {
+	text := "{{FrontSide}} {{#Audio}}{{Audio}}{{/Audio}} {{^Image}}none{{/Image}} {{type:Bulgarian}} {{tts bg_BG:Bulgarian}}"
+	want := []string{"FrontSide", "Audio", "Image", "Bulgarian"}
+	if got := referencedFields(text); !slices.Equal(got, want) {
+		t.Errorf("referencedFields() = %v; want %v", got, want)
+	}
+}
+
+func TestDumpTemplatesKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, cssFile), []byte("mine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	written, skipped, err := DumpTemplates(dir)
+	if err != nil {
+		t.Fatalf("DumpTemplates() error = %v", err)
+	}
+	if !slices.Equal(skipped, []string{cssFile}) {
+		t.Errorf("skipped = %v; want %s", skipped, cssFile)
+	}
+	if len(written) != 14 {
+		t.Errorf("written = %v; want the fourteen templates", written)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, cssFile)); string(data) != "mine" {
+		t.Errorf("existing %s was overwritten", cssFile)
+	}
+	if _, err := LoadCardTemplate(dir); err != nil {
+		t.Errorf("dumped templates do not load: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package anki
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDailyLimit is the largest daily limit Anki's deck options accept.
+const maxDailyLimit = 9999
+
+// DeckOptions are the scheduling settings of the deck options preset written
+// into an APKG. Anki only applies them when the package is imported with
+// "Import any deck presets" (or the equivalent of the Anki version) enabled.
+type DeckOptions struct {
+	// NewPerDay is the maximum number of new cards introduced per day.
+	NewPerDay int
+	// ReviewsPerDay is the maximum number of reviews shown per day.
+	ReviewsPerDay int
+	// LearningSteps are the intervals a new card is shown at before it
+	// graduates to the review queue.
+	LearningSteps []time.Duration
+	// BurySiblings hides the other card of a note until the next day once
+	// one of them was reviewed or is in learning.
+	BurySiblings bool
+	// ReverseSameDay introduces the reverse card of a new note on the same
+	// day as the forward card instead of burying it until the next day.
+	ReverseSameDay bool
+}
+
+// DefaultDeckOptions returns the options earlier releases hard-coded.
+func DefaultDeckOptions() DeckOptions {
+	return DeckOptions{
+		NewPerDay:      20,
+		ReviewsPerDay:  100,
+		LearningSteps:  []time.Duration{time.Minute, 10 * time.Minute},
+		BurySiblings:   true,
+		ReverseSameDay: false,
+	}
+}
+
+// Validate reports the options Anki would reject or silently clamp.
+func (o DeckOptions) Validate() error {
+	var errs []error
+	if o.NewPerDay < 0 || o.NewPerDay > maxDailyLimit {
+		errs = append(errs, fmt.Errorf("new cards per day must be between 0 and %d, got %d", maxDailyLimit, o.NewPerDay))
+	}
+	if o.ReviewsPerDay < 0 || o.ReviewsPerDay > maxDailyLimit {
+		errs = append(errs, fmt.Errorf("reviews per day must be between 0 and %d, got %d", maxDailyLimit, o.ReviewsPerDay))
+	}
+	for _, step := range o.LearningSteps {
+		if step < time.Second {
+			errs = append(errs, fmt.Errorf("learning step %s is shorter than one second", step))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// learningStepMinutes returns the learning steps in minutes, the unit of
+// the "delays" list of Anki's deck config.
+func (o DeckOptions) learningStepMinutes() []float64 {
+	minutes := make([]float64, 0, len(o.LearningSteps))
+	for _, step := range o.LearningSteps {
+		minutes = append(minutes, step.Minutes())
+	}
+	return minutes
+}
+
+// ParseLearningSteps parses learning steps written the way Anki's deck
+// options show them, e.g. "1m 10m 1h 1d". Steps may also be separated by
+// commas; a number without unit means minutes. An empty string yields no
+// steps, which makes new cards graduate after their first review.
+func ParseLearningSteps(s string) ([]time.Duration, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+
+	steps := make([]time.Duration, 0, len(fields))
+	for _, field := range fields {
+		step, err := parseLearningStep(field)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseLearningStep(field string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		's': time.Second,
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+	}
+
+	number, unit := field, time.Minute
+	if u, ok := units[field[len(field)-1]]; ok {
+		number, unit = field[:len(field)-1], u
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid learning step %q (want e.g. 30s, 10m, 1h or 1d)", field)
+	}
+	return time.Duration(value * float64(unit)), nil
+}
+
+// FormatLearningSteps is the inverse of ParseLearningSteps, using the
+// largest unit that represents each step exactly.
+func FormatLearningSteps(steps []time.Duration) string {
+	parts := make([]string, 0, len(steps))
+	for _, step := range steps {
+		switch {
+		case step%(24*time.Hour) == 0:
+			parts = append(parts, fmt.Sprintf("%dd", step/(24*time.Hour)))
+		case step%time.Hour == 0:
+			parts = append(parts, fmt.Sprintf("%dh", step/time.Hour))
+		case step%time.Minute == 0:
+			parts = append(parts, fmt.Sprintf("%dm", step/time.Minute))
+		default:
+			parts = append(parts, strconv.FormatFloat(step.Seconds(), 'f', -1, 64)+"s")
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,59 @@
+package anki
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLearningSteps(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []time.Duration
+	}{
+		{"1m 10m", []time.Duration{time.Minute, 10 * time.Minute}},
+		{"30s,1h, 2d", []time.Duration{30 * time.Second, time.Hour, 48 * time.Hour}},
+		{"15 1.5h", []time.Duration{15 * time.Minute, 90 * time.Minute}},
+		{"", []time.Duration{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLearningSteps(tt.in)
+		if err != nil {
+			t.Errorf("ParseLearningSteps(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseLearningSteps(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"10x", "m", "-5m", "0"} {
+		if _, err := ParseLearningSteps(in); err == nil {
+			t.Errorf("ParseLearningSteps(%q) error = nil; want error", in)
+		}
+	}
+}
+
+func TestFormatLearningStepsRoundTrips(t *testing.T) {
+	const in = "30s 1m 90m 2h 1d"
+	steps, err := ParseLearningSteps(in)
+	if err != nil {
+		t.Fatalf("ParseLearningSteps() error = %v", err)
+	}
+	if got := FormatLearningSteps(steps); got != in {
+		t.Errorf("FormatLearningSteps() = %q; want %q", got, in)
+	}
+}
+
+func TestDeckOptionsValidate(t *testing.T) {
+	if err := DefaultDeckOptions().Validate(); err != nil {
+		t.Errorf("DefaultDeckOptions().Validate() error = %v", err)
+	}
+
+	options := DefaultDeckOptions()
+	options.ReviewsPerDay = 10000
+	options.LearningSteps = []time.Duration{time.Millisecond}
+	if err := options.Validate(); err == nil {
+		t.Error("Validate() error = nil; want limit and step errors")
+	}
+}
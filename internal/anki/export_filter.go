@@ -0,0 +1,127 @@
+package anki
+
+// export_filter.go selects the cards of incremental exports and records
+// finished exports in the export ledger of the card store.
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// Export formats as recorded in the export ledger.
+const (
+	ExportFormatAPKG        = "apkg"
+	ExportFormatCSV         = "csv"
+	ExportFormatAnkiConnect = "ankiconnect"
+)
+
+// ExportFilter narrows an export to some of the cards. All set conditions
+// must hold; the zero filter exports every card.
+type ExportFilter struct {
+	// SinceLastExport keeps cards that were never exported to the target
+	// of the export or changed after their last export to it.
+	SinceLastExport bool
+	// ChangedSince keeps cards changed after this time.
+	ChangedSince time.Time
+	// Tag keeps cards with this tag. Anki tags ignore case, so does the
+	// match.
+	Tag string
+}
+
+// IsZero reports whether the filter keeps every card.
+func (f ExportFilter) IsZero() bool {
+	return !f.SinceLastExport && f.ChangedSince.IsZero() && f.Tag == ""
+}
+
+// String describes the filter for the export ledger, e.g. "since last
+// export, tag food".
+func (f ExportFilter) String() string {
+	var parts []string
+	if f.SinceLastExport {
+		parts = append(parts, "since last export")
+	}
+	if !f.ChangedSince.IsZero() {
+		parts = append(parts, "changed since "+f.ChangedSince.Format("2006-01-02 15:04"))
+	}
+	if f.Tag != "" {
+		parts = append(parts, "tag "+f.Tag)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Match reports whether the filter keeps card. ledger is only consulted for
+// SinceLastExport and should hold the exports to the same target only, see
+// store.ExportLedger.ExportsTo. Cards that were not loaded from a card directory, or
+// whose manifest predates change times, cannot be compared and are kept.
+func (f ExportFilter) Match(card Card, ledger *store.ExportLedger) bool {
+	if f.Tag != "" && !slices.ContainsFunc(card.Tags, func(tag string) bool {
+		return strings.EqualFold(tag, f.Tag)
+	}) {
+		return false
+	}
+	if !f.ChangedSince.IsZero() && !card.Modified.IsZero() && !card.Modified.After(f.ChangedSince) {
+		return false
+	}
+	if f.SinceLastExport && card.Dir != "" && !ledger.ChangedSinceExport(card.Dir) {
+		return false
+	}
+	return true
+}
+
+// exportDateLayouts are the accepted forms of ParseExportDate, most precise
+// last.
+var exportDateLayouts = []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339}
+
+// ParseExportDate parses the date of a "changed since" filter: a day
+// (2006-01-02), a day and time (2006-01-02 15:04), both in local time, or an
+// RFC 3339 timestamp.
+func ParseExportDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range exportDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD, \"YYYY-MM-DD HH:MM\" or RFC 3339)", value)
+}
+
+// FilterCards drops the cards that filter does not keep from an export in
+// format to destination: the written file, or the deck for AnkiConnect. The
+// export ledger is read from cs only when the filter needs it.
+func (g *Generator) FilterCards(filter ExportFilter, cs *store.CardStore, format, destination string) error {
+	if filter.IsZero() {
+		return nil
+	}
+	ledger := &store.ExportLedger{}
+	if filter.SinceLastExport {
+		var err error
+		if ledger, err = cs.ExportLedger(); err != nil {
+			return err
+		}
+		ledger = ledger.ExportsTo(format, destination)
+	}
+	g.cards = slices.DeleteFunc(g.cards, func(card Card) bool {
+		return !filter.Match(card, ledger)
+	})
+	return nil
+}
+
+// RecordExport adds an export of cards to the export ledger of cs, with the
+// current state of each card. Cards not loaded from a card directory are not
+// tracked.
+func RecordExport(cs *store.CardStore, export store.Export, cards []Card) error {
+	export.Cards = make([]store.ExportedCard, 0, len(cards))
+	for _, card := range cards {
+		if card.Dir != "" {
+			export.Cards = append(export.Cards, store.ExportedCardState(card.Dir))
+		}
+	}
+	if err := cs.RecordExport(export); err != nil {
+		return fmt.Errorf("failed to record export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package anki
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+func TestFilterCards(t *testing.T) {
+	cs := store.New(t.TempDir())
+	for _, word := range []string{"ябълка", "котка", "куче"} {
+		dir := cs.FindOrCreateCardDirectory(word)
+		if err := store.UpdateManifest(dir, func(m *store.Manifest) { m.Translation = word }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.AddTags(cs.FindCardDirectory("котка"), []string{"Animals"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddTags(cs.FindCardDirectory("куче"), []string{"animals"}); err != nil {
+		t.Fatal(err)
+	}
+
+	words := func(filter ExportFilter) []string {
+		gen := NewGenerator(nil)
+		if err := gen.GenerateFromDirectory(cs.OutputDir()); err != nil {
+			t.Fatal(err)
+		}
+		if err := gen.FilterCards(filter, cs, ExportFormatAPKG, "deck.apkg"); err != nil {
+			t.Fatalf("FilterCards(%s) error = %v", filter, err)
+		}
+		var words []string
+		for _, card := range gen.GetCards() {
+			words = append(words, card.Bulgarian)
+		}
+		slices.Sort(words)
+		return words
+	}
+
+	if got := words(ExportFilter{}); len(got) != 3 {
+		t.Errorf("zero filter kept %v", got)
+	}
+	if got := words(ExportFilter{Tag: "ANIMALS"}); !slices.Equal(got, []string{"котка", "куче"}) {
+		t.Errorf("tag filter kept %v", got)
+	}
+	if got := words(ExportFilter{ChangedSince: time.Now().Add(time.Hour)}); len(got) != 0 {
+		t.Errorf("changed-since filter kept %v", got)
+	}
+
+	// Export the tagged cards, then change one of them.
+	gen := NewGenerator(nil)
+	if err := gen.GenerateFromDirectory(cs.OutputDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.FilterCards(ExportFilter{Tag: "animals"}, cs, ExportFormatAPKG, "deck.apkg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordExport(cs, store.Export{File: "deck.apkg", Format: ExportFormatAPKG, Destination: "deck.apkg"}, gen.GetCards()); err != nil {
+		t.Fatalf("RecordExport() error = %v", err)
+	}
+	if got := words(ExportFilter{SinceLastExport: true}); !slices.Equal(got, []string{"ябълка"}) {
+		t.Errorf("since-last-export filter kept %v after the export", got)
+	}
+	if err := store.UpdateManifest(cs.FindCardDirectory("куче"), func(m *store.Manifest) { m.Translation = "dog" }); err != nil {
+		t.Fatal(err)
+	}
+	if got := words(ExportFilter{SinceLastExport: true}); !slices.Equal(got, []string{"куче", "ябълка"}) {
+		t.Errorf("since-last-export filter kept %v after a change", got)
+	}
+	if got := words(ExportFilter{SinceLastExport: true, Tag: "animals"}); !slices.Equal(got, []string{"куче"}) {
+		t.Errorf("combined filter kept %v", got)
+	}
+
+	ledger, err := cs.ExportLedger()
+	if err != nil || len(ledger.Exports) != 1 || len(ledger.Exports[0].Cards) != 2 {
+		t.Fatalf("ExportLedger() = %+v, %v", ledger, err)
+	}
+	if got := ledger.Exports[0].Cards[0].ID; got != filepath.Base(cs.FindCardDirectory(ledger.Exports[0].Cards[0].Word)) {
+		t.Errorf("recorded card ID = %q", got)
+	}
+}
+
+// TestFilterCardsPerTarget checks that an export only counts for later
+// exports in the same format to the same file or deck.
+func TestFilterCardsPerTarget(t *testing.T) {
+	cs := store.New(t.TempDir())
+	for _, word := range []string{"ябълка", "котка"} {
+		if err := store.UpdateManifest(cs.FindOrCreateCardDirectory(word), func(m *store.Manifest) { m.Translation = word }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	due := func(format, destination string) int {
+		gen := NewGenerator(nil)
+		if err := gen.GenerateFromDirectory(cs.OutputDir()); err != nil {
+			t.Fatal(err)
+		}
+		if err := gen.FilterCards(ExportFilter{SinceLastExport: true}, cs, format, destination); err != nil {
+			t.Fatalf("FilterCards(%s, %s) error = %v", format, destination, err)
+		}
+		return len(gen.GetCards())
+	}
+	record := func(export store.Export) {
+		gen := NewGenerator(nil)
+		if err := gen.GenerateFromDirectory(cs.OutputDir()); err != nil {
+			t.Fatal(err)
+		}
+		if err := RecordExport(cs, export, gen.GetCards()); err != nil {
+			t.Fatalf("RecordExport() error = %v", err)
+		}
+	}
+
+	record(store.Export{File: "deck.apkg", Format: ExportFormatAPKG, Destination: "deck.apkg"})
+	record(store.Export{Format: ExportFormatAnkiConnect, Destination: "Bulgarian", Deck: "Bulgarian"})
+	if got := due(ExportFormatAPKG, "deck.apkg"); got != 0 {
+		t.Errorf("%d cards due for the same APKG file", got)
+	}
+	if got := due(ExportFormatAnkiConnect, "Bulgarian"); got != 0 {
+		t.Errorf("%d cards due for the same deck", got)
+	}
+	for _, target := range [][2]string{
+		{ExportFormatAPKG, "other.apkg"},
+		{ExportFormatCSV, "deck.apkg"},
+		{ExportFormatCSV, "anki_import/anki_import.tsv"},
+		{ExportFormatAnkiConnect, "Other"},
+	} {
+		if got := due(target[0], target[1]); got != 2 {
+			t.Errorf("%d cards due for %s to %s, want 2", got, target[0], target[1])
+		}
+	}
+
+	// Ledgers written before exports had a destination kept it in File.
+	record(store.Export{File: "old.apkg", Format: ExportFormatAPKG})
+	if got := due(ExportFormatAPKG, "old.apkg"); got != 0 {
+		t.Errorf("%d cards due for a file recorded without destination", got)
+	}
+}
+
+func TestParseExportDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"2025-03-01":                time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local),
+		" 2025-03-01 14:30 ":        time.Date(2025, 3, 1, 14, 30, 0, 0, time.Local),
+		"2025-03-01T14:30:00Z":      time.Date(2025, 3, 1, 14, 30, 0, 0, time.UTC),
+		"2025-03-01T14:30:00+02:00": time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		if got, err := ParseExportDate(value); err != nil || !got.Equal(want) {
+			t.Errorf("ParseExportDate(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, invalid := range []string{"", "yesterday", "01.03.2025"} {
+		if _, err := ParseExportDate(invalid); err == nil {
+			t.Errorf("ParseExportDate(%q) succeeded; want error", invalid)
+		}
+	}
+}
+
+func TestExportFilterString(t *testing.T) {
+	if got := (ExportFilter{}).String(); got != "" {
+		t.Errorf("zero filter = %q", got)
+	}
+	filter := ExportFilter{SinceLastExport: true, ChangedSince: time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), Tag: "food"}
+	if got, want := filter.String(), "since last export, changed since 2025-03-01 00:00, tag food"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+}
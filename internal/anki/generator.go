@@ -5,35 +5,73 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
+	"codeberg.org/snonux/totalrecall/internal/translit"
 )
 
 // Card represents a single Anki flashcard
 type Card struct {
-	Bulgarian     string // The Bulgarian word/phrase
-	AudioFile     string // Path to audio file (for en-bg: Bulgarian audio, for bg-bg: front audio)
-	AudioFileBack string // Path to back audio file (only for bg-bg cards)
-	ImageFile     string // Path to image file
-	Translation   string // Translation (English for en-bg, Bulgarian definition for bg-bg)
-	Notes         string // Optional notes
-	CardType      string // Card type: "en-bg" or "bg-bg"
+	Bulgarian          string   // The Bulgarian word/phrase
+	AudioFile          string   // Path to audio file (for en-bg: Bulgarian audio, for bg-bg: front audio)
+	AudioFileBack      string   // Path to back audio file (only for bg-bg cards)
+	AudioFileSlow      string   // Path to the slow rendition of AudioFile
+	AudioFileBackSlow  string   // Path to the slow rendition of AudioFileBack
+	ImageFile          string   // Path to image file
+	Translation        string   // Translation (English for en-bg, Bulgarian definition for bg-bg)
+	Notes              string   // Optional notes
+	IPA                string   // IPA transcription, line breaks as <br>
+	Transliteration    string   // The word in Latin script (Streamlined System)
+	Example            string   // Bulgarian example sentence
+	ExampleTranslation string   // Translation of the example sentence
+	CardType           string   // Card type: "en-bg", "bg-bg" or "sentence"
+	Cloze              string   // Sentence with cloze deletions (only for sentence cards)
+	Tags               []string // Anki note tags
+	// Directions selects the cards of a word note; zero uses the
+	// directions of the export.
+	Directions internal.CardDirections
+	// Section is the batch file section of the card, e.g. "Lesson 3::Food",
+	// used by DeckGroupingSection.
+	Section string
+	// Modified is when the card last changed. It becomes the note and card
+	// mod time, which Anki compares to decide whether a re-imported note was
+	// edited. Zero means "now".
+	Modified time.Time
+	// Dir is the card directory the card was loaded from; empty for cards
+	// built elsewhere. Incremental exports use it to find the card in the
+	// export ledger.
+	Dir string
 }
 
 // GeneratorOptions configures the Anki export
 type GeneratorOptions struct {
-	OutputPath     string // Output CSV file path
+	OutputPath     string // Output TSV file path of CSV exports
 	MediaFolder    string // Folder containing media files
 	IncludeHeaders bool   // Include CSV headers
 	AudioFormat    string // Audio file format (mp3, wav)
 	ImageFormat    string // Image file format (jpg, png)
+	// DeckOptions are the scheduling options of APKG exports; nil means
+	// DefaultDeckOptions.
+	DeckOptions *DeckOptions
+	// CardDirections are the card directions of cards that do not choose
+	// their own; zero means internal.DefaultCardDirections.
+	CardDirections internal.CardDirections
+	// PackageFormat is the layout of APKG exports; empty means
+	// DefaultPackageFormat.
+	PackageFormat PackageFormat
+	// DeckGrouping splits APKG exports into subdecks; empty means
+	// DeckGroupingNone.
+	DeckGrouping DeckGrouping
 }
 
 // DefaultGeneratorOptions returns sensible defaults
 func DefaultGeneratorOptions() *GeneratorOptions {
 	return &GeneratorOptions{
-		OutputPath:     "anki_import.csv",
+		OutputPath:     filepath.Join(CSVBundleDir, CSVFileName),
 		MediaFolder:    ".",
 		IncludeHeaders: true,
 		AudioFormat:    "mp3",
@@ -45,6 +83,9 @@ func DefaultGeneratorOptions() *GeneratorOptions {
 type Generator struct {
 	options *GeneratorOptions
 	cards   []Card
+	// written is the file of the last export; APKG files get a timestamped
+	// name, so it differs from the requested path.
+	written string
 }
 
 // NewGenerator creates a new Anki generator
@@ -68,10 +109,72 @@ func (g *Generator) GetCards() []Card {
 	return g.cards
 }
 
-// GenerateCSV creates a CSV file for Anki import.
-func (g *Generator) GenerateCSV() (err error) {
-	// Create output file
-	file, err := os.Create(g.options.OutputPath)
+// OutputFile returns the file written by the last GenerateCSV or
+// GenerateAPKG call.
+func (g *Generator) OutputFile() string {
+	return g.written
+}
+
+// A CSV export is a bundle directory holding the TSV file and a folder with
+// its media files.
+const (
+	CSVBundleDir = "anki_import"
+	CSVFileName  = "anki_import.tsv"
+	CSVMediaDir  = "media"
+)
+
+// GenerateCSV writes the cards to OutputPath as a tab-separated file for
+// Anki's text import and copies their media into a media folder next to it,
+// named as in the notes. Files missing on disk leave their field empty. The
+// header directives tell Anki the separator, that fields are HTML, and which
+// columns hold the note type, deck, tags and GUID, so the import needs no
+// manual field mapping. The cards are mapped onto the stock Basic (optional
+// reversed card) and Cloze note types, which every Anki profile has, so the
+// bundle imports into a fresh profile as well. Its notes are kept apart from
+// the TotalRecall notes of the APKG and AnkiConnect exports by their GUIDs.
+func (g *Generator) GenerateCSV(deckName string) error {
+	dir := filepath.Dir(g.options.OutputPath)
+	return g.writeCSVBundle(g.options.OutputPath, filepath.Join(dir, CSVMediaDir), deckName)
+}
+
+// writeCSVBundle writes the TSV file to csvPath and copies the media of the
+// cards into mediaDir.
+func (g *Generator) writeCSVBundle(csvPath, mediaDir, deckName string) (err error) {
+	if err := os.MkdirAll(mediaDir, 0755); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	exported := make(map[string]bool)
+	for _, card := range g.cards {
+		for _, path := range []string{card.AudioFile, card.AudioFileBack, card.AudioFileSlow, card.AudioFileBackSlow, card.ImageFile} {
+			if exported[path] || path == "" || !fileExists(path) {
+				continue
+			}
+			if err := copyFile(path, filepath.Join(mediaDir, MediaFileName(path))); err != nil {
+				return fmt.Errorf("failed to copy media file %s: %w", path, err)
+			}
+			exported[path] = true
+		}
+	}
+
+	rows := make([][]string, 0, len(g.cards))
+	noteTypes := make([]string, 0, len(g.cards))
+	fieldCount := 0
+	for _, card := range g.cards {
+		noteType, fields := g.stockNote(card, func(path string) bool { return exported[path] })
+		fieldCount = max(fieldCount, len(fields))
+		noteTypes = append(noteTypes, noteType)
+
+		row := []string{
+			noteType,
+			g.options.DeckGrouping.DeckFor(deckName, card),
+			strings.TrimSpace(noteTags(card.Tags)),
+			ankiGUID("csv_" + noteSeed(card)),
+		}
+		rows = append(rows, append(row, fields...))
+	}
+
+	file, err := os.Create(csvPath)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %w", err)
 	}
@@ -81,56 +184,60 @@ func (g *Generator) GenerateCSV() (err error) {
 		}
 	}()
 
-	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer func() {
-		writer.Flush()
-		if flushErr := writer.Error(); err == nil && flushErr != nil {
-			err = fmt.Errorf("failed to flush CSV file: %w", flushErr)
-		}
-	}()
-
-	// Write headers if requested
+	header := []string{"#separator:tab", "#html:true", "#notetype column:1", "#deck column:2", "#tags column:3", "#guid column:4"}
 	if g.options.IncludeHeaders {
-		headers := []string{"Bulgarian", "Audio", "Image", "Translation", "Notes"}
-		if err := writer.Write(headers); err != nil {
-			return fmt.Errorf("failed to write headers: %w", err)
-		}
+		header = append(header, "#columns:"+strings.Join(csvColumnNames(noteTypes, fieldCount), "\t"))
+	}
+	if _, err := fmt.Fprintln(file, strings.Join(header, "\n")); err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
 	}
 
-	// Write cards
-	for _, card := range g.cards {
-		record := []string{
-			card.Bulgarian,
-			g.formatAudioField(card.AudioFile),
-			g.formatImageField(card.ImageFile),
-			card.Translation,
-			card.Notes,
+	writer := csv.NewWriter(file)
+	writer.Comma = '\t'
+	for _, row := range rows {
+		// Rows of the smaller note type are padded, so every row has the
+		// same number of columns.
+		for len(row) < 4+fieldCount {
+			row = append(row, "")
 		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(row); err != nil {
 			return fmt.Errorf("failed to write card: %w", err)
 		}
 	}
-
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV file: %w", err)
+	}
+	g.written = csvPath
 	return nil
 }
 
+// csvColumnNames returns the column names of a CSV export: the field names
+// when all cards share a note type, generic names otherwise.
+func csvColumnNames(noteTypes []string, fieldCount int) []string {
+	names := []string{"Note type", "Deck", "Tags", "GUID"}
+	var fields []string
+	if len(noteTypes) > 0 && !slices.ContainsFunc(noteTypes, func(noteType string) bool { return noteType != noteTypes[0] }) {
+		fields = stockNoteFieldNames(noteTypes[0])
+	}
+	for i := range fieldCount {
+		if i < len(fields) {
+			names = append(names, fields[i])
+		} else {
+			names = append(names, fmt.Sprintf("Field %d", i+1))
+		}
+	}
+	return names
+}
+
 // formatAudioField formats the audio file reference for Anki
 func (g *Generator) formatAudioField(audioFile string) string {
 	if audioFile == "" {
 		return ""
 	}
 
-	// Get card ID from the source path (parent directory name)
-	cardID := filepath.Base(filepath.Dir(audioFile))
-	originalFilename := filepath.Base(audioFile)
-
-	// Create filename with card ID prefix for uniqueness in Anki
-	filename := fmt.Sprintf("%s_%s", cardID, originalFilename)
-
 	// Anki audio format: [sound:filename.mp3]
-	return fmt.Sprintf("[sound:%s]", filename)
+	return fmt.Sprintf("[sound:%s]", MediaFileName(audioFile))
 }
 
 // formatImageField formats image file reference for Anki
@@ -139,14 +246,7 @@ func (g *Generator) formatImageField(imageFile string) string {
 		return ""
 	}
 
-	// Get card ID from the source path (parent directory name)
-	cardID := filepath.Base(filepath.Dir(imageFile))
-	originalFilename := filepath.Base(imageFile)
-
-	// Create filename with card ID prefix for uniqueness in Anki
-	filename := fmt.Sprintf("%s_%s", cardID, originalFilename)
-
-	return fmt.Sprintf(`<img src="%s">`, filename)
+	return fmt.Sprintf(`<img src="%s">`, MediaFileName(imageFile))
 }
 
 // GenerateFromDirectory creates cards from a directory of materials
@@ -168,66 +268,10 @@ func (g *Generator) GenerateFromDirectory(dir string) error {
 			continue
 		}
 
-		wordDir := filepath.Join(dir, entry.Name())
-
-		// Create card for this word
-		card := Card{}
-
-		// Load card type (defaults to en-bg for backwards compatibility)
-		cardType := internal.LoadCardType(wordDir)
-		card.CardType = string(cardType)
-
-		// Read the original Bulgarian word from word.txt
-		wordFile := filepath.Join(wordDir, "word.txt")
-		if data, err := os.ReadFile(wordFile); err == nil {
-			card.Bulgarian = strings.TrimSpace(string(data))
-		} else {
-			// Try old format with underscore for backward compatibility
-			wordFile = filepath.Join(wordDir, "_word.txt")
-			if data, err := os.ReadFile(wordFile); err == nil {
-				card.Bulgarian = strings.TrimSpace(string(data))
-			} else {
-				// Skip directories without word.txt
-				continue
-			}
-		}
-
-		// Try to load translation
-		translationFile := filepath.Join(wordDir, "translation.txt")
-		if data, err := os.ReadFile(translationFile); err == nil {
-			content := string(data)
-			if parts := strings.Split(content, "="); len(parts) >= 2 {
-				card.Translation = strings.TrimSpace(parts[1])
-			}
-		}
-
-		// Look for audio file(s)
-		if cardType.IsBgBg() {
-			card.AudioFile = ResolveAudioFile(wordDir, "audio_front", "")
-			card.AudioFileBack = ResolveAudioFile(wordDir, "audio_back", "")
-		} else {
-			card.AudioFile = ResolveAudioFile(wordDir, "audio", "")
-		}
-
-		// Look for image files
-		imagePatterns := []string{
-			"image.jpg",
-			"image.png",
-		}
-		for _, pattern := range imagePatterns {
-			imageFile := filepath.Join(wordDir, pattern)
-			if _, err := os.Stat(imageFile); err == nil {
-				card.ImageFile = imageFile
-				break
-			}
-		}
-
-		// Load phonetic information as notes
-		phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-		if data, err := os.ReadFile(phoneticFile); err == nil {
-			// Preserve line breaks by converting \n to <br> for HTML display
-			notes := strings.TrimSpace(string(data))
-			card.Notes = strings.ReplaceAll(notes, "\n", "<br>")
+		card, ok := CardFromDirectory(filepath.Join(dir, entry.Name()), "")
+		if !ok {
+			// Skip directories without a word
+			continue
 		}
 
 		// Only add card if it has at least some content
@@ -239,52 +283,82 @@ func (g *Generator) GenerateFromDirectory(dir string) error {
 	return nil
 }
 
-// GeneratePackage creates a complete Anki package with media files
-// Deprecated: Use GenerateAPKG for proper .apkg format
-func (g *Generator) GeneratePackage(outputDir string) error {
-	// Create output directory
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+// CardFromDirectory builds a Card from the manifest of a single card
+// directory. preferredFormat is a hint for audio lookups on cards whose audio
+// has not been recorded in the manifest. It returns false when the directory
+// does not describe a word.
+func CardFromDirectory(wordDir, preferredFormat string) (Card, bool) {
+	manifest := store.LoadManifest(wordDir)
+	if manifest.Word == "" {
+		return Card{}, false
 	}
 
-	// Create media directory
-	mediaDir := filepath.Join(outputDir, "collection.media")
-	if err := os.MkdirAll(mediaDir, 0755); err != nil {
-		return fmt.Errorf("failed to create media directory: %w", err)
+	// Card type defaults to en-bg for backwards compatibility
+	cardType := internal.ParseCardType(manifest.CardType)
+	card := Card{
+		Bulgarian:   manifest.Word,
+		Translation: manifest.Translation,
+		CardType:    string(cardType),
+		Cloze:       manifest.Cloze,
+		Tags:        manifest.Tags,
+		Section:     manifest.Section,
+		Modified:    manifest.UpdatedAt,
+		Dir:         wordDir,
 	}
 
-	// Copy media files and update paths
-	for i, card := range g.cards {
-		// Copy audio file
-		if card.AudioFile != "" {
-			newPath, err := g.copyMediaFile(card.AudioFile, mediaDir)
-			if err != nil {
-				return fmt.Errorf("failed to copy audio file: %w", err)
-			}
-			g.cards[i].AudioFile = newPath
-		}
+	if cardType.IsBgBg() {
+		card.AudioFile = ResolveAudioFile(wordDir, "audio_front", preferredFormat)
+		card.AudioFileBack = ResolveAudioFile(wordDir, "audio_back", preferredFormat)
+		card.AudioFileSlow = ResolveAudioFile(wordDir, "audio_front_slow", preferredFormat)
+		card.AudioFileBackSlow = ResolveAudioFile(wordDir, "audio_back_slow", preferredFormat)
+	} else {
+		card.AudioFile = ResolveAudioFile(wordDir, "audio", preferredFormat)
+		card.AudioFileSlow = ResolveAudioFile(wordDir, "audio_slow", preferredFormat)
+	}
 
-		// Copy image file
-		if card.ImageFile != "" {
-			newPath, err := g.copyMediaFile(card.ImageFile, mediaDir)
-			if err != nil {
-				return fmt.Errorf("failed to copy image file: %w", err)
-			}
-			g.cards[i].ImageFile = newPath
-		}
+	// An unreadable choice is treated as none, leaving the card to the
+	// export-wide directions.
+	card.Directions, _ = internal.ParseCardDirections(manifest.Directions)
+
+	// A sentence card without cloze text still needs a deletion for Anki
+	// to create a card; asking for the whole sentence is the safe choice.
+	if cardType.IsSentence() && card.Cloze == "" {
+		card.Cloze = "{{c1::" + manifest.Word + "}}"
 	}
 
-	// Update output path to package directory
-	g.options.OutputPath = filepath.Join(outputDir, "import.csv")
+	card.ImageFile = manifest.AssetPath(wordDir, store.AssetImage)
+
+	// Preserve line breaks of the phonetic information as <br> for HTML
+	// display
+	card.IPA = strings.ReplaceAll(manifest.IPA, "\n", "<br>")
+	card.Transliteration = translit.Bulgarian(manifest.Word)
+	card.Example = manifest.Example
+	card.ExampleTranslation = manifest.ExampleTranslation
 
-	// Generate CSV
-	return g.GenerateCSV()
+	return card, true
+}
+
+// GeneratePackage creates a complete Anki package with media files: the
+// CSV file import.csv and a collection.media folder in outputDir.
+// Deprecated: Use GenerateAPKG for proper .apkg format
+func (g *Generator) GeneratePackage(outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	return g.writeCSVBundle(filepath.Join(outputDir, "import.csv"), filepath.Join(outputDir, "collection.media"), "")
 }
 
 // GenerateAPKG creates a proper .apkg file for Anki import
 func (g *Generator) GenerateAPKG(outputPath, deckName string) error {
 	// Create APKG generator
 	apkgGen := NewAPKGGenerator(deckName)
+	if g.options.DeckOptions != nil {
+		apkgGen.SetDeckOptions(*g.options.DeckOptions)
+	}
+	apkgGen.SetCardDirections(g.options.CardDirections)
+	apkgGen.SetPackageFormat(g.options.PackageFormat)
+	apkgGen.SetDeckGrouping(g.options.DeckGrouping)
 
 	// Add all cards
 	for _, card := range g.cards {
@@ -292,72 +366,11 @@ func (g *Generator) GenerateAPKG(outputPath, deckName string) error {
 	}
 
 	// Generate the .apkg file
-	return apkgGen.GenerateAPKG(outputPath)
-}
-
-// copyMediaFile copies a media file to the destination directory.
-func (g *Generator) copyMediaFile(src, destDir string) (filename string, err error) {
-	// Get source file info
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return "", err
-	}
-
-	// Get the card ID from the source path (parent directory name)
-	cardID := filepath.Base(filepath.Dir(src))
-
-	// Create destination filename with card ID prefix
-	originalFilename := filepath.Base(src)
-	filename = fmt.Sprintf("%s_%s", cardID, originalFilename)
-	destPath := filepath.Join(destDir, filename)
-
-	// Check if file already exists
-	if _, err := os.Stat(destPath); err == nil {
-		// File exists, generate unique name
-		ext := filepath.Ext(filename)
-		base := strings.TrimSuffix(filename, ext)
-		for i := 1; ; i++ {
-			filename = fmt.Sprintf("%s_%d%s", base, i, ext)
-			destPath = filepath.Join(destDir, filename)
-			if _, err := os.Stat(destPath); os.IsNotExist(err) {
-				break
-			}
-		}
+	if err := apkgGen.GenerateAPKG(outputPath); err != nil {
+		return err
 	}
-
-	// Open source file
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		if closeErr := srcFile.Close(); err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close source media file: %w", closeErr)
-		}
-	}()
-
-	// Create destination file
-	destFile, err := os.Create(destPath)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		if closeErr := destFile.Close(); err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close destination media file: %w", closeErr)
-		}
-	}()
-
-	// Copy content
-	if _, err := destFile.ReadFrom(srcFile); err != nil {
-		return "", err
-	}
-
-	// Preserve file mode
-	if err := os.Chmod(destPath, srcInfo.Mode()); err != nil {
-		return "", err
-	}
-
-	return filename, nil
+	g.written = apkgGen.PackagePath()
+	return nil
 }
 
 // Stats returns statistics about the card collection
@@ -4,15 +4,20 @@ import (
 	"encoding/csv"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 func TestDefaultGeneratorOptions(t *testing.T) {
 	opts := DefaultGeneratorOptions()
 
-	if opts.OutputPath != "anki_import.csv" {
-		t.Errorf("Expected output path 'anki_import.csv', got '%s'", opts.OutputPath)
+	if want := filepath.Join(CSVBundleDir, CSVFileName); opts.OutputPath != want {
+		t.Errorf("Expected output path '%s', got '%s'", want, opts.OutputPath)
 	}
 
 	if opts.MediaFolder != "." {
@@ -167,137 +172,127 @@ func TestFormatImageField(t *testing.T) {
 
 func TestGenerateCSV(t *testing.T) {
 	tempDir := t.TempDir()
-	outputPath := filepath.Join(tempDir, "test.csv")
+	outputPath := filepath.Join(tempDir, "bundle", "test.tsv")
 
 	gen := NewGenerator(&GeneratorOptions{
 		OutputPath:     outputPath,
 		IncludeHeaders: true,
+		CardDirections: internal.CardDirections{Forward: true},
+		DeckGrouping:   DeckGroupingTag,
 	})
-
-	// Add test cards
 	gen.AddCard(Card{
-		Bulgarian:   "ябълка",
-		AudioFile:   "/path/to/apple/audio.mp3",
-		ImageFile:   "/path/to/apple/image.jpg",
-		Translation: "apple",
-		Notes:       "A fruit",
+		Bulgarian:     "ябълка",
+		AudioFile:     writeMediaFile(t, tempDir, "apple", "audio.mp3"),
+		AudioFileSlow: writeMediaFile(t, tempDir, "apple", "audio_slow.mp3"),
+		ImageFile:     filepath.Join(tempDir, "apple", "missing.jpg"),
+		Translation:   "apple",
+		Notes:         "A fruit\twith a tab",
+		Tags:          []string{"food"},
 	})
-
 	gen.AddCard(Card{
-		Bulgarian:   "котка",
-		AudioFile:   "/path/to/cat/audio.mp3",
-		ImageFile:   "/path/to/cat/image.jpg",
-		Translation: "cat",
-		Notes:       "An animal",
+		Bulgarian:     "котка",
+		Translation:   "домашно животно",
+		CardType:      "bg-bg",
+		AudioFile:     writeMediaFile(t, tempDir, "cat", "audio_front.mp3"),
+		AudioFileBack: writeMediaFile(t, tempDir, "cat", "audio_back.mp3"),
+		Directions:    internal.CardDirections{Forward: true, Reverse: true, TypeIn: true},
 	})
+	gen.AddCard(Card{Bulgarian: "ябълка", Translation: "apple", Directions: internal.CardDirections{Reverse: true}})
+	gen.AddCard(Card{Bulgarian: "Аз обичам ябълки.", CardType: "sentence", Cloze: "Аз обичам {{c1::ябълки}}."})
 
-	// Generate CSV
-	err := gen.GenerateCSV()
-	if err != nil {
+	if err := gen.GenerateCSV("Vocabulary"); err != nil {
 		t.Fatalf("GenerateCSV() error = %v", err)
 	}
 
-	// Verify file exists
-	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-		t.Fatal("CSV file was not created")
-	}
-
-	// Read and verify content
-	file, err := os.Open(outputPath)
+	data, err := os.ReadFile(outputPath)
 	if err != nil {
-		t.Fatalf("Failed to open CSV file: %v", err)
+		t.Fatalf("Failed to read TSV file: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 8)
+	wantHeader := []string{"#separator:tab", "#html:true", "#notetype column:1", "#deck column:2", "#tags column:3", "#guid column:4"}
+	if !slices.Equal(lines[:6], wantHeader) {
+		t.Errorf("header = %q; want %q", lines[:6], wantHeader)
+	}
+	if lines[6] != "#columns:Note type\tDeck\tTags\tGUID\tField 1\tField 2\tField 3" {
+		t.Errorf("columns = %q", lines[6])
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			t.Errorf("Failed to close CSV file: %v", closeErr)
-		}
-	}()
 
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(strings.NewReader(lines[7]))
+	reader.Comma = '\t'
 	records, err := reader.ReadAll()
 	if err != nil {
-		t.Fatalf("Failed to read CSV: %v", err)
+		t.Fatalf("Failed to read TSV rows: %v", err)
 	}
-
-	// Check headers
-	if len(records) < 1 {
-		t.Fatal("CSV file is empty")
-	}
-
-	expectedHeaders := []string{"Bulgarian", "Audio", "Image", "Translation", "Notes"}
-	if len(records[0]) != len(expectedHeaders) {
-		t.Errorf("Expected %d columns, got %d", len(expectedHeaders), len(records[0]))
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(records))
 	}
-
-	for i, header := range expectedHeaders {
-		if records[0][i] != header {
-			t.Errorf("Expected header '%s' at position %d, got '%s'", header, i, records[0][i])
+	for _, record := range records {
+		if len(record) != 7 {
+			t.Errorf("row %q has %d columns; want 7", record[4], len(record))
 		}
 	}
 
-	// Check first data row
-	if len(records) < 2 {
-		t.Fatal("CSV file has no data rows")
+	// Only the forward card: no reverse card is added.
+	apple := records[0]
+	want := []string{BasicOptionalReversedNoteTypeName, "Vocabulary::food", "food", ankiGUID("csv_tr_ябълка"),
+		"<div>apple</div>",
+		"<div>ябълка</div><div>[sound:apple_audio.mp3]</div><div>[sound:apple_audio_slow.mp3]</div><div>A fruit\twith a tab</div>",
+		""}
+	if !slices.Equal(apple, want) {
+		t.Errorf("en-bg row = %q; want %q", apple, want)
 	}
 
-	if records[1][0] != "ябълка" {
-		t.Errorf("Expected Bulgarian 'ябълка', got '%s'", records[1][0])
+	cat := records[1]
+	want = []string{BasicOptionalReversedNoteTypeName, "Vocabulary", "", ankiGUID("csv_tr_bgbg_котка"),
+		"<div>котка</div><div>[sound:cat_audio_front.mp3]</div>",
+		"<div>домашно животно</div><div>[sound:cat_audio_back.mp3]</div>",
+		"y"}
+	if !slices.Equal(cat, want) {
+		t.Errorf("bg-bg row = %q; want %q", cat, want)
 	}
 
-	if records[1][1] != "[sound:apple_audio.mp3]" {
-		t.Errorf("Expected audio field '[sound:apple_audio.mp3]', got '%s'", records[1][1])
+	// A reverse-only card swaps the sides.
+	if reverse := records[2]; reverse[4] != "<div>ябълка</div>" || reverse[5] != "<div>apple</div>" || reverse[6] != "" {
+		t.Errorf("reverse-only row = %q", reverse)
 	}
 
-	if records[1][2] != `<img src="apple_image.jpg">` {
-		t.Errorf("Expected image field '<img src=\"apple_image.jpg\">', got '%s'", records[1][2])
+	sentence := records[3]
+	if sentence[0] != ClozeNoteTypeName || sentence[4] != "<div>Аз обичам {{c1::ябълки}}.</div>" || sentence[5] != "" || sentence[6] != "" {
+		t.Errorf("sentence row = %q", sentence)
 	}
 
-	if records[1][3] != "apple" {
-		t.Errorf("Expected translation 'apple', got '%s'", records[1][3])
+	for _, name := range []string{"apple_audio.mp3", "apple_audio_slow.mp3", "cat_audio_front.mp3", "cat_audio_back.mp3"} {
+		content, err := os.ReadFile(filepath.Join(tempDir, "bundle", CSVMediaDir, name))
+		if err != nil || !strings.HasSuffix(string(content), strings.Replace(name, "_", "/", 1)) {
+			t.Errorf("media %s = %q, %v", name, content, err)
+		}
 	}
 }
 
-func TestGenerateCSVWithoutHeaders(t *testing.T) {
-	tempDir := t.TempDir()
-	outputPath := filepath.Join(tempDir, "test.csv")
-
-	gen := NewGenerator(&GeneratorOptions{
-		OutputPath:     outputPath,
-		IncludeHeaders: false,
-	})
-
-	gen.AddCard(Card{
-		Bulgarian: "ябълка",
-	})
-
-	err := gen.GenerateCSV()
-	if err != nil {
+func TestGenerateCSVColumnNames(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "test.tsv")
+	gen := NewGenerator(&GeneratorOptions{OutputPath: outputPath, IncludeHeaders: true})
+	gen.AddCard(Card{Bulgarian: "ябълка"})
+	gen.AddCard(Card{Bulgarian: "котка"})
+	if err := gen.GenerateCSV("Vocabulary"); err != nil {
 		t.Fatalf("GenerateCSV() error = %v", err)
 	}
 
-	// Read and verify no headers
-	file, err := os.Open(outputPath)
+	data, err := os.ReadFile(outputPath)
 	if err != nil {
-		t.Fatalf("Failed to open CSV file: %v", err)
+		t.Fatal(err)
 	}
-	defer func() {
-		if closeErr := file.Close(); closeErr != nil {
-			t.Errorf("Failed to close CSV file: %v", closeErr)
-		}
-	}()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		t.Fatalf("Failed to read CSV: %v", err)
+	want := "#columns:Note type\tDeck\tTags\tGUID\tFront\tBack\tAdd Reverse\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("TSV file has no %q line:\n%s", want, data)
 	}
 
-	if len(records) != 1 {
-		t.Errorf("Expected 1 record (no headers), got %d", len(records))
+	gen.options.IncludeHeaders = false
+	if err := gen.GenerateCSV("Vocabulary"); err != nil {
+		t.Fatalf("GenerateCSV() error = %v", err)
 	}
-
-	if records[0][0] != "ябълка" {
-		t.Errorf("First field should be 'ябълка', got '%s'", records[0][0])
+	if data, _ := os.ReadFile(outputPath); strings.Contains(string(data), "#columns:") {
+		t.Errorf("TSV file without headers has a #columns line:\n%s", data)
 	}
 }
 
@@ -388,8 +383,14 @@ func TestGenerateFromDirectory(t *testing.T) {
 		t.Errorf("Expected image file to end with 'image.jpg', got '%s'", appleCard.ImageFile)
 	}
 
-	if !strings.Contains(appleCard.Notes, "YA-bul-ka<br>Stress on first syllable") {
-		t.Errorf("Expected phonetic notes with HTML breaks, got '%s'", appleCard.Notes)
+	if appleCard.IPA != "YA-bul-ka<br>Stress on first syllable" {
+		t.Errorf("Expected phonetic information with HTML breaks, got '%s'", appleCard.IPA)
+	}
+	if appleCard.Notes != "" {
+		t.Errorf("Expected empty notes, got '%s'", appleCard.Notes)
+	}
+	if appleCard.Transliteration != "yabalka" {
+		t.Errorf("Expected transliteration 'yabalka', got '%s'", appleCard.Transliteration)
 	}
 }
 
@@ -429,68 +430,96 @@ func TestGenerateFromDirectoryPrefersMultiVoiceAudioFiles(t *testing.T) {
 	}
 }
 
-func TestCopyMediaFile(t *testing.T) {
-	tempDir := t.TempDir()
-
-	// Create source file structure
-	srcDir := filepath.Join(tempDir, "src", "word123")
-	if err := os.MkdirAll(srcDir, 0755); err != nil {
-		t.Fatalf("Failed to create source directory: %v", err)
-	}
-
-	srcFile := filepath.Join(srcDir, "audio.mp3")
-	if err := os.WriteFile(srcFile, []byte("test audio"), 0644); err != nil {
-		t.Fatalf("Failed to write source audio file: %v", err)
-	}
-
-	// Create destination directory
-	destDir := filepath.Join(tempDir, "dest")
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		t.Fatalf("Failed to create destination directory: %v", err)
-	}
-
-	gen := NewGenerator(nil)
-
-	// Test copying file
-	newPath, err := gen.copyMediaFile(srcFile, destDir)
-	if err != nil {
-		t.Fatalf("copyMediaFile() error = %v", err)
-	}
-
-	expectedName := "word123_audio.mp3"
-	if newPath != expectedName {
-		t.Errorf("Expected filename '%s', got '%s'", expectedName, newPath)
-	}
-
-	// Verify file was copied
-	destFile := filepath.Join(destDir, newPath)
-	if _, err := os.Stat(destFile); os.IsNotExist(err) {
-		t.Error("Destination file was not created")
-	}
+// TestCardFromDirectoryUsesManifest verifies that card.json wins over stale
+// sidecars and that recorded assets are used for media paths.
+func TestCardFromDirectoryUsesManifest(t *testing.T) {
+	wordDir := t.TempDir()
 
-	// Verify content
-	content, err := os.ReadFile(destFile)
-	if err != nil {
-		t.Fatalf("Failed to read destination file: %v", err)
+	files := map[string]string{
+		"translation.txt":     "куче = stale",
+		"audio.mp3":           "stale audio",
+		"audio_front.wav":     "front",
+		"audio_back.wav":      "back",
+		"audio_back_slow.wav": "slow back",
+		"image.jpg":           "stale image",
+		"image.png":           "image",
 	}
-
-	if string(content) != "test audio" {
-		t.Errorf("File content mismatch: got '%s', want 'test audio'", string(content))
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(wordDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
 	}
 
-	// Test copying same file again (should create unique name)
-	newPath2, err := gen.copyMediaFile(srcFile, destDir)
-	if err != nil {
-		t.Fatalf("copyMediaFile() second call error = %v", err)
+	manifest := store.NewManifest("куче")
+	manifest.Translation = "животно, което лае"
+	manifest.CardType = "bg-bg"
+	manifest.IPA = "[ˈkutʃɛ]\nnoun"
+	manifest.Example = "Кучето лае."
+	manifest.ExampleTranslation = "Кучето издава звук."
+	manifest.Tags = []string{"animals"}
+	manifest.PutAsset(store.Asset{File: "audio_front.wav", Provider: "gemini"})
+	manifest.PutAsset(store.Asset{File: "audio_back.wav", Provider: "gemini"})
+	manifest.PutAsset(store.Asset{File: "audio_back_slow.wav", Provider: "gemini"})
+	manifest.PutAsset(store.Asset{File: "image.png", Provider: "nanobanana"})
+	if err := store.SaveManifest(wordDir, manifest); err != nil {
+		t.Fatalf("SaveManifest() error = %v", err)
+	}
+
+	card, ok := CardFromDirectory(wordDir, "mp3")
+	if !ok {
+		t.Fatal("CardFromDirectory() returned false")
+	}
+
+	want := Card{
+		Bulgarian:          "куче",
+		Translation:        "животно, което лае",
+		CardType:           "bg-bg",
+		AudioFile:          filepath.Join(wordDir, "audio_front.wav"),
+		AudioFileBack:      filepath.Join(wordDir, "audio_back.wav"),
+		AudioFileBackSlow:  filepath.Join(wordDir, "audio_back_slow.wav"),
+		ImageFile:          filepath.Join(wordDir, "image.png"),
+		IPA:                "[ˈkutʃɛ]<br>noun",
+		Transliteration:    "kuche",
+		Example:            "Кучето лае.",
+		ExampleTranslation: "Кучето издава звук.",
+		Tags:               []string{"animals"},
+		Modified:           store.LoadManifest(wordDir).UpdatedAt,
+		Dir:                wordDir,
+	}
+	if !reflect.DeepEqual(card, want) {
+		t.Errorf("CardFromDirectory() = %+v; want %+v", card, want)
 	}
+}
 
-	if newPath2 == newPath {
-		t.Error("Second copy should have unique name")
+// TestCardFromDirectorySentence checks that sentence cards carry their
+// cloze text and that a sentence without one asks for the whole sentence.
+func TestCardFromDirectorySentence(t *testing.T) {
+	tests := []struct {
+		name  string
+		cloze string
+		want  string
+	}{
+		{"with cloze", "Къде е {{c1::гарата}}?", "Къде е {{c1::гарата}}?"},
+		{"without cloze", "", "{{c1::Къде е гарата?}}"},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wordDir := t.TempDir()
+			manifest := store.NewManifest("Къде е гарата?")
+			manifest.CardType = "sentence"
+			manifest.Cloze = tt.cloze
+			if err := store.SaveManifest(wordDir, manifest); err != nil {
+				t.Fatalf("SaveManifest() error = %v", err)
+			}
 
-	expectedName2 := "word123_audio_1.mp3"
-	if newPath2 != expectedName2 {
-		t.Errorf("Expected filename '%s', got '%s'", expectedName2, newPath2)
+			card, ok := CardFromDirectory(wordDir, "mp3")
+			if !ok {
+				t.Fatal("CardFromDirectory() returned false")
+			}
+			if card.CardType != "sentence" || card.Cloze != tt.want {
+				t.Errorf("CardFromDirectory() = type %q cloze %q; want sentence %q", card.CardType, card.Cloze, tt.want)
+			}
+		})
 	}
 }
 
@@ -0,0 +1,497 @@
+package anki
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// ImportProvider is the asset provider recorded for media copied out of an
+// APKG file. --retry-failed-assets keeps such assets even though, unlike
+// generated ones, they come without an image prompt.
+const ImportProvider = "anki-import"
+
+// FieldMapping names the note fields ImportAPKG reads a card from. Empty
+// fields are detected from the field names and contents of each note type.
+type FieldMapping struct {
+	Bulgarian   string
+	Translation string
+	Audio       string
+	Image       string
+}
+
+// ParseFieldMapping parses a mapping such as
+// "bulgarian=Front,translation=Back,audio=Sound". Keys are case-insensitive,
+// field names are kept as written; the empty string maps nothing.
+func ParseFieldMapping(s string) (FieldMapping, error) {
+	var mapping FieldMapping
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, field, found := strings.Cut(part, "=")
+		field = strings.TrimSpace(field)
+		if !found || field == "" {
+			return FieldMapping{}, fmt.Errorf("invalid field mapping %q (want key=field)", strings.TrimSpace(part))
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "bulgarian", "word":
+			mapping.Bulgarian = field
+		case "translation":
+			mapping.Translation = field
+		case "audio":
+			mapping.Audio = field
+		case "image":
+			mapping.Image = field
+		default:
+			return FieldMapping{}, fmt.Errorf("unknown import field %q (want bulgarian, translation, audio or image)", strings.TrimSpace(key))
+		}
+	}
+	return mapping, nil
+}
+
+// ImportOptions configures ImportAPKG.
+type ImportOptions struct {
+	Fields FieldMapping
+}
+
+// ImportStatus is the outcome of importing one note.
+type ImportStatus string
+
+const (
+	ImportStatusImported ImportStatus = "imported"
+	// ImportStatusSkipped marks notes without Bulgarian text and notes whose
+	// word has a card directory already; existing cards are never changed.
+	ImportStatusSkipped ImportStatus = "skipped"
+	ImportStatusFailed  ImportStatus = "failed"
+)
+
+// ImportedNote is the outcome of importing one note of the package.
+type ImportedNote struct {
+	NoteID   int64        `json:"note_id"`
+	Word     string       `json:"word"`
+	CardType string       `json:"card_type,omitempty"`
+	Status   ImportStatus `json:"status"`
+	// Message explains skipped and failed notes and lists the media of an
+	// imported note that could not be copied.
+	Message string `json:"message,omitempty"`
+	// Media lists the files copied into the card directory.
+	Media []string `json:"media,omitempty"`
+}
+
+// ImportResult lists the notes of an imported package in note ID order.
+type ImportResult struct {
+	Path  string         `json:"path"`
+	Notes []ImportedNote `json:"notes"`
+}
+
+// Count returns the number of notes with status.
+func (r *ImportResult) Count(status ImportStatus) int {
+	n := 0
+	for _, note := range r.Notes {
+		if note.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Field names ImportAPKG looks for, in order of preference, when the mapping
+// leaves a field empty. They cover the TotalRecall note types and the Basic
+// note types of Anki.
+var (
+	bulgarianFieldCandidates   = []string{"Bulgarian", "BulgarianFront", "Sentence", "Word", "Front"}
+	translationFieldCandidates = []string{"English", "Translation", "BulgarianBack", "Meaning", "Back"}
+)
+
+var (
+	soundReference = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+	imageReference = regexp.MustCompile(`<img[^>]*?\ssrc=["']?([^"'>\s]+)`)
+	lineBreak      = regexp.MustCompile(`(?i)<br\s*/?>|</?(div|p)\b[^>]*>`)
+	htmlTag        = regexp.MustCompile(`<[^>]*>`)
+)
+
+// importedImageExtensions maps the image extensions card directories may
+// hold to the one ImportAPKG writes.
+var importedImageExtensions = map[string]string{".jpg": ".jpg", ".jpeg": ".jpg", ".png": ".png", ".webp": ".webp"}
+
+// ImportAPKG creates a card directory in cs for every note of the APKG file
+// at path whose word has none yet, and copies the audio and image of the
+// note into it. Notes of the TotalRecall note types come back as the cards
+// they were exported from; for other note types the fields are taken from
+// options.Fields or guessed from their names. Assets the package lacks are
+// left for --retry-failed-assets. The error is only set when the package
+// cannot be read; problems with single notes are reported in the result.
+func ImportAPKG(path string, cs *store.CardStore, options ImportOptions) (*ImportResult, error) {
+	pkg, err := openPackage(path)
+	if err != nil {
+		return nil, err
+	}
+	defer pkg.Close()
+
+	var schema int
+	if err := pkg.db.QueryRow("SELECT ver FROM col").Scan(&schema); err != nil {
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+	noteTypes, _, err := readNoteTypesAndDecks(pkg.db, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection: %w", err)
+	}
+	media, err := pkg.mediaList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media list: %w", err)
+	}
+
+	imp := &importer{
+		pkg:         pkg,
+		cs:          cs,
+		fields:      options.Fields,
+		source:      filepath.Base(path),
+		mediaByName: make(map[string]string, len(media)),
+	}
+	for entry, name := range media {
+		imp.mediaByName[name] = entry
+	}
+
+	var notes []ankiNote
+	err = queryRows(pkg.db, "SELECT id, mid, flds, tags FROM notes ORDER BY id", func(rows *sql.Rows) error {
+		var note ankiNote
+		var flds string
+		if err := rows.Scan(&note.id, &note.noteTypeID, &flds, &note.tags); err != nil {
+			return err
+		}
+		note.fields = strings.Split(flds, "\x1f")
+		notes = append(notes, note)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes: %w", err)
+	}
+
+	result := &ImportResult{Path: path}
+	for _, note := range notes {
+		result.Notes = append(result.Notes, imp.importNote(note, noteTypes[note.noteTypeID]))
+	}
+	return result, nil
+}
+
+type ankiNote struct {
+	id         int64
+	noteTypeID int64
+	fields     []string
+	tags       string
+}
+
+// importedCard is a note mapped to the content of a card directory. Audio
+// holds the media names of the front and, for bg-bg cards, the back audio.
+type importedCard struct {
+	word        string
+	translation string
+	cardType    internal.CardType
+	cloze       string
+	directions  internal.CardDirections
+	audio       []string
+	image       string
+}
+
+type importer struct {
+	pkg    *apkgPackage
+	cs     *store.CardStore
+	fields FieldMapping
+	// source is the file name of the package, for attributions.
+	source      string
+	mediaByName map[string]string
+}
+
+func (imp *importer) importNote(note ankiNote, noteType *InspectedNoteType) ImportedNote {
+	result := ImportedNote{NoteID: note.id, Status: ImportStatusFailed}
+	if noteType == nil {
+		result.Message = fmt.Sprintf("unknown note type %d", note.noteTypeID)
+		return result
+	}
+
+	card, err := imp.mapNote(note, noteType)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Word = card.word
+	result.CardType = card.cardType.String()
+
+	if card.word == "" {
+		result.Status = ImportStatusSkipped
+		result.Message = "no Bulgarian text"
+		return result
+	}
+
+	// Checking for an existing card and creating the new one is a single
+	// step, so a card the GUI or a batch run creates meanwhile is not
+	// overwritten.
+	cardDir, created, err := imp.cs.CreateCardDirectory(card.word)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if !created {
+		result.Status = ImportStatusSkipped
+		result.Message = "a card directory for the word exists already"
+		return result
+	}
+	if err := saveImportedCard(cardDir, card, store.ParseTags(note.tags)); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	var missing []string
+	result.Media, missing, err = imp.copyMedia(cardDir, note.id, card)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if len(missing) > 0 {
+		result.Message = "media not imported: " + strings.Join(missing, ", ")
+	}
+	result.Status = ImportStatusImported
+	return result
+}
+
+// mapNote reads the card of note. Without a mapping for the Bulgarian and
+// translation fields the two are swapped when only the translation is
+// Cyrillic, which covers Basic notes with the Bulgarian side on the back.
+func (imp *importer) mapNote(note ankiNote, noteType *InspectedNoteType) (importedCard, error) {
+	fieldIndex := func(name string) int {
+		for i, field := range noteType.Fields {
+			if strings.EqualFold(field, name) && i < len(note.fields) {
+				return i
+			}
+		}
+		return -1
+	}
+	value := func(name string) string {
+		if i := fieldIndex(name); i >= 0 {
+			return note.fields[i]
+		}
+		return ""
+	}
+	mapped := func(name string, candidates []string, fallback int) (string, error) {
+		if name != "" {
+			if fieldIndex(name) < 0 {
+				return "", fmt.Errorf("note type %q has no field %q", noteType.Name, name)
+			}
+			return value(name), nil
+		}
+		for _, candidate := range candidates {
+			if i := fieldIndex(candidate); i >= 0 {
+				return note.fields[i], nil
+			}
+		}
+		if fallback < len(note.fields) {
+			return note.fields[fallback], nil
+		}
+		return "", nil
+	}
+
+	var card importedCard
+	word, err := mapped(imp.fields.Bulgarian, bulgarianFieldCandidates, 0)
+	if err != nil {
+		return card, err
+	}
+	translation, err := mapped(imp.fields.Translation, translationFieldCandidates, 1)
+	if err != nil {
+		return card, err
+	}
+	card.word, card.translation = plainText(word), plainText(translation)
+	if card.translation == "Translation needed" {
+		card.translation = ""
+	}
+
+	switch {
+	case noteType.Cloze:
+		card.cardType = internal.CardTypeSentence
+		card.cloze = plainText(value("Text"))
+		if card.cloze == "" {
+			card.cloze = plainText(note.fields[0])
+		}
+		// Only the TotalRecall note type keeps the plain sentence in a field
+		// of its own.
+		if card.word == "" || imp.fields.Bulgarian == "" && fieldIndex("Sentence") < 0 {
+			card.word = internal.ClozeText(card.cloze)
+		}
+	case noteType.Name == BgBgNoteTypeName:
+		card.cardType = internal.CardTypeBgBg
+	default:
+		if imp.fields.Bulgarian == "" && imp.fields.Translation == "" && !hasCyrillic(card.word) && hasCyrillic(card.translation) {
+			card.word, card.translation = card.translation, card.word
+		}
+		card.cardType = internal.CardTypeEnBg
+		if hasCyrillic(card.word) && hasCyrillic(card.translation) {
+			card.cardType = internal.CardTypeBgBg
+		}
+	}
+
+	if fieldIndex("NoForward") >= 0 && !card.cardType.IsSentence() {
+		card.directions = internal.CardDirections{
+			Forward: value("NoForward") == "",
+			Reverse: value("NoReverse") == "",
+			TypeIn:  value("TypeIn") != "",
+		}
+	}
+
+	audioText, imageText := strings.Join(note.fields, " "), strings.Join(note.fields, " ")
+	if imp.fields.Audio != "" {
+		if fieldIndex(imp.fields.Audio) < 0 {
+			return card, fmt.Errorf("note type %q has no field %q", noteType.Name, imp.fields.Audio)
+		}
+		audioText = value(imp.fields.Audio)
+	}
+	if imp.fields.Image != "" {
+		if fieldIndex(imp.fields.Image) < 0 {
+			return card, fmt.Errorf("note type %q has no field %q", noteType.Name, imp.fields.Image)
+		}
+		imageText = value(imp.fields.Image)
+	}
+	audioCount := 1
+	if card.cardType.IsBgBg() {
+		audioCount = 2
+	}
+	for _, match := range soundReference.FindAllStringSubmatch(audioText, audioCount) {
+		card.audio = append(card.audio, html.UnescapeString(match[1]))
+	}
+	if match := imageReference.FindStringSubmatch(imageText); match != nil {
+		card.image = html.UnescapeString(match[1])
+	}
+	return card, nil
+}
+
+// saveImportedCard writes the text of card to its new card directory.
+func saveImportedCard(cardDir string, card importedCard, tags []string) error {
+	if err := store.SaveTranslation(cardDir, card.word, card.translation); err != nil {
+		return err
+	}
+	if err := internal.SaveCardType(cardDir, card.cardType); err != nil {
+		return err
+	}
+	if card.cloze != "" {
+		if err := store.SaveCloze(cardDir, card.cloze); err != nil {
+			return err
+		}
+	}
+	if card.directions != (internal.CardDirections{}) && card.directions != internal.DefaultCardDirections() {
+		if err := internal.SaveCardDirections(cardDir, card.directions); err != nil {
+			return err
+		}
+	}
+	if len(tags) > 0 {
+		if err := store.AddTags(cardDir, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyMedia copies the audio and image of card into cardDir under the names
+// the generators use, writes their attribution sidecars and records them in
+// the card manifest. It returns the copied files and the media that could
+// not be copied, with the reason.
+func (imp *importer) copyMedia(cardDir string, noteID int64, card importedCard) (copied, missing []string, err error) {
+	audioBases := []string{"audio"}
+	if card.cardType.IsBgBg() {
+		audioBases = []string{"audio_front", "audio_back"}
+	}
+
+	type mediaCopy struct {
+		name, base, ext string
+		kind            store.AssetKind
+	}
+	var copies []mediaCopy
+	for i, name := range card.audio {
+		copies = append(copies, mediaCopy{name, audioBases[i], strings.ToLower(filepath.Ext(name)), store.AssetKind(audioBases[i])})
+	}
+	if card.image != "" {
+		ext, ok := importedImageExtensions[strings.ToLower(filepath.Ext(card.image))]
+		if ok {
+			copies = append(copies, mediaCopy{card.image, "image", ext, store.AssetImage})
+		} else {
+			missing = append(missing, card.image+" (unsupported image format)")
+		}
+	}
+
+	var audioFiles []string
+	for _, c := range copies {
+		entry, ok := imp.mediaByName[c.name]
+		if !ok {
+			missing = append(missing, c.name+" (not in the package)")
+			continue
+		}
+		data, err := imp.pkg.readEntry(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		file := c.base + c.ext
+		if err := store.WriteFileAtomic(filepath.Join(cardDir, file), data); err != nil {
+			return nil, nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		attribution := fmt.Sprintf("Imported from %s (Anki note %d, media file %s)\n", imp.source, noteID, c.name)
+		if err := store.WriteFileAtomic(filepath.Join(cardDir, c.base+"_attribution.txt"), []byte(attribution)); err != nil {
+			return nil, nil, fmt.Errorf("failed to write attribution of %s: %w", file, err)
+		}
+		if c.kind != store.AssetImage {
+			audioFiles = append(audioFiles, file)
+		}
+		if err := store.RecordAsset(cardDir, store.Asset{Kind: c.kind, File: file, Provider: ImportProvider}); err != nil {
+			return nil, nil, fmt.Errorf("failed to record %s in card manifest: %w", file, err)
+		}
+		copied = append(copied, file)
+	}
+
+	if len(audioFiles) > 0 {
+		if err := writeImportedAudioMetadata(cardDir, card.cardType, audioFiles); err != nil {
+			return nil, nil, err
+		}
+	}
+	return copied, missing, nil
+}
+
+// writeImportedAudioMetadata writes audio_metadata.txt for copied audio, as
+// the audio generators do, so older releases and the GUI find the files.
+func writeImportedAudioMetadata(cardDir string, cardType internal.CardType, files []string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "provider=%s\n", ImportProvider)
+	fmt.Fprintf(&b, "format=%s\n", strings.TrimPrefix(filepath.Ext(files[0]), "."))
+	fmt.Fprintf(&b, "cardtype=%s\n", cardType)
+	fmt.Fprintf(&b, "audio_file=%s\n", files[0])
+	if len(files) > 1 {
+		fmt.Fprintf(&b, "audio_file_back=%s\n", files[1])
+	}
+	if err := store.WriteFileAtomic(filepath.Join(cardDir, store.AudioMetadataFileName), []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to save audio metadata: %w", err)
+	}
+	return nil
+}
+
+// plainText returns the text of a note field without media references and
+// HTML markup.
+func plainText(field string) string {
+	text := soundReference.ReplaceAllString(field, " ")
+	text = lineBreak.ReplaceAllString(text, " ")
+	text = htmlTag.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func hasCyrillic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package anki
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// writeMediaFile writes a media file into its own directory of dir, as card
+// directories do, and returns its path.
+func writeMediaFile(t *testing.T, dir, cardID, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, cardID, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("data of "+cardID+"/"+name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportAPKG(t *testing.T) {
+	for _, format := range []PackageFormat{PackageFormatLegacy, PackageFormatAnki21b} {
+		dir := t.TempDir()
+		gen := NewAPKGGenerator("Test Deck")
+		gen.SetPackageFormat(format)
+		gen.AddCard(Card{
+			Bulgarian:   "ябълка",
+			Translation: "apple",
+			AudioFile:   writeMediaFile(t, dir, "apple", "audio.mp3"),
+			ImageFile:   writeMediaFile(t, dir, "apple", "image.png"),
+			Tags:        []string{"food"},
+			Directions:  internal.CardDirections{Forward: true, TypeIn: true},
+		})
+		gen.AddCard(Card{
+			Bulgarian:     "котка",
+			Translation:   "домашно животно",
+			CardType:      "bg-bg",
+			AudioFile:     writeMediaFile(t, dir, "cat", "audio_front.mp3"),
+			AudioFileBack: writeMediaFile(t, dir, "cat", "audio_back.mp3"),
+		})
+		gen.AddCard(Card{Bulgarian: "Аз обичам ябълки.", Translation: "I love apples.", CardType: "sentence", Cloze: "Аз обичам {{c1::ябълки}}."})
+		if err := gen.GenerateAPKG(filepath.Join(dir, "test.apkg")); err != nil {
+			t.Fatalf("%s: GenerateAPKG() error = %v", format, err)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.apkg"))
+		if err != nil || len(files) != 1 {
+			t.Fatalf("%s: expected 1 apkg file, found %v (%v)", format, files, err)
+		}
+		apkg := files[0]
+
+		cs := store.New(filepath.Join(dir, "cards"))
+		result, err := ImportAPKG(apkg, cs, ImportOptions{})
+		if err != nil {
+			t.Fatalf("%s: ImportAPKG() error = %v", format, err)
+		}
+		if got := result.Count(ImportStatusImported); got != 3 {
+			t.Fatalf("%s: imported %d notes; want 3: %+v", format, got, result.Notes)
+		}
+
+		apple := cs.FindCardDirectory("ябълка")
+		m := store.LoadManifest(apple)
+		if m.Translation != "apple" || m.CardType != "en-bg" || m.Directions != "forward,type" || !slices.Equal(m.Tags, []string{"food"}) {
+			t.Errorf("%s: apple manifest = %+v", format, m)
+		}
+		data, err := os.ReadFile(filepath.Join(apple, "audio.mp3"))
+		if err != nil || string(data) != "data of apple/audio.mp3" {
+			t.Errorf("%s: audio.mp3 = %q, %v", format, data, err)
+		}
+		for _, file := range []string{"image.png", "image_attribution.txt", "audio_attribution.txt", store.AudioMetadataFileName} {
+			if _, err := os.Stat(filepath.Join(apple, file)); err != nil {
+				t.Errorf("%s: %v", format, err)
+			}
+		}
+		if assets := m.AssetsOfKind(store.AssetImage); len(assets) != 1 || assets[0].Provider != ImportProvider {
+			t.Errorf("%s: image assets = %+v", format, assets)
+		}
+
+		cat := store.LoadManifest(cs.FindCardDirectory("котка"))
+		if cat.CardType != "bg-bg" || cat.Translation != "домашно животно" {
+			t.Errorf("%s: cat manifest = %+v", format, cat)
+		}
+		if front, back := cat.AssetPath(cs.FindCardDirectory("котка"), store.AssetAudioFront), cat.AssetPath(cs.FindCardDirectory("котка"), store.AssetAudioBack); !strings.HasSuffix(front, "audio_front.mp3") || !strings.HasSuffix(back, "audio_back.mp3") {
+			t.Errorf("%s: cat audio = %q, %q", format, front, back)
+		}
+
+		sentence := store.LoadManifest(cs.FindCardDirectory("Аз обичам ябълки."))
+		if sentence.CardType != "sentence" || sentence.Cloze != "Аз обичам {{c1::ябълки}}." || sentence.Translation != "I love apples." {
+			t.Errorf("%s: sentence manifest = %+v", format, sentence)
+		}
+
+		// Importing again leaves the cards alone.
+		result, err = ImportAPKG(apkg, cs, ImportOptions{})
+		if err != nil {
+			t.Fatalf("%s: second ImportAPKG() error = %v", format, err)
+		}
+		if got := result.Count(ImportStatusSkipped); got != 3 {
+			t.Errorf("%s: skipped %d notes on the second import; want 3: %+v", format, got, result.Notes)
+		}
+	}
+}
+
+func TestImportAPKGReportsMissingMedia(t *testing.T) {
+	cs := store.New(t.TempDir())
+	imp := &importer{cs: cs, source: "test.apkg", mediaByName: map[string]string{}}
+	noteType := &InspectedNoteType{Name: EnBgNoteTypeName, Fields: enBgFieldNames}
+	note := ankiNote{id: 1, fields: NoteFieldValues(Card{Bulgarian: "куче", Translation: "dog", AudioFile: "/x/dog/audio.mp3"}, MediaFileName)}
+
+	result := imp.importNote(note, noteType)
+	if result.Status != ImportStatusImported || !strings.Contains(result.Message, "dog_audio.mp3 (not in the package)") {
+		t.Errorf("importNote() = %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(cs.FindCardDirectory("куче"), "audio.mp3")); !os.IsNotExist(err) {
+		t.Errorf("audio.mp3 exists without media: %v", err)
+	}
+}
+
+func TestMapNote(t *testing.T) {
+	basic := &InspectedNoteType{Name: "Basic", Fields: []string{"Front", "Back"}}
+	cloze := &InspectedNoteType{Name: "Cloze", Fields: []string{"Text", "Back Extra"}, Cloze: true}
+
+	tests := []struct {
+		name     string
+		fields   FieldMapping
+		noteType *InspectedNoteType
+		values   []string
+		want     importedCard
+	}{
+		{
+			name:     "bulgarian on the back",
+			noteType: basic,
+			values:   []string{"cat<br>", `котка [sound:kotka.mp3]<img src="cat.jpg">`},
+			want:     importedCard{word: "котка", translation: "cat", cardType: internal.CardTypeEnBg, audio: []string{"kotka.mp3"}, image: "cat.jpg"},
+		},
+		{
+			name:     "both sides Bulgarian",
+			noteType: basic,
+			values:   []string{"котка", "домашно&nbsp;животно"},
+			want:     importedCard{word: "котка", translation: "домашно животно", cardType: internal.CardTypeBgBg},
+		},
+		{
+			name:     "mapped fields",
+			fields:   FieldMapping{Bulgarian: "back", Translation: "Front"},
+			noteType: basic,
+			values:   []string{"котка", "cat"},
+			want:     importedCard{word: "cat", translation: "котка", cardType: internal.CardTypeEnBg},
+		},
+		{
+			name:     "cloze",
+			noteType: cloze,
+			values:   []string{"<b>Аз</b> обичам {{c1::ябълки}}.", "I love apples."},
+			want:     importedCard{word: "Аз обичам ябълки.", translation: "I love apples.", cardType: internal.CardTypeSentence, cloze: "Аз обичам {{c1::ябълки}}."},
+		},
+	}
+	for _, tt := range tests {
+		imp := &importer{fields: tt.fields}
+		got, err := imp.mapNote(ankiNote{fields: tt.values}, tt.noteType)
+		if err != nil {
+			t.Errorf("%s: mapNote() error = %v", tt.name, err)
+			continue
+		}
+		if got.word != tt.want.word || got.translation != tt.want.translation || got.cardType != tt.want.cardType ||
+			got.cloze != tt.want.cloze || !slices.Equal(got.audio, tt.want.audio) || got.image != tt.want.image {
+			t.Errorf("%s: mapNote() = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	imp := &importer{fields: FieldMapping{Audio: "Sound"}}
+	if _, err := imp.mapNote(ankiNote{fields: []string{"a", "b"}}, basic); err == nil {
+		t.Error("mapNote() with an unknown audio field succeeded; want error")
+	}
+}
+
+func TestParseFieldMapping(t *testing.T) {
+	got, err := ParseFieldMapping(" Bulgarian=Front , translation = Back,audio=Sound,image=Picture ")
+	want := FieldMapping{Bulgarian: "Front", Translation: "Back", Audio: "Sound", Image: "Picture"}
+	if err != nil || got != want {
+		t.Errorf("ParseFieldMapping() = %+v, %v; want %+v", got, err, want)
+	}
+	if got, err := ParseFieldMapping(""); err != nil || got != (FieldMapping{}) {
+		t.Errorf("ParseFieldMapping(\"\") = %+v, %v", got, err)
+	}
+	for _, invalid := range []string{"front", "bulgarian=", "notes=Extra"} {
+		if _, err := ParseFieldMapping(invalid); err == nil {
+			t.Errorf("ParseFieldMapping(%q) succeeded; want error", invalid)
+		}
+	}
+}
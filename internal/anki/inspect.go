@@ -0,0 +1,330 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity ranks the findings of InspectAPKG.
+type Severity string
+
+const (
+	// SeverityError marks a problem that makes Anki reject the package or
+	// import it incompletely.
+	SeverityError Severity = "error"
+	// SeverityWarning marks something Anki accepts that is probably not
+	// intended, such as media no note refers to.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a single problem InspectAPKG found.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Check names the check that found it: schema, notes, cards, fields,
+	// guids or media.
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// InspectedNoteType summarizes a note type of an inspected package.
+type InspectedNoteType struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Cloze     bool     `json:"cloze"`
+	Fields    []string `json:"fields"`
+	Templates []string `json:"templates"`
+	Notes     int      `json:"notes"`
+}
+
+// InspectReport describes an APKG file and everything wrong with it.
+type InspectReport struct {
+	Path string `json:"path"`
+	// Format is the package layout: anki21b, anki21 or legacy.
+	Format        PackageFormat       `json:"format"`
+	Collection    string              `json:"collection"`
+	SchemaVersion int                 `json:"schema_version"`
+	Notes         int                 `json:"notes"`
+	Cards         int                 `json:"cards"`
+	Decks         []string            `json:"decks"`
+	NoteTypes     []InspectedNoteType `json:"note_types"`
+	Media         int                 `json:"media"`
+	Findings      []Finding           `json:"findings"`
+}
+
+// Errors returns the number of error findings.
+func (r *InspectReport) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *InspectReport) addf(severity Severity, check, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+// mediaReference matches the media a note field refers to.
+var mediaReference = regexp.MustCompile(`\[sound:([^\]]+)\]|<img[^>]*?\ssrc=["']?([^"'>\s]+)`)
+
+// InspectAPKG opens an APKG file written by APKGGenerator or by Anki and
+// checks its collection schema, notes and cards, note types, GUIDs and
+// media. Problems are reported as findings; the error is only set when the
+// file cannot be read as a package at all.
+func InspectAPKG(path string) (*InspectReport, error) {
+	pkg, err := openPackage(path)
+	if err != nil {
+		return nil, err
+	}
+	defer pkg.Close()
+
+	report := &InspectReport{Path: path, Format: pkg.format, Collection: pkg.collection}
+	if err := inspectCollection(pkg.db, report); err != nil {
+		return nil, err
+	}
+
+	media, err := pkg.mediaList()
+	if err != nil {
+		report.addf(SeverityError, "media", "%v", err)
+	} else {
+		inspectMedia(pkg.db, pkg.entries, media, report)
+	}
+	return report, nil
+}
+
+// inspectCollection checks the schema, the note types, the notes and the
+// cards of the collection.
+func inspectCollection(db *sql.DB, report *InspectReport) error {
+	if err := db.QueryRow("SELECT ver FROM col").Scan(&report.SchemaVersion); err != nil {
+		report.addf(SeverityError, "schema", "cannot read the col table: %v", err)
+		return nil
+	}
+
+	required := []string{"col", "notes", "cards", "revlog", "graves"}
+	if report.SchemaVersion > 11 {
+		required = append(required, "notetypes", "fields", "templates", "decks")
+	}
+	var missing bool
+	for _, table := range required {
+		var name string
+		err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&name)
+		if err != nil {
+			report.addf(SeverityError, "schema", "table %s is missing", table)
+			missing = true
+		}
+	}
+	if report.SchemaVersion != 11 && (report.SchemaVersion < 15 || report.SchemaVersion > 18) {
+		report.addf(SeverityWarning, "schema", "unknown schema version %d", report.SchemaVersion)
+	}
+	if missing {
+		return nil
+	}
+
+	noteTypes, decks, err := readNoteTypesAndDecks(db, report.SchemaVersion)
+	if err != nil {
+		report.addf(SeverityError, "schema", "%v", err)
+		return nil
+	}
+	for _, name := range decks {
+		report.Decks = append(report.Decks, name)
+	}
+	sort.Strings(report.Decks)
+
+	if err := inspectNotes(db, noteTypes, report); err != nil {
+		return err
+	}
+	if err := inspectCards(db, noteTypes, decks, report); err != nil {
+		return err
+	}
+
+	for _, noteType := range noteTypes {
+		report.NoteTypes = append(report.NoteTypes, *noteType)
+	}
+	sort.Slice(report.NoteTypes, func(i, j int) bool { return report.NoteTypes[i].Name < report.NoteTypes[j].Name })
+	return nil
+}
+
+// inspectNotes checks that every note has a known note type, the field
+// count of that type, a unique GUID and at least one card.
+func inspectNotes(db *sql.DB, noteTypes map[int64]*InspectedNoteType, report *InspectReport) error {
+	guids := make(map[string]int64)
+	err := queryRows(db, "SELECT n.id, n.guid, n.mid, n.flds, n.sfld, (SELECT COUNT(*) FROM cards c WHERE c.nid = n.id) FROM notes n ORDER BY n.id", func(rows *sql.Rows) error {
+		var id, mid int64
+		var guid, flds, sfld string
+		var cards int
+		if err := rows.Scan(&id, &guid, &mid, &flds, &sfld, &cards); err != nil {
+			return err
+		}
+		report.Notes++
+		label := noteLabel(id, sfld)
+
+		switch other, seen := guids[guid]; {
+		case guid == "":
+			report.addf(SeverityError, "guids", "%s has no GUID", label)
+		case seen:
+			report.addf(SeverityError, "guids", "%s has the same GUID %q as note %d", label, guid, other)
+		default:
+			guids[guid] = id
+		}
+
+		noteType, ok := noteTypes[mid]
+		if !ok {
+			report.addf(SeverityError, "notes", "%s uses the unknown note type %d", label, mid)
+		} else {
+			noteType.Notes++
+			if got := len(strings.Split(flds, "\x1f")); got != len(noteType.Fields) {
+				report.addf(SeverityError, "fields", "%s has %d fields, note type %q has %d", label, got, noteType.Name, len(noteType.Fields))
+			}
+		}
+		if cards == 0 {
+			report.addf(SeverityWarning, "notes", "%s has no cards", label)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read notes: %w", err)
+	}
+	return nil
+}
+
+// inspectCards checks that every card belongs to an existing note and deck
+// and that its template exists.
+func inspectCards(db *sql.DB, noteTypes map[int64]*InspectedNoteType, decks map[int64]string, report *InspectReport) error {
+	err := queryRows(db, "SELECT c.id, c.nid, c.did, c.ord, n.mid FROM cards c LEFT JOIN notes n ON n.id = c.nid ORDER BY c.id", func(rows *sql.Rows) error {
+		var id, nid, did int64
+		var ord int
+		var mid sql.NullInt64
+		if err := rows.Scan(&id, &nid, &did, &ord, &mid); err != nil {
+			return err
+		}
+		report.Cards++
+
+		if !mid.Valid {
+			report.addf(SeverityError, "cards", "card %d belongs to the missing note %d", id, nid)
+			return nil
+		}
+		if _, ok := decks[did]; !ok {
+			report.addf(SeverityError, "cards", "card %d is in the missing deck %d", id, did)
+		}
+		if noteType, ok := noteTypes[mid.Int64]; ok && !noteType.Cloze && ord >= len(noteType.Templates) {
+			report.addf(SeverityError, "cards", "card %d of note %d uses template %d, note type %q has %d", id, nid, ord, noteType.Name, len(noteType.Templates))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read cards: %w", err)
+	}
+	return nil
+}
+
+func noteLabel(id int64, sortField string) string {
+	if sortField == "" {
+		return fmt.Sprintf("note %d", id)
+	}
+	return fmt.Sprintf("note %d (%s)", id, sortField)
+}
+
+// inspectMedia compares the media list with the zip entries and with the
+// media the notes refer to.
+func inspectMedia(db *sql.DB, entries map[string]*zip.File, media map[string]string, report *InspectReport) {
+	report.Media = len(media)
+	names := make(map[string]bool, len(media))
+	for _, key := range sortedKeys(media) {
+		name := media[key]
+		if _, ok := entries[key]; !ok {
+			report.addf(SeverityError, "media", "%s is listed as zip entry %s, which is missing", name, key)
+		}
+		if names[name] {
+			report.addf(SeverityError, "media", "%s is listed more than once", name)
+		}
+		names[name] = true
+	}
+	for _, key := range sortedKeys(entries) {
+		if _, err := strconv.Atoi(key); err == nil && media[key] == "" {
+			report.addf(SeverityWarning, "media", "zip entry %s is not in the media list", key)
+		}
+	}
+
+	referenced := make(map[string]bool)
+	err := queryRows(db, "SELECT id, sfld, flds FROM notes ORDER BY id", func(rows *sql.Rows) error {
+		var id int64
+		var sfld, flds string
+		if err := rows.Scan(&id, &sfld, &flds); err != nil {
+			return err
+		}
+		for _, match := range mediaReference.FindAllStringSubmatch(flds, -1) {
+			name := html.UnescapeString(match[1] + match[2])
+			if !names[name] && !referenced[name] {
+				report.addf(SeverityError, "media", "%s refers to %s, which is not in the package", noteLabel(id, sfld), name)
+			}
+			referenced[name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		// A broken schema was reported already; without notes there is
+		// nothing to compare the media with.
+		return
+	}
+
+	for _, key := range sortedKeys(media) {
+		// Anki keeps files starting with "_" for use in templates.
+		if name := media[key]; !referenced[name] && !strings.HasPrefix(name, "_") {
+			report.addf(SeverityWarning, "media", "%s is not used by any note", name)
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in numeric order where they are numbers,
+// so findings come out in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// WriteText writes the report in a human readable form.
+func (r *InspectReport) WriteText(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s\n", r.Path)
+	fmt.Fprintf(&buf, "  Format:     %s (%s, schema %d)\n", r.Format, r.Collection, r.SchemaVersion)
+	fmt.Fprintf(&buf, "  Notes:      %d\n", r.Notes)
+	fmt.Fprintf(&buf, "  Cards:      %d\n", r.Cards)
+	fmt.Fprintf(&buf, "  Media:      %d\n", r.Media)
+	fmt.Fprintf(&buf, "  Decks:      %s\n", strings.Join(r.Decks, ", "))
+	for _, noteType := range r.NoteTypes {
+		fmt.Fprintf(&buf, "  Note type:  %s (%d notes, %d fields, %d templates)\n",
+			noteType.Name, noteType.Notes, len(noteType.Fields), len(noteType.Templates))
+	}
+
+	if len(r.Findings) == 0 {
+		fmt.Fprintf(&buf, "No problems found.\n")
+	} else {
+		for _, f := range r.Findings {
+			fmt.Fprintf(&buf, "  %-7s [%s] %s\n", f.Severity, f.Check, f.Message)
+		}
+		fmt.Fprintf(&buf, "%d error(s), %d warning(s)\n", r.Errors(), len(r.Findings)-r.Errors())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
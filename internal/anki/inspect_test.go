@@ -0,0 +1,154 @@
+package anki
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generateTestPackage writes an APKG with two cards, one with audio and an
+// image, and returns its path.
+func generateTestPackage(t *testing.T, format PackageFormat) string {
+	t.Helper()
+	dir := t.TempDir()
+	audioFile := filepath.Join(dir, "audio.mp3")
+	imageFile := filepath.Join(dir, "image.jpg")
+	for _, path := range []string{audioFile, imageFile} {
+		if err := os.WriteFile(path, []byte("data of "+path), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.SetPackageFormat(format)
+	gen.AddCard(Card{Bulgarian: "ябълка", Translation: "apple", AudioFile: audioFile, ImageFile: imageFile})
+	gen.AddCard(Card{Bulgarian: "Аз обичам ябълки.", CardType: "sentence", Cloze: "Аз обичам {{c1::ябълки}}."})
+	if err := gen.GenerateAPKG(filepath.Join(dir, "test.apkg")); err != nil {
+		t.Fatalf("GenerateAPKG() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.apkg"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 apkg file, found %v (%v)", files, err)
+	}
+	return files[0]
+}
+
+func TestInspectAPKG(t *testing.T) {
+	for _, format := range []PackageFormat{PackageFormatLegacy, PackageFormatAnki21b} {
+		report, err := InspectAPKG(generateTestPackage(t, format))
+		if err != nil {
+			t.Fatalf("%s: InspectAPKG() error = %v", format, err)
+		}
+		if len(report.Findings) > 0 {
+			t.Errorf("%s: findings = %+v; want none", format, report.Findings)
+		}
+		if report.Format != format || report.SchemaVersion != 11 {
+			t.Errorf("%s: format = %s, schema %d", format, report.Format, report.SchemaVersion)
+		}
+		// The word note gets a forward and a reverse card.
+		if report.Notes != 2 || report.Cards != 3 || report.Media != 2 {
+			t.Errorf("%s: %d notes, %d cards, %d media; want 2, 3, 2", format, report.Notes, report.Cards, report.Media)
+		}
+		if len(report.Decks) != 2 || report.Decks[1] != "Test Deck" {
+			t.Errorf("%s: decks = %q; want Default and Test Deck", format, report.Decks)
+		}
+
+		var text bytes.Buffer
+		if err := report.WriteText(&text); err != nil || !strings.Contains(text.String(), "No problems found.") {
+			t.Errorf("%s: WriteText() = %q, %v", format, text.String(), err)
+		}
+	}
+}
+
+func TestInspectAPKGFindsProblems(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging")
+	if err := os.Mkdir(staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	audioFile := filepath.Join(dir, "audio.mp3")
+	if err := os.WriteFile(audioFile, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.AddCard(Card{Bulgarian: "котка", Translation: "cat", AudioFile: audioFile})
+	if err := gen.copyMediaFiles(staging); err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.createMediaMapping(staging); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(staging, "collection.anki2")
+	if err := gen.createDatabase(dbPath); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		// A second note with the GUID of the first, too few fields and an
+		// image that is not in the package.
+		`INSERT INTO notes SELECT id + 1, guid, mid, mod, usn, tags, 'куче' || char(31) || '<img src="dog.jpg">', 'куче', csum, flags, data FROM notes`,
+		`INSERT INTO cards SELECT id + 10, nid + 1, did, 7, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data FROM cards WHERE ord = 0`,
+		`INSERT INTO cards SELECT id + 20, 42, did, 0, mod, usn, type, queue, due, ivl, factor, reps, lapses, left, odue, odid, flags, data FROM cards WHERE ord = 0`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = db.Close()
+	// The audio file is listed in the media map but missing from the zip.
+	if err := os.Remove(filepath.Join(staging, "0")); err != nil {
+		t.Fatal(err)
+	}
+
+	apkg := filepath.Join(dir, "broken.apkg")
+	if err := NewZipPackager().CreatePackage(staging, apkg); err != nil {
+		t.Fatal(err)
+	}
+	report, err := InspectAPKG(apkg)
+	if err != nil {
+		t.Fatalf("InspectAPKG() error = %v", err)
+	}
+
+	want := map[string]string{
+		"guids":  "has the same GUID",
+		"fields": "has 2 fields",
+		"cards":  "uses template 7",
+		"media":  "dog.jpg, which is not in the package",
+	}
+	for check, message := range want {
+		var found bool
+		for _, f := range report.Findings {
+			found = found || (f.Check == check && f.Severity == SeverityError && strings.Contains(f.Message, message))
+		}
+		if !found {
+			t.Errorf("no %s error containing %q in %+v", check, message, report.Findings)
+		}
+	}
+	var missingNote, missingEntry bool
+	for _, f := range report.Findings {
+		missingNote = missingNote || strings.Contains(f.Message, "missing note 42")
+		missingEntry = missingEntry || strings.Contains(f.Message, "zip entry 0, which is missing")
+	}
+	if !missingNote || !missingEntry {
+		t.Errorf("missing note reported = %v, missing zip entry reported = %v: %+v", missingNote, missingEntry, report.Findings)
+	}
+}
+
+func TestInspectAPKGRejectsNonPackages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InspectAPKG(path); err == nil {
+		t.Error("InspectAPKG() succeeded for a text file; want error")
+	}
+}
@@ -0,0 +1,222 @@
+package anki
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"codeberg.org/snonux/totalrecall/internal"
+)
+
+// Note type names as they appear in Anki. Exporters look existing note types
+// up by these names, so they must not change between releases.
+const (
+	EnBgNoteTypeName     = "Vocabulary from TotalRecall (Basic + Reverse)"
+	BgBgNoteTypeName     = "Bulgarian-Bulgarian from TotalRecall"
+	SentenceNoteTypeName = "Sentence from TotalRecall (Cloze)"
+)
+
+// Field names of the note types. Fields are only ever appended, so that
+// merging the updated note type on import keeps existing field contents: a
+// package with new fields changes the schema of a note type the collection
+// already has (the model IDs are stable), and Anki asks the user to accept
+// that by merging.
+//
+// NoForward, NoReverse and TypeIn hold the card directions of a word note
+// ("y" when set). The templates only render a front when the direction is
+// chosen, which is how Anki decides which cards a note has. The forward and
+// reverse flags are negated so that notes of older releases, which have the
+// fields empty, keep both of their cards.
+//
+// The *Slow fields hold the slow renditions of the audio fields, shown next
+// to them on the answer side.
+var (
+	enBgFieldNames = []string{"English", "Bulgarian", "Image", "Audio", "Notes",
+		"IPA", "Transliteration", "Example", "ExampleTranslation",
+		"NoForward", "NoReverse", "TypeIn", "AudioSlow"}
+	bgBgFieldNames = []string{"BulgarianFront", "BulgarianBack", "Image", "AudioFront", "AudioBack", "Notes",
+		"IPA", "Transliteration", "Example", "ExampleTranslation",
+		"NoForward", "NoReverse", "TypeIn", "AudioFrontSlow", "AudioBackSlow"}
+	// Text holds the cloze deletions, Sentence the plain sentence that
+	// identifies the note.
+	sentenceFieldNames = []string{"Text", "Sentence", "Translation", "Image", "Audio", "Notes",
+		"IPA", "Transliteration", "AudioSlow"}
+)
+
+// NoteType describes a note type independently of the collection format, for
+// exporters that create note types through an API rather than in
+// collection.anki2.
+type NoteType struct {
+	Name string
+	// Fields lists the field names in order.
+	Fields    []string
+	Templates []NoteTemplate
+	CSS       string
+	// KeyField is the field that identifies a note of this type: the
+	// Bulgarian word or sentence.
+	KeyField string
+	// Cloze is set for cloze note types, whose cards come from the cloze
+	// deletions of a note rather than from the templates.
+	Cloze bool
+}
+
+// NoteTemplate is one card template of a note type.
+type NoteTemplate struct {
+	Name  string
+	Front string
+	Back  string
+}
+
+// EnBgNoteType returns the English–Bulgarian note type.
+func (c *CardTemplate) EnBgNoteType() NoteType {
+	return NoteType{
+		Name:   EnBgNoteTypeName,
+		Fields: append([]string(nil), enBgFieldNames...),
+		Templates: []NoteTemplate{
+			{Name: "Forward", Front: c.enBgFront, Back: c.enBgBack},
+			{Name: "Reverse", Front: c.enBgReverseFront, Back: c.enBgReverseBack},
+			{Name: "Type answer", Front: c.enBgTypeFront, Back: c.enBgTypeBack},
+		},
+		CSS:      c.css,
+		KeyField: "Bulgarian",
+	}
+}
+
+// BgBgNoteType returns the Bulgarian–Bulgarian note type.
+func (c *CardTemplate) BgBgNoteType() NoteType {
+	return NoteType{
+		Name:   BgBgNoteTypeName,
+		Fields: append([]string(nil), bgBgFieldNames...),
+		Templates: []NoteTemplate{
+			{Name: "Forward", Front: c.bgBgFront, Back: c.bgBgBack},
+			{Name: "Reverse", Front: c.bgBgReverseFront, Back: c.bgBgReverseBack},
+			{Name: "Type answer", Front: c.bgBgTypeFront, Back: c.bgBgTypeBack},
+		},
+		CSS:      c.css,
+		KeyField: "BulgarianFront",
+	}
+}
+
+// SentenceNoteType returns the cloze note type of sentence cards.
+func (c *CardTemplate) SentenceNoteType() NoteType {
+	return NoteType{
+		Name:   SentenceNoteTypeName,
+		Fields: append([]string(nil), sentenceFieldNames...),
+		Templates: []NoteTemplate{
+			{Name: "Cloze", Front: c.sentenceFront, Back: c.sentenceBack},
+		},
+		CSS:      c.css,
+		KeyField: "Sentence",
+		Cloze:    true,
+	}
+}
+
+// noteTypeFieldNames returns the field names of the note type of a card
+// type.
+func noteTypeFieldNames(cardType string) []string {
+	switch cardType {
+	case "bg-bg":
+		return bgBgFieldNames
+	case "sentence":
+		return sentenceFieldNames
+	}
+	return enBgFieldNames
+}
+
+// NoteTypeFor returns the note type card is exported as.
+func (c *CardTemplate) NoteTypeFor(card Card) NoteType {
+	switch card.CardType {
+	case "bg-bg":
+		return c.BgBgNoteType()
+	case "sentence":
+		return c.SentenceNoteType()
+	}
+	return c.EnBgNoteType()
+}
+
+// MediaFileName is the collection media name of a card file. The card
+// directory ID keeps equally named files of different cards apart.
+func MediaFileName(path string) string {
+	return fmt.Sprintf("%s_%s", filepath.Base(filepath.Dir(path)), filepath.Base(path))
+}
+
+// NoteFieldValues returns the field values of card in the field order of its
+// note type. mediaName maps a card file to its collection media name and
+// returns "" for files that are not exported; their fields stay empty.
+func NoteFieldValues(card Card, mediaName func(path string) string) []string {
+	media := func(path, format string) string {
+		if path == "" {
+			return ""
+		}
+		if name := mediaName(path); name != "" {
+			return fmt.Sprintf(format, name)
+		}
+		return ""
+	}
+	image := media(card.ImageFile, `<img src="%s">`)
+	audio := media(card.AudioFile, "[sound:%s]")
+	audioSlow := media(card.AudioFileSlow, "[sound:%s]")
+	directions := card.Directions.Or(internal.DefaultCardDirections())
+
+	if card.CardType == "sentence" {
+		return []string{
+			card.Cloze,
+			card.Bulgarian,
+			card.Translation,
+			image,
+			audio,
+			card.Notes,
+			card.IPA,
+			card.Transliteration,
+			audioSlow,
+		}
+	}
+
+	if card.CardType == "bg-bg" {
+		return []string{
+			card.Bulgarian,
+			card.Translation,
+			image,
+			audio,
+			media(card.AudioFileBack, "[sound:%s]"),
+			card.Notes,
+			card.IPA,
+			card.Transliteration,
+			card.Example,
+			card.ExampleTranslation,
+			flag(!directions.Forward),
+			flag(!directions.Reverse),
+			flag(directions.TypeIn),
+			audioSlow,
+			media(card.AudioFileBackSlow, "[sound:%s]"),
+		}
+	}
+
+	english := card.Translation
+	if english == "" {
+		english = "Translation needed"
+	}
+	return []string{
+		english,
+		card.Bulgarian,
+		image,
+		audio,
+		card.Notes,
+		card.IPA,
+		card.Transliteration,
+		card.Example,
+		card.ExampleTranslation,
+		flag(!directions.Forward),
+		flag(!directions.Reverse),
+		flag(directions.TypeIn),
+		audioSlow,
+	}
+}
+
+// flag renders a boolean note field: "y" when set, empty otherwise, so
+// templates can test it with {{#Field}}.
+func flag(set bool) string {
+	if set {
+		return "y"
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package anki
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PackageFormat is the layout of an APKG file.
+type PackageFormat string
+
+const (
+	// PackageFormatAnki21b is the package format of Anki 2.1.50 and newer:
+	// a zstd-compressed collection.anki21b, zstd-compressed media files, a
+	// protobuf media list and a meta file naming the package version.
+	PackageFormatAnki21b PackageFormat = "anki21b"
+	// PackageFormatLegacy is the collection.anki2 package with a JSON media
+	// map that every Anki version can import.
+	PackageFormatLegacy PackageFormat = "legacy"
+)
+
+// DefaultPackageFormat is the format APKG exports use unless configured.
+const DefaultPackageFormat = PackageFormatAnki21b
+
+// packageVersionLatest is PackageMetadata.Version.VERSION_LATEST of Anki's
+// package.proto.
+const packageVersionLatest = 3
+
+// ParsePackageFormat parses a package format name; the empty string is the
+// default format.
+func ParsePackageFormat(s string) (PackageFormat, error) {
+	switch format := PackageFormat(strings.ToLower(strings.TrimSpace(s))); format {
+	case "":
+		return DefaultPackageFormat, nil
+	case PackageFormatAnki21b, PackageFormatLegacy:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown APKG format %q (want %s or %s)", s, PackageFormatAnki21b, PackageFormatLegacy)
+	}
+}
+
+// convertToAnki21b turns the legacy package staged in tempDir into the
+// anki21b layout. The collection keeps schema 11, which Anki upgrades when it
+// opens the package. collection.anki2 is replaced by a one-note collection
+// that asks users of Anki versions without anki21b support to update, the
+// same way Anki's own exports do.
+func (g *APKGGenerator) convertToAnki21b(tempDir string) error {
+	collection, err := os.ReadFile(filepath.Join(tempDir, "collection.anki2"))
+	if err != nil {
+		return fmt.Errorf("failed to read collection: %w", err)
+	}
+	if err := writeZstdFile(filepath.Join(tempDir, "collection.anki21b"), collection); err != nil {
+		return err
+	}
+	if err := g.createUpdateNotice(filepath.Join(tempDir, "collection.anki2")); err != nil {
+		return fmt.Errorf("failed to create update notice collection: %w", err)
+	}
+
+	mediaList, err := g.compressMediaFiles(tempDir)
+	if err != nil {
+		return err
+	}
+	if err := writeZstdFile(filepath.Join(tempDir, "media"), mediaList); err != nil {
+		return err
+	}
+
+	meta := protowire.AppendTag(nil, 1, protowire.VarintType)
+	meta = protowire.AppendVarint(meta, packageVersionLatest)
+	return os.WriteFile(filepath.Join(tempDir, "meta"), meta, 0644)
+}
+
+// compressMediaFiles compresses the numbered media files in place and
+// returns the MediaEntries protobuf listing them. Anki identifies a media
+// file by its position in the list, which is its number.
+func (g *APKGGenerator) compressMediaFiles(tempDir string) ([]byte, error) {
+	names := make([]string, len(g.mediaFiles))
+	for name, num := range g.mediaFiles {
+		names[num] = name
+	}
+
+	var list []byte
+	for num, name := range names {
+		path := filepath.Join(tempDir, fmt.Sprintf("%d", num))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read media file %s: %w", name, err)
+		}
+		if err := writeZstdFile(path, data); err != nil {
+			return nil, fmt.Errorf("failed to write media file %s: %w", name, err)
+		}
+
+		sum := sha1.Sum(data)
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, name)
+		entry = protowire.AppendTag(entry, 2, protowire.VarintType)
+		entry = protowire.AppendVarint(entry, uint64(len(data)))
+		entry = protowire.AppendTag(entry, 3, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, sum[:])
+
+		list = protowire.AppendTag(list, 1, protowire.BytesType)
+		list = protowire.AppendBytes(list, entry)
+	}
+	return list, nil
+}
+
+// createUpdateNotice writes a legacy collection with a single card telling
+// users of old Anki versions that the package needs a newer one.
+func (g *APKGGenerator) createUpdateNotice(dbPath string) error {
+	if err := os.Remove(dbPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	notice := NewAPKGGenerator(g.deckName)
+	notice.templates = g.templates
+	notice.AddCard(Card{
+		Bulgarian:   "Моля, обновете Anki",
+		Translation: "Please update to Anki 2.1.50 or newer and import this file again, or export the deck in the legacy APKG format.",
+	})
+	return notice.createDatabase(dbPath)
+}
+
+// writeZstdFile writes data to path compressed with zstd.
+func writeZstdFile(path string, data []byte) error {
+	return os.WriteFile(path, zstdEncoder.EncodeAll(data, nil), 0644)
+}
+
+// zstdDecompress decompresses a zstd frame written by Anki or by
+// writeZstdFile.
+func zstdDecompress(data []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(data, nil)
+}
+
+// The encoder and decoder are safe for concurrent EncodeAll and DecodeAll
+// calls, so one of each serves every package. Without options their
+// constructors cannot fail.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
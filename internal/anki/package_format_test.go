@@ -0,0 +1,147 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParsePackageFormat(t *testing.T) {
+	tests := map[string]PackageFormat{
+		"":         PackageFormatAnki21b,
+		"anki21b":  PackageFormatAnki21b,
+		" Legacy ": PackageFormatLegacy,
+	}
+	for in, want := range tests {
+		got, err := ParsePackageFormat(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePackageFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParsePackageFormat("anki2"); err == nil {
+		t.Error("ParsePackageFormat(\"anki2\") succeeded; want error")
+	}
+}
+
+func TestGenerateAPKGAnki21b(t *testing.T) {
+	tempDir := t.TempDir()
+	audioData := []byte("test audio data")
+	audioFile := filepath.Join(tempDir, "audio.mp3")
+	if err := os.WriteFile(audioFile, audioData, 0644); err != nil {
+		t.Fatalf("Failed to create test audio file: %v", err)
+	}
+
+	gen := NewAPKGGenerator("Test Deck")
+	gen.AddCard(Card{Bulgarian: "ябълка", Translation: "apple", AudioFile: audioFile})
+	if err := gen.GenerateAPKG(filepath.Join(tempDir, "test.apkg")); err != nil {
+		t.Fatalf("GenerateAPKG() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(tempDir, "*.apkg"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 apkg file, found %v (%v)", files, err)
+	}
+	entries := readZipEntries(t, files[0])
+
+	for _, name := range []string{"collection.anki2", "collection.anki21b", "media", "meta", "0"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("Required file %q not found in APKG", name)
+		}
+	}
+	if !bytes.Equal(entries["meta"], []byte{0x08, packageVersionLatest}) {
+		t.Errorf("meta = %x; want version %d", entries["meta"], packageVersionLatest)
+	}
+
+	collection := mustZstdDecompress(t, entries["collection.anki21b"])
+	if !bytes.HasPrefix(collection, []byte("SQLite format 3\x00")) {
+		t.Error("collection.anki21b does not decompress to an SQLite database")
+	}
+	if got := mustZstdDecompress(t, entries["0"]); !bytes.Equal(got, audioData) {
+		t.Errorf("media file 0 = %q; want %q", got, audioData)
+	}
+
+	name, size, sum := decodeMediaEntry(t, mustZstdDecompress(t, entries["media"]))
+	wantSum := sha1.Sum(audioData)
+	if !strings.HasSuffix(name, "audio.mp3") || size != uint64(len(audioData)) || !bytes.Equal(sum, wantSum[:]) {
+		t.Errorf("media entry = %q, %d, %x; want *audio.mp3, %d, %x", name, size, sum, len(audioData), wantSum)
+	}
+}
+
+func readZipEntries(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open APKG as zip: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	entries := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file.Name, err)
+		}
+		entries[file.Name] = data
+	}
+	return entries
+}
+
+func mustZstdDecompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+	out, err := zstdDecompress(data)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	return out
+}
+
+// decodeMediaEntry decodes a MediaEntries message holding a single entry.
+func decodeMediaEntry(t *testing.T, list []byte) (name string, size uint64, sum []byte) {
+	t.Helper()
+	num, typ, n := protowire.ConsumeTag(list)
+	if num != 1 || typ != protowire.BytesType {
+		t.Fatalf("unexpected MediaEntries field %d", num)
+	}
+	entry, m := protowire.ConsumeBytes(list[n:])
+	if m < 0 || n+m != len(list) {
+		t.Fatalf("MediaEntries has %d bytes; want a single entry", len(list))
+	}
+
+	for len(entry) > 0 {
+		num, typ, n := protowire.ConsumeTag(entry)
+		if n < 0 {
+			t.Fatalf("invalid MediaEntry tag")
+		}
+		entry = entry[n:]
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(entry)
+			name = string(v)
+		case num == 2 && typ == protowire.VarintType:
+			size, n = protowire.ConsumeVarint(entry)
+		case num == 3 && typ == protowire.BytesType:
+			sum, n = protowire.ConsumeBytes(entry)
+		default:
+			t.Fatalf("unexpected MediaEntry field %d", num)
+		}
+		if n < 0 {
+			t.Fatalf("invalid MediaEntry field %d", num)
+		}
+		entry = entry[n:]
+	}
+	return name, size, sum
+}
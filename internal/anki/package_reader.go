@@ -0,0 +1,305 @@
+package anki
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// packageFormatAnki21 is the uncompressed collection.anki21 layout of Anki
+// 2.1 before anki21b; APKGGenerator does not write it.
+const packageFormatAnki21 PackageFormat = "anki21"
+
+// apkgPackage is an opened APKG file whose collection was unpacked into a
+// temporary SQLite file.
+type apkgPackage struct {
+	reader  *zip.ReadCloser
+	entries map[string]*zip.File
+	// format and collection name the package layout and the zip entry the
+	// collection was read from.
+	format     PackageFormat
+	collection string
+	db         *sql.DB
+	tempDir    string
+}
+
+// openPackage opens the APKG file at path. The collection is read from the
+// newest layout the package has, because packages of newer Anki versions
+// carry a dummy collection.anki2 for older clients.
+func openPackage(path string) (_ *apkgPackage, err error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	pkg := &apkgPackage{reader: reader, entries: make(map[string]*zip.File, len(reader.File))}
+	defer func() {
+		if err != nil {
+			pkg.Close()
+		}
+	}()
+	for _, file := range reader.File {
+		pkg.entries[file.Name] = file
+	}
+
+	for _, candidate := range []struct {
+		name   string
+		format PackageFormat
+	}{
+		{"collection.anki21b", PackageFormatAnki21b},
+		{"collection.anki21", packageFormatAnki21},
+		{"collection.anki2", PackageFormatLegacy},
+	} {
+		if _, ok := pkg.entries[candidate.name]; ok {
+			pkg.format = candidate.format
+			pkg.collection = candidate.name
+			break
+		}
+	}
+	if pkg.collection == "" {
+		return nil, fmt.Errorf("package contains no collection (collection.anki21b, collection.anki21 or collection.anki2)")
+	}
+
+	data, err := pkg.readEntry(pkg.collection)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite cannot open a database from memory.
+	if pkg.tempDir, err = os.MkdirTemp("", "anki_package_*"); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	dbPath := filepath.Join(pkg.tempDir, "collection.db")
+	if err := os.WriteFile(dbPath, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write collection: %w", err)
+	}
+	if pkg.db, err = sql.Open("sqlite3", dbPath); err != nil {
+		return nil, fmt.Errorf("failed to open collection: %w", err)
+	}
+	return pkg, nil
+}
+
+// Close releases the package and removes the unpacked collection.
+func (p *apkgPackage) Close() {
+	if p.db != nil {
+		_ = p.db.Close()
+	}
+	if p.tempDir != "" {
+		_ = os.RemoveAll(p.tempDir)
+	}
+	_ = p.reader.Close()
+}
+
+// readEntry returns the content of a zip entry. The collection and the media
+// files of anki21b packages are zstd-compressed and are returned
+// decompressed.
+func (p *apkgPackage) readEntry(name string) ([]byte, error) {
+	file, ok := p.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("package has no %s entry", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if p.format == PackageFormatAnki21b && name != "meta" {
+		if data, err = zstdDecompress(data); err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+// mediaList returns the media file names of the package by their zip
+// entry name: a JSON map in older packages, a zstd-compressed protobuf list
+// in anki21b packages.
+func (p *apkgPackage) mediaList() (map[string]string, error) {
+	data, err := p.readEntry("media")
+	if err != nil {
+		return nil, err
+	}
+
+	media := make(map[string]string)
+	if p.format != PackageFormatAnki21b {
+		if err := json.Unmarshal(data, &media); err != nil {
+			return nil, fmt.Errorf("cannot decode the media map: %w", err)
+		}
+		return media, nil
+	}
+
+	for index := 0; len(data) > 0; index++ {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			return nil, fmt.Errorf("cannot decode the media list")
+		}
+		entry, m := protowire.ConsumeBytes(data[n:])
+		if m < 0 {
+			return nil, fmt.Errorf("cannot decode the media list")
+		}
+		data = data[n+m:]
+		media[strconv.Itoa(index)] = mediaEntryName(entry)
+	}
+	return media, nil
+}
+
+// notetypeKind returns the kind of a NotetypeConfig protobuf message: 0 for
+// standard note types, 1 for cloze.
+func notetypeKind(config []byte) uint64 {
+	for len(config) > 0 {
+		num, typ, n := protowire.ConsumeTag(config)
+		if n < 0 {
+			return 0
+		}
+		config = config[n:]
+		if num == 1 && typ == protowire.VarintType {
+			kind, _ := protowire.ConsumeVarint(config)
+			return kind
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, config); n < 0 {
+			return 0
+		}
+		config = config[n:]
+	}
+	return 0
+}
+
+func queryRows(db *sql.DB, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// mediaEntryName returns the name field of a MediaEntry protobuf message.
+func mediaEntryName(entry []byte) string {
+	for len(entry) > 0 {
+		num, typ, n := protowire.ConsumeTag(entry)
+		if n < 0 {
+			return ""
+		}
+		entry = entry[n:]
+		if num == 1 && typ == protowire.BytesType {
+			name, _ := protowire.ConsumeBytes(entry)
+			return string(name)
+		}
+		if n = protowire.ConsumeFieldValue(num, typ, entry); n < 0 {
+			return ""
+		}
+		entry = entry[n:]
+	}
+	return ""
+}
+
+// readNoteTypesAndDecks reads the note types and deck names from the JSON
+// columns of the col table (schema 11) or from their own tables (newer
+// schemas).
+func readNoteTypesAndDecks(db *sql.DB, schema int) (map[int64]*InspectedNoteType, map[int64]string, error) {
+	noteTypes := make(map[int64]*InspectedNoteType)
+	decks := make(map[int64]string)
+
+	if schema <= 11 {
+		var modelsJSON, decksJSON string
+		if err := db.QueryRow("SELECT models, decks FROM col").Scan(&modelsJSON, &decksJSON); err != nil {
+			return nil, nil, fmt.Errorf("cannot read note types: %w", err)
+		}
+		var models map[string]struct {
+			Name string `json:"name"`
+			Type int    `json:"type"`
+			Flds []struct {
+				Name string `json:"name"`
+			} `json:"flds"`
+			Tmpls []struct {
+				Name string `json:"name"`
+			} `json:"tmpls"`
+		}
+		if err := json.Unmarshal([]byte(modelsJSON), &models); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode note types: %w", err)
+		}
+		for key, model := range models {
+			id, _ := strconv.ParseInt(key, 10, 64)
+			noteType := &InspectedNoteType{ID: id, Name: model.Name, Cloze: model.Type == 1}
+			for _, field := range model.Flds {
+				noteType.Fields = append(noteType.Fields, field.Name)
+			}
+			for _, tmpl := range model.Tmpls {
+				noteType.Templates = append(noteType.Templates, tmpl.Name)
+			}
+			noteTypes[id] = noteType
+		}
+
+		var deckMap map[string]struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal([]byte(decksJSON), &deckMap); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode decks: %w", err)
+		}
+		for key, deck := range deckMap {
+			id, _ := strconv.ParseInt(key, 10, 64)
+			decks[id] = deck.Name
+		}
+		return noteTypes, decks, nil
+	}
+
+	if err := queryRows(db, "SELECT id, name, config FROM notetypes", func(rows *sql.Rows) error {
+		var noteType InspectedNoteType
+		var config []byte
+		if err := rows.Scan(&noteType.ID, &noteType.Name, &config); err != nil {
+			return err
+		}
+		noteType.Cloze = notetypeKind(config) == 1
+		noteTypes[noteType.ID] = &noteType
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("cannot read note types: %w", err)
+	}
+	for _, table := range []string{"fields", "templates"} {
+		if err := queryRows(db, "SELECT ntid, name FROM "+table+" ORDER BY ntid, ord", func(rows *sql.Rows) error {
+			var id int64
+			var name string
+			if err := rows.Scan(&id, &name); err != nil {
+				return err
+			}
+			if noteType, ok := noteTypes[id]; ok && table == "fields" {
+				noteType.Fields = append(noteType.Fields, name)
+			} else if ok {
+				noteType.Templates = append(noteType.Templates, name)
+			}
+			return nil
+		}); err != nil {
+			return nil, nil, fmt.Errorf("cannot read %s: %w", table, err)
+		}
+	}
+	if err := queryRows(db, "SELECT id, name FROM decks", func(rows *sql.Rows) error {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		// Newer schemas separate deck levels with 0x1f instead of "::".
+		decks[id] = strings.ReplaceAll(name, "\x1f", DeckSeparator)
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("cannot read decks: %w", err)
+	}
+	return noteTypes, decks, nil
+}
@@ -7,11 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
-	"path/filepath"
 	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // SQLiteSchemer creates and populates the Anki SQLite database (collection.anki2).
@@ -152,14 +154,17 @@ func (s *SQLiteSchemer) insertCollection(db *sql.DB, g *APKGGenerator, tmpl *Car
 			"extendNew":        10,
 			"extendRev":        50,
 		},
-		fmt.Sprintf("%d", g.deckID): map[string]interface{}{
-			"id":               g.deckID,
-			"name":             g.deckName,
+	}
+	// Subdecks share the options preset of the deck.
+	for _, name := range g.deckNames() {
+		deck := map[string]interface{}{
+			"id":               g.deckIDOf(name),
+			"name":             name,
 			"mod":              now,
-			"desc":             "Bulgarian vocabulary cards created by TotalRecall",
+			"desc":             "",
 			"collapsed":        false,
 			"dyn":              0,
-			"conf":             1,
+			"conf":             g.deckConfID,
 			"usn":              0,
 			"newToday":         []int{0, 0},
 			"revToday":         []int{0, 0},
@@ -168,7 +173,11 @@ func (s *SQLiteSchemer) insertCollection(db *sql.DB, g *APKGGenerator, tmpl *Car
 			"browserCollapsed": false,
 			"extendNew":        10,
 			"extendRev":        50,
-		},
+		}
+		if name == g.deckName {
+			deck["desc"] = "Bulgarian vocabulary cards created by TotalRecall"
+		}
+		decks[fmt.Sprintf("%d", g.deckIDOf(name))] = deck
 	}
 	decksJSON, err := marshalJSON("decks", decks)
 	if err != nil {
@@ -176,8 +185,9 @@ func (s *SQLiteSchemer) insertCollection(db *sql.DB, g *APKGGenerator, tmpl *Car
 	}
 
 	models := map[string]interface{}{
-		fmt.Sprintf("%d", g.modelID):     tmpl.EnBgNoteTypeConfig(g.modelID, g.deckID),
-		fmt.Sprintf("%d", g.modelIDBgBg): tmpl.BgBgNoteTypeConfig(g.modelIDBgBg, g.deckID),
+		fmt.Sprintf("%d", g.modelID):      tmpl.EnBgNoteTypeConfig(g.modelID, g.deckID),
+		fmt.Sprintf("%d", g.modelIDBgBg):  tmpl.BgBgNoteTypeConfig(g.modelIDBgBg, g.deckID),
+		fmt.Sprintf("%d", g.modelIDCloze): tmpl.SentenceNoteTypeConfig(g.modelIDCloze, g.deckID),
 	}
 	modelsJSON, err := marshalJSON("models", models)
 	if err != nil {
@@ -205,49 +215,22 @@ func (s *SQLiteSchemer) insertCollection(db *sql.DB, g *APKGGenerator, tmpl *Car
 		return err
 	}
 
+	// The deck gets its own options preset so importing the package does
+	// not change the options of the Default preset other decks share.
 	dconf := map[string]interface{}{
-		"1": map[string]interface{}{
-			"id":   1,
-			"name": "Default",
-			"dyn":  0,
-			"new": map[string]interface{}{
-				"delays":        []int{1, 10},
-				"ints":          []int{1, 4, 7},
-				"initialFactor": 2500,
-				"perDay":        20,
-				"order":         1,
-				"bury":          true,
-				"separate":      true,
-			},
-			"lapse": map[string]interface{}{
-				"delays":      []int{10},
-				"mult":        0,
-				"minInt":      1,
-				"leechFails":  8,
-				"leechAction": 0,
-			},
-			"rev": map[string]interface{}{
-				"perDay":   100,
-				"ease4":    1.3,
-				"fuzz":     0.05,
-				"maxIvl":   36500,
-				"ivlFct":   1,
-				"bury":     true,
-				"minSpace": 1,
-			},
-			"timer":    0,
-			"maxTaken": 60,
-			"usn":      0,
-			"mod":      now,
-			"autoplay": true,
-			"replayq":  true,
-		},
+		"1":                             deckConfig(1, "Default", DefaultDeckOptions(), now),
+		fmt.Sprintf("%d", g.deckConfID): deckConfig(g.deckConfID, g.deckName, g.deckOptions, now),
 	}
 	dconfJSON, err := marshalJSON("dconf", dconf)
 	if err != nil {
 		return err
 	}
 
+	tagsJSON, err := marshalJSON("tags", tagRegistry(g.cards))
+	if err != nil {
+		return err
+	}
+
 	query := `INSERT INTO col VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err = db.Exec(query,
 		1,
@@ -262,11 +245,77 @@ func (s *SQLiteSchemer) insertCollection(db *sql.DB, g *APKGGenerator, tmpl *Car
 		string(modelsJSON),
 		string(decksJSON),
 		string(dconfJSON),
-		"{}",
+		string(tagsJSON),
 	)
 	return err
 }
 
+// deckConfig builds a dconf entry. Burying new siblings is what keeps the
+// reverse card of a new note out of today's queue, so it is the inverse of
+// ReverseSameDay; BurySiblings covers review and interday learning siblings.
+func deckConfig(id int64, name string, options DeckOptions, now int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   id,
+		"name": name,
+		"dyn":  0,
+		"new": map[string]interface{}{
+			"delays":        options.learningStepMinutes(),
+			"ints":          []int{1, 4, 7},
+			"initialFactor": 2500,
+			"perDay":        options.NewPerDay,
+			"order":         1,
+			"bury":          !options.ReverseSameDay,
+			"separate":      true,
+		},
+		"lapse": map[string]interface{}{
+			"delays":      []int{10},
+			"mult":        0,
+			"minInt":      1,
+			"leechFails":  8,
+			"leechAction": 0,
+		},
+		"rev": map[string]interface{}{
+			"perDay":   options.ReviewsPerDay,
+			"ease4":    1.3,
+			"fuzz":     0.05,
+			"maxIvl":   36500,
+			"ivlFct":   1,
+			"bury":     options.BurySiblings,
+			"minSpace": 1,
+		},
+		"buryInterdayLearning": options.BurySiblings,
+		"timer":                0,
+		"maxTaken":             60,
+		"usn":                  0,
+		"mod":                  now,
+		"autoplay":             true,
+		"replayq":              true,
+	}
+}
+
+// noteTags renders tags in the notes.tags format: space separated with a
+// leading and trailing space, so Anki can match " tag " without parsing.
+func noteTags(tags []string) string {
+	tags = store.NormalizeTags(tags...)
+	if len(tags) == 0 {
+		return ""
+	}
+	return " " + strings.Join(tags, " ") + " "
+}
+
+// tagRegistry builds the col.tags map of every tag used by the cards. Anki
+// reads it for the browser sidebar; the value is the tag's update sequence
+// number.
+func tagRegistry(cards []Card) map[string]int {
+	registry := make(map[string]int)
+	for _, card := range cards {
+		for _, tag := range store.NormalizeTags(card.Tags...) {
+			registry[tag] = 0
+		}
+	}
+	return registry
+}
+
 func marshalJSON(name string, value any) ([]byte, error) {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -276,56 +325,40 @@ func marshalJSON(name string, value any) ([]byte, error) {
 	return data, nil
 }
 
+// insertNotesAndCards writes one note per Card with a card per chosen
+// direction, or one card per cloze deletion for sentence cards. Note and card IDs
+// are derived from the note's identity and the mod time from Card.Modified,
+// so re-exporting an unchanged card produces the same rows: Anki then leaves
+// the note alone on re-import, updates it when its mod time moved, and keeps
+// the review history of its cards either way.
 func (s *SQLiteSchemer) insertNotesAndCards(db *sql.DB, g *APKGGenerator) error {
 	now := time.Now()
+	usedIDs := make(map[int64]bool)
+	// For new cards (type=0), due is the position in the new-card queue
+	due := 0
+
+	for _, card := range g.cards {
+		card.Directions = card.Directions.Or(g.directions)
+		seed := noteSeed(card)
+		noteID := uniqueID(usedIDs, stableID(seed))
+		deckID := g.deckIDOf(g.deckOf(card))
+
+		mod := card.Modified
+		if mod.IsZero() {
+			mod = now
+		}
 
-	for i, card := range g.cards {
-		noteID := now.UnixMilli() + int64(i*3)
-		cardID1 := noteID + 1
-		cardID2 := noteID + 2
-
-		isBgBg := card.CardType == "bg-bg"
-
-		imageField := buildMediaField(card.ImageFile, g.mediaFiles, func(name string) string {
-			return fmt.Sprintf(`<img src="%s">`, name)
-		})
-		audioField := buildMediaField(card.AudioFile, g.mediaFiles, func(name string) string {
-			return fmt.Sprintf("[sound:%s]", name)
-		})
-		audioFieldBack := buildMediaField(card.AudioFileBack, g.mediaFiles, func(name string) string {
-			return fmt.Sprintf("[sound:%s]", name)
-		})
-
-		var fields string
-		var modelID int64
-		var guid string
-
-		if isBgBg {
-			fields = strings.Join([]string{
-				card.Bulgarian,
-				card.Translation,
-				imageField,
-				audioField,
-				audioFieldBack,
-				card.Notes,
-			}, "\x1f")
+		modelID := g.modelID
+		switch card.CardType {
+		case "bg-bg":
 			modelID = g.modelIDBgBg
-			guid = ankiGUID(fmt.Sprintf("tr_bgbg_%s", card.Bulgarian))
-		} else {
-			english := card.Translation
-			if english == "" {
-				english = "Translation needed"
-			}
-			fields = strings.Join([]string{
-				english,
-				card.Bulgarian,
-				imageField,
-				audioField,
-				card.Notes,
-			}, "\x1f")
-			modelID = g.modelID
-			guid = ankiGUID(fmt.Sprintf("tr_%s", card.Bulgarian))
+		case "sentence":
+			modelID = g.modelIDCloze
 		}
+		fields := strings.Join(NoteFieldValues(card, func(path string) string {
+			return exportedMediaName(path, g.mediaFiles)
+		}), "\x1f")
+		guid := ankiGUID(seed)
 
 		csum := fieldChecksum(card.Bulgarian)
 
@@ -334,9 +367,9 @@ func (s *SQLiteSchemer) insertNotesAndCards(db *sql.DB, g *APKGGenerator) error
 			noteID,
 			guid,
 			modelID,
-			now.Unix(),
+			mod.Unix(),
 			-1,
-			"",
+			noteTags(card.Tags),
 			fields,
 			card.Bulgarian,
 			csum,
@@ -347,53 +380,84 @@ func (s *SQLiteSchemer) insertNotesAndCards(db *sql.DB, g *APKGGenerator) error
 			return fmt.Errorf("failed to insert note: %w", err)
 		}
 
-		// For new cards (type=0), due is the position in the new-card queue
-		dueForward := i * 2
-		dueReverse := i*2 + 1
-
 		cardQuery := `INSERT INTO cards VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-		_, err = db.Exec(cardQuery,
-			cardID1, noteID, g.deckID,
-			0,            // ord
-			now.Unix(),   // mod
-			-1,           // usn
-			0,            // type (new)
-			0,            // queue (new)
-			dueForward,   // due (position in new queue)
-			0, 0, 0, 0, 0, 0, 0, 0, "",
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert forward card: %w", err)
+		for _, ord := range cardOrds(card) {
+			cardID := uniqueID(usedIDs, stableID(fmt.Sprintf("%s/card/%d", seed, ord)))
+			_, err = db.Exec(cardQuery,
+				cardID, noteID, deckID,
+				ord,        // ord
+				mod.Unix(), // mod
+				-1,         // usn
+				0,          // type (new)
+				0,          // queue (new)
+				due,        // due (position in new queue)
+				0, 0, 0, 0, 0, 0, 0, 0, "",
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert card %d: %w", ord, err)
+			}
+			due++
 		}
+	}
 
-		_, err = db.Exec(cardQuery,
-			cardID2, noteID, g.deckID,
-			1,            // ord
-			now.Unix(),   // mod
-			-1,           // usn
-			0,            // type (new)
-			0,            // queue (new)
-			dueReverse,   // due (position in new queue)
-			0, 0, 0, 0, 0, 0, 0, 0, "",
-		)
-		if err != nil {
-			return fmt.Errorf("failed to insert reverse card: %w", err)
+	return nil
+}
+
+// cardOrds returns the template ordinals of the cards of a note: one per
+// direction of a word card (forward 0, reverse 1, type-in 2), and one per
+// cloze number of a sentence card, whose cloze cN is rendered as ordinal N-1.
+func cardOrds(card Card) []int {
+	var ords []int
+	if card.CardType != "sentence" {
+		directions := card.Directions.Or(internal.DefaultCardDirections())
+		if directions.Forward {
+			ords = append(ords, 0)
 		}
+		if directions.Reverse {
+			ords = append(ords, 1)
+		}
+		if directions.TypeIn {
+			ords = append(ords, 2)
+		}
+		return ords
+	}
+	for _, n := range internal.ClozeNumbers(card.Cloze) {
+		ords = append(ords, n-1)
 	}
+	return ords
+}
 
-	return nil
+// noteSeed identifies the note of a card across exports. It is also the GUID
+// seed, so a note keeps its GUID and ID as long as its word and card type do.
+func noteSeed(card Card) string {
+	switch card.CardType {
+	case "bg-bg":
+		return "tr_bgbg_" + card.Bulgarian
+	case "sentence":
+		return "tr_sentence_" + card.Bulgarian
+	}
+	return "tr_" + card.Bulgarian
+}
+
+// uniqueID returns id, or the next free ID after it when another row of this
+// export already uses it (a hash collision or a word exported twice).
+func uniqueID(used map[int64]bool, id int64) int64 {
+	for used[id] {
+		id++
+	}
+	used[id] = true
+	return id
 }
 
-// buildMediaField resolves a media file path to an Anki field string using the formatter.
-func buildMediaField(filePath string, mediaFiles map[string]int, formatter func(string) string) string {
-	if filePath == "" || !fileExists(filePath) {
+// exportedMediaName returns the media name of filePath when it is part of
+// the package, or "" so that the field stays empty.
+func exportedMediaName(filePath string, mediaFiles map[string]int) string {
+	if !fileExists(filePath) {
 		return ""
 	}
-	cardDirID := filepath.Base(filepath.Dir(filePath))
-	originalFilename := filepath.Base(filePath)
-	uniqueFilename := fmt.Sprintf("%s_%s", cardDirID, originalFilename)
-	if _, ok := mediaFiles[uniqueFilename]; ok {
-		return formatter(uniqueFilename)
+	name := MediaFileName(filePath)
+	if _, ok := mediaFiles[name]; ok {
+		return name
 	}
 	return ""
 }
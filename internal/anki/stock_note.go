@@ -0,0 +1,96 @@
+package anki
+
+import (
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal"
+)
+
+// Stock note types of every Anki profile. The CSV bundle maps its cards onto
+// them, so it imports into a profile that has never seen the TotalRecall
+// note types.
+const (
+	// BasicOptionalReversedNoteTypeName has the fields Front, Back and Add
+	// Reverse; the reverse card exists when Add Reverse is not empty.
+	BasicOptionalReversedNoteTypeName = "Basic (optional reversed card)"
+	// ClozeNoteTypeName has the fields Text and Back Extra.
+	ClozeNoteTypeName = "Cloze"
+)
+
+var (
+	basicOptionalReversedFieldNames = []string{"Front", "Back", "Add Reverse"}
+	clozeFieldNames                 = []string{"Text", "Back Extra"}
+)
+
+// stockNote returns the stock note type card is exported as in a CSV bundle
+// and its field values. The fields hold what the TotalRecall templates show
+// on the front and on the back. exported reports whether a card file is part
+// of the bundle; fields of other files stay empty.
+//
+// Word cards choose their cards through Add Reverse; a reverse-only card
+// swaps front and back instead. Stock note types have no type-in card, so
+// that direction is left out, and a card with only the type-in direction
+// becomes a forward card.
+func (g *Generator) stockNote(card Card, exported func(path string) bool) (string, []string) {
+	image := ""
+	if card.ImageFile != "" && exported(card.ImageFile) {
+		image = g.formatImageField(card.ImageFile)
+	}
+	audio := func(path string) string {
+		if path == "" || !exported(path) {
+			return ""
+		}
+		return g.formatAudioField(path)
+	}
+	example := card.Example
+	if example != "" && card.ExampleTranslation != "" {
+		example += "<br>" + card.ExampleTranslation
+	}
+
+	if card.CardType == "sentence" {
+		return ClozeNoteTypeName, []string{
+			htmlLines(image, card.Cloze, card.Translation),
+			htmlLines(audio(card.AudioFile), audio(card.AudioFileSlow), card.IPA, card.Transliteration, card.Notes),
+		}
+	}
+
+	var front, back string
+	if card.CardType == "bg-bg" {
+		front = htmlLines(image, card.Bulgarian, audio(card.AudioFile), audio(card.AudioFileSlow))
+		back = htmlLines(card.Translation, audio(card.AudioFileBack), audio(card.AudioFileBackSlow),
+			card.IPA, card.Transliteration, example, card.Notes)
+	} else {
+		english := card.Translation
+		if english == "" {
+			english = "Translation needed"
+		}
+		front = htmlLines(image, english)
+		back = htmlLines(card.Bulgarian, audio(card.AudioFile), audio(card.AudioFileSlow),
+			card.IPA, card.Transliteration, example, card.Notes)
+	}
+
+	directions := card.Directions.Or(g.options.CardDirections).Or(internal.DefaultCardDirections())
+	if directions.Reverse && !directions.Forward {
+		return BasicOptionalReversedNoteTypeName, []string{back, front, ""}
+	}
+	return BasicOptionalReversedNoteTypeName, []string{front, back, flag(directions.Reverse)}
+}
+
+// stockNoteFieldNames returns the field names of a stock note type.
+func stockNoteFieldNames(noteType string) []string {
+	if noteType == ClozeNoteTypeName {
+		return clozeFieldNames
+	}
+	return basicOptionalReversedFieldNames
+}
+
+// htmlLines puts each non-empty part on a line of its own.
+func htmlLines(parts ...string) string {
+	var lines []string
+	for _, part := range parts {
+		if part != "" {
+			lines = append(lines, "<div>"+part+"</div>")
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,85 @@
+package anki
+
+import (
+	"fmt"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// DeckSeparator separates the levels of a deck name in Anki, e.g.
+// "Bulgarian Vocabulary::Lesson 3".
+const DeckSeparator = "::"
+
+// DeckGrouping selects the subdecks an export puts its cards into.
+type DeckGrouping string
+
+const (
+	// DeckGroupingNone puts every card into the deck itself.
+	DeckGroupingNone DeckGrouping = "none"
+	// DeckGroupingType creates one subdeck per card type, e.g.
+	// "Bulgarian Vocabulary::Sentence (cloze)".
+	DeckGroupingType DeckGrouping = "type"
+	// DeckGroupingTag creates one subdeck per tag. A card with several tags
+	// goes into the subdeck of the first one in alphabetical order, and a
+	// hierarchical tag such as "lesson-3::food" makes nested subdecks.
+	DeckGroupingTag DeckGrouping = "tag"
+	// DeckGroupingSection creates one subdeck per batch file section, e.g.
+	// "Bulgarian Vocabulary::Lesson 3::Food".
+	DeckGroupingSection DeckGrouping = "section"
+)
+
+// ParseDeckGrouping parses a grouping name; the empty string means
+// DeckGroupingNone.
+func ParseDeckGrouping(s string) (DeckGrouping, error) {
+	switch grouping := DeckGrouping(strings.ToLower(strings.TrimSpace(s))); grouping {
+	case "":
+		return DeckGroupingNone, nil
+	case DeckGroupingNone, DeckGroupingType, DeckGroupingTag, DeckGroupingSection:
+		return grouping, nil
+	default:
+		return "", fmt.Errorf("unknown deck grouping %q (want none, type, tag or section)", s)
+	}
+}
+
+// DeckFor returns the full name of the deck card belongs to below deck.
+// Cards without a tag or section stay in deck itself.
+func (g DeckGrouping) DeckFor(deck string, card Card) string {
+	var child string
+	switch g {
+	case DeckGroupingType:
+		child = internal.ParseCardType(card.CardType).DisplayName()
+	case DeckGroupingTag:
+		if tags := store.NormalizeTags(card.Tags...); len(tags) > 0 {
+			child = tags[0]
+		}
+	case DeckGroupingSection:
+		child = card.Section
+	}
+	return JoinDeckName(deck, child)
+}
+
+// JoinDeckName appends the levels of child to the deck name parent. Empty
+// levels are dropped, because Anki would otherwise create decks named
+// "Blank".
+func JoinDeckName(parent, child string) string {
+	var levels []string
+	for _, level := range strings.Split(parent+DeckSeparator+child, DeckSeparator) {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, DeckSeparator)
+}
+
+// deckAncestors returns the names of deck and all its parents, the top
+// level deck first.
+func deckAncestors(deck string) []string {
+	levels := strings.Split(deck, DeckSeparator)
+	names := make([]string, len(levels))
+	for i := range levels {
+		names[i] = strings.Join(levels[:i+1], DeckSeparator)
+	}
+	return names
+}
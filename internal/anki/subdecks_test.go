@@ -0,0 +1,68 @@
+package anki
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDeckGrouping(t *testing.T) {
+	tests := map[string]DeckGrouping{
+		"":          DeckGroupingNone,
+		"none":      DeckGroupingNone,
+		" Section ": DeckGroupingSection,
+		"tag":       DeckGroupingTag,
+		"type":      DeckGroupingType,
+	}
+	for in, want := range tests {
+		got, err := ParseDeckGrouping(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDeckGrouping(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseDeckGrouping("lesson"); err == nil {
+		t.Error("ParseDeckGrouping(\"lesson\") succeeded; want error")
+	}
+}
+
+func TestDeckFor(t *testing.T) {
+	card := Card{
+		Bulgarian: "котка",
+		CardType:  "bg-bg",
+		Tags:      []string{"lesson-3::animals", "food"},
+		Section:   " Lesson 3 :: Animals ",
+	}
+	tests := []struct {
+		grouping DeckGrouping
+		card     Card
+		want     string
+	}{
+		{DeckGroupingNone, card, "Vocabulary"},
+		{DeckGroupingType, card, "Vocabulary::Bulgarian → Bulgarian"},
+		{DeckGroupingType, Card{}, "Vocabulary::English → Bulgarian"},
+		{DeckGroupingTag, card, "Vocabulary::food"},
+		{DeckGroupingTag, Card{Tags: []string{"lesson-3::animals"}}, "Vocabulary::lesson-3::animals"},
+		{DeckGroupingTag, Card{}, "Vocabulary"},
+		{DeckGroupingSection, card, "Vocabulary::Lesson 3::Animals"},
+		{DeckGroupingSection, Card{}, "Vocabulary"},
+	}
+	for _, tt := range tests {
+		if got := tt.grouping.DeckFor("Vocabulary", tt.card); got != tt.want {
+			t.Errorf("%s.DeckFor(%+v) = %q; want %q", tt.grouping, tt.card, got, tt.want)
+		}
+	}
+}
+
+func TestDeckNamesIncludeParents(t *testing.T) {
+	gen := NewAPKGGenerator("Vocabulary")
+	gen.SetDeckGrouping(DeckGroupingSection)
+	gen.AddCard(Card{Bulgarian: "котка", Section: "Lesson 3::Animals"})
+	gen.AddCard(Card{Bulgarian: "хляб", Section: "Lesson 1"})
+	gen.AddCard(Card{Bulgarian: "куче", Section: "Lesson 3::Animals"})
+	gen.AddCard(Card{Bulgarian: "вода"})
+
+	want := []string{"Vocabulary", "Vocabulary::Lesson 1", "Vocabulary::Lesson 3", "Vocabulary::Lesson 3::Animals"}
+	if got := gen.deckNames(); !slices.Equal(got, want) {
+		t.Errorf("deckNames() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+// Package ankiconnect pushes cards into a running Anki through the
+// AnkiConnect add-on (https://foosoft.net/projects/anki-connect/), as an
+// alternative to importing an APKG file by hand.
+package ankiconnect
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/httpctx"
+)
+
+const (
+	// DefaultURL is where AnkiConnect listens unless configured otherwise.
+	DefaultURL = "http://127.0.0.1:8765"
+
+	// apiVersion is the AnkiConnect API version the requests are written for.
+	apiVersion = 6
+)
+
+// Client calls the AnkiConnect JSON API.
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the AnkiConnect endpoint at url, or at
+// DefaultURL when url is empty.
+func NewClient(url string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{url: url, httpClient: httpctx.AnkiConnectHTTPClient()}
+}
+
+// URL returns the endpoint the client talks to.
+func (c *Client) URL() string {
+	return c.url
+}
+
+type request struct {
+	Action  string `json:"action"`
+	Version int    `json:"version"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+// invoke runs action with params and decodes the result into result unless
+// it is nil. AnkiConnect reports failures in the error member of an HTTP 200
+// response.
+func (c *Client) invoke(ctx context.Context, action string, params, result any) error {
+	body, err := json.Marshal(request{Action: action, Version: apiVersion, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", action, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach AnkiConnect at %s (is Anki running with the AnkiConnect add-on?): %w", c.url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("AnkiConnect %s failed: HTTP %d: %s", action, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var r response
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", action, err)
+	}
+	if r.Error != nil {
+		return fmt.Errorf("AnkiConnect %s failed: %s", action, *r.Error)
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", action, err)
+	}
+	return nil
+}
+
+// CreateDeck creates deck unless it exists already.
+func (c *Client) CreateDeck(ctx context.Context, deck string) error {
+	return c.invoke(ctx, "createDeck", map[string]any{"deck": deck}, nil)
+}
+
+// ModelNames returns the names of all note types in the collection.
+func (c *Client) ModelNames(ctx context.Context) ([]string, error) {
+	var names []string
+	err := c.invoke(ctx, "modelNames", nil, &names)
+	return names, err
+}
+
+// CardTemplate is one card template of a note type to create.
+type CardTemplate struct {
+	Name  string `json:"Name"`
+	Front string `json:"Front"`
+	Back  string `json:"Back"`
+}
+
+// CreateModel creates a note type. A cloze note type has a single template
+// that renders one card per cloze deletion of a note.
+func (c *Client) CreateModel(ctx context.Context, name string, fields []string, css string, isCloze bool, templates []CardTemplate) error {
+	return c.invoke(ctx, "createModel", map[string]any{
+		"modelName":     name,
+		"inOrderFields": fields,
+		"css":           css,
+		"isCloze":       isCloze,
+		"cardTemplates": templates,
+	}, nil)
+}
+
+// ModelFieldNames returns the field names of note type name in order.
+func (c *Client) ModelFieldNames(ctx context.Context, name string) ([]string, error) {
+	var fields []string
+	err := c.invoke(ctx, "modelFieldNames", map[string]any{"modelName": name}, &fields)
+	return fields, err
+}
+
+// ModelFieldAdd adds field to note type name at position index.
+func (c *Client) ModelFieldAdd(ctx context.Context, name, field string, index int) error {
+	return c.invoke(ctx, "modelFieldAdd", map[string]any{
+		"modelName": name,
+		"fieldName": field,
+		"index":     index,
+	}, nil)
+}
+
+// ModelTemplateNames returns the card template names of note type name.
+func (c *Client) ModelTemplateNames(ctx context.Context, name string) ([]string, error) {
+	var templates map[string]json.RawMessage
+	if err := c.invoke(ctx, "modelTemplates", map[string]any{"modelName": name}, &templates); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(templates))
+	for templateName := range templates {
+		names = append(names, templateName)
+	}
+	return names, nil
+}
+
+// ModelTemplateAdd adds a card template to note type name.
+func (c *Client) ModelTemplateAdd(ctx context.Context, name string, template CardTemplate) error {
+	return c.invoke(ctx, "modelTemplateAdd", map[string]any{
+		"modelName": name,
+		"template":  template,
+	}, nil)
+}
+
+// UpdateModelTemplates replaces the front and back of the named card
+// templates of note type name.
+func (c *Client) UpdateModelTemplates(ctx context.Context, name string, templates []CardTemplate) error {
+	byName := make(map[string]map[string]string, len(templates))
+	for _, t := range templates {
+		byName[t.Name] = map[string]string{"Front": t.Front, "Back": t.Back}
+	}
+	return c.invoke(ctx, "updateModelTemplates", map[string]any{
+		"model": map[string]any{"name": name, "templates": byName},
+	}, nil)
+}
+
+// UpdateModelStyling replaces the CSS of note type name.
+func (c *Client) UpdateModelStyling(ctx context.Context, name, css string) error {
+	return c.invoke(ctx, "updateModelStyling", map[string]any{
+		"model": map[string]any{"name": name, "css": css},
+	}, nil)
+}
+
+// StoreMediaFile uploads the file at path into the collection media folder
+// under name, replacing an existing file of that name.
+func (c *Client) StoreMediaFile(ctx context.Context, name, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read media file %s: %w", filepath.Base(path), err)
+	}
+	return c.invoke(ctx, "storeMediaFile", map[string]any{
+		"filename": name,
+		"data":     base64.StdEncoding.EncodeToString(data),
+	}, nil)
+}
+
+// FindNotes returns the IDs of the notes matching an Anki search query.
+func (c *Client) FindNotes(ctx context.Context, query string) ([]int64, error) {
+	var ids []int64
+	err := c.invoke(ctx, "findNotes", map[string]any{"query": query}, &ids)
+	return ids, err
+}
+
+// NoteInfo is the part of a notesInfo entry the exporter compares.
+type NoteInfo struct {
+	NoteID    int64                `json:"noteId"`
+	ModelName string               `json:"modelName"`
+	Tags      []string             `json:"tags"`
+	Fields    map[string]NoteField `json:"fields"`
+}
+
+// NoteField is one field value of a NoteInfo.
+type NoteField struct {
+	Value string `json:"value"`
+	Order int    `json:"order"`
+}
+
+// NotesInfo returns the notes with the given IDs.
+func (c *Client) NotesInfo(ctx context.Context, ids []int64) ([]NoteInfo, error) {
+	var notes []NoteInfo
+	err := c.invoke(ctx, "notesInfo", map[string]any{"notes": ids}, &notes)
+	return notes, err
+}
+
+// Note is a note to add.
+type Note struct {
+	Deck   string
+	Model  string
+	Fields map[string]string
+	Tags   []string
+}
+
+// AddNote adds note and returns its ID. Anki's own duplicate check compares
+// the first field only, which for en-bg notes is the English translation;
+// callers look notes up by the Bulgarian word instead, so the check is off.
+func (c *Client) AddNote(ctx context.Context, note Note) (int64, error) {
+	var id int64
+	err := c.invoke(ctx, "addNote", map[string]any{
+		"note": map[string]any{
+			"deckName":  note.Deck,
+			"modelName": note.Model,
+			"fields":    note.Fields,
+			"tags":      note.Tags,
+			"options":   map[string]any{"allowDuplicate": true},
+		},
+	}, &id)
+	return id, err
+}
+
+// UpdateNoteFields replaces the given fields of note id.
+func (c *Client) UpdateNoteFields(ctx context.Context, id int64, fields map[string]string) error {
+	return c.invoke(ctx, "updateNoteFields", map[string]any{
+		"note": map[string]any{"id": id, "fields": fields},
+	}, nil)
+}
+
+// AddTags adds tags to the notes with the given IDs.
+func (c *Client) AddTags(ctx context.Context, ids []int64, tags []string) error {
+	return c.invoke(ctx, "addTags", map[string]any{
+		"notes": ids,
+		"tags":  strings.Join(tags, " "),
+	}, nil)
+}
@@ -0,0 +1,350 @@
+package ankiconnect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// Status is the outcome of exporting one card.
+type Status string
+
+const (
+	// StatusAdded means a new note was created.
+	StatusAdded Status = "added"
+	// StatusUpdated means an existing note got new field values or tags.
+	StatusUpdated Status = "updated"
+	// StatusSkipped means the note was already present and up to date.
+	StatusSkipped Status = "skipped"
+	// StatusFailed means the card could not be exported; see Result.Err.
+	StatusFailed Status = "failed"
+)
+
+// Result reports what happened to one card.
+type Result struct {
+	Word   string
+	Status Status
+	NoteID int64
+	Err    error
+}
+
+// Exporter adds cards to a deck of a running Anki, creating the deck and the
+// TotalRecall note types when they are missing. Notes are matched by note
+// type and Bulgarian word, so exporting the same cards again only touches
+// the notes whose content changed.
+type Exporter struct {
+	client     *Client
+	deck       string
+	tmpl       *anki.CardTemplate
+	directions internal.CardDirections
+	grouping   anki.DeckGrouping
+}
+
+// NewExporter returns an exporter that adds notes to deck through client.
+func NewExporter(client *Client, deck string) (*Exporter, error) {
+	tmpl, err := anki.NewCardTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load card templates: %w", err)
+	}
+	return &Exporter{client: client, deck: deck, tmpl: tmpl}, nil
+}
+
+// SetCardDirections sets the directions of the cards that do not choose
+// their own; the zero value means internal.DefaultCardDirections. Anki
+// creates the cards of new directions when a note is added or updated, but
+// never deletes cards of directions that were dropped later.
+func (e *Exporter) SetCardDirections(directions internal.CardDirections) {
+	e.directions = directions
+}
+
+// SetDeckGrouping selects the subdecks new notes are added to. Notes that
+// are already in Anki stay in their deck.
+func (e *Exporter) SetDeckGrouping(grouping anki.DeckGrouping) {
+	e.grouping = grouping
+}
+
+// Export pushes cards into Anki and returns one result per card in input
+// order. The error is only set when nothing could be exported, e.g. because
+// Anki is not running; failures of single cards are reported in the results.
+func (e *Exporter) Export(ctx context.Context, cards []anki.Card) ([]Result, error) {
+	if len(cards) == 0 {
+		return nil, nil
+	}
+	if err := e.ensureDecks(ctx, cards); err != nil {
+		return nil, err
+	}
+	if err := e.ensureNoteTypes(ctx, cards); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(cards))
+	for _, card := range cards {
+		if err := ctx.Err(); err != nil {
+			results = append(results, Result{Word: card.Bulgarian, Status: StatusFailed, Err: err})
+			continue
+		}
+		results = append(results, e.exportCard(ctx, card))
+	}
+	return results, nil
+}
+
+// ensureDecks creates the deck and the subdecks the cards go into. Anki
+// creates the parents of a subdeck itself.
+func (e *Exporter) ensureDecks(ctx context.Context, cards []anki.Card) error {
+	decks := []string{e.deck}
+	for _, card := range cards {
+		if deck := e.grouping.DeckFor(e.deck, card); !slices.Contains(decks, deck) {
+			decks = append(decks, deck)
+		}
+	}
+	for _, deck := range decks {
+		if err := e.client.CreateDeck(ctx, deck); err != nil {
+			return fmt.Errorf("failed to create deck %q: %w", deck, err)
+		}
+	}
+	return nil
+}
+
+// ensureNoteTypes creates the note types the cards need unless Anki has
+// them already, e.g. from an earlier APKG import. Existing note types are
+// only touched when they lack fields of this release (see upgradeNoteType),
+// so template customizations made in Anki survive otherwise.
+func (e *Exporter) ensureNoteTypes(ctx context.Context, cards []anki.Card) error {
+	existing, err := e.client.ModelNames(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list note types: %w", err)
+	}
+
+	checked := make(map[string]bool)
+	for _, card := range cards {
+		noteType := e.tmpl.NoteTypeFor(card)
+		if checked[noteType.Name] {
+			continue
+		}
+		checked[noteType.Name] = true
+
+		if slices.Contains(existing, noteType.Name) {
+			if err := e.upgradeNoteType(ctx, noteType); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.client.CreateModel(ctx, noteType.Name, noteType.Fields, noteType.CSS, noteType.Cloze, cardTemplates(noteType)); err != nil {
+			return fmt.Errorf("failed to create note type %q: %w", noteType.Name, err)
+		}
+	}
+	return nil
+}
+
+// upgradeNoteType adds the fields and card templates a note type created by
+// an older release is missing, the fields at the end as in the APKG note
+// types, and installs the current templates and CSS because the old ones
+// would not show the new fields.
+func (e *Exporter) upgradeNoteType(ctx context.Context, noteType anki.NoteType) error {
+	fields, err := e.client.ModelFieldNames(ctx, noteType.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read fields of note type %q: %w", noteType.Name, err)
+	}
+
+	var added bool
+	for _, field := range noteType.Fields {
+		if slices.Contains(fields, field) {
+			continue
+		}
+		if err := e.client.ModelFieldAdd(ctx, noteType.Name, field, len(fields)); err != nil {
+			return fmt.Errorf("failed to add field %q to note type %q: %w", field, noteType.Name, err)
+		}
+		fields = append(fields, field)
+		added = true
+	}
+
+	// Templates may reference the new fields, so they come second.
+	templateNames, err := e.client.ModelTemplateNames(ctx, noteType.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read templates of note type %q: %w", noteType.Name, err)
+	}
+	for _, template := range cardTemplates(noteType) {
+		if slices.Contains(templateNames, template.Name) {
+			continue
+		}
+		if err := e.client.ModelTemplateAdd(ctx, noteType.Name, template); err != nil {
+			return fmt.Errorf("failed to add template %q to note type %q: %w", template.Name, noteType.Name, err)
+		}
+		added = true
+	}
+	if !added {
+		return nil
+	}
+
+	if err := e.client.UpdateModelTemplates(ctx, noteType.Name, cardTemplates(noteType)); err != nil {
+		return fmt.Errorf("failed to update templates of note type %q: %w", noteType.Name, err)
+	}
+	if err := e.client.UpdateModelStyling(ctx, noteType.Name, noteType.CSS); err != nil {
+		return fmt.Errorf("failed to update styling of note type %q: %w", noteType.Name, err)
+	}
+	return nil
+}
+
+func cardTemplates(noteType anki.NoteType) []CardTemplate {
+	templates := make([]CardTemplate, 0, len(noteType.Templates))
+	for _, t := range noteType.Templates {
+		templates = append(templates, CardTemplate{Name: t.Name, Front: t.Front, Back: t.Back})
+	}
+	return templates
+}
+
+func (e *Exporter) exportCard(ctx context.Context, card anki.Card) Result {
+	result := Result{Word: card.Bulgarian}
+	fail := func(err error) Result {
+		result.Status = StatusFailed
+		result.Err = err
+		return result
+	}
+	if card.Bulgarian == "" {
+		return fail(fmt.Errorf("card has no Bulgarian word"))
+	}
+
+	card.Directions = card.Directions.Or(e.directions)
+	noteType := e.tmpl.NoteTypeFor(card)
+	media := make(map[string]string)
+	values := anki.NoteFieldValues(card, func(path string) string {
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return ""
+		}
+		name := anki.MediaFileName(path)
+		media[name] = path
+		return name
+	})
+	fields := make(map[string]string, len(values))
+	for i, value := range values {
+		fields[noteType.Fields[i]] = value
+	}
+	tags := store.NormalizeTags(card.Tags...)
+
+	existing, err := e.findNote(ctx, noteType, card.Bulgarian)
+	if err != nil {
+		return fail(err)
+	}
+
+	var changedFields bool
+	var newTags []string
+	if existing != nil {
+		result.NoteID = existing.NoteID
+		changedFields = !sameFields(existing.Fields, fields)
+		newTags = missingTags(existing.Tags, tags)
+		if !changedFields && len(newTags) == 0 {
+			result.Status = StatusSkipped
+			return result
+		}
+	}
+
+	if err := e.uploadMedia(ctx, media); err != nil {
+		return fail(err)
+	}
+
+	if existing == nil {
+		id, err := e.client.AddNote(ctx, Note{Deck: e.grouping.DeckFor(e.deck, card), Model: noteType.Name, Fields: fields, Tags: tags})
+		if err != nil {
+			return fail(fmt.Errorf("failed to add note: %w", err))
+		}
+		result.NoteID = id
+		result.Status = StatusAdded
+		return result
+	}
+
+	if changedFields {
+		if err := e.client.UpdateNoteFields(ctx, existing.NoteID, fields); err != nil {
+			return fail(fmt.Errorf("failed to update note: %w", err))
+		}
+	}
+	if len(newTags) > 0 {
+		if err := e.client.AddTags(ctx, []int64{existing.NoteID}, newTags); err != nil {
+			return fail(fmt.Errorf("failed to add tags: %w", err))
+		}
+	}
+	result.Status = StatusUpdated
+	return result
+}
+
+// findNote returns the note of the given type for word, or nil.
+func (e *Exporter) findNote(ctx context.Context, noteType anki.NoteType, word string) (*NoteInfo, error) {
+	query := searchTerm("note:"+noteType.Name) + " " + searchTerm(noteType.KeyField+":"+word)
+	ids, err := e.client.FindNotes(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up note: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	slices.Sort(ids)
+	notes, err := e.client.NotesInfo(ctx, ids[:1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read note %d: %w", ids[0], err)
+	}
+	if len(notes) == 0 {
+		return nil, nil
+	}
+	return &notes[0], nil
+}
+
+func (e *Exporter) uploadMedia(ctx context.Context, media map[string]string) error {
+	names := make([]string, 0, len(media))
+	for name := range media {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := e.client.StoreMediaFile(ctx, name, media[name]); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// searchTerm quotes term for an Anki search. Inside quotes only the
+// backslash, the quote and the wildcards * and _ need escaping.
+func searchTerm(term string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `*`, `\*`, `_`, `\_`)
+	return `"` + replacer.Replace(term) + `"`
+}
+
+func sameFields(existing map[string]NoteField, fields map[string]string) bool {
+	for name, value := range fields {
+		if existing[name].Value != value {
+			return false
+		}
+	}
+	return true
+}
+
+// missingTags returns the tags that have is lacking. Anki compares tags
+// case-insensitively.
+func missingTags(have, want []string) []string {
+	var missing []string
+	for _, tag := range want {
+		if !slices.ContainsFunc(have, func(h string) bool { return strings.EqualFold(h, tag) }) {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
+
+// Summary counts the results by status, e.g. "2 added, 0 updated, 5
+// skipped, 1 failed".
+func Summary(results []Result) string {
+	counts := make(map[Status]int)
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	return fmt.Sprintf("%d added, %d updated, %d skipped, %d failed",
+		counts[StatusAdded], counts[StatusUpdated], counts[StatusSkipped], counts[StatusFailed])
+}
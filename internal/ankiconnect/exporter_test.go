@@ -0,0 +1,518 @@
+package ankiconnect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
+)
+
+// fakeAnki is a stand-in for AnkiConnect that keeps decks, note types, media
+// and notes in memory. It understands the search queries findNote builds.
+type fakeAnki struct {
+	mu     sync.Mutex
+	decks  map[string]bool
+	models map[string][]string
+	cloze  map[string]bool
+	// templates holds the card template names of every note type.
+	templates map[string][]string
+	media     map[string][]byte
+	notes     map[int64]*NoteInfo
+	// noteDecks holds the deck every added note went into.
+	noteDecks map[int64]string
+	nextID    int64
+	actions   []string
+	// failAdd makes addNote fail for notes with this key field value.
+	failAdd string
+}
+
+func newFakeAnki(t *testing.T) (*fakeAnki, *Client) {
+	t.Helper()
+	f := &fakeAnki{
+		decks:     make(map[string]bool),
+		models:    make(map[string][]string),
+		cloze:     make(map[string]bool),
+		templates: make(map[string][]string),
+		media:     make(map[string][]byte),
+		notes:     make(map[int64]*NoteInfo),
+		noteDecks: make(map[int64]string),
+		nextID:    1000,
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(server.Close)
+	return f, NewClient(server.URL)
+}
+
+var queryTerm = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"`)
+
+func (f *fakeAnki) serve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action  string          `json:"action"`
+		Version int             `json:"version"`
+		Params  json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version != apiVersion {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.actions = append(f.actions, req.Action)
+
+	result, errMsg := f.handle(req.Action, req.Params)
+	resp := map[string]any{"result": result, "error": nil}
+	if errMsg != "" {
+		resp["error"] = errMsg
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeAnki) handle(action string, raw json.RawMessage) (any, string) {
+	switch action {
+	case "createDeck":
+		var p struct{ Deck string }
+		_ = json.Unmarshal(raw, &p)
+		f.decks[p.Deck] = true
+		return 1, ""
+	case "modelNames":
+		names := []string{"Basic"}
+		for name := range f.models {
+			names = append(names, name)
+		}
+		return names, ""
+	case "createModel":
+		var p struct {
+			ModelName     string   `json:"modelName"`
+			InOrderFields []string `json:"inOrderFields"`
+			IsCloze       bool     `json:"isCloze"`
+			CardTemplates []struct {
+				Name string `json:"Name"`
+			} `json:"cardTemplates"`
+		}
+		_ = json.Unmarshal(raw, &p)
+		f.models[p.ModelName] = p.InOrderFields
+		f.cloze[p.ModelName] = p.IsCloze
+		for _, tmpl := range p.CardTemplates {
+			f.templates[p.ModelName] = append(f.templates[p.ModelName], tmpl.Name)
+		}
+		return nil, ""
+	case "modelFieldNames":
+		var p struct {
+			ModelName string `json:"modelName"`
+		}
+		_ = json.Unmarshal(raw, &p)
+		return f.models[p.ModelName], ""
+	case "modelFieldAdd":
+		var p struct {
+			ModelName string `json:"modelName"`
+			FieldName string `json:"fieldName"`
+			Index     int    `json:"index"`
+		}
+		_ = json.Unmarshal(raw, &p)
+		f.models[p.ModelName] = slices.Insert(f.models[p.ModelName], p.Index, p.FieldName)
+		return nil, ""
+	case "modelTemplates":
+		var p struct {
+			ModelName string `json:"modelName"`
+		}
+		_ = json.Unmarshal(raw, &p)
+		templates := map[string]any{}
+		for _, name := range f.templates[p.ModelName] {
+			templates[name] = map[string]string{"Front": "", "Back": ""}
+		}
+		return templates, ""
+	case "modelTemplateAdd":
+		var p struct {
+			ModelName string `json:"modelName"`
+			Template  struct {
+				Name string `json:"Name"`
+			} `json:"template"`
+		}
+		_ = json.Unmarshal(raw, &p)
+		f.templates[p.ModelName] = append(f.templates[p.ModelName], p.Template.Name)
+		return nil, ""
+	case "updateModelTemplates", "updateModelStyling":
+		return nil, ""
+	case "storeMediaFile":
+		var p struct{ Filename, Data string }
+		_ = json.Unmarshal(raw, &p)
+		f.media[p.Filename] = []byte(p.Data)
+		return p.Filename, ""
+	case "findNotes":
+		var p struct{ Query string }
+		_ = json.Unmarshal(raw, &p)
+		return f.find(p.Query), ""
+	case "notesInfo":
+		var p struct{ Notes []int64 }
+		_ = json.Unmarshal(raw, &p)
+		var infos []NoteInfo
+		for _, id := range p.Notes {
+			if note, ok := f.notes[id]; ok {
+				infos = append(infos, *note)
+			}
+		}
+		return infos, ""
+	case "addNote":
+		var p struct {
+			Note struct {
+				DeckName  string            `json:"deckName"`
+				ModelName string            `json:"modelName"`
+				Fields    map[string]string `json:"fields"`
+				Tags      []string          `json:"tags"`
+			}
+		}
+		_ = json.Unmarshal(raw, &p)
+		if !f.decks[p.Note.DeckName] {
+			return nil, "deck was not found"
+		}
+		order, ok := f.models[p.Note.ModelName]
+		if !ok {
+			return nil, "model was not found"
+		}
+		note := &NoteInfo{NoteID: f.nextID, ModelName: p.Note.ModelName, Tags: p.Note.Tags, Fields: map[string]NoteField{}}
+		for i, name := range order {
+			if f.failAdd != "" && p.Note.Fields[name] == f.failAdd {
+				return nil, "cannot create note"
+			}
+			note.Fields[name] = NoteField{Value: p.Note.Fields[name], Order: i}
+		}
+		f.notes[note.NoteID] = note
+		f.noteDecks[note.NoteID] = p.Note.DeckName
+		f.nextID++
+		return note.NoteID, ""
+	case "updateNoteFields":
+		var p struct {
+			Note struct {
+				ID     int64             `json:"id"`
+				Fields map[string]string `json:"fields"`
+			}
+		}
+		_ = json.Unmarshal(raw, &p)
+		note := f.notes[p.Note.ID]
+		for name, value := range p.Note.Fields {
+			field := note.Fields[name]
+			field.Value = value
+			note.Fields[name] = field
+		}
+		return nil, ""
+	case "addTags":
+		var p struct {
+			Notes []int64
+			Tags  string
+		}
+		_ = json.Unmarshal(raw, &p)
+		for _, id := range p.Notes {
+			f.notes[id].Tags = append(f.notes[id].Tags, strings.Fields(p.Tags)...)
+		}
+		return nil, ""
+	}
+	return nil, "unsupported action " + action
+}
+
+// find evaluates queries of the form "note:<model>" "<field>:<value>".
+func (f *fakeAnki) find(query string) []int64 {
+	unescape := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\*`, `*`, `\_`, `_`)
+	var model, field, value string
+	for _, m := range queryTerm.FindAllStringSubmatch(query, -1) {
+		term := unescape.Replace(m[1])
+		if name, ok := strings.CutPrefix(term, "note:"); ok {
+			model = name
+			continue
+		}
+		field, value, _ = strings.Cut(term, ":")
+	}
+
+	ids := []int64{}
+	for id, note := range f.notes {
+		if note.ModelName == model && note.Fields[field].Value == value {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (f *fakeAnki) count(action string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, a := range f.actions {
+		if a == action {
+			n++
+		}
+	}
+	return n
+}
+
+func statuses(results []Result) []Status {
+	var got []Status
+	for _, r := range results {
+		got = append(got, r.Status)
+	}
+	return got
+}
+
+// TestExportAddsSkipsAndUpdates exports the same cards three times: the first
+// run creates everything, the second finds nothing to do and the third only
+// updates the card whose translation and tags changed.
+func TestExportAddsSkipsAndUpdates(t *testing.T) {
+	fake, client := newFakeAnki(t)
+
+	cardDir := filepath.Join(t.TempDir(), "card_1")
+	if err := os.MkdirAll(cardDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	audio := filepath.Join(cardDir, "audio.mp3")
+	if err := os.WriteFile(audio, []byte("mp3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cards := []anki.Card{
+		{Bulgarian: "ябълка", Translation: "apple", AudioFile: audio, Tags: []string{"food"}},
+		{Bulgarian: "котка", Translation: "домашно животно", CardType: "bg-bg"},
+		{Bulgarian: `"*_`, Translation: "quote"},
+	}
+
+	exporter, err := NewExporter(client, "Bulgarian Vocabulary")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+
+	results, err := exporter.Export(context.Background(), cards)
+	if err != nil {
+		t.Fatalf("first Export() error = %v", err)
+	}
+	if got := Summary(results); got != "3 added, 0 updated, 0 skipped, 0 failed" {
+		t.Fatalf("first Export() = %s", got)
+	}
+	if len(fake.models) != 2 {
+		t.Errorf("created note types = %v; want both TotalRecall note types", fake.models)
+	}
+	if _, ok := fake.media["card_1_audio.mp3"]; !ok {
+		t.Errorf("media = %v; want card_1_audio.mp3", fake.media)
+	}
+	if got := fake.notes[results[0].NoteID].Fields["Audio"].Value; got != "[sound:card_1_audio.mp3]" {
+		t.Errorf("Audio field = %q", got)
+	}
+
+	results, err = exporter.Export(context.Background(), cards)
+	if err != nil {
+		t.Fatalf("second Export() error = %v", err)
+	}
+	if got := Summary(results); got != "0 added, 0 updated, 3 skipped, 0 failed" {
+		t.Fatalf("second Export() = %s", got)
+	}
+	if n := fake.count("storeMediaFile"); n != 1 {
+		t.Errorf("storeMediaFile called %d times; want media of skipped notes not re-uploaded", n)
+	}
+	if n := fake.count("createModel"); n != 2 {
+		t.Errorf("createModel called %d times; want existing note types reused", n)
+	}
+
+	cards[0].Translation = "apples"
+	cards[0].Tags = []string{"food", "lesson-1"}
+	results, err = exporter.Export(context.Background(), cards)
+	if err != nil {
+		t.Fatalf("third Export() error = %v", err)
+	}
+	if got, want := statuses(results), []Status{StatusUpdated, StatusSkipped, StatusSkipped}; !slices.Equal(got, want) {
+		t.Fatalf("third Export() = %v; want %v", got, want)
+	}
+	note := fake.notes[results[0].NoteID]
+	if note.Fields["English"].Value != "apples" {
+		t.Errorf("English field = %q; want apples", note.Fields["English"].Value)
+	}
+	if !slices.Equal(note.Tags, []string{"food", "lesson-1"}) {
+		t.Errorf("tags = %v; want food lesson-1", note.Tags)
+	}
+	if len(fake.notes) != 3 {
+		t.Errorf("notes = %d; want 3", len(fake.notes))
+	}
+}
+
+// TestExportReportsFailedCards keeps going after a card fails.
+// TestExportSentenceCard checks that sentence cards get a cloze note type
+// and are found again by their plain sentence.
+func TestExportSentenceCard(t *testing.T) {
+	fake, client := newFakeAnki(t)
+
+	cards := []anki.Card{
+		{Bulgarian: "Къде е гарата?", Translation: "Where is the station?", CardType: "sentence",
+			Cloze: "{{c1::Къде}} е гарата?"},
+	}
+	exporter, err := NewExporter(client, "Bulgarian Vocabulary")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+
+	results, err := exporter.Export(context.Background(), cards)
+	if err != nil {
+		t.Fatalf("first Export() error = %v", err)
+	}
+	if got := Summary(results); got != "1 added, 0 updated, 0 skipped, 0 failed" {
+		t.Fatalf("first Export() = %s", got)
+	}
+	if !fake.cloze[anki.SentenceNoteTypeName] {
+		t.Errorf("note type %q was not created as a cloze note type", anki.SentenceNoteTypeName)
+	}
+	if got := fake.notes[results[0].NoteID].Fields["Text"].Value; got != "{{c1::Къде}} е гарата?" {
+		t.Errorf("Text field = %q", got)
+	}
+
+	results, err = exporter.Export(context.Background(), cards)
+	if err != nil {
+		t.Fatalf("second Export() error = %v", err)
+	}
+	if got := Summary(results); got != "0 added, 0 updated, 1 skipped, 0 failed" {
+		t.Fatalf("second Export() = %s", got)
+	}
+}
+
+// TestExportDeckGrouping checks that new notes go into the subdeck of their
+// section and that the subdecks are created first.
+func TestExportDeckGrouping(t *testing.T) {
+	fake, client := newFakeAnki(t)
+
+	exporter, err := NewExporter(client, "Deck")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	exporter.SetDeckGrouping(anki.DeckGroupingSection)
+	results, err := exporter.Export(context.Background(), []anki.Card{
+		{Bulgarian: "котка", Translation: "cat", Section: "Lesson 3::Animals"},
+		{Bulgarian: "хляб", Translation: "bread"},
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := map[string]string{"котка": "Deck::Lesson 3::Animals", "хляб": "Deck"}
+	for _, result := range results {
+		if result.Status != StatusAdded {
+			t.Fatalf("%s: status = %s (%v); want added", result.Word, result.Status, result.Err)
+		}
+		if got := fake.noteDecks[result.NoteID]; got != want[result.Word] {
+			t.Errorf("%s: deck = %q; want %q", result.Word, got, want[result.Word])
+		}
+	}
+}
+
+// TestExportCardDirections checks that the direction fields carry the
+// export-wide directions unless a card chooses its own.
+func TestExportCardDirections(t *testing.T) {
+	fake, client := newFakeAnki(t)
+
+	exporter, err := NewExporter(client, "Deck")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	exporter.SetCardDirections(internal.CardDirections{Forward: true, TypeIn: true})
+	results, err := exporter.Export(context.Background(), []anki.Card{
+		{Bulgarian: "котка", Translation: "cat"},
+		{Bulgarian: "куче", Translation: "dog", Directions: internal.CardDirections{Reverse: true}},
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	tests := []struct {
+		result                       Result
+		noForward, noReverse, typeIn string
+	}{
+		{results[0], "", "y", "y"},
+		{results[1], "y", "", ""},
+	}
+	for _, tt := range tests {
+		fields := fake.notes[tt.result.NoteID].Fields
+		got := []string{fields["NoForward"].Value, fields["NoReverse"].Value, fields["TypeIn"].Value}
+		if want := []string{tt.noForward, tt.noReverse, tt.typeIn}; !slices.Equal(got, want) {
+			t.Errorf("%s: NoForward, NoReverse, TypeIn = %q; want %q", tt.result.Word, got, want)
+		}
+	}
+}
+
+func TestExportReportsFailedCards(t *testing.T) {
+	fake, client := newFakeAnki(t)
+	fake.failAdd = "куче"
+
+	exporter, err := NewExporter(client, "Deck")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	results, err := exporter.Export(context.Background(), []anki.Card{
+		{Bulgarian: "куче", Translation: "dog"},
+		{Bulgarian: "", Translation: "orphan"},
+		{Bulgarian: "хляб", Translation: "bread"},
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if got, want := statuses(results), []Status{StatusFailed, StatusFailed, StatusAdded}; !slices.Equal(got, want) {
+		t.Fatalf("Export() = %v; want %v", got, want)
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "cannot create note") {
+		t.Errorf("first result error = %v; want the AnkiConnect error", results[0].Err)
+	}
+}
+
+func TestExportWithoutAnki(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	exporter, err := NewExporter(NewClient(url), "Deck")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	_, err = exporter.Export(context.Background(), []anki.Card{{Bulgarian: "куче"}})
+	if err == nil || !strings.Contains(err.Error(), "is Anki running") {
+		t.Fatalf("Export() error = %v; want a hint that Anki is not running", err)
+	}
+}
+
+// TestExportUpgradesOldNoteType adds the fields a note type created by an
+// older release lacks, without recreating it.
+func TestExportUpgradesOldNoteType(t *testing.T) {
+	fake, client := newFakeAnki(t)
+	fake.models[anki.EnBgNoteTypeName] = []string{"English", "Bulgarian", "Image", "Audio", "Notes"}
+	fake.templates[anki.EnBgNoteTypeName] = []string{"Forward", "Reverse"}
+
+	exporter, err := NewExporter(client, "Deck")
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	results, err := exporter.Export(context.Background(), []anki.Card{
+		{Bulgarian: "куче", Translation: "dog", IPA: "[ˈkutʃɛ]", Transliteration: "kuche"},
+	})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := []string{"English", "Bulgarian", "Image", "Audio", "Notes", "IPA", "Transliteration", "Example", "ExampleTranslation",
+		"NoForward", "NoReverse", "TypeIn", "AudioSlow"}
+	if got := fake.models[anki.EnBgNoteTypeName]; !slices.Equal(got, want) {
+		t.Errorf("fields = %v; want %v", got, want)
+	}
+	if got, want := fake.templates[anki.EnBgNoteTypeName], []string{"Forward", "Reverse", "Type answer"}; !slices.Equal(got, want) {
+		t.Errorf("templates = %v; want %v", got, want)
+	}
+	if n := fake.count("createModel"); n != 0 {
+		t.Errorf("createModel called %d times; want the note type upgraded in place", n)
+	}
+	if n := fake.count("updateModelTemplates"); n != 1 {
+		t.Errorf("updateModelTemplates called %d times; want 1", n)
+	}
+	if got := fake.notes[results[0].NoteID].Fields["Transliteration"].Value; got != "kuche" {
+		t.Errorf("Transliteration field = %q; want kuche", got)
+	}
+}
@@ -91,3 +91,10 @@ func OpenAIImage[T any](fn func() (T, error)) (T, error) {
 func GeminiNanoBanana[T any](fn func() (T, error)) (T, error) {
 	return runValue(geminiNanoBananaBreaker, fn)
 }
+
+// IsOpen reports whether err means a breaker rejected the call without trying
+// it: open, or half-open with its trial requests used up. Callers can then
+// move to another backend instead of waiting for the breaker to recover.
+func IsOpen(err error) bool {
+	return errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests)
+}
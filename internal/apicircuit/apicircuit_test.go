@@ -3,6 +3,7 @@ package apicircuit
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/sony/gobreaker"
@@ -52,6 +53,12 @@ func TestBreakerOpensAndShortCircuits(t *testing.T) {
 	if called {
 		t.Fatal("open breaker must short-circuit and not invoke fn")
 	}
+	if !IsOpen(fmt.Errorf("gemini API error: %w", err)) {
+		t.Fatalf("IsOpen(%v) = false for a wrapped open-state error", err)
+	}
+	if IsOpen(apiErr) {
+		t.Fatal("IsOpen() must not match upstream failures")
+	}
 }
 
 func TestIsSuccessful_ContextCanceled(t *testing.T) {
@@ -1,66 +1,366 @@
 package archive
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// Format selects how ArchiveCards stores the cards directory.
+type Format string
+
+const (
+	// FormatDirectory moves the cards directory into the archive directory
+	// unchanged. It is the default and the only format of older releases.
+	FormatDirectory Format = "dir"
+	// FormatTarGz packs the cards directory into a gzip-compressed tarball.
+	FormatTarGz Format = "tar.gz"
+	// FormatTarZst packs the cards directory into a zstd-compressed tarball.
+	FormatTarZst Format = "tar.zst"
 )
 
+const (
+	// archiveDirName is the directory next to the cards directory that holds
+	// all archives.
+	archiveDirName = "archive"
+	archivePrefix  = "cards-"
+	// ManifestFileName is the word manifest stored at the top of every
+	// archive: inside archived directories and as the first entry of
+	// tarballs, so listing a compressed archive only decompresses its head.
+	ManifestFileName = ".archive.json"
+	manifestVersion  = 1
+
+	timestampLayout = "20060102-150405"
+)
+
+// Formats lists the supported archive formats.
+var Formats = []Format{FormatDirectory, FormatTarGz, FormatTarZst}
+
+// ParseFormat converts a format name as given on the command line.
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown archive format %q (use dir, tar.gz or tar.zst)", name)
+}
+
 // Archiver moves the cards directory into a timestamped archive folder under
-// the parent state directory. Implementations are typically injected at
-// composition roots (cmd, GUI) so callers depend on this abstraction rather
-// than package-level functions alone.
+// the parent state directory, and lists, restores and prunes those archives.
+// Implementations are typically injected at composition roots (cmd, GUI) so
+// callers depend on this abstraction rather than package-level functions
+// alone.
 type Archiver interface {
 	ArchiveCards(cardsDir string) error
+	// List returns the archives of cardsDir, newest first.
+	List(cardsDir string) ([]Archive, error)
+	// Restore brings the named archive back into cardsDir.
+	Restore(cardsDir, name string, opts RestoreOptions) (RestoreResult, error)
+	// Prune deletes all but the newest keep archives and returns the
+	// deleted ones.
+	Prune(cardsDir string, keep int) ([]Archive, error)
 }
 
-// DefaultArchiver implements Archiver using the local filesystem.
-type DefaultArchiver struct{}
+// Archive describes one stored archive.
+type Archive struct {
+	// Name is the file or directory name inside the archive directory.
+	Name      string
+	Path      string
+	Format    Format
+	CreatedAt time.Time
+	// Words lists the words of the archived cards, sorted.
+	Words []string
+}
+
+// Manifest records what an archive contains.
+type Manifest struct {
+	Version   int       `json:"version"`
+	Format    Format    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Words     []string  `json:"words"`
+}
+
+// DefaultArchiver implements Archiver using the local filesystem. The zero
+// value archives into plain directories.
+type DefaultArchiver struct {
+	Format Format
+}
 
 // ArchiveCards implements Archiver.
-func (DefaultArchiver) ArchiveCards(cardsDir string) error {
-	return archiveCards(cardsDir)
+func (d DefaultArchiver) ArchiveCards(cardsDir string) error {
+	return archiveCards(cardsDir, d.Format)
+}
+
+// List implements Archiver.
+func (DefaultArchiver) List(cardsDir string) ([]Archive, error) {
+	return listArchives(cardsDir)
+}
+
+// Restore implements Archiver.
+func (DefaultArchiver) Restore(cardsDir, name string, opts RestoreOptions) (RestoreResult, error) {
+	return restoreArchive(cardsDir, name, opts)
+}
+
+// Prune implements Archiver.
+func (DefaultArchiver) Prune(cardsDir string, keep int) ([]Archive, error) {
+	return pruneArchives(cardsDir, keep)
+}
+
+// ArchiveDir returns the directory that holds the archives of cardsDir.
+func ArchiveDir(cardsDir string) string {
+	return filepath.Join(filepath.Dir(cardsDir), archiveDirName)
 }
 
 // archiveCards moves the cards directory to an archive with timestamp.
-func archiveCards(cardsDir string) error {
+func archiveCards(cardsDir string, format Format) error {
+	if format == "" {
+		format = FormatDirectory
+	}
+
 	// Check if cards directory exists
 	if _, err := os.Stat(cardsDir); os.IsNotExist(err) {
 		return fmt.Errorf("cards directory does not exist: %s", cardsDir)
 	}
 
-	// Get parent directory and create archive path
-	parentDir := filepath.Dir(cardsDir)
-	archiveDir := filepath.Join(parentDir, "archive")
-
 	// Create archive directory if it doesn't exist
+	archiveDir := ArchiveDir(cardsDir)
 	if err := os.MkdirAll(archiveDir, 0755); err != nil {
 		return fmt.Errorf("failed to create archive directory: %w", err)
 	}
 
-	// Generate timestamp
-	timestamp := time.Now().Format("20060102-150405")
-	archiveName := fmt.Sprintf("cards-%s", timestamp)
-	archivePath := filepath.Join(archiveDir, archiveName)
-
-	// Check if archive already exists (unlikely but possible)
-	if _, err := os.Stat(archivePath); err == nil {
-		// Add microseconds to make it unique
-		timestamp = time.Now().Format("20060102-150405.000000")
-		archiveName = fmt.Sprintf("cards-%s", timestamp)
-		archivePath = filepath.Join(archiveDir, archiveName)
+	now := time.Now()
+	manifest := Manifest{
+		Version:   manifestVersion,
+		Format:    format,
+		CreatedAt: now,
+		Words:     collectWords(cardsDir),
 	}
 
-	// Rename cards directory to archive
-	if err := os.Rename(cardsDir, archivePath); err != nil {
-		return fmt.Errorf("failed to archive cards directory: %w", err)
+	baseName := uniqueBaseName(archiveDir, now, format)
+	archivePath := filepath.Join(archiveDir, baseName+formatExtension(format))
+
+	switch format {
+	case FormatDirectory:
+		// Rename cards directory to archive
+		if err := os.Rename(cardsDir, archivePath); err != nil {
+			return fmt.Errorf("failed to archive cards directory: %w", err)
+		}
+		if err := writeManifestFile(archivePath, manifest); err != nil {
+			fmt.Printf("Warning: failed to write archive manifest: %v\n", err)
+		}
+	case FormatTarGz, FormatTarZst:
+		if err := writeTarball(cardsDir, archivePath, format, manifest); err != nil {
+			return fmt.Errorf("failed to archive cards directory: %w", err)
+		}
+		if err := os.RemoveAll(cardsDir); err != nil {
+			return fmt.Errorf("archived to %s but failed to remove cards directory: %w", archivePath, err)
+		}
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
 	}
 
 	fmt.Printf("Cards directory archived to: %s\n", archivePath)
 	return nil
 }
 
+// uniqueBaseName returns "cards-TIMESTAMP", adding microseconds when an
+// archive of that second already exists (unlikely but possible).
+func uniqueBaseName(archiveDir string, now time.Time, format Format) string {
+	baseName := archivePrefix + now.Format(timestampLayout)
+	if _, err := os.Stat(filepath.Join(archiveDir, baseName+formatExtension(format))); err == nil {
+		baseName = archivePrefix + now.Format(timestampLayout+".000000")
+	}
+	return baseName
+}
+
+func formatExtension(format Format) string {
+	if format == FormatDirectory {
+		return ""
+	}
+	return "." + string(format)
+}
+
+// collectWords returns the sorted words of the card directories in dir.
+// Archives are read rarely, so directories are read directly rather than
+// through the card index, which would write its cache into the archive.
+func collectWords(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	words := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if word := strings.TrimSpace(store.LoadManifest(filepath.Join(dir, entry.Name())).Word); word != "" {
+			words = append(words, word)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+func encodeManifest(manifest Manifest) ([]byte, error) {
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+func writeManifestFile(dir string, manifest Manifest) error {
+	data, err := encodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+	return store.WriteFileAtomic(filepath.Join(dir, ManifestFileName), data)
+}
+
+func decodeManifest(data []byte) (Manifest, error) {
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, err
+	}
+	return manifest, nil
+}
+
+// listArchives returns the archives next to cardsDir, newest first.
+func listArchives(cardsDir string) ([]Archive, error) {
+	archiveDir := ArchiveDir(cardsDir)
+	entries, err := os.ReadDir(archiveDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		arc, ok := describeArchive(archiveDir, entry)
+		if ok {
+			archives = append(archives, arc)
+		}
+	}
+
+	sort.Slice(archives, func(i, j int) bool {
+		if !archives[i].CreatedAt.Equal(archives[j].CreatedAt) {
+			return archives[i].CreatedAt.After(archives[j].CreatedAt)
+		}
+		return archives[i].Name > archives[j].Name
+	})
+	return archives, nil
+}
+
+// describeArchive recognises an archive directory entry and loads its
+// manifest. Directory archives of older releases have no manifest; their
+// words are read from the card directories instead.
+func describeArchive(archiveDir string, entry os.DirEntry) (Archive, bool) {
+	name := entry.Name()
+	if !strings.HasPrefix(name, archivePrefix) {
+		return Archive{}, false
+	}
+
+	arc := Archive{Name: name, Path: filepath.Join(archiveDir, name)}
+	switch {
+	case entry.IsDir():
+		arc.Format = FormatDirectory
+	case strings.HasSuffix(name, formatExtension(FormatTarGz)):
+		arc.Format = FormatTarGz
+	case strings.HasSuffix(name, formatExtension(FormatTarZst)):
+		arc.Format = FormatTarZst
+	default:
+		return Archive{}, false
+	}
+	baseName := strings.TrimSuffix(name, formatExtension(arc.Format))
+
+	var manifest Manifest
+	if arc.Format == FormatDirectory {
+		data, err := os.ReadFile(filepath.Join(arc.Path, ManifestFileName))
+		if err == nil {
+			manifest, err = decodeManifest(data)
+		}
+		if err != nil {
+			manifest.Words = collectWords(arc.Path)
+		}
+	} else if m, err := readTarManifest(arc.Path, arc.Format); err == nil {
+		manifest = m
+	}
+
+	arc.Words = manifest.Words
+	arc.CreatedAt = manifest.CreatedAt
+	if arc.CreatedAt.IsZero() {
+		arc.CreatedAt = timestampFromName(baseName)
+	}
+	if arc.CreatedAt.IsZero() {
+		if info, err := entry.Info(); err == nil {
+			arc.CreatedAt = info.ModTime()
+		}
+	}
+	return arc, true
+}
+
+func timestampFromName(baseName string) time.Time {
+	stamp := strings.TrimPrefix(baseName, archivePrefix)
+	for _, layout := range []string{timestampLayout, timestampLayout + ".000000"} {
+		if t, err := time.ParseInLocation(layout, stamp, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// findArchive resolves name to an archive. The name may omit the format
+// extension, and "latest" selects the newest archive.
+func findArchive(cardsDir, name string) (Archive, error) {
+	archives, err := listArchives(cardsDir)
+	if err != nil {
+		return Archive{}, err
+	}
+	if len(archives) == 0 {
+		return Archive{}, fmt.Errorf("no archives found in %s", ArchiveDir(cardsDir))
+	}
+	if name == "latest" {
+		return archives[0], nil
+	}
+
+	for _, arc := range archives {
+		if arc.Name == name || strings.TrimSuffix(arc.Name, formatExtension(arc.Format)) == name {
+			return arc, nil
+		}
+	}
+	return Archive{}, fmt.Errorf("archive %q not found in %s", name, ArchiveDir(cardsDir))
+}
+
+// pruneArchives deletes all but the newest keep archives.
+func pruneArchives(cardsDir string, keep int) ([]Archive, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("number of archives to keep must not be negative: %d", keep)
+	}
+
+	archives, err := listArchives(cardsDir)
+	if err != nil || len(archives) <= keep {
+		return nil, err
+	}
+
+	var pruned []Archive
+	var errs []error
+	for _, arc := range archives[keep:] {
+		if err := os.RemoveAll(arc.Path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete archive %s: %w", arc.Name, err))
+			continue
+		}
+		pruned = append(pruned, arc)
+	}
+	return pruned, errors.Join(errs...)
+}
+
 // ArchiveCards archives cards using DefaultArchiver. It exists for call sites
 // that do not use dependency injection (e.g. tests and legacy scripts).
 func ArchiveCards(cardsDir string) error {
@@ -1,11 +1,14 @@
 package archive
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 func TestArchiveCards(t *testing.T) {
@@ -145,3 +148,120 @@ func TestArchiveCards_MultipleArchives(t *testing.T) {
 		t.Error("Archive names are not unique")
 	}
 }
+
+// writeCard creates a card directory with a manifest for word.
+func writeCard(t *testing.T, cardsDir, id, word string) {
+	t.Helper()
+
+	cardDir := filepath.Join(cardsDir, id)
+	if err := os.MkdirAll(cardDir, 0755); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := store.SaveManifest(cardDir, store.NewManifest(word)); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cardDir, "image.jpg"), []byte("jpeg of "+word), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+}
+
+func TestCompressedArchiveRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatTarGz, FormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			cardsDir := filepath.Join(t.TempDir(), "cards")
+			writeCard(t, cardsDir, "card1", "ябълка")
+			writeCard(t, cardsDir, "card2", "котка")
+
+			archiver := DefaultArchiver{Format: format}
+			if err := archiver.ArchiveCards(cardsDir); err != nil {
+				t.Fatalf("ArchiveCards() error = %v", err)
+			}
+			if _, err := os.Stat(cardsDir); !os.IsNotExist(err) {
+				t.Fatalf("cards directory still exists after archiving")
+			}
+
+			archives, err := archiver.List(cardsDir)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(archives) != 1 || archives[0].Format != format {
+				t.Fatalf("List() = %+v", archives)
+			}
+			if words := strings.Join(archives[0].Words, ","); words != "котка,ябълка" {
+				t.Errorf("archive words = %q", words)
+			}
+
+			result, err := archiver.Restore(cardsDir, "latest", RestoreOptions{})
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if len(result.Restored) != 2 {
+				t.Errorf("Restored = %v", result.Restored)
+			}
+			data, err := os.ReadFile(filepath.Join(cardsDir, "card1", "image.jpg"))
+			if err != nil || string(data) != "jpeg of ябълка" {
+				t.Errorf("restored image = %q, %v", data, err)
+			}
+			if _, err := os.Stat(archives[0].Path); err != nil {
+				t.Errorf("archive was removed by restore: %v", err)
+			}
+		})
+	}
+}
+
+func TestRestoreMergeSkipsExistingWords(t *testing.T) {
+	cardsDir := filepath.Join(t.TempDir(), "cards")
+	writeCard(t, cardsDir, "card1", "ябълка")
+	writeCard(t, cardsDir, "card2", "котка")
+	if err := ArchiveCards(cardsDir); err != nil {
+		t.Fatalf("ArchiveCards() error = %v", err)
+	}
+
+	writeCard(t, cardsDir, "card3", "котка")
+
+	archiver := DefaultArchiver{}
+	if _, err := archiver.Restore(cardsDir, "latest", RestoreOptions{}); !errors.Is(err, ErrCardsDirNotEmpty) {
+		t.Fatalf("Restore() without merge error = %v; want ErrCardsDirNotEmpty", err)
+	}
+
+	result, err := archiver.Restore(cardsDir, "latest", RestoreOptions{Merge: true})
+	if err != nil {
+		t.Fatalf("Restore(merge) error = %v", err)
+	}
+	if strings.Join(result.Restored, ",") != "ябълка" || strings.Join(result.Skipped, ",") != "котка" {
+		t.Errorf("Restore(merge) = restored %v, skipped %v", result.Restored, result.Skipped)
+	}
+	if got := store.FindCardDirectory(cardsDir, "котка"); filepath.Base(got) != "card3" {
+		t.Errorf("existing card was replaced: %q", got)
+	}
+	if got := store.FindCardDirectory(cardsDir, "ябълка"); got == "" {
+		t.Error("merged card not found")
+	}
+}
+
+func TestPruneKeepsNewestArchives(t *testing.T) {
+	tmpDir := t.TempDir()
+	cardsDir := filepath.Join(tmpDir, "cards")
+	archiveDir := ArchiveDir(cardsDir)
+	for _, name := range []string{"cards-20240101-120000", "cards-20240102-120000", "cards-20240103-120000"} {
+		if err := os.MkdirAll(filepath.Join(archiveDir, name), 0755); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+
+	pruned, err := DefaultArchiver{}.Prune(cardsDir, 1)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if len(pruned) != 2 {
+		t.Errorf("Prune() removed %d archives; want 2", len(pruned))
+	}
+
+	entries, err := os.ReadDir(archiveDir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "cards-20240103-120000" {
+		t.Errorf("remaining archives = %v; want only the newest", entries)
+	}
+}
@@ -1,3 +1,4 @@
 // Package archive handles archiving of the cards directory with timestamps.
-// It provides functionality to move existing cards to timestamped archive folders.
+// It moves existing cards to timestamped archive folders or packs them into
+// compressed tarballs, and lists, restores and prunes those archives.
 package archive
@@ -0,0 +1,220 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// ErrCardsDirNotEmpty reports that a non-merging restore would overwrite an
+// existing card collection.
+var ErrCardsDirNotEmpty = errors.New("cards directory is not empty")
+
+// RestoreOptions controls how an archive is restored.
+type RestoreOptions struct {
+	// Merge adds the archived cards to the existing cards directory instead
+	// of requiring it to be empty. Archived cards whose word already has a
+	// card are skipped.
+	Merge bool
+}
+
+// RestoreResult reports what Restore did.
+type RestoreResult struct {
+	Archive Archive
+	// Restored lists the words of the restored cards, sorted.
+	Restored []string
+	// Skipped lists archived words that were not restored because the cards
+	// directory already has a card for them (merge only), sorted.
+	Skipped []string
+}
+
+// restoreArchive unpacks or copies the archive into a staging directory next
+// to cardsDir and then moves it into place, so a failed restore never leaves
+// a half-restored collection behind. The archive itself is kept.
+func restoreArchive(cardsDir, name string, opts RestoreOptions) (RestoreResult, error) {
+	arc, err := findArchive(cardsDir, name)
+	if err != nil {
+		return RestoreResult{}, err
+	}
+	result := RestoreResult{Archive: arc}
+
+	if !opts.Merge && hasContent(cardsDir) {
+		return result, fmt.Errorf("cannot restore %s into %s: %w (archive it first or merge)", arc.Name, cardsDir, ErrCardsDirNotEmpty)
+	}
+
+	parentDir := filepath.Dir(cardsDir)
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return result, fmt.Errorf("failed to create %s: %w", parentDir, err)
+	}
+	staging, err := os.MkdirTemp(parentDir, ".restore-*")
+	if err != nil {
+		return result, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(staging) }()
+	// MkdirTemp creates 0700; the staging directory becomes the cards directory.
+	if err := os.Chmod(staging, 0755); err != nil {
+		return result, err
+	}
+
+	if arc.Format == FormatDirectory {
+		err = copyTree(arc.Path, staging)
+	} else {
+		err = extractTarball(arc.Path, arc.Format, staging)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to read archive %s: %w", arc.Name, err)
+	}
+
+	if opts.Merge {
+		if err := mergeCards(staging, cardsDir, &result); err != nil {
+			return result, err
+		}
+		return result, nil
+	}
+
+	if err := os.RemoveAll(cardsDir); err != nil {
+		return result, fmt.Errorf("failed to replace empty cards directory: %w", err)
+	}
+	if err := os.Rename(staging, cardsDir); err != nil {
+		return result, fmt.Errorf("failed to move restored cards into place: %w", err)
+	}
+	result.Restored = collectWords(cardsDir)
+	return result, nil
+}
+
+// hasContent reports whether dir holds anything worth keeping: cards or a
+// non-empty trash bin. The card index is only a cache.
+func hasContent(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		switch entry.Name() {
+		case store.IndexDirName:
+			continue
+		case store.TrashDirName:
+			if trashed, err := os.ReadDir(filepath.Join(dir, entry.Name())); err == nil && len(trashed) == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// mergeCards moves the card directories of staging into cardsDir, skipping
+// words that already have a card. Trashed cards of the archive are merged
+// into the trash bin. The output directory lock keeps concurrent card
+// creation from racing the word checks.
+func mergeCards(staging, cardsDir string, result *RestoreResult) error {
+	lock, err := store.LockOutputDirectory(cardsDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+
+	cs := store.New(cardsDir)
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		source := filepath.Join(staging, entry.Name())
+		word := strings.TrimSpace(store.LoadManifest(source).Word)
+		if word == "" {
+			continue
+		}
+		if cs.FindCardDirectory(word) != "" {
+			result.Skipped = append(result.Skipped, word)
+			continue
+		}
+
+		target := filepath.Join(cardsDir, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			target = filepath.Join(cardsDir, store.GenerateCardID(word))
+		}
+		if err := os.Rename(source, target); err != nil {
+			return fmt.Errorf("failed to restore card %q: %w", word, err)
+		}
+		result.Restored = append(result.Restored, word)
+	}
+
+	if err := mergeTrash(filepath.Join(staging, store.TrashDirName), filepath.Join(cardsDir, store.TrashDirName)); err != nil {
+		return err
+	}
+
+	sort.Strings(result.Restored)
+	sort.Strings(result.Skipped)
+	return nil
+}
+
+// mergeTrash moves archived trash bin entries that do not exist yet.
+func mergeTrash(sourceDir, targetDir string) error {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		target := filepath.Join(targetDir, entry.Name())
+		if _, err := os.Stat(target); err == nil {
+			continue
+		}
+		if err := os.Rename(filepath.Join(sourceDir, entry.Name()), target); err != nil {
+			return fmt.Errorf("failed to restore trashed card %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
+// copyTree copies the archived directory sourceDir into targetDir, leaving
+// out the archive manifest and the card index.
+func copyTree(sourceDir, targetDir string) error {
+	return filepath.WalkDir(sourceDir, func(file string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(sourceDir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		if rel == ManifestFileName {
+			return nil
+		}
+		if entry.IsDir() && rel == store.IndexDirName {
+			return filepath.SkipDir
+		}
+
+		target := filepath.Join(targetDir, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(file, target)
+	})
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	return extractFile(in, target)
+}
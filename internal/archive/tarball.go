@@ -0,0 +1,232 @@
+package archive
+
+// tarball.go reads and writes the compressed archive formats. gzip uses the
+// standard library, zstd the same pure-Go encoder as anki21b packages.
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxManifestSize bounds how much of a tarball's first entry is read as its
+// manifest.
+const maxManifestSize = 16 << 20
+
+// writeTarball packs cardsDir into archivePath. The manifest is written as
+// the first entry. The card index is skipped: it is a cache that rebuilds
+// itself after a restore.
+func writeTarball(cardsDir, archivePath string, format Format, manifest Manifest) (err error) {
+	out, err := store.CreateAtomic(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	compressed, err := newCompressor(out, format)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := compressed.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = out.Commit()
+		}
+	}()
+
+	tw := tar.NewWriter(compressed)
+	data, err := encodeManifest(manifest)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestFileName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := filepath.WalkDir(cardsDir, func(file string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(cardsDir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		if entry.IsDir() && rel == store.IndexDirName {
+			return filepath.SkipDir
+		}
+		return addTarEntry(tw, file, filepath.ToSlash(rel), entry)
+	}); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+func addTarEntry(tw *tar.Writer, file, name string, entry fs.DirEntry) error {
+	info, err := entry.Info()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		// Sockets, symlinks and the like never belong to a card.
+		return nil
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	_, err = io.Copy(tw, in)
+	return err
+}
+
+// extractTarball unpacks archivePath into targetDir, skipping the manifest.
+// Entries that would escape targetDir are rejected.
+func extractTarball(archivePath string, format Format, targetDir string) error {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	decompressed, err := newDecompressor(in, format)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = decompressed.Close() }()
+
+	tr := tar.NewReader(decompressed)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		name := path.Clean(header.Name)
+		if name == ManifestFileName || name == "." {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("archive entry %q points outside the cards directory", header.Name)
+		}
+		target := filepath.Join(targetDir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := extractFile(tr, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	return decompressed.Close()
+}
+
+func extractFile(r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readTarManifest reads the manifest entry at the head of a tarball.
+func readTarManifest(archivePath string, format Format) (Manifest, error) {
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer func() { _ = in.Close() }()
+
+	decompressed, err := newDecompressor(in, format)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer func() { _ = decompressed.Close() }()
+
+	tr := tar.NewReader(decompressed)
+	header, err := tr.Next()
+	if err != nil {
+		return Manifest{}, err
+	}
+	if path.Clean(header.Name) != ManifestFileName {
+		return Manifest{}, fmt.Errorf("archive %s has no manifest", filepath.Base(archivePath))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(tr, maxManifestSize))
+	if err != nil {
+		return Manifest{}, err
+	}
+	return decodeManifest(data)
+}
+
+func newCompressor(w io.Writer, format Format) (io.WriteCloser, error) {
+	switch format {
+	case FormatTarGz:
+		return gzip.NewWriter(w), nil
+	case FormatTarZst:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
+
+func newDecompressor(r io.Reader, format Format) (io.ReadCloser, error) {
+	switch format {
+	case FormatTarGz:
+		return gzip.NewReader(r)
+	case FormatTarZst:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
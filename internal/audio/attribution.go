@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // AttributionParams describes metadata included in generated audio attribution files.
@@ -16,6 +18,10 @@ type AttributionParams struct {
 	ProcessedText string
 	Speed         float64
 	GeneratedAt   time.Time
+	// FailoverFrom lists the providers that failed before this one spoke.
+	FailoverFrom string
+	// CacheHit records that the clip was copied from the TTS cache.
+	CacheHit bool
 }
 
 // AttributionParamsFrom builds an AttributionParams from a flat Config and the
@@ -31,12 +37,19 @@ func AttributionParamsFrom(config *Config, word, instruction, processedText stri
 	if config == nil {
 		return base
 	}
+	base.FailoverFrom = config.FailoverFrom
+	base.CacheHit = config.CacheHit
 	switch strings.ToLower(strings.TrimSpace(config.Provider)) {
 	case "gemini":
 		g := geminiAudioConfigFrom(config)
 		base.Model = g.TTSModel
 		base.Voice = g.Voice
 		base.Speed = g.Speed
+	case LocalProviderName:
+		l := normalizeLocalAudioConfig(localAudioConfigFrom(config))
+		base.Model = localModelLabel(l)
+		base.Voice = localVoiceLabel(l)
+		base.Speed = l.Speed
 	default:
 		o := openAIAudioConfigFrom(config)
 		base.Model = o.Model
@@ -46,6 +59,21 @@ func AttributionParamsFrom(config *Config, word, instruction, processedText stri
 	return base
 }
 
+// ManifestAsset describes a generated audio file for the card manifest, using
+// the same provenance that is written to its attribution sidecar.
+func ManifestAsset(providerName, audioFile string, params AttributionParams) store.Asset {
+	return store.Asset{
+		File:        audioFile,
+		Provider:    strings.ToLower(strings.TrimSpace(providerName)),
+		Model:       params.Model,
+		Voice:       params.Voice,
+		Prompt:      params.Instruction,
+		Attribution: AttributionPath(audioFile),
+		Cached:      params.CacheHit,
+		CreatedAt:   params.GeneratedAt,
+	}
+}
+
 // AttributionPath returns the sidecar attribution file path for a generated audio file.
 func AttributionPath(audioFile string) string {
 	return strings.TrimSuffix(audioFile, filepath.Ext(audioFile)) + "_attribution.txt"
@@ -61,6 +89,12 @@ func BuildGeminiAttribution(params AttributionParams) string {
 	return buildAttribution("Audio generated by Google Gemini TTS", params)
 }
 
+// BuildLocalAttribution builds the attribution content for audio generated
+// offline by Piper or espeak-ng.
+func BuildLocalAttribution(params AttributionParams) string {
+	return buildAttribution("Audio generated offline by local TTS", params)
+}
+
 func buildAttribution(header string, params AttributionParams) string {
 	var b strings.Builder
 	b.WriteString(header)
@@ -69,6 +103,12 @@ func buildAttribution(header string, params AttributionParams) string {
 	fmt.Fprintf(&b, "Model: %s\n", params.Model)
 	fmt.Fprintf(&b, "Voice: %s\n", params.Voice)
 	fmt.Fprintf(&b, "Speed: %.2f\n", params.Speed)
+	if params.FailoverFrom != "" {
+		fmt.Fprintf(&b, "Fallback after: %s\n", params.FailoverFrom)
+	}
+	if params.CacheHit {
+		b.WriteString("Copied from the TTS cache\n")
+	}
 
 	if params.Instruction != "" {
 		fmt.Fprintf(&b, "\nVoice instructions:\n%s\n", params.Instruction)
@@ -0,0 +1,219 @@
+package audio
+
+// cache.go keeps rendered clips in a content-addressed cache under the state
+// directory, so regenerating a card, or the same word in another deck, does
+// not pay the provider twice for identical audio. A clip is identified by
+// everything that shapes it: provider, model, voice, speed, instruction and
+// the processed text. The cache is capped in size and evicts the least
+// recently used clips first, using file modification times as the clock.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/config"
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// DefaultCacheSizeMB is the default size cap of the TTS cache.
+const DefaultCacheSizeMB = 500
+
+// Cache is a size-capped, content-addressed store of rendered audio clips.
+type Cache struct {
+	dir      string
+	maxBytes int64
+	// Refresh skips lookups, so every clip is generated afresh; the new
+	// take still replaces the cached one.
+	Refresh bool
+}
+
+// NewCache returns a cache of at most maxBytes in dir.
+func NewCache(dir string, maxBytes int64) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes}
+}
+
+// DefaultCacheDir returns the tts-cache directory of the state directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := config.StateDir()
+	return filepath.Join(dir, "tts-cache"), err
+}
+
+// CacheKey identifies the clip config renders for text in format. It is ""
+// when the clip cannot be cached because the provider would pick a random
+// voice for it.
+func CacheKey(config *Config, text, format string) string {
+	provider := strings.ToLower(strings.TrimSpace(config.Provider))
+	instruction := InstructionForProvider(provider, config, text)
+	params := AttributionParamsFrom(config, text, instruction, ProcessedTextForProvider(provider, text), time.Time{})
+	if strings.TrimSpace(params.Voice) == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		provider,
+		params.Model,
+		params.Voice,
+		fmt.Sprintf("%.2f", params.Speed),
+		params.Instruction,
+		params.ProcessedText,
+		strings.ToLower(format),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateWithCache writes the audio of text to outputFile. An identical
+// clip from the cache is copied without creating a provider, so cached audio
+// needs neither an API key nor quota; otherwise newProvider renders it and the
+// result is cached. It reports whether the clip came from the cache. A nil
+// cache always generates.
+func GenerateWithCache(ctx context.Context, cache *Cache, config *Config, newProvider ProviderFactory, text, outputFile string) (bool, error) {
+	key := ""
+	if cache != nil {
+		key = CacheKey(config, text, strings.TrimPrefix(filepath.Ext(outputFile), "."))
+	}
+
+	if key != "" && !cache.Refresh {
+		hit, err := cache.fetch(key, outputFile)
+		if err != nil {
+			fmt.Printf("Warning: failed to read cached audio: %v\n", err)
+		}
+		if hit {
+			fmt.Printf("Using cached audio for '%s'\n", text)
+			return true, nil
+		}
+	}
+
+	provider, err := newProvider(config)
+	if err != nil {
+		return false, err
+	}
+	if err := provider.GenerateAudio(ctx, text, outputFile); err != nil {
+		return false, err
+	}
+
+	if key != "" {
+		if err := cache.store(key, outputFile); err != nil {
+			fmt.Printf("Warning: failed to cache audio: %v\n", err)
+		}
+	}
+	return false, nil
+}
+
+// entryPath spreads the entries over subdirectories named after the first
+// two characters of their key.
+func (c *Cache) entryPath(key, format string) string {
+	return filepath.Join(c.dir, key[:2], key+"."+format)
+}
+
+// fetch copies the cached clip of key to outputFile and marks it as recently
+// used. It reports false when there is no such clip.
+func (c *Cache) fetch(key, outputFile string) (bool, error) {
+	entry := c.entryPath(key, strings.TrimPrefix(filepath.Ext(outputFile), "."))
+	if _, err := os.Stat(entry); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := ensureOutputDirectory(outputFile); err != nil {
+		return false, err
+	}
+	if err := copyFileAtomic(entry, outputFile); err != nil {
+		return false, fmt.Errorf("failed to copy cached audio: %w", err)
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(entry, now, now)
+	return true, nil
+}
+
+// store copies audioFile into the cache under key and evicts the least
+// recently used clips beyond the size cap.
+func (c *Cache) store(key, audioFile string) error {
+	entry := c.entryPath(key, strings.TrimPrefix(filepath.Ext(audioFile), "."))
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := copyFileAtomic(audioFile, entry); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used clips until the cache fits its size
+// cap. Hidden files are temporary files of writes in progress.
+func (c *Cache) evict() error {
+	type cachedClip struct {
+		path   string
+		size   int64
+		usedAt time.Time
+	}
+
+	var clips []cachedClip
+	var total int64
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Evicted by a concurrent run.
+			return nil
+		}
+		clips = append(clips, cachedClip{path: path, size: info.Size(), usedAt: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan audio cache: %w", err)
+	}
+
+	sort.Slice(clips, func(i, j int) bool {
+		return clips[i].usedAt.Before(clips[j].usedAt)
+	})
+	for _, clip := range clips {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(clip.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to evict cached audio: %w", err)
+		}
+		total -= clip.size
+	}
+	return nil
+}
+
+func copyFileAtomic(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := store.CreateAtomic(target)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Commit()
+}
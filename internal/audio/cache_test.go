@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingProvider writes its call count as the audio, so tests can tell a
+// fresh take from a cached one.
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) GenerateAudio(_ context.Context, _ string, outputFile string) error {
+	p.calls++
+	return os.WriteFile(outputFile, []byte{byte('0' + p.calls)}, 0644)
+}
+
+func (p *countingProvider) Name() string                              { return "gemini" }
+func (p *countingProvider) IsAvailable() error                        { return nil }
+func (p *countingProvider) Voices() []string                          { return GeminiVoices }
+func (p *countingProvider) BuildAttribution(AttributionParams) string { return "" }
+
+func cacheTestConfig() *Config {
+	config := DefaultProviderConfig()
+	config.Provider = "gemini"
+	config.GeminiVoice = "Kore"
+	config.GeminiSpeed = 1.0
+	return config
+}
+
+func TestCacheKey(t *testing.T) {
+	base := CacheKey(cacheTestConfig(), "ябълка", "mp3")
+	if base == "" || base != CacheKey(cacheTestConfig(), " ябълка ", "MP3") {
+		t.Fatalf("CacheKey() = %q, want a stable key for the same processed text", base)
+	}
+
+	variants := map[string]func(*Config) (string, string){
+		"voice":    func(c *Config) (string, string) { c.GeminiVoice = "Puck"; return "ябълка", "mp3" },
+		"speed":    func(c *Config) (string, string) { c.GeminiSpeed = 0.8; return "ябълка", "mp3" },
+		"model":    func(c *Config) (string, string) { c.GeminiTTSModel = "other-tts"; return "ябълка", "mp3" },
+		"text":     func(c *Config) (string, string) { return "котка", "mp3" },
+		"format":   func(c *Config) (string, string) { return "ябълка", "wav" },
+		"provider": func(c *Config) (string, string) { c.Provider = LocalProviderName; return "ябълка", "mp3" },
+	}
+	for name, change := range variants {
+		config := cacheTestConfig()
+		text, format := change(config)
+		if key := CacheKey(config, text, format); key == base {
+			t.Errorf("CacheKey() ignores the %s", name)
+		}
+	}
+
+	random := cacheTestConfig()
+	random.GeminiVoice = ""
+	if key := CacheKey(random, "ябълка", "mp3"); key != "" {
+		t.Errorf("CacheKey() = %q for a random voice, want no key", key)
+	}
+}
+
+func TestGenerateWithCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "cache"), 1<<20)
+	provider := &countingProvider{}
+	newProvider := func(*Config) (Provider, error) { return provider, nil }
+	generate := func(cache *Cache, newProvider ProviderFactory, outputFile string) (bool, string) {
+		t.Helper()
+		cached, err := GenerateWithCache(context.Background(), cache, cacheTestConfig(), newProvider, "ябълка", outputFile)
+		if err != nil {
+			t.Fatalf("GenerateWithCache() error = %v", err)
+		}
+		data, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cached, string(data)
+	}
+
+	if cached, data := generate(cache, newProvider, filepath.Join(dir, "a.mp3")); cached || data != "1" {
+		t.Fatalf("first generation = %v, %q; want a fresh take", cached, data)
+	}
+
+	// A hit needs no provider at all.
+	offline := func(*Config) (Provider, error) { return nil, errors.New("OpenAI API key is required") }
+	if cached, data := generate(cache, offline, filepath.Join(dir, "b.mp3")); !cached || data != "1" {
+		t.Fatalf("second generation = %v, %q; want the cached take", cached, data)
+	}
+
+	cache.Refresh = true
+	if cached, data := generate(cache, newProvider, filepath.Join(dir, "c.mp3")); cached || data != "2" {
+		t.Fatalf("refresh = %v, %q; want a fresh take", cached, data)
+	}
+	cache.Refresh = false
+	if cached, data := generate(cache, offline, filepath.Join(dir, "d.mp3")); !cached || data != "2" {
+		t.Fatalf("after refresh = %v, %q; want the fresh take cached", cached, data)
+	}
+
+	if cached, data := generate(nil, newProvider, filepath.Join(dir, "e.mp3")); cached || data != "3" {
+		t.Fatalf("nil cache = %v, %q; want a fresh take", cached, data)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "cache"), 25)
+	clip := filepath.Join(dir, "clip.mp3")
+	if err := os.WriteFile(clip, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, key := range []string{"aa01", "bb02"} {
+		if err := cache.store(key, clip); err != nil {
+			t.Fatalf("store(%s) error = %v", key, err)
+		}
+		usedAt := time.Now().Add(time.Duration(i-10) * time.Minute)
+		if err := os.Chtimes(cache.entryPath(key, "mp3"), usedAt, usedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Using the older clip makes the other one the least recently used.
+	if hit, err := cache.fetch("aa01", filepath.Join(dir, "out.mp3")); err != nil || !hit {
+		t.Fatalf("fetch() = %v, %v; want a hit", hit, err)
+	}
+	if err := cache.store("cc03", clip); err != nil {
+		t.Fatalf("store(cc03) error = %v", err)
+	}
+
+	for key, want := range map[string]bool{"aa01": true, "bb02": false, "cc03": true} {
+		_, err := os.Stat(cache.entryPath(key, "mp3"))
+		if exists := err == nil; exists != want {
+			t.Errorf("entry %s exists = %v, want %v", key, exists, want)
+		}
+	}
+}
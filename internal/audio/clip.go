@@ -0,0 +1,70 @@
+package audio
+
+// clip.go renders one clip of a card from start to finish. The CLI processor
+// and the GUI both go through RenderClip, so every clip gets the same
+// revision, cache, post-processing and provenance handling.
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// RenderClip writes the audio of text to outputFile with config. It keeps the
+// card's current files as a revision, copies the clip from cache or renders
+// it with newProvider, post-processes it and writes its attribution and card
+// manifest entry. With a slowSpeed above 0 the slow variant next to the clip
+// follows the same steps. It reports whether the normal-speed clip came from
+// the cache.
+func RenderClip(ctx context.Context, cache *Cache, newProvider ProviderFactory, config *Config, text, outputFile string, slowSpeed float64) (bool, error) {
+	if err := store.SnapshotAssets(filepath.Dir(outputFile)); err != nil {
+		return false, fmt.Errorf("failed to preserve previous audio: %w", err)
+	}
+
+	cached, err := renderClipFile(ctx, cache, newProvider, config, text, outputFile)
+	if err != nil {
+		return false, err
+	}
+	if slowSpeed <= 0 {
+		return cached, nil
+	}
+
+	fmt.Printf("Generating slow audio for '%s' at speed %.2f\n", text, slowSpeed)
+	if _, err := renderClipFile(ctx, cache, newProvider, config.SlowVariant(slowSpeed), text, SlowAudioPath(outputFile)); err != nil {
+		return false, fmt.Errorf("failed to generate slow audio: %w", err)
+	}
+	return cached, nil
+}
+
+// renderClipFile generates, post-processes and records a single file.
+func renderClipFile(ctx context.Context, cache *Cache, newProvider ProviderFactory, config *Config, text, outputFile string) (bool, error) {
+	cached, err := GenerateWithCache(ctx, cache, config, newProvider, text, outputFile)
+	if err != nil {
+		return false, err
+	}
+	if err := PostProcessAudio(config.PostProcess, outputFile); err != nil {
+		return false, err
+	}
+
+	recorded := *config
+	recorded.CacheHit = cached
+	return cached, recordClip(&recorded, text, outputFile)
+}
+
+// recordClip writes the attribution of audioFile and records it in the card
+// manifest.
+func recordClip(config *Config, text, audioFile string) error {
+	instruction := InstructionForProvider(config.Provider, config, text)
+	params := AttributionParamsFrom(config, text, instruction, ProcessedTextForProvider(config.Provider, text), time.Now())
+
+	if err := store.WriteFileAtomic(AttributionPath(audioFile), []byte(BuildAttributionFor(config.Provider, params))); err != nil {
+		return fmt.Errorf("failed to write audio attribution file: %w", err)
+	}
+	if err := store.RecordAsset(filepath.Dir(audioFile), ManifestAsset(config.Provider, audioFile, params)); err != nil {
+		return fmt.Errorf("failed to record audio in card manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+func TestRenderClip(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(filepath.Join(dir, "cache"), 1<<20)
+	provider := &countingProvider{}
+	newProvider := func(*Config) (Provider, error) { return provider, nil }
+
+	// render renders the clip of a new card with its slow variant and returns
+	// the card's manifest entries by file name.
+	render := func(card string) (bool, map[string]store.Asset) {
+		t.Helper()
+		cardDir := filepath.Join(dir, card)
+		if err := os.MkdirAll(cardDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		cached, err := RenderClip(context.Background(), cache, newProvider, cacheTestConfig(), "ябълка", filepath.Join(cardDir, "audio.mp3"), 0.7)
+		if err != nil {
+			t.Fatalf("RenderClip() error = %v", err)
+		}
+		assets := make(map[string]store.Asset)
+		for _, asset := range store.LoadManifest(cardDir).Assets {
+			assets[asset.File] = asset
+		}
+		for _, file := range []string{"audio.mp3", "audio_slow.mp3"} {
+			if _, err := os.Stat(AttributionPath(filepath.Join(cardDir, file))); err != nil {
+				t.Fatalf("attribution of %s: %v", file, err)
+			}
+		}
+		return cached, assets
+	}
+
+	cached, assets := render("first")
+	if cached || provider.calls != 2 || len(assets) != 2 || assets["audio.mp3"].Cached || assets["audio_slow.mp3"].Cached {
+		t.Fatalf("first card = %v, %d calls, %+v; want a fresh clip and slow variant", cached, provider.calls, assets)
+	}
+	cached, assets = render("second")
+	if !cached || provider.calls != 2 || !assets["audio.mp3"].Cached || !assets["audio_slow.mp3"].Cached {
+		t.Fatalf("second card = %v, %d calls, %+v; want both clips from the cache", cached, provider.calls, assets)
+	}
+	attribution, err := os.ReadFile(AttributionPath(filepath.Join(dir, "second", "audio_slow.mp3")))
+	if err != nil || !strings.Contains(string(attribution), "Speed: 0.70") || !strings.Contains(string(attribution), "Copied from the TTS cache") {
+		t.Fatalf("slow attribution = %q, %v; want the slow speed and the cache note", attribution, err)
+	}
+}
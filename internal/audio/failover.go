@@ -0,0 +1,150 @@
+package audio
+
+// failover.go moves audio generation along an ordered chain of providers,
+// e.g. gemini -> openai -> local, when a provider cannot serve the request at
+// all: its quota is used up, its key is missing or rejected, its circuit
+// breaker is open or it refuses the text. Other errors, such as a full disk,
+// would fail with every provider alike and end the chain.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+
+	"codeberg.org/snonux/totalrecall/internal/apicircuit"
+)
+
+// Failover reasons, as recorded in the audio sidecars.
+const (
+	FailoverQuota       = "quota exceeded"
+	FailoverAuth        = "authentication failed"
+	FailoverCircuitOpen = "circuit open"
+	FailoverSafety      = "blocked by safety filter"
+)
+
+// FailoverReason returns why err lets the next provider of the chain take
+// over, or "" when it does not.
+func FailoverReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case apicircuit.IsOpen(err):
+		return FailoverCircuitOpen
+	case errors.Is(err, ErrGeminiAudioBlocked):
+		return FailoverSafety
+	}
+
+	status, code := apiErrorStatus(err)
+	switch status {
+	case http.StatusTooManyRequests:
+		return FailoverQuota
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return FailoverAuth
+	}
+
+	// Missing keys and some API errors only say what happened in the text.
+	message := strings.ToLower(code + " " + err.Error())
+	switch {
+	case containsAny(message, "quota", "resource_exhausted", "rate limit"):
+		return FailoverQuota
+	case containsAny(message, "api key", "unauthorized", "unauthenticated", "permission_denied"):
+		return FailoverAuth
+	case containsAny(message, "content_policy", "moderation_blocked", "safety system"):
+		return FailoverSafety
+	}
+	return ""
+}
+
+// apiErrorStatus returns the HTTP status and error code of an OpenAI or
+// Gemini API error in err's chain, or 0 and "".
+func apiErrorStatus(err error) (int, string) {
+	var openAIErr *openai.APIError
+	if errors.As(err, &openAIErr) {
+		return openAIErr.HTTPStatusCode, fmt.Sprint(openAIErr.Code)
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return requestErr.HTTPStatusCode, ""
+	}
+	var geminiErr genai.APIError
+	if errors.As(err, &geminiErr) {
+		return geminiErr.Code, geminiErr.Status
+	}
+	return 0, ""
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderChain returns the providers to try in order: primary, then the
+// fallbacks. Fallback entries may hold comma-separated lists; duplicates are
+// dropped and unknown providers are an error.
+func ProviderChain(primary string, fallbacks []string) ([]string, error) {
+	names := []string{primary}
+	for _, entry := range fallbacks {
+		names = append(names, strings.Split(entry, ",")...)
+	}
+
+	var chain []string
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			if i == 0 {
+				chain = append(chain, DefaultProviderConfig().Provider)
+			}
+			continue
+		}
+		if _, ok := defaultAudioProviders.Get(name); !ok {
+			return nil, fmt.Errorf("unknown audio provider: %s", name)
+		}
+		if !slices.Contains(chain, name) {
+			chain = append(chain, name)
+		}
+	}
+	return chain, nil
+}
+
+// RunWithProviderFailover calls generate with each provider of chain until
+// one succeeds, and returns that provider. It moves on only for errors with a
+// FailoverReason; warnFailover is told about each provider that is skipped.
+// failoverFrom lists the providers that failed before, e.g.
+// "gemini (quota exceeded)", so the audio sidecars can record why a fallback
+// provider spoke.
+func RunWithProviderFailover(chain []string, generate func(provider, failoverFrom string) error, warnFailover func(provider, reason string, err error)) (usedProvider string, err error) {
+	if len(chain) == 0 {
+		return "", errors.New("no audio provider configured")
+	}
+
+	var failed []string
+	for _, provider := range chain {
+		err = generate(provider, strings.Join(failed, ", "))
+		if err == nil {
+			return provider, nil
+		}
+
+		reason := FailoverReason(err)
+		if reason == "" {
+			return "", err
+		}
+		failed = append(failed, fmt.Sprintf("%s (%s)", provider, reason))
+		if len(failed) < len(chain) && warnFailover != nil {
+			warnFailover(provider, reason, err)
+		}
+	}
+
+	if len(chain) == 1 {
+		return "", err
+	}
+	return "", fmt.Errorf("all audio providers failed: %s: %w", strings.Join(failed, ", "), err)
+}
@@ -0,0 +1,126 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sony/gobreaker"
+	"google.golang.org/genai"
+)
+
+func TestFailoverReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "open circuit", err: fmt.Errorf("gemini API error: %w", gobreaker.ErrOpenState), want: FailoverCircuitOpen},
+		{name: "half-open circuit", err: gobreaker.ErrTooManyRequests, want: FailoverCircuitOpen},
+		{name: "gemini quota", err: fmt.Errorf("gemini API error: %w", genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}), want: FailoverQuota},
+		{name: "gemini invalid key", err: fmt.Errorf("gemini API error: %w", genai.APIError{Code: 400, Message: "API key not valid. Please pass a valid API key."}), want: FailoverAuth},
+		{name: "openai unauthorized", err: &openai.APIError{HTTPStatusCode: 401, Message: "Incorrect API key provided"}, want: FailoverAuth},
+		{name: "openai quota", err: &openai.APIError{HTTPStatusCode: 429, Message: "You exceeded your current quota"}, want: FailoverQuota},
+		{name: "openai content policy", err: &openai.APIError{HTTPStatusCode: 400, Code: "content_policy_violation"}, want: FailoverSafety},
+		{name: "missing key", err: errors.New("OpenAI API key is required"), want: FailoverAuth},
+		{name: "gemini safety block", err: fmt.Errorf("%w: SAFETY", ErrGeminiAudioBlocked), want: FailoverSafety},
+		{name: "moderation message", err: errors.New("error, status code: 400, message: moderation_blocked"), want: FailoverSafety},
+		{name: "no audio data", err: ErrGeminiNoAudioData, want: ""},
+		{name: "disk error", err: errors.New("failed to write output file: no space left on device"), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FailoverReason(tt.err); got != tt.want {
+				t.Fatalf("FailoverReason(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderChain(t *testing.T) {
+	chain, err := ProviderChain(" Gemini ", []string{"openai,local", "gemini", " local"})
+	if err != nil {
+		t.Fatalf("ProviderChain() error = %v", err)
+	}
+	if want := []string{"gemini", "openai", "local"}; !slices.Equal(chain, want) {
+		t.Fatalf("ProviderChain() = %v, want %v", chain, want)
+	}
+
+	chain, err = ProviderChain("", nil)
+	if err != nil || !slices.Equal(chain, []string{DefaultProviderConfig().Provider}) {
+		t.Fatalf("ProviderChain(\"\", nil) = %v, %v; want the default provider", chain, err)
+	}
+
+	if _, err := ProviderChain("gemini", []string{"festival"}); err == nil || err.Error() != "unknown audio provider: festival" {
+		t.Fatalf("ProviderChain() error = %v, want unknown provider", err)
+	}
+}
+
+func TestRunWithProviderFailover(t *testing.T) {
+	quotaErr := &openai.APIError{HTTPStatusCode: 429, Message: "quota"}
+
+	t.Run("moves to the next provider", func(t *testing.T) {
+		var calls, warned []string
+		var lastFailoverFrom string
+		used, err := RunWithProviderFailover([]string{"gemini", "openai", "local"}, func(provider, failoverFrom string) error {
+			calls = append(calls, provider)
+			lastFailoverFrom = failoverFrom
+			switch provider {
+			case "gemini":
+				return fmt.Errorf("gemini API error: %w", gobreaker.ErrOpenState)
+			case "openai":
+				return quotaErr
+			}
+			return nil
+		}, func(provider, reason string, err error) {
+			warned = append(warned, provider+": "+reason)
+		})
+		if err != nil || used != "local" {
+			t.Fatalf("RunWithProviderFailover() = %q, %v; want local", used, err)
+		}
+		if !slices.Equal(calls, []string{"gemini", "openai", "local"}) {
+			t.Errorf("calls = %v", calls)
+		}
+		if want := "gemini (circuit open), openai (quota exceeded)"; lastFailoverFrom != want {
+			t.Errorf("failoverFrom = %q, want %q", lastFailoverFrom, want)
+		}
+		if !slices.Equal(warned, []string{"gemini: circuit open", "openai: quota exceeded"}) {
+			t.Errorf("warnings = %v", warned)
+		}
+	})
+
+	t.Run("stops on other errors", func(t *testing.T) {
+		diskErr := errors.New("no space left on device")
+		var calls []string
+		_, err := RunWithProviderFailover([]string{"gemini", "openai"}, func(provider, _ string) error {
+			calls = append(calls, provider)
+			return diskErr
+		}, nil)
+		if !errors.Is(err, diskErr) || len(calls) != 1 {
+			t.Fatalf("RunWithProviderFailover() error = %v after %v, want the disk error from gemini only", err, calls)
+		}
+	})
+
+	t.Run("reports every failed provider", func(t *testing.T) {
+		_, err := RunWithProviderFailover([]string{"gemini", "openai"}, func(provider, _ string) error {
+			return quotaErr
+		}, nil)
+		if !errors.Is(err, quotaErr) || !strings.Contains(err.Error(), "all audio providers failed: gemini (quota exceeded), openai (quota exceeded)") {
+			t.Fatalf("RunWithProviderFailover() error = %v", err)
+		}
+	})
+
+	t.Run("single provider keeps its error", func(t *testing.T) {
+		_, err := RunWithProviderFailover([]string{"gemini"}, func(string, string) error {
+			return quotaErr
+		}, nil)
+		if err != quotaErr {
+			t.Fatalf("RunWithProviderFailover() error = %v, want the provider error", err)
+		}
+	})
+}
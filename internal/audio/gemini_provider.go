@@ -15,6 +15,7 @@ import (
 
 	"codeberg.org/snonux/totalrecall/internal/apicircuit"
 	"codeberg.org/snonux/totalrecall/internal/httpctx"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 const (
@@ -28,6 +29,10 @@ const (
 
 var ErrGeminiNoAudioData = errors.New("no audio data returned from Gemini")
 
+// ErrGeminiAudioBlocked means Gemini refused the text on safety grounds. Every
+// voice would be refused alike, so voice fallbacks do not retry it.
+var ErrGeminiAudioBlocked = errors.New("gemini blocked the audio request")
+
 var execLookPath = exec.LookPath
 var execCommand = exec.Command
 
@@ -127,7 +132,7 @@ func (p *GeminiProvider) IsAvailable() error {
 
 func (p *GeminiProvider) buildPrompt(text string) string {
 	var prompt strings.Builder
-	prompt.WriteString(geminiPromptInstruction(p.config))
+	prompt.WriteString(geminiPromptInstruction(p.config, text))
 	prompt.WriteString("\n")
 	prompt.WriteString(strings.TrimSpace(text))
 
@@ -186,9 +191,30 @@ func extractAudioData(response *genai.GenerateContentResponse) ([]byte, string,
 		}
 	}
 
+	if reason := geminiBlockReason(response); reason != "" {
+		return nil, "", fmt.Errorf("%w: %s", ErrGeminiAudioBlocked, reason)
+	}
 	return nil, "", ErrGeminiNoAudioData
 }
 
+// geminiBlockReason returns why Gemini blocked the prompt or stopped the
+// candidates for safety, or "" when it did not.
+func geminiBlockReason(response *genai.GenerateContentResponse) string {
+	if response.PromptFeedback != nil && response.PromptFeedback.BlockReason != "" {
+		return string(response.PromptFeedback.BlockReason)
+	}
+	for _, candidate := range response.Candidates {
+		if candidate == nil {
+			continue
+		}
+		switch candidate.FinishReason {
+		case genai.FinishReasonSafety, genai.FinishReasonProhibitedContent, genai.FinishReasonBlocklist, genai.FinishReasonSPII:
+			return string(candidate.FinishReason)
+		}
+	}
+	return ""
+}
+
 // IsGeminiNoAudioDataError reports whether the error means Gemini returned no audio payload.
 func IsGeminiNoAudioDataError(err error) bool {
 	return errors.Is(err, ErrGeminiNoAudioData)
@@ -207,12 +233,14 @@ func writeGeminiAudioFile(outputFile string, audioData []byte, mimeType string)
 
 	switch ext {
 	case ".wav":
-		if err := os.WriteFile(outputFile, encoded, 0644); err != nil {
+		if err := store.WriteFileAtomic(outputFile, encoded); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
 		return nil
 	case ".mp3":
-		return transcodeWAVToMP3(encoded, outputFile)
+		return store.WriteAtomically(outputFile, func(tmpPath string) error {
+			return transcodeWAVToMP3(encoded, tmpPath)
+		})
 	default:
 		return fmt.Errorf("gemini TTS only supports .wav and .mp3 output files, got %q", outputFile)
 	}
@@ -305,7 +333,7 @@ func encodePCMAsWAV(pcmData []byte) ([]byte, error) {
 func transcodeWAVToMP3(wavData []byte, outputFile string) error {
 	ffmpegPath, err := execLookPath("ffmpeg")
 	if err != nil {
-		return fmt.Errorf("ffmpeg is required to convert Gemini audio to mp3: %w", err)
+		return fmt.Errorf("ffmpeg is required to convert audio to mp3: %w", err)
 	}
 
 	cmd := execCommand(
@@ -328,7 +356,7 @@ func transcodeWAVToMP3(wavData []byte, outputFile string) error {
 		if message == "" {
 			message = err.Error()
 		}
-		return fmt.Errorf("failed to convert Gemini audio to mp3: %s", message)
+		return fmt.Errorf("failed to convert audio to mp3: %s", message)
 	}
 
 	return nil
@@ -173,6 +173,22 @@ func TestExtractAudioDataErrors(t *testing.T) {
 			},
 			wantError: "no audio data returned from Gemini",
 		},
+		{
+			name: "response blocked for safety",
+			response: &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{
+					{FinishReason: genai.FinishReasonSafety},
+				},
+			},
+			wantError: "gemini blocked the audio request: SAFETY",
+		},
+		{
+			name: "prompt blocked",
+			response: &genai.GenerateContentResponse{
+				PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonProhibitedContent},
+			},
+			wantError: "gemini blocked the audio request: PROHIBITED_CONTENT",
+		},
 	}
 
 	for _, tt := range tests {
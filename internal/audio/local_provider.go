@@ -0,0 +1,275 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// Local TTS engines supported by LocalProvider.
+const (
+	LocalEnginePiper   = "piper"
+	LocalEngineEspeak  = "espeak-ng"
+	defaultEspeakVoice = "bg"
+	// espeakDefaultWPM is espeak-ng's own default rate; speeds scale it.
+	espeakDefaultWPM = 175
+)
+
+// EspeakVoices lists Bulgarian espeak-ng voice variants, male and female.
+var EspeakVoices = []string{
+	"bg",
+	"bg+m3",
+	"bg+m5",
+	"bg+f2",
+	"bg+f4",
+}
+
+// LocalAudioConfig holds settings specific to the offline TTS backend.
+type LocalAudioConfig struct {
+	Engine string  // LocalEnginePiper or LocalEngineEspeak; empty picks Piper when a model is set
+	Binary string  // Path of the engine binary; empty looks the engine name up in PATH
+	Model  string  // Piper voice model (.onnx); unused by espeak-ng
+	Voice  string  // espeak-ng voice, e.g. "bg+f2"; Piper speaks with the voice of its model
+	Speed  float64 // 1.0 is the engine's normal rate
+}
+
+// LocalProvider implements Provider by running a locally installed Piper or
+// espeak-ng, so audio can be generated offline and without API quota. Both
+// engines write WAV, which takes the same ffmpeg path to mp3 as Gemini audio.
+type LocalProvider struct {
+	config       LocalAudioConfig
+	outputFormat string
+}
+
+var _ Provider = (*LocalProvider)(nil)
+
+// NewLocalProvider creates a local TTS provider from the local sub-config.
+// Whether the engine is installed is checked by IsAvailable, so the provider
+// can be created to report a missing binary or model.
+func NewLocalProvider(config LocalAudioConfig, outputFormat string) (Provider, error) {
+	normalized := normalizeLocalAudioConfig(config)
+	if normalized.Engine != LocalEnginePiper && normalized.Engine != LocalEngineEspeak {
+		return nil, fmt.Errorf("unknown local TTS engine %q (want %s or %s)", config.Engine, LocalEnginePiper, LocalEngineEspeak)
+	}
+
+	return &LocalProvider{
+		config:       normalized,
+		outputFormat: outputFormat,
+	}, nil
+}
+
+// GenerateAudio runs the local engine and writes its audio to outputFile.
+func (p *LocalProvider) GenerateAudio(ctx context.Context, text string, outputFile string) error {
+	if err := ValidateBulgarianText(text); err != nil {
+		return err
+	}
+	if err := p.IsAvailable(); err != nil {
+		return err
+	}
+
+	wavFile, err := os.CreateTemp("", "totalrecall-tts-*.wav")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary audio file: %w", err)
+	}
+	wavPath := wavFile.Name()
+	_ = wavFile.Close()
+	defer func() {
+		_ = os.Remove(wavPath)
+	}()
+
+	binary, _ := p.binaryPath()
+	cmd := execCommand(binary, p.arguments(wavPath)...)
+	cmd.Stdin = strings.NewReader(ProcessedTextForProvider(p.Name(), text))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	fmt.Printf("Local TTS: Using %s with voice '%s' at speed %.2f\n", localModelLabel(p.config), localVoiceLabel(p.config), p.config.Speed)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", p.config.Engine, err)
+	}
+	// The engines are not context-aware; killing the process honours
+	// cancellation.
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		return ctx.Err()
+	case err := <-done:
+		if err != nil {
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = err.Error()
+			}
+			return fmt.Errorf("%s failed: %s", p.config.Engine, message)
+		}
+	}
+
+	output, err := os.ReadFile(wavPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s output: %w", p.config.Engine, err)
+	}
+	if len(output) == 0 {
+		return fmt.Errorf("no audio data received from %s", p.config.Engine)
+	}
+
+	return writeLocalAudioFile(outputFile, output)
+}
+
+// arguments returns the engine arguments that write the text read from
+// stdin as WAV to wavPath.
+func (p *LocalProvider) arguments(wavPath string) []string {
+	if p.config.Engine == LocalEnginePiper {
+		return []string{
+			"--model", p.config.Model,
+			"--output_file", wavPath,
+			// Piper stretches the phoneme lengths, so slower is longer.
+			"--length_scale", strconv.FormatFloat(1/p.config.Speed, 'f', 2, 64),
+		}
+	}
+	return []string{
+		"-v", p.config.Voice,
+		"-s", strconv.Itoa(int(math.Round(espeakDefaultWPM * p.config.Speed))),
+		"-w", wavPath,
+		"--stdin",
+	}
+}
+
+// Name returns the provider name.
+func (p *LocalProvider) Name() string {
+	return LocalProviderName
+}
+
+// IsAvailable checks that the engine binary and, for Piper, its voice model
+// exist.
+func (p *LocalProvider) IsAvailable() error {
+	if _, err := p.binaryPath(); err != nil {
+		return fmt.Errorf("local TTS engine %s not found: %w", p.config.Engine, err)
+	}
+	if p.config.Engine != LocalEnginePiper {
+		return nil
+	}
+	if p.config.Model == "" {
+		return errors.New("piper needs a voice model (audio.local_model or --local-tts-model)")
+	}
+	if info, err := os.Stat(p.config.Model); err != nil || info.IsDir() {
+		return fmt.Errorf("piper voice model %s not found", p.config.Model)
+	}
+	return nil
+}
+
+// Voices returns the espeak-ng Bulgarian voices, or the name of the Piper
+// model, which has a single voice.
+func (p *LocalProvider) Voices() []string {
+	return LocalVoices(p.config)
+}
+
+// BuildAttribution returns the local TTS attribution text for a generated
+// audio file.
+func (p *LocalProvider) BuildAttribution(params AttributionParams) string {
+	return BuildLocalAttribution(params)
+}
+
+// localModelLabel names the engine, and for Piper its model, in attribution
+// and metadata files, e.g. "piper (bg_BG-dimitar-medium.onnx)".
+func localModelLabel(config LocalAudioConfig) string {
+	if config.Engine == LocalEnginePiper {
+		return fmt.Sprintf("%s (%s)", config.Engine, filepath.Base(config.Model))
+	}
+	return config.Engine
+}
+
+// localVoiceLabel returns the voice the engine speaks with.
+func localVoiceLabel(config LocalAudioConfig) string {
+	if config.Engine == LocalEnginePiper {
+		return piperVoiceName(config.Model)
+	}
+	return config.Voice
+}
+
+func (p *LocalProvider) binaryPath() (string, error) {
+	if p.config.Binary != "" {
+		if _, err := os.Stat(p.config.Binary); err != nil {
+			return "", err
+		}
+		return p.config.Binary, nil
+	}
+	return execLookPath(p.config.Engine)
+}
+
+// LocalVoices returns the voices of the local engine config selects.
+func LocalVoices(config LocalAudioConfig) []string {
+	config = normalizeLocalAudioConfig(config)
+	if config.Engine == LocalEnginePiper {
+		return []string{piperVoiceName(config.Model)}
+	}
+	return EspeakVoices
+}
+
+// piperVoiceName names a Piper voice after its model file, e.g.
+// "bg_BG-dimitar-medium" for bg_BG-dimitar-medium.onnx.
+func piperVoiceName(model string) string {
+	if model == "" {
+		return LocalEnginePiper
+	}
+	return strings.TrimSuffix(filepath.Base(model), filepath.Ext(model))
+}
+
+// normalizeLocalAudioConfig applies defaults and trims whitespace from a
+// LocalAudioConfig.
+func normalizeLocalAudioConfig(config LocalAudioConfig) LocalAudioConfig {
+	config.Engine = strings.ToLower(strings.TrimSpace(config.Engine))
+	config.Binary = strings.TrimSpace(config.Binary)
+	config.Model = strings.TrimSpace(config.Model)
+	config.Voice = strings.TrimSpace(config.Voice)
+
+	switch config.Engine {
+	case "":
+		config.Engine = LocalEngineEspeak
+		if config.Model != "" {
+			config.Engine = LocalEnginePiper
+		}
+	case "espeak", "espeakng":
+		config.Engine = LocalEngineEspeak
+	}
+	if config.Voice == "" {
+		config.Voice = defaultEspeakVoice
+	}
+	if config.Speed <= 0 {
+		config.Speed = 1.0
+	}
+	return config
+}
+
+// writeLocalAudioFile writes the WAV output of a local engine to outputFile,
+// converting it to mp3 when the file asks for it.
+func writeLocalAudioFile(outputFile string, wavData []byte) error {
+	if err := ensureOutputDirectory(outputFile); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(outputFile)) {
+	case ".wav":
+		if err := store.WriteFileAtomic(outputFile, wavData); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		return nil
+	case ".mp3":
+		return store.WriteAtomically(outputFile, func(tmpPath string) error {
+			return transcodeWAVToMP3(wavData, tmpPath)
+		})
+	default:
+		return fmt.Errorf("local TTS only supports .wav and .mp3 output files, got %q", outputFile)
+	}
+}
@@ -0,0 +1,278 @@
+package audio
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFakeEngine writes a shell script that records its arguments and stdin
+// next to itself and writes a fake WAV file to the path following outFlag.
+func writeFakeEngine(t *testing.T, dir, name, outFlag string) string {
+	t.Helper()
+	script := "#!/bin/sh\n" +
+		"dir=$(dirname \"$0\")\n" +
+		"printf '%s\\n' \"$@\" > \"$dir/args\"\n" +
+		"cat > \"$dir/stdin\"\n" +
+		"out=\"\"\nprev=\"\"\n" +
+		"for arg in \"$@\"; do if [ \"$prev\" = \"" + outFlag + "\" ]; then out=\"$arg\"; fi; prev=\"$arg\"; done\n" +
+		"printf 'RIFFwav' > \"$out\"\n"
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake %s script: %v", name, err)
+	}
+	return path
+}
+
+func TestNewLocalProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     LocalAudioConfig
+		wantErr    bool
+		wantEngine string
+		wantVoice  string
+	}{
+		{
+			name:       "defaults to espeak-ng",
+			config:     LocalAudioConfig{},
+			wantEngine: LocalEngineEspeak,
+			wantVoice:  "bg",
+		},
+		{
+			name:       "model selects piper",
+			config:     LocalAudioConfig{Model: "/voices/bg_BG-dimitar-medium.onnx"},
+			wantEngine: LocalEnginePiper,
+			wantVoice:  "bg",
+		},
+		{
+			name:       "espeak alias",
+			config:     LocalAudioConfig{Engine: " Espeak ", Voice: "bg+f2"},
+			wantEngine: LocalEngineEspeak,
+			wantVoice:  "bg+f2",
+		},
+		{
+			name:    "unknown engine",
+			config:  LocalAudioConfig{Engine: "festival"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewLocalProvider(tt.config, "mp3")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLocalProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			localProvider := provider.(*LocalProvider)
+			if localProvider.config.Engine != tt.wantEngine {
+				t.Errorf("Engine = %q, want %q", localProvider.config.Engine, tt.wantEngine)
+			}
+			if localProvider.config.Voice != tt.wantVoice {
+				t.Errorf("Voice = %q, want %q", localProvider.config.Voice, tt.wantVoice)
+			}
+			if localProvider.config.Speed != 1.0 {
+				t.Errorf("Speed = %v, want 1.0", localProvider.config.Speed)
+			}
+		})
+	}
+}
+
+func TestLocalProviderIsAvailable(t *testing.T) {
+	dir := t.TempDir()
+	binary := writeFakeEngine(t, dir, "piper", "--output_file")
+	model := filepath.Join(dir, "bg_BG-dimitar-medium.onnx")
+	if err := os.WriteFile(model, []byte("model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  LocalAudioConfig
+		wantErr string
+	}{
+		{
+			name:   "piper with binary and model",
+			config: LocalAudioConfig{Engine: LocalEnginePiper, Binary: binary, Model: model},
+		},
+		{
+			name:    "missing binary",
+			config:  LocalAudioConfig{Engine: LocalEngineEspeak, Binary: filepath.Join(dir, "missing")},
+			wantErr: "local TTS engine espeak-ng not found",
+		},
+		{
+			name:    "piper without model",
+			config:  LocalAudioConfig{Engine: LocalEnginePiper, Binary: binary},
+			wantErr: "piper needs a voice model",
+		},
+		{
+			name:    "piper with missing model",
+			config:  LocalAudioConfig{Engine: LocalEnginePiper, Binary: binary, Model: filepath.Join(dir, "missing.onnx")},
+			wantErr: "piper voice model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewLocalProvider(tt.config, "mp3")
+			if err != nil {
+				t.Fatalf("NewLocalProvider() error = %v", err)
+			}
+			err = provider.IsAvailable()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("IsAvailable() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("IsAvailable() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalProviderGenerateAudioWithEspeak(t *testing.T) {
+	dir := t.TempDir()
+	engineDir := filepath.Join(dir, "engine")
+	if err := os.Mkdir(engineDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := writeFakeEngine(t, engineDir, "espeak-ng", "-w")
+
+	originalLookPath := execLookPath
+	execLookPath = func(file string) (string, error) {
+		if file == LocalEngineEspeak {
+			return binary, nil
+		}
+		return originalLookPath(file)
+	}
+	t.Cleanup(func() {
+		execLookPath = originalLookPath
+	})
+
+	provider, err := NewLocalProvider(LocalAudioConfig{Voice: "bg+f2", Speed: 0.8}, "wav")
+	if err != nil {
+		t.Fatalf("NewLocalProvider() error = %v", err)
+	}
+	outputFile := filepath.Join(dir, "audio.wav")
+	if err := provider.GenerateAudio(context.Background(), "ябълка", outputFile); err != nil {
+		t.Fatalf("GenerateAudio() error = %v", err)
+	}
+
+	if data, err := os.ReadFile(outputFile); err != nil || string(data) != "RIFFwav" {
+		t.Fatalf("output file = %q, %v; want fake WAV payload", data, err)
+	}
+	args, err := os.ReadFile(filepath.Join(engineDir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotArgs := strings.Split(strings.TrimSpace(string(args)), "\n")
+	if !slices.Equal(gotArgs[:4], []string{"-v", "bg+f2", "-s", "140"}) || gotArgs[len(gotArgs)-1] != "--stdin" {
+		t.Errorf("espeak-ng arguments = %q", gotArgs)
+	}
+	if stdin, _ := os.ReadFile(filepath.Join(engineDir, "stdin")); !strings.Contains(string(stdin), "ябълка") {
+		t.Errorf("espeak-ng stdin = %q, want the word", stdin)
+	}
+}
+
+func TestLocalProviderGenerateAudioWithPiperToMP3(t *testing.T) {
+	dir := t.TempDir()
+	engineDir := filepath.Join(dir, "engine")
+	if err := os.Mkdir(engineDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := writeFakeEngine(t, engineDir, "piper", "--output_file")
+	model := filepath.Join(dir, "bg_BG-dimitar-medium.onnx")
+	if err := os.WriteFile(model, []byte("model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ffmpegScript := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\nout=\"\"\nfor arg in \"$@\"; do out=\"$arg\"; done\ncat >/dev/null\nprintf 'mp3' > \"$out\"\n"
+	if err := os.WriteFile(ffmpegScript, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg script: %v", err)
+	}
+	originalLookPath := execLookPath
+	execLookPath = func(file string) (string, error) {
+		if file == "ffmpeg" {
+			return ffmpegScript, nil
+		}
+		return originalLookPath(file)
+	}
+	t.Cleanup(func() {
+		execLookPath = originalLookPath
+	})
+
+	provider, err := NewLocalProvider(LocalAudioConfig{Binary: binary, Model: model, Speed: 0.5}, "mp3")
+	if err != nil {
+		t.Fatalf("NewLocalProvider() error = %v", err)
+	}
+	outputFile := filepath.Join(dir, "audio.mp3")
+	if err := provider.GenerateAudio(context.Background(), "котка", outputFile); err != nil {
+		t.Fatalf("GenerateAudio() error = %v", err)
+	}
+
+	if data, err := os.ReadFile(outputFile); err != nil || string(data) != "mp3" {
+		t.Fatalf("output file = %q, %v; want fake mp3 payload", data, err)
+	}
+	args, err := os.ReadFile(filepath.Join(engineDir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(args); !strings.Contains(got, "--model\n"+model+"\n") || !strings.Contains(got, "--length_scale\n2.00\n") {
+		t.Errorf("piper arguments = %q", got)
+	}
+}
+
+func TestLocalProviderGenerateAudioReportsEngineErrors(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "espeak-ng")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\necho 'voice not found' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewLocalProvider(LocalAudioConfig{Engine: LocalEngineEspeak, Binary: binary}, "wav")
+	if err != nil {
+		t.Fatalf("NewLocalProvider() error = %v", err)
+	}
+	outputFile := filepath.Join(dir, "audio.wav")
+	err = provider.GenerateAudio(context.Background(), "куче", outputFile)
+	if err == nil || !strings.Contains(err.Error(), "voice not found") {
+		t.Fatalf("GenerateAudio() error = %v, want engine stderr", err)
+	}
+	if _, statErr := os.Stat(outputFile); !os.IsNotExist(statErr) {
+		t.Fatalf("expected no output file to be written, statErr=%v", statErr)
+	}
+}
+
+func TestLocalVoices(t *testing.T) {
+	if got := LocalVoices(LocalAudioConfig{}); !slices.Equal(got, EspeakVoices) {
+		t.Errorf("LocalVoices(espeak-ng) = %v", got)
+	}
+	if got := LocalVoices(LocalAudioConfig{Model: "/voices/bg_BG-dimitar-medium.onnx"}); !slices.Equal(got, []string{"bg_BG-dimitar-medium"}) {
+		t.Errorf("LocalVoices(piper) = %v", got)
+	}
+	config := &Config{Provider: LocalProviderName, LocalModel: "/voices/bg_BG-dimitar-medium.onnx"}
+	if got := VoicesForConfig(config); !slices.Equal(got, []string{"bg_BG-dimitar-medium"}) {
+		t.Errorf("VoicesForConfig(piper) = %v", got)
+	}
+}
+
+func TestBuildLocalAttribution(t *testing.T) {
+	config := &Config{Provider: LocalProviderName, LocalModel: "/voices/bg_BG-dimitar-medium.onnx", LocalSpeed: 1.0}
+	params := AttributionParamsFrom(config, "ябълка", "", "ябълка", time.Now())
+	text := BuildAttributionFor(config.Provider, params)
+	for _, want := range []string{"Audio generated offline by local TTS", "piper (bg_BG-dimitar-medium.onnx)", "bg_BG-dimitar-medium", "ябълка"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("attribution %q does not contain %q", text, want)
+		}
+	}
+}
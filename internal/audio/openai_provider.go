@@ -13,6 +13,7 @@ import (
 
 	"codeberg.org/snonux/totalrecall/internal/apicircuit"
 	"codeberg.org/snonux/totalrecall/internal/httpctx"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // Compile-time check that OpenAIProvider implements the Provider interface.
@@ -117,16 +118,12 @@ func (p *OpenAIProvider) GenerateAudio(ctx context.Context, text string, outputF
 		}
 	}
 
-	// Create output file
-	out, err := os.Create(outputFile)
+	// Write to a temporary file so readers never see a truncated clip.
+	out, err := store.CreateAtomic(outputFile)
 	if err != nil {
 		return fmt.Errorf("failed to create output file: %w", err)
 	}
-	defer func() {
-		if closeErr := out.Close(); err == nil && closeErr != nil {
-			err = fmt.Errorf("failed to close output file: %w", closeErr)
-		}
-	}()
+	defer out.Abort()
 
 	// Copy the audio data
 	written, err := io.Copy(out, response)
@@ -138,6 +135,10 @@ func (p *OpenAIProvider) GenerateAudio(ctx context.Context, text string, outputF
 		return errors.New("no audio data received from OpenAI")
 	}
 
+	if err := out.Commit(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,153 @@
+package audio
+
+// postprocess.go evens out the clips of all providers with one ffmpeg pass:
+// Gemini and OpenAI clips vary widely in volume and often start or end with
+// long silence. The cache keeps the clips as the provider rendered them, so
+// changing these settings does not need new TTS calls.
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// Post-processing defaults: -16 LUFS is the usual loudness of speech
+// podcasts, and -50 dB sits well below speech but above the noise floor of
+// TTS output.
+const (
+	DefaultLoudnessLUFS = -16.0
+	DefaultSilenceDB    = -50.0
+)
+
+const (
+	// keptSilence is the silence in seconds left at each end of a clip, so
+	// playback does not start or stop abruptly.
+	keptSilence = 0.1
+	// postProcessSampleRate is the rate of Gemini and OpenAI TTS; loudnorm
+	// would otherwise upsample to 192 kHz.
+	postProcessSampleRate = "24000"
+)
+
+// PostProcessConfig selects the post-processing of generated clips. The zero
+// value leaves clips untouched.
+type PostProcessConfig struct {
+	LoudnessLUFS float64 // EBU R128 integrated loudness target; 0 disables normalization
+	SilenceDB    float64 // Leading and trailing audio below this level is trimmed; 0 disables trimming
+	FadeMs       int     // Fade-in and fade-out length; 0 disables fading
+}
+
+// Enabled reports whether c changes clips at all.
+func (c PostProcessConfig) Enabled() bool {
+	return c.LoudnessLUFS != 0 || c.SilenceDB != 0 || c.FadeMs > 0
+}
+
+// Validate checks that the settings are within what ffmpeg accepts.
+func (c PostProcessConfig) Validate() error {
+	if c.LoudnessLUFS != 0 && (c.LoudnessLUFS < -70 || c.LoudnessLUFS > -5) {
+		return fmt.Errorf("loudness target %g LUFS is outside -70 to -5", c.LoudnessLUFS)
+	}
+	if c.SilenceDB > 0 || c.SilenceDB < -100 {
+		return fmt.Errorf("silence threshold %g dB is outside -100 to 0", c.SilenceDB)
+	}
+	if c.FadeMs < 0 {
+		return fmt.Errorf("fade length %d ms is negative", c.FadeMs)
+	}
+	return nil
+}
+
+// filters returns the ffmpeg audio filter chain of c. Trimming and fading
+// work on the start of the clip, so the chain treats the start, reverses the
+// clip to treat the end the same way and reverses it back. Loudness is
+// measured last, on the trimmed speech only.
+func (c PostProcessConfig) filters() string {
+	var ends []string
+	if c.SilenceDB != 0 {
+		ends = append(ends, fmt.Sprintf("silenceremove=start_periods=1:start_threshold=%gdB:start_silence=%g", c.SilenceDB, keptSilence))
+	}
+	if c.FadeMs > 0 {
+		ends = append(ends, fmt.Sprintf("afade=t=in:d=%g", float64(c.FadeMs)/1000))
+	}
+
+	var chain []string
+	if len(ends) > 0 {
+		chain = append(chain, ends...)
+		chain = append(chain, "areverse")
+		chain = append(chain, ends...)
+		chain = append(chain, "areverse")
+	}
+	if c.LoudnessLUFS != 0 {
+		chain = append(chain, fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11", c.LoudnessLUFS))
+	}
+	return strings.Join(chain, ",")
+}
+
+var warnNoFFmpegOnce sync.Once
+
+// AvailablePostProcess returns c, or the zero config with a one-time warning
+// when ffmpeg is not installed, so clips are kept as generated instead of
+// failing and the sidecars do not claim processing that did not happen.
+func AvailablePostProcess(c PostProcessConfig) PostProcessConfig {
+	if !c.Enabled() {
+		return c
+	}
+	if _, err := execLookPath("ffmpeg"); err != nil {
+		warnNoFFmpegOnce.Do(func() {
+			fmt.Printf("Warning: ffmpeg not found, generated audio is not normalized or trimmed: %v\n", err)
+		})
+		return PostProcessConfig{}
+	}
+	return c
+}
+
+// PostProcessAudio applies c to audioFile in place. It does nothing for the
+// zero config.
+func PostProcessAudio(c PostProcessConfig, audioFile string) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	ffmpegPath, err := execLookPath("ffmpeg")
+	if err != nil {
+		return fmt.Errorf("ffmpeg is required for audio post-processing: %w", err)
+	}
+
+	var codec []string
+	switch strings.ToLower(filepath.Ext(audioFile)) {
+	case ".mp3":
+		codec = []string{"-codec:a", "libmp3lame", "-q:a", "4"}
+	case ".wav":
+		codec = []string{"-codec:a", "pcm_s16le"}
+	default:
+		return errors.New("audio post-processing only supports .wav and .mp3 files")
+	}
+
+	return store.WriteAtomically(audioFile, func(tmpPath string) error {
+		args := []string{
+			"-nostdin",
+			"-hide_banner",
+			"-loglevel", "error",
+			"-y",
+			"-i", audioFile,
+			"-af", c.filters(),
+		}
+		if c.LoudnessLUFS != 0 {
+			args = append(args, "-ar", postProcessSampleRate)
+		}
+		args = append(args, codec...)
+		args = append(args, tmpPath)
+
+		output, err := execCommand(ffmpegPath, args...).CombinedOutput()
+		if err != nil {
+			message := strings.TrimSpace(string(output))
+			if message == "" {
+				message = err.Error()
+			}
+			return fmt.Errorf("failed to post-process audio: %s", message)
+		}
+		return nil
+	})
+}
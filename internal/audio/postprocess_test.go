@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPostProcessConfigFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PostProcessConfig
+		want   string
+	}{
+		{
+			name:   "loudness only",
+			config: PostProcessConfig{LoudnessLUFS: -16},
+			want:   "loudnorm=I=-16:TP=-1.5:LRA=11",
+		},
+		{
+			name:   "trim and fade both ends before normalizing",
+			config: PostProcessConfig{LoudnessLUFS: -18, SilenceDB: -50, FadeMs: 20},
+			want: "silenceremove=start_periods=1:start_threshold=-50dB:start_silence=0.1,afade=t=in:d=0.02,areverse," +
+				"silenceremove=start_periods=1:start_threshold=-50dB:start_silence=0.1,afade=t=in:d=0.02,areverse," +
+				"loudnorm=I=-18:TP=-1.5:LRA=11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.filters(); got != tt.want {
+				t.Fatalf("filters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostProcessConfigValidate(t *testing.T) {
+	valid := []PostProcessConfig{{}, {LoudnessLUFS: DefaultLoudnessLUFS, SilenceDB: DefaultSilenceDB, FadeMs: 20}}
+	for _, config := range valid {
+		if err := config.Validate(); err != nil {
+			t.Errorf("Validate(%+v) error = %v", config, err)
+		}
+	}
+
+	invalid := []PostProcessConfig{{LoudnessLUFS: 3}, {LoudnessLUFS: -90}, {SilenceDB: 10}, {FadeMs: -5}}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded; want error", config)
+		}
+	}
+}
+
+func TestPostProcessAudio(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegScript := filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > \"$(dirname \"$0\")/args\"\nout=\"\"\nfor arg in \"$@\"; do out=\"$arg\"; done\nprintf 'processed' > \"$out\"\n"
+	if err := os.WriteFile(ffmpegScript, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg script: %v", err)
+	}
+	originalLookPath := execLookPath
+	execLookPath = func(file string) (string, error) {
+		if file == "ffmpeg" {
+			return ffmpegScript, nil
+		}
+		return originalLookPath(file)
+	}
+	t.Cleanup(func() {
+		execLookPath = originalLookPath
+	})
+
+	audioFile := filepath.Join(dir, "audio.mp3")
+	if err := os.WriteFile(audioFile, []byte("raw"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PostProcessAudio(PostProcessConfig{}, audioFile); err != nil {
+		t.Fatalf("PostProcessAudio(zero) error = %v", err)
+	}
+	if data, _ := os.ReadFile(audioFile); string(data) != "raw" {
+		t.Fatalf("zero config changed the audio to %q", data)
+	}
+
+	config := PostProcessConfig{LoudnessLUFS: -16, SilenceDB: -50}
+	if got := AvailablePostProcess(config); got != config {
+		t.Fatalf("AvailablePostProcess() = %+v with ffmpeg installed, want %+v", got, config)
+	}
+	if err := PostProcessAudio(config, audioFile); err != nil {
+		t.Fatalf("PostProcessAudio() error = %v", err)
+	}
+	if data, _ := os.ReadFile(audioFile); string(data) != "processed" {
+		t.Fatalf("audio = %q, want the ffmpeg output in place", data)
+	}
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"-i\n" + audioFile + "\n", "-af\n" + config.filters() + "\n", "-ar\n24000\n", "libmp3lame"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("ffmpeg arguments = %q, missing %q", args, want)
+		}
+	}
+}
+
+func TestAvailablePostProcessWithoutFFmpeg(t *testing.T) {
+	originalLookPath := execLookPath
+	execLookPath = func(file string) (string, error) {
+		return "", os.ErrNotExist
+	}
+	t.Cleanup(func() {
+		execLookPath = originalLookPath
+	})
+
+	if got := AvailablePostProcess(PostProcessConfig{LoudnessLUFS: -16}); got.Enabled() {
+		t.Fatalf("AvailablePostProcess() = %+v without ffmpeg, want the zero config", got)
+	}
+}
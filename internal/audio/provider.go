@@ -48,11 +48,15 @@ type GeminiAudioConfig struct {
 	Speed    float64 // Prompt hint for desired speech speed
 }
 
+// LocalProviderName is the provider name of the offline Piper / espeak-ng
+// backend.
+const LocalProviderName = "local"
+
 // Config holds common configuration for audio providers. Provider-specific
-// settings are grouped into OpenAI and Gemini sub-configs so callers and
-// implementations only see the fields relevant to their backend.
+// settings are grouped into OpenAI, Gemini and local sub-configs so callers
+// and implementations only see the fields relevant to their backend.
 type Config struct {
-	Provider     string // Provider name: "openai" or "gemini"
+	Provider     string // Provider name: "openai", "gemini" or "local"
 	OutputDir    string // Directory for output files
 	OutputFormat string // Output format: "mp3" or "wav"
 
@@ -68,25 +72,63 @@ type Config struct {
 	GeminiTTSModel string  // "gemini-2.5-flash-preview-tts"
 	GeminiVoice    string  // One of GeminiVoices; empty lets the caller choose a random voice.
 	GeminiSpeed    float64 // Prompt hint for desired speech speed
+
+	// Local settings — only used when Provider == "local".
+	LocalEngine string  // "piper" or "espeak-ng"; empty picks Piper when a model is set
+	LocalBinary string  // Engine binary; empty looks the engine up in PATH
+	LocalModel  string  // Piper voice model (.onnx)
+	LocalVoice  string  // espeak-ng voice, e.g. "bg+f2"
+	LocalSpeed  float64 // 1.0 is the engine's normal rate
+
+	// FailoverFrom lists the providers of the failover chain that failed
+	// before this one, e.g. "gemini (quota exceeded)"; empty for the first.
+	FailoverFrom string
+	// CacheHit records that the clip was copied from the TTS cache instead
+	// of being generated.
+	CacheHit bool
+
+	// PostProcess normalizes, trims and fades the clip after generation.
+	PostProcess PostProcessConfig
 }
 
 // VoicesFor returns the voice list for the named provider. This is a
 // convenience for callers that need voices before constructing a Provider.
+// The local voices depend on the engine; use VoicesForConfig for them.
 func VoicesFor(providerName string) []string {
-	if strings.ToLower(strings.TrimSpace(providerName)) == "gemini" {
+	switch strings.ToLower(strings.TrimSpace(providerName)) {
+	case "gemini":
 		return GeminiVoices
+	case LocalProviderName:
+		return EspeakVoices
+	default:
+		return OpenAIVoices
+	}
+}
+
+// VoicesForConfig returns the voice list of the provider config selects,
+// including the voice of a Piper model.
+func VoicesForConfig(config *Config) []string {
+	if config != nil && strings.ToLower(strings.TrimSpace(config.Provider)) == LocalProviderName {
+		return LocalVoices(localAudioConfigFrom(config))
+	}
+	if config == nil {
+		return VoicesFor("")
 	}
-	return OpenAIVoices
+	return VoicesFor(config.Provider)
 }
 
 // BuildAttributionFor builds the attribution text for the named provider
 // without requiring a Provider instance. Use Provider.BuildAttribution when
 // you already have an instance.
 func BuildAttributionFor(providerName string, params AttributionParams) string {
-	if strings.ToLower(strings.TrimSpace(providerName)) == "gemini" {
+	switch strings.ToLower(strings.TrimSpace(providerName)) {
+	case "gemini":
 		return BuildGeminiAttribution(params)
+	case LocalProviderName:
+		return BuildLocalAttribution(params)
+	default:
+		return BuildOpenAIAttribution(params)
 	}
-	return BuildOpenAIAttribution(params)
 }
 
 // openAIAudioConfigFrom extracts the OpenAI-specific sub-config from the flat Config.
@@ -118,6 +160,21 @@ func geminiAudioConfigFrom(c *Config) GeminiAudioConfig {
 	}
 }
 
+// localAudioConfigFrom extracts the local sub-config from the flat Config.
+// A nil Config produces a zero-value LocalAudioConfig.
+func localAudioConfigFrom(c *Config) LocalAudioConfig {
+	if c == nil {
+		return LocalAudioConfig{}
+	}
+	return LocalAudioConfig{
+		Engine: c.LocalEngine,
+		Binary: c.LocalBinary,
+		Model:  c.LocalModel,
+		Voice:  c.LocalVoice,
+		Speed:  c.LocalSpeed,
+	}
+}
+
 // DefaultProviderConfig returns default configuration (shared literals live in
 // internal/config/defaults.go).
 func DefaultProviderConfig() *Config {
@@ -131,6 +188,7 @@ func DefaultProviderConfig() *Config {
 		OpenAIInstruction: config.DefaultOpenAIAudioInstruction,
 		GeminiTTSModel:    config.DefaultGeminiTTSModel,
 		GeminiSpeed:       config.DefaultGeminiAudioSpeed,
+		LocalSpeed:        1.0,
 	}
 }
 
@@ -146,6 +204,7 @@ var defaultAudioProviders = func() *registry.Registry[string, func(*Config) (Pro
 	r := registry.New[string, func(*Config) (Provider, error)]()
 	r.Register("openai", newOpenAIProviderFromConfig)
 	r.Register("gemini", newGeminiProviderFromConfig)
+	r.Register(LocalProviderName, newLocalProviderFromConfig)
 	return r
 }()
 
@@ -163,6 +222,10 @@ func newGeminiProviderFromConfig(config *Config) (Provider, error) {
 	return NewGeminiProvider(geminiAudioConfigFrom(config), config.OutputFormat)
 }
 
+func newLocalProviderFromConfig(config *Config) (Provider, error) {
+	return NewLocalProvider(localAudioConfigFrom(config), config.OutputFormat)
+}
+
 // NewProvider creates the appropriate audio provider based on configuration.
 // It extracts provider-specific sub-configs so each implementation only
 // receives the fields it needs (ISP).
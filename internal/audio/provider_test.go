@@ -102,6 +102,24 @@ func TestNewProvider(t *testing.T) {
 			wantErr:      false,
 			wantProvider: "gemini",
 		},
+		{
+			name: "local provider needs no key",
+			config: &Config{
+				Provider:    "local",
+				LocalEngine: "espeak",
+			},
+			wantErr:      false,
+			wantProvider: "local",
+		},
+		{
+			name: "local provider with unknown engine",
+			config: &Config{
+				Provider:    "local",
+				LocalEngine: "festival",
+			},
+			wantErr: true,
+			errMsg:  `unknown local TTS engine "festival" (want piper or espeak-ng)`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -22,10 +22,27 @@ type SidecarMetadataParams struct {
 	GeminiTTSModel string
 	GeminiVoice    string
 	GeminiSpeed    float64
+
+	LocalEngine string
+	LocalModel  string
+	LocalVoice  string
+	LocalSpeed  float64
+
+	// FailoverFrom lists the providers that failed before Provider spoke.
+	FailoverFrom string
+	// CacheHit records that the audio was copied from the TTS cache.
+	CacheHit bool
+	// PostProcess is the post-processing applied to the audio.
+	PostProcess PostProcessConfig
 }
 
 // ProcessedTextForWord returns the sanitized text sent to TTS providers.
+// Sentences keep their punctuation (see isSentence).
 func ProcessedTextForWord(text string) string {
+	if isSentence(text) {
+		return sentenceText(text)
+	}
+
 	cleanedText := strings.TrimSpace(text)
 	punctuationToRemove := []string{"!", "?", ".", ",", ";", ":", "\"", "'", "(", ")", "[", "]", "{", "}", "-", "—", "–"}
 	for _, punct := range punctuationToRemove {
@@ -48,19 +65,38 @@ func ProcessedTextForProvider(provider, text string) string {
 }
 
 // InstructionForProvider returns the provider-specific instruction semantics written to attribution files.
-// It accepts the flat Config and extracts the relevant sub-config internally.
-func InstructionForProvider(provider string, config *Config) string {
+// It accepts the flat Config and extracts the relevant sub-config internally;
+// text is the spoken text, which decides between word and sentence delivery.
+func InstructionForProvider(provider string, config *Config, text string) string {
 	switch strings.ToLower(strings.TrimSpace(provider)) {
 	case "openai":
 		return openAIInstructionForAttribution(openAIAudioConfigFrom(config))
 	case "gemini":
-		return geminiPromptInstruction(geminiAudioConfigFrom(config))
+		return geminiPromptInstruction(geminiAudioConfigFrom(config), text)
 	default:
 		return ""
 	}
 }
 
+// isSentence reports whether text is a phrase or sentence rather than a
+// single word. Across several words the punctuation carries the intonation
+// (a question, a pause before a clause), so it is kept for TTS; a single
+// word is spoken more cleanly without it.
+func isSentence(text string) bool {
+	return len(strings.Fields(text)) > 1
+}
+
+// sentenceText collapses the whitespace of a sentence and keeps everything
+// else as written.
+func sentenceText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
 func openAIProcessedText(text string) string {
+	if isSentence(text) {
+		return sentenceText(text)
+	}
+
 	cleanedText := strings.TrimSpace(text)
 	punctuationToRemove := []string{"!", "?", ".", ",", ";", ":", "\"", "'", "(", ")", "[", "]", "{", "}", "-", "—", "–"}
 	for _, punct := range punctuationToRemove {
@@ -87,10 +123,13 @@ func openAIModelUsesInstructions(model string) bool {
 	}
 }
 
-func geminiPromptInstruction(config GeminiAudioConfig) string {
+func geminiPromptInstruction(config GeminiAudioConfig, text string) string {
 	var prompt strings.Builder
 	prompt.WriteString("You are speaking Bulgarian language (български език). ")
 	prompt.WriteString("Pronounce the Bulgarian text with authentic Bulgarian phonetics, not Russian.")
+	if isSentence(text) {
+		prompt.WriteString(" Read it as one natural sentence and follow its punctuation: pause at commas and use rising intonation for questions.")
+	}
 
 	if speedHint := geminiSpeedHint(config.Speed); speedHint != "" {
 		prompt.WriteString(" ")
@@ -110,6 +149,8 @@ func geminiPromptInstruction(config GeminiAudioConfig) string {
 
 func geminiSpeedHint(speed float64) string {
 	switch {
+	case speed < 0.8:
+		return "Speak very slowly, syllable by syllable, so that learners hear every sound."
 	case speed < 0.95:
 		return "Speak slowly and clearly for language learners."
 	case speed > 1.05:
@@ -132,6 +173,12 @@ func BuildSidecarMetadata(params SidecarMetadataParams) string {
 	}
 
 	fmt.Fprintf(&b, "provider=%s\n", provider)
+	if failoverFrom := strings.TrimSpace(params.FailoverFrom); failoverFrom != "" {
+		fmt.Fprintf(&b, "failover_from=%s\n", failoverFrom)
+	}
+	if params.CacheHit {
+		b.WriteString("cache=hit\n")
+	}
 	switch provider {
 	case "gemini":
 		model := strings.TrimSpace(params.GeminiTTSModel)
@@ -145,6 +192,11 @@ func BuildSidecarMetadata(params SidecarMetadataParams) string {
 		}
 		fmt.Fprintf(&b, "voice=%s\n", voice)
 		fmt.Fprintf(&b, "speed=%.2f\n", params.GeminiSpeed)
+	case LocalProviderName:
+		local := normalizeLocalAudioConfig(LocalAudioConfig{Engine: params.LocalEngine, Model: params.LocalModel, Voice: params.LocalVoice, Speed: params.LocalSpeed})
+		fmt.Fprintf(&b, "model=%s\n", localModelLabel(local))
+		fmt.Fprintf(&b, "voice=%s\n", localVoiceLabel(local))
+		fmt.Fprintf(&b, "speed=%.2f\n", local.Speed)
 	default:
 		model := strings.TrimSpace(params.OpenAIModel)
 		if model == "" {
@@ -172,6 +224,15 @@ func BuildSidecarMetadata(params SidecarMetadataParams) string {
 		format = DefaultProviderConfig().OutputFormat
 	}
 	fmt.Fprintf(&b, "format=%s\n", format)
+	if params.PostProcess.LoudnessLUFS != 0 {
+		fmt.Fprintf(&b, "loudness_lufs=%.1f\n", params.PostProcess.LoudnessLUFS)
+	}
+	if params.PostProcess.SilenceDB != 0 {
+		fmt.Fprintf(&b, "silence_trim_db=%.1f\n", params.PostProcess.SilenceDB)
+	}
+	if params.PostProcess.FadeMs > 0 {
+		fmt.Fprintf(&b, "fade_ms=%d\n", params.PostProcess.FadeMs)
+	}
 	if params.CardType != "" {
 		fmt.Fprintf(&b, "cardtype=%s\n", params.CardType)
 	}
@@ -10,6 +10,11 @@ func TestProcessedTextForWord(t *testing.T) {
 	if got != "ябълка..." {
 		t.Fatalf("ProcessedTextForWord() = %q, want %q", got, "ябълка...")
 	}
+
+	got = ProcessedTextForWord(" Къде е  гарата? ")
+	if got != "Къде е гарата?" {
+		t.Fatalf("ProcessedTextForWord() = %q, want the sentence with punctuation", got)
+	}
 }
 
 func TestProcessedTextForProvider(t *testing.T) {
@@ -31,6 +36,12 @@ func TestProcessedTextForProvider(t *testing.T) {
 			input:    "  ябълка!?  ",
 			want:     "ябълка!?",
 		},
+		{
+			name:     "openai keeps punctuation of sentences",
+			provider: "openai",
+			input:    " Аз обичам ябълки, нали? ",
+			want:     "Аз обичам ябълки, нали?",
+		},
 	}
 
 	for _, tt := range tests {
@@ -47,6 +58,7 @@ func TestInstructionForProvider(t *testing.T) {
 		name     string
 		provider string
 		config   *Config
+		text     string
 		want     []string
 		wantNot  []string
 	}{
@@ -84,13 +96,28 @@ func TestInstructionForProvider(t *testing.T) {
 				GeminiSpeed: 0.9,
 				GeminiVoice: "Kore",
 			},
-			want: []string{"Speak slowly and clearly for language learners.", "voice named Kore."},
+			want:    []string{"Speak slowly and clearly for language learners.", "voice named Kore."},
+			wantNot: []string{"intonation"},
+		},
+		{
+			name:     "gemini slow variant",
+			provider: "gemini",
+			config:   DefaultProviderConfig().SlowVariant(DefaultSlowSpeed),
+			want:     []string{"syllable by syllable"},
+			wantNot:  []string{"natural pace"},
+		},
+		{
+			name:     "gemini sentence delivery",
+			provider: "gemini",
+			config:   &Config{GeminiSpeed: 1.0},
+			text:     "Къде е гарата?",
+			want:     []string{"rising intonation for questions"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := InstructionForProvider(tt.provider, tt.config)
+			got := InstructionForProvider(tt.provider, tt.config, tt.text)
 			for _, want := range tt.want {
 				if want == "" {
 					if got != "" {
@@ -184,6 +211,48 @@ func TestBuildSidecarMetadata(t *testing.T) {
 			},
 			wantNot: []string{"instruction=Speak clearly."},
 		},
+		{
+			name: "local fallback records the failed providers",
+			got: BuildSidecarMetadata(SidecarMetadataParams{
+				Provider:     LocalProviderName,
+				OutputFormat: "mp3",
+				AudioFile:    "audio.mp3",
+				LocalVoice:   "bg+f2",
+				FailoverFrom: "gemini (quota exceeded), openai (authentication failed)",
+			}),
+			want: []string{
+				"provider=local",
+				"failover_from=gemini (quota exceeded), openai (authentication failed)",
+				"model=espeak-ng",
+				"voice=bg+f2",
+				"speed=1.00",
+			},
+			wantNot: []string{"cache=hit"},
+		},
+		{
+			name: "cached audio records the cache hit",
+			got: BuildSidecarMetadata(SidecarMetadataParams{
+				Provider:       "gemini",
+				OutputFormat:   "mp3",
+				AudioFile:      "audio.mp3",
+				GeminiTTSModel: "gemini-2.5-flash-preview-tts",
+				GeminiVoice:    "Kore",
+				GeminiSpeed:    1.0,
+				CacheHit:       true,
+			}),
+			want: []string{"provider=gemini", "cache=hit", "voice=Kore"},
+		},
+		{
+			name: "post-processing settings",
+			got: BuildSidecarMetadata(SidecarMetadataParams{
+				Provider:     "openai",
+				OutputFormat: "mp3",
+				AudioFile:    "audio.mp3",
+				OpenAIVoice:  "nova",
+				PostProcess:  PostProcessConfig{LoudnessLUFS: -16, SilenceDB: -50, FadeMs: 20},
+			}),
+			want: []string{"format=mp3\nloudness_lufs=-16.0\nsilence_trim_db=-50.0\nfade_ms=20\n"},
+		},
 	}
 
 	for _, tt := range tests {
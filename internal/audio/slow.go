@@ -0,0 +1,44 @@
+package audio
+
+// slow.go renders the optional slow variant of a clip: the same text, provider
+// and voice at a reduced speed, stored next to the clip as <base>_slow.<ext>.
+// OpenAI and the local engines slow down natively; Gemini has no speed
+// parameter and only gets the matching prompt hint.
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// DefaultSlowSpeed is the speed of slow variants: slow enough to hear every
+// syllable without stretching the vowels unnaturally.
+const DefaultSlowSpeed = 0.7
+
+// ValidateSlowSpeed checks that speed is slower than normal and within what
+// the OpenAI API accepts.
+func ValidateSlowSpeed(speed float64) error {
+	if speed < 0.25 || speed >= 1 {
+		return fmt.Errorf("slow audio speed %g is outside 0.25 to 1", speed)
+	}
+	return nil
+}
+
+// SlowVariant returns a copy of c that renders the slow variant at speed.
+func (c *Config) SlowVariant(speed float64) *Config {
+	slow := *c
+	slow.OpenAISpeed = speed
+	slow.GeminiSpeed = speed
+	slow.LocalSpeed = speed
+	slow.CacheHit = false
+	return &slow
+}
+
+// SlowAudioPath returns the path of the slow variant of audioFile, e.g.
+// audio_front_slow.mp3 for audio_front.mp3.
+func SlowAudioPath(audioFile string) string {
+	ext := filepath.Ext(audioFile)
+	return strings.TrimSuffix(audioFile, ext) + store.SlowAudioSuffix + ext
+}
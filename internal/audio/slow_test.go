@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSlowAudioPath(t *testing.T) {
+	tests := map[string]string{
+		"audio.mp3":                              "audio_slow.mp3",
+		"audio_front.wav":                        "audio_front_slow.wav",
+		filepath.Join("cards", "audio_back.mp3"): filepath.Join("cards", "audio_back_slow.mp3"),
+	}
+	for audioFile, want := range tests {
+		if got := SlowAudioPath(audioFile); got != want {
+			t.Errorf("SlowAudioPath(%q) = %q, want %q", audioFile, got, want)
+		}
+	}
+}
+
+func TestSlowVariant(t *testing.T) {
+	config := cacheTestConfig()
+	config.CacheHit = true
+
+	slow := config.SlowVariant(0.6)
+	if slow.GeminiSpeed != 0.6 || slow.OpenAISpeed != 0.6 || slow.LocalSpeed != 0.6 || slow.CacheHit {
+		t.Fatalf("SlowVariant() = %+v, want speed 0.6 for every provider and no cache hit", slow)
+	}
+	if config.GeminiSpeed != 1.0 || !config.CacheHit {
+		t.Fatalf("SlowVariant() changed the original config to %+v", config)
+	}
+	if CacheKey(slow, "ябълка", "mp3") == CacheKey(config, "ябълка", "mp3") {
+		t.Error("the slow variant shares the cache key of the normal clip")
+	}
+}
+
+func TestValidateSlowSpeed(t *testing.T) {
+	for _, speed := range []float64{0.25, DefaultSlowSpeed, 0.9} {
+		if err := ValidateSlowSpeed(speed); err != nil {
+			t.Errorf("ValidateSlowSpeed(%g) error = %v", speed, err)
+		}
+	}
+	for _, speed := range []float64{0, 0.1, 1, 1.5} {
+		if err := ValidateSlowSpeed(speed); err == nil {
+			t.Errorf("ValidateSlowSpeed(%g) succeeded; want error", speed)
+		}
+	}
+}
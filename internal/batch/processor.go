@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"unicode"
 
 	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // WordEntry represents a word with optional translation
@@ -14,8 +16,23 @@ type WordEntry struct {
 	Translation string
 	// NeedsTranslation indicates if translation from English to Bulgarian is needed
 	NeedsTranslation bool
-	// CardType indicates whether this is en-bg or bg-bg card
+	// CardType indicates whether this is an en-bg, bg-bg or sentence card
 	CardType internal.CardType
+	// Cloze is the sentence with its cloze deletions for sentence cards;
+	// Bulgarian holds the plain sentence then.
+	Cloze string
+	// Tags are the Anki tags given as trailing "#tag" words on the line
+	Tags []string
+	// Directions are the card directions given as a trailing "@forward",
+	// "@both,type" etc. word; zero leaves them to the export.
+	Directions internal.CardDirections
+	// Example and ExampleTranslation are the example sentence given after a
+	// '|' and its optional translation.
+	Example            string
+	ExampleTranslation string
+	// Section is the path of the section headers the line is listed under,
+	// e.g. "Lesson 3::Food"; empty outside of sections.
+	Section string
 }
 
 // ReadBatchFile reads words from a file and returns WordEntry slice
@@ -24,6 +41,19 @@ type WordEntry struct {
 // - With translation: "ябълка = apple" (both provided, no translation needed)
 // - English only: "= apple" (will be translated to Bulgarian)
 // - Bulgarian-Bulgarian: "word1 == definition" (bg-bg card, double equals)
+// - Sentence: "Аз обичам [ябълки]. = I love apples." (sentence card)
+//
+// In sentences, bracketed words become cloze deletions; {{c1::...}} works as
+// well. The translation of a sentence is optional.
+//
+// Any of these may end with tags: "ябълка = apple #food #lesson-3", and with
+// the card directions of the word: "ябълка = apple @forward,type". An
+// example sentence and its translation follow a '|':
+// "ябълка = apple | Ям ябълка. = I eat an apple."
+//
+// Lines starting with "# " are section headers, nested by the number of
+// hashes: the lines after "# Lesson 3" and "## Food" are in the section
+// "Lesson 3::Food". A header without title ends the section of its level.
 func ReadBatchFile(filename string) ([]WordEntry, error) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
@@ -35,12 +65,19 @@ func ReadBatchFile(filename string) ([]WordEntry, error) {
 	normalized := strings.ReplaceAll(string(content), "\r\n", "\n")
 	lines := strings.Split(normalized, "\n")
 	entries := make([]WordEntry, 0, len(lines))
+	var sections []string
 	for _, line := range lines {
-		if line = strings.TrimSpace(line); line != "" {
-			entry := parseBatchLine(line)
-			if entry != nil {
-				entries = append(entries, *entry)
-			}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if level, title, ok := parseSectionHeader(line); ok {
+			sections = append(sections[:min(level-1, len(sections))], title)
+			continue
+		}
+
+		if entry := parseBatchLine(line); entry != nil {
+			entry.Section = sectionPath(sections)
+			entries = append(entries, *entry)
 		}
 	}
 
@@ -50,8 +87,88 @@ func ReadBatchFile(filename string) ([]WordEntry, error) {
 	return entries, nil
 }
 
+// parseSectionHeader recognizes a "## Title" line. A hash directly followed
+// by a word is a tag, so "#food" is not a header.
+func parseSectionHeader(line string) (level int, title string, ok bool) {
+	level = len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || (len(line) > level && !unicode.IsSpace(rune(line[level]))) {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(line[level:]), true
+}
+
+// sectionPath joins the titles of the open section headers with Anki's
+// deck separator, skipping levels without title.
+func sectionPath(sections []string) string {
+	var parts []string
+	for _, title := range sections {
+		if title != "" {
+			parts = append(parts, title)
+		}
+	}
+	return strings.Join(parts, "::")
+}
+
 // parseBatchLine parses a single batch file line and returns the appropriate WordEntry
 func parseBatchLine(line string) *WordEntry {
+	line, tags, directions := splitMarkers(line)
+	if line == "" {
+		return nil
+	}
+
+	line, example, _ := strings.Cut(line, "|")
+	if line = strings.TrimSpace(line); line == "" {
+		return nil
+	}
+	entry := parseBatchWords(line)
+	if entry == nil {
+		return nil
+	}
+	entry.Example, entry.ExampleTranslation = store.ParseExample(example)
+	entry.Tags = tags
+	entry.Directions = directions
+	return entry
+}
+
+// splitMarkers removes the trailing "#tag" and "@directions" words from line
+// and returns the normalized tags and the directions. Only trailing words
+// count, so a '#' or '@' inside a translation is left alone, and so is a
+// trailing "@home" that names no card direction.
+func splitMarkers(line string) (string, []string, internal.CardDirections) {
+	var tags, directions []string
+loop:
+	for {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		start := strings.LastIndexFunc(line, unicode.IsSpace) + 1
+		word := line[start:]
+		if len(word) < 2 {
+			break
+		}
+		switch word[0] {
+		case '#':
+			tags = append(tags, word)
+		case '@':
+			if _, err := internal.ParseCardDirections(word[1:]); err != nil {
+				break loop
+			}
+			directions = append(directions, word[1:])
+		default:
+			break loop
+		}
+		line = line[:start]
+	}
+
+	// Every part parsed on its own, so their combination does as well.
+	d, _ := internal.ParseCardDirections(strings.Join(directions, ","))
+	return line, store.NormalizeTags(tags...), d
+}
+
+// parseBatchWords parses the word part of a batch line
+func parseBatchWords(line string) *WordEntry {
+	if bulgarian, translation, _ := strings.Cut(line, "="); internal.HasCloze(bulgarian) {
+		return parseSentence(bulgarian, strings.TrimPrefix(translation, "="))
+	}
+
 	// Check for Bulgarian-Bulgarian format first (double equals ==)
 	if strings.Contains(line, "==") {
 		parts := strings.SplitN(line, "==", 2)
@@ -107,3 +224,19 @@ func parseBatchLine(line string) *WordEntry {
 		CardType:         internal.CardTypeEnBg,
 	}
 }
+
+// parseSentence builds the entry of a sentence card. A missing translation
+// is filled in by the translator like for single words.
+func parseSentence(sentence, translation string) *WordEntry {
+	cloze := internal.ExpandClozeShorthand(strings.TrimSpace(sentence))
+	if len(internal.ClozeNumbers(cloze)) == 0 {
+		return nil
+	}
+	return &WordEntry{
+		Bulgarian:        internal.ClozeText(cloze),
+		Translation:      strings.TrimSpace(translation),
+		NeedsTranslation: false,
+		CardType:         internal.CardTypeSentence,
+		Cloze:            cloze,
+	}
+}
@@ -133,6 +133,86 @@ func TestReadBatchFile(t *testing.T) {
 				{Bulgarian: "вода", Translation: "течност", NeedsTranslation: false, CardType: internal.CardTypeBgBg},
 			},
 		},
+		{
+			name: "trailing tags",
+			fileContent: `ябълка = apple #food #lesson-3
+котка == домашно животно #animals
+= C# programmer #it
+#orphan`,
+			want: []WordEntry{
+				{Bulgarian: "ябълка", Translation: "apple", NeedsTranslation: false, CardType: internal.CardTypeEnBg, Tags: []string{"food", "lesson-3"}},
+				{Bulgarian: "котка", Translation: "домашно животно", NeedsTranslation: false, CardType: internal.CardTypeBgBg, Tags: []string{"animals"}},
+				{Bulgarian: "", Translation: "C# programmer", NeedsTranslation: true, CardType: internal.CardTypeEnBg, Tags: []string{"it"}},
+			},
+		},
+		{
+			name: "sentences with cloze deletions",
+			fileContent: `Аз обичам [ябълки]. = I love apples. #food
+[Къде] е {{c1::гарата::station}}?
+котка = cat [pet]`,
+			want: []WordEntry{
+				{Bulgarian: "Аз обичам ябълки.", Translation: "I love apples.", CardType: internal.CardTypeSentence,
+					Cloze: "Аз обичам {{c1::ябълки}}.", Tags: []string{"food"}},
+				{Bulgarian: "Къде е гарата?", CardType: internal.CardTypeSentence,
+					Cloze: "{{c2::Къде}} е {{c1::гарата::station}}?"},
+				{Bulgarian: "котка", Translation: "cat [pet]", CardType: internal.CardTypeEnBg},
+			},
+		},
+		{
+			name: "card directions",
+			fileContent: `книга = book @forward,type #school
+котка == домашно животно @reverse
+мейл = user@example.org`,
+			want: []WordEntry{
+				{Bulgarian: "книга", Translation: "book", CardType: internal.CardTypeEnBg, Tags: []string{"school"},
+					Directions: internal.CardDirections{Forward: true, TypeIn: true}},
+				{Bulgarian: "котка", Translation: "домашно животно", CardType: internal.CardTypeBgBg,
+					Directions: internal.CardDirections{Reverse: true}},
+				{Bulgarian: "мейл", Translation: "user@example.org", CardType: internal.CardTypeEnBg},
+			},
+		},
+		{
+			name: "section headers",
+			fileContent: `хляб = bread
+# Lesson 3
+ябълка = apple #food
+## Animals
+котка = cat
+# Lesson 4
+### Verbs
+ям = to eat
+#
+куче = dog`,
+			want: []WordEntry{
+				{Bulgarian: "хляб", Translation: "bread", CardType: internal.CardTypeEnBg},
+				{Bulgarian: "ябълка", Translation: "apple", CardType: internal.CardTypeEnBg, Tags: []string{"food"}, Section: "Lesson 3"},
+				{Bulgarian: "котка", Translation: "cat", CardType: internal.CardTypeEnBg, Section: "Lesson 3::Animals"},
+				{Bulgarian: "ям", Translation: "to eat", CardType: internal.CardTypeEnBg, Section: "Lesson 4::Verbs"},
+				{Bulgarian: "куче", Translation: "dog", CardType: internal.CardTypeEnBg},
+			},
+		},
+		{
+			name: "example sentences",
+			fileContent: `ябълка = apple | Ям ябълка. = I eat an apple. #food
+хляб | Купих хляб.
+котка == домашно животно | Котката спи.`,
+			want: []WordEntry{
+				{Bulgarian: "ябълка", Translation: "apple", CardType: internal.CardTypeEnBg, Tags: []string{"food"}, Example: "Ям ябълка.", ExampleTranslation: "I eat an apple."},
+				{Bulgarian: "хляб", CardType: internal.CardTypeEnBg, Example: "Купих хляб."},
+				{Bulgarian: "котка", Translation: "домашно животно", CardType: internal.CardTypeBgBg, Example: "Котката спи."},
+			},
+		},
+		{
+			name: "at words that are no card direction",
+			fileContent: `вкъщи = @home
+имейл = write to me@example.com #contact
+книга = book @sideways @type`,
+			want: []WordEntry{
+				{Bulgarian: "вкъщи", Translation: "@home", CardType: internal.CardTypeEnBg},
+				{Bulgarian: "имейл", Translation: "write to me@example.com", CardType: internal.CardTypeEnBg, Tags: []string{"contact"}},
+				{Bulgarian: "книга", Translation: "book @sideways", CardType: internal.CardTypeEnBg, Directions: internal.CardDirections{TypeIn: true}},
+			},
+		},
 	}
 
 	for _, tt := range tests {
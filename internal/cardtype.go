@@ -1,9 +1,9 @@
 package internal
 
 import (
-	"os"
-	"path/filepath"
 	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // CardType represents the type of flashcard
@@ -14,6 +14,8 @@ const (
 	CardTypeEnBg CardType = "en-bg"
 	// CardTypeBgBg represents Bulgarian-Bulgarian cards
 	CardTypeBgBg CardType = "bg-bg"
+	// CardTypeSentence represents a Bulgarian sentence with cloze deletions
+	CardTypeSentence CardType = "sentence"
 )
 
 // String returns the string representation of the card type
@@ -26,33 +28,41 @@ func (ct CardType) IsBgBg() bool {
 	return ct == CardTypeBgBg
 }
 
+// IsSentence returns true if this is a sentence (cloze) card
+func (ct CardType) IsSentence() bool {
+	return ct == CardTypeSentence
+}
+
 // DisplayName returns a human-readable name for the card type
 func (ct CardType) DisplayName() string {
 	switch ct {
 	case CardTypeBgBg:
 		return "Bulgarian → Bulgarian"
+	case CardTypeSentence:
+		return "Sentence (cloze)"
 	default:
 		return "English → Bulgarian"
 	}
 }
 
-// SaveCardType saves the card type to a file in the card directory
+// SaveCardType saves the card type to the card manifest
 func SaveCardType(cardDir string, cardType CardType) error {
-	cardTypePath := filepath.Join(cardDir, "cardtype.txt")
-	return os.WriteFile(cardTypePath, []byte(string(cardType)), 0644)
+	return store.SaveCardType(cardDir, string(cardType))
 }
 
-// LoadCardType loads the card type from a card directory
+// LoadCardType loads the card type from the card manifest
 // Returns CardTypeEnBg as default for backwards compatibility
 func LoadCardType(cardDir string) CardType {
-	cardTypePath := filepath.Join(cardDir, "cardtype.txt")
-	data, err := os.ReadFile(cardTypePath)
-	if err != nil {
+	return ParseCardType(store.LoadManifest(cardDir).CardType)
+}
+
+// ParseCardType converts a stored card type string into a CardType.
+// Unknown or empty values map to CardTypeEnBg for backwards compatibility.
+func ParseCardType(value string) CardType {
+	switch cardType := CardType(strings.TrimSpace(value)); cardType {
+	case CardTypeBgBg, CardTypeSentence:
+		return cardType
+	default:
 		return CardTypeEnBg
 	}
-	cardType := CardType(strings.TrimSpace(string(data)))
-	if cardType == CardTypeBgBg {
-		return CardTypeBgBg
-	}
-	return CardTypeEnBg
 }
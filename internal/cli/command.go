@@ -14,7 +14,7 @@ func CreateRootCommand(flags *Flags) *cobra.Command {
 		Long: `totalrecall generates Anki flashcard materials from Bulgarian words.
 
 It creates audio pronunciation files using Gemini TTS by default and downloads
-representative images. Launching with no arguments opens the interactive GUI, which uses Nano Banana for images by default. Explicit CLI and batch runs also use Nano Banana by default, and can be switched to OpenAI via --image-api openai. Audio can be switched between Gemini and OpenAI with --audio-provider.
+representative images. Launching with no arguments opens the interactive GUI, which uses Nano Banana for images by default. Explicit CLI and batch runs also use Nano Banana by default, and can be switched to OpenAI via --image-api openai. Audio can be switched between Gemini, OpenAI and an offline local TTS engine with --audio-provider.
 
 Gemini audio model and voice flags are available for Gemini TTS generation.
 
@@ -22,8 +22,36 @@ Examples:
   totalrecall                     # Launch interactive GUI (default)
   totalrecall ябълка              # Generate materials for "apple" via CLI
   totalrecall --batch words.txt   # Process multiple words from file
+  totalrecall ябълка --audio-provider local --local-tts-voice bg+f2  # Offline audio with espeak-ng
+  totalrecall ябълка --no-audio-cache  # Generate a fresh take instead of reusing cached audio
+  totalrecall ябълка --loudness-target -20 --fade-ms 20  # Quieter audio with a short fade
+  totalrecall ябълка --slow-audio  # Also a slow variant for practising pronunciation
+  totalrecall --batch words.txt --audio-fallback openai,local  # Fall back when Gemini runs out of quota
+  totalrecall --batch words.txt --tag lesson-3  # ... and tag the cards for Anki
+  totalrecall --batch words.txt --anki-connect  # ... and add the cards to the running Anki
+  totalrecall --anki --new-per-day 10 --reverse-same-day  # APKG with custom deck options
+  totalrecall --anki --card-directions forward,type  # Forward and type-in-answer cards only
+  totalrecall --anki --apkg-format legacy  # APKG for Anki versions before 2.1.50
+  totalrecall --batch lessons.txt --anki --subdecks section  # One subdeck per batch file section
+  totalrecall --anki --since-last-export  # APKG with only the new and changed cards
+  totalrecall --anki-connect --only-tag lesson-3 --changed-since 2025-01-01
+  totalrecall --list-exports      # Show which cards went into which export
+  totalrecall --dump-templates ~/anki-templates  # Export the card templates for editing
+  totalrecall --inspect-apkg deck.apkg --json  # Check an APKG file before importing it
+  totalrecall --import-apkg deck.apkg  # Turn an existing Anki deck into card directories
   totalrecall --retry-failed-assets # Resume incomplete cards in the output directory
   totalrecall --archive           # Archive existing cards directory
+  totalrecall --archive --archive-format tar.zst  # ... as a compressed tarball
+  totalrecall --list-archives     # Show archives and the words they contain
+  totalrecall --restore-archive latest --restore-merge  # Merge the newest archive back
+  totalrecall --prune-archives 5  # Keep only the five newest archives
+  totalrecall --migrate-cards     # Write card.json manifests for existing cards
+  totalrecall ябълка --list-revisions      # Show earlier images and audio of a card
+  totalrecall ябълка --restore-revision 2  # Bring back image revision 2
+  totalrecall ябълка --restore-revision 1 --revision-asset audio
+  totalrecall --list-trash        # Show deleted cards
+  totalrecall --restore-trash ябълка       # Bring a deleted card back
+  totalrecall --purge-trash --trash-retention 7  # Empty trash older than a week
 
 Batch file formats:
   ябълка                          # Bulgarian word (will be translated to English)
@@ -52,9 +52,32 @@ func TestCreateRootCommand(t *testing.T) {
 		{"anki", true},
 		{"anki-csv", true},
 		{"deck-name", true},
+		{"anki-connect", true},
+		{"anki-connect-url", true},
+		{"new-per-day", true},
+		{"reviews-per-day", true},
+		{"learning-steps", true},
+		{"bury-siblings", true},
+		{"reverse-same-day", true},
+		{"card-directions", true},
+		{"apkg-format", true},
+		{"subdecks", true},
+		{"dump-templates", true},
+		{"since-last-export", true},
+		{"changed-since", true},
+		{"only-tag", true},
+		{"list-exports", true},
+		{"inspect-apkg", true},
+		{"import-apkg", true},
+		{"import-fields", true},
+		{"json", true},
 		{"list-models", true},
 		{"all-voices", true},
 		{"no-auto-play", true},
+		{"local-tts-engine", true},
+		{"local-tts-binary", true},
+		{"local-tts-model", true},
+		{"local-tts-voice", true},
 		{"openai-model", true},
 		{"openai-voice", true},
 		{"openai-speed", true},
@@ -64,6 +87,14 @@ func TestCreateRootCommand(t *testing.T) {
 		{"openai-image-quality", true},
 		{"openai-image-style", true},
 		{"audio-provider", true},
+		{"audio-fallback", true},
+		{"audio-cache-size", true},
+		{"no-audio-cache", true},
+		{"loudness-target", true},
+		{"silence-threshold", true},
+		{"fade-ms", true},
+		{"slow-audio", true},
+		{"slow-audio-speed", true},
 		{"gemini-tts-model", true},
 		{"gemini-voice", true},
 		{"nanobanana-model", true},
@@ -10,6 +10,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
 	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/config"
 )
@@ -22,8 +24,12 @@ type Flags struct {
 	AudioFormat string
 	// AudioFormatSpecified records whether the audio format was explicitly set on the CLI.
 	AudioFormatSpecified bool
-	// AudioProvider selects the text-to-speech backend ("gemini" or "openai").
-	AudioProvider     string
+	// AudioProvider selects the text-to-speech backend ("gemini", "openai"
+	// or "local").
+	AudioProvider string
+	// AudioFallback lists the providers tried in order when AudioProvider
+	// fails on quota, authentication, an open circuit or a safety block.
+	AudioFallback     []string
 	ImageAPI          string
 	ImageAPISpecified bool
 	BatchFile         string
@@ -33,10 +39,76 @@ type Flags struct {
 	GenerateAnki      bool
 	AnkiCSV           bool
 	DeckName          string
-	ListModels        bool
-	AllVoices         bool
-	NoAutoPlay        bool
-	Archive           bool
+	// AnkiConnect pushes the cards into a running Anki via AnkiConnect.
+	AnkiConnect bool
+	// AnkiConnectURL overrides the AnkiConnect endpoint (anki.connect_url).
+	AnkiConnectURL string
+	// Deck options written into APKG exports (anki.* in the config file).
+	NewPerDay      int
+	ReviewsPerDay  int
+	LearningSteps  string
+	BurySiblings   bool
+	ReverseSameDay bool
+	// CardDirections selects the cards of exported word notes, e.g. "both"
+	// or "forward,type" (anki.card_directions).
+	CardDirections string
+	// PackageFormat is the layout of APKG exports, anki21b or legacy
+	// (anki.apkg_format).
+	PackageFormat string
+	// Subdecks groups exported cards into subdecks by none, type, tag or
+	// section (anki.subdecks).
+	Subdecks string
+	// InspectAPKG is an .apkg file to check instead of generating cards.
+	InspectAPKG string
+	// ImportAPKG is an .apkg file whose notes become card directories.
+	ImportAPKG string
+	// ImportFields maps note fields for --import-apkg, e.g.
+	// "bulgarian=Back,translation=Front".
+	ImportFields string
+	// SinceLastExport exports only cards that are new or changed since
+	// their last export, as recorded in the export ledger.
+	SinceLastExport bool
+	// ChangedSince exports only cards changed after this date.
+	ChangedSince string
+	// OnlyTag exports only cards with this tag.
+	OnlyTag string
+	// ListExports prints the export ledger of the output directory.
+	ListExports bool
+	// JSON prints the --inspect-apkg report, the --import-apkg result or
+	// the --list-exports ledger as JSON.
+	JSON bool
+	// DumpTemplates is the directory to write the built-in Anki templates to.
+	DumpTemplates string
+	// Tags are attached to every card generated or reprocessed in this run.
+	Tags       []string
+	ListModels bool
+	AllVoices  bool
+	NoAutoPlay bool
+	Archive    bool
+	// ArchiveFormat selects how --archive stores the cards: dir, tar.gz or tar.zst.
+	ArchiveFormat string
+	ListArchives  bool
+	// RestoreArchive names the archive to restore ("latest" for the newest).
+	RestoreArchive string
+	// RestoreMerge merges a restored archive into the existing cards.
+	RestoreMerge bool
+	// PruneArchives keeps only the newest N archives; 0 disables pruning.
+	PruneArchives int
+	MigrateCards  bool
+	// ListRevisions prints the stored asset revisions of the word argument.
+	ListRevisions bool
+	// RestoreRevision restores revision N of RevisionAsset for the word argument.
+	RestoreRevision int
+	// RevisionAsset is the asset slot ("image", "audio", "audio_back", ...)
+	// that RestoreRevision applies to.
+	RevisionAsset string
+	// ListTrash prints the cards in the trash bin.
+	ListTrash bool
+	// RestoreTrash restores a trashed card, given by entry name or word.
+	RestoreTrash string
+	// PurgeTrash deletes trashed cards older than TrashRetentionDays.
+	PurgeTrash         bool
+	TrashRetentionDays int
 
 	// OpenAI flags
 	OpenAIModel       string
@@ -56,6 +128,36 @@ type Flags struct {
 	// GeminiVoice selects a specific Gemini voice; empty picks a random Gemini voice.
 	GeminiVoice string
 
+	// Local audio flags
+	// LocalTTSEngine selects the offline engine, piper or espeak-ng.
+	LocalTTSEngine string
+	// LocalTTSBinary is the engine binary; empty looks it up in PATH.
+	LocalTTSBinary string
+	// LocalTTSModel is the Piper voice model (.onnx).
+	LocalTTSModel string
+	// LocalTTSVoice is the espeak-ng voice, e.g. "bg+f2".
+	LocalTTSVoice string
+
+	// TTS cache flags
+	// AudioCacheSize caps the TTS cache in MB; 0 disables it.
+	AudioCacheSize int
+	// NoAudioCache generates fresh audio instead of reusing cached clips.
+	NoAudioCache bool
+
+	// Audio post-processing flags
+	// LoudnessTarget is the EBU R128 loudness target in LUFS; 0 disables normalization.
+	LoudnessTarget float64
+	// SilenceThreshold trims leading and trailing audio below it, in dB; 0 disables trimming.
+	SilenceThreshold float64
+	// FadeMs fades clips in and out; 0 disables fading.
+	FadeMs int
+
+	// Slow audio flags
+	// SlowAudio also renders a slow variant of every clip (audio_slow.mp3).
+	SlowAudio bool
+	// SlowAudioSpeed is the speed of the slow variants.
+	SlowAudioSpeed float64
+
 	// NanoBananaModel is the Gemini image model used for Nano Banana generation.
 	NanoBananaModel string
 	// NanoBananaModelSpecified records whether the Nano Banana image model was explicitly set on the CLI.
@@ -69,12 +171,24 @@ type Flags struct {
 // NewFlags creates a new Flags instance with default values
 func NewFlags() *Flags {
 	defaults := audio.DefaultProviderConfig()
+	deckOptions := anki.DefaultDeckOptions()
 
 	return &Flags{
 		AudioFormat:         defaults.OutputFormat,
 		AudioProvider:       defaults.Provider,
 		ImageAPI:            "nanobanana",
 		DeckName:            "Bulgarian Vocabulary",
+		NewPerDay:           deckOptions.NewPerDay,
+		ReviewsPerDay:       deckOptions.ReviewsPerDay,
+		LearningSteps:       anki.FormatLearningSteps(deckOptions.LearningSteps),
+		BurySiblings:        deckOptions.BurySiblings,
+		ReverseSameDay:      deckOptions.ReverseSameDay,
+		CardDirections:      internal.DefaultCardDirections().String(),
+		PackageFormat:       string(anki.DefaultPackageFormat),
+		Subdecks:            string(anki.DeckGroupingNone),
+		RevisionAsset:       "image",
+		TrashRetentionDays:  30,
+		ArchiveFormat:       "dir",
 		OpenAIModel:         "gpt-4o-mini-tts",
 		OpenAISpeed:         0.9,
 		OpenAIImageModel:    "dall-e-2",
@@ -82,6 +196,10 @@ func NewFlags() *Flags {
 		OpenAIImageQuality:  "standard",
 		OpenAIImageStyle:    "natural",
 		GeminiTTSModel:      defaults.GeminiTTSModel,
+		AudioCacheSize:      audio.DefaultCacheSizeMB,
+		LoudnessTarget:      audio.DefaultLoudnessLUFS,
+		SilenceThreshold:    audio.DefaultSilenceDB,
+		SlowAudioSpeed:      audio.DefaultSlowSpeed,
 		NanoBananaModel:     config.DefaultNanoBananaModel,
 		NanoBananaTextModel: config.DefaultNanoBananaTextModel,
 	}
@@ -107,13 +225,46 @@ func setupFlags(cmd *cobra.Command, flags *Flags) {
 	cmd.Flags().BoolVar(&flags.SkipAudio, "skip-audio", false, "Skip audio generation")
 	cmd.Flags().BoolVar(&flags.SkipImages, "skip-images", false, "Skip image download")
 	cmd.Flags().BoolVar(&flags.RetryFailedAssets, "retry-failed-assets", false, "Scan existing cards and regenerate missing or failed audio/image assets, stopping on the first error")
-	cmd.Flags().BoolVar(&flags.GenerateAnki, "anki", false, "Generate Anki import file (APKG format by default, use --anki-csv for legacy CSV)")
-	cmd.Flags().BoolVar(&flags.AnkiCSV, "anki-csv", false, "Generate legacy CSV format instead of APKG when using --anki")
+	cmd.Flags().BoolVar(&flags.GenerateAnki, "anki", false, "Generate Anki import file (APKG format by default, use --anki-csv for a CSV bundle)")
+	cmd.Flags().BoolVar(&flags.AnkiCSV, "anki-csv", false, "Generate a CSV bundle (anki_import/ with a TSV file and its media) instead of APKG when using --anki")
 	cmd.Flags().StringVar(&flags.DeckName, "deck-name", flags.DeckName, "Deck name for APKG export")
+	cmd.Flags().BoolVar(&flags.AnkiConnect, "anki-connect", false, "Add the cards to the deck of a running Anki via the AnkiConnect add-on")
+	cmd.Flags().StringVar(&flags.AnkiConnectURL, "anki-connect-url", "", "AnkiConnect endpoint (default http://127.0.0.1:8765; config file anki.connect_url also applies)")
+	cmd.Flags().IntVar(&flags.NewPerDay, "new-per-day", flags.NewPerDay, "New cards per day in the deck options of APKG exports")
+	cmd.Flags().IntVar(&flags.ReviewsPerDay, "reviews-per-day", flags.ReviewsPerDay, "Maximum reviews per day in the deck options of APKG exports")
+	cmd.Flags().StringVar(&flags.LearningSteps, "learning-steps", flags.LearningSteps, "Learning steps of new cards in APKG exports, e.g. \"1m 10m 1h\"")
+	cmd.Flags().BoolVar(&flags.BurySiblings, "bury-siblings", flags.BurySiblings, "Bury the other card of a note until the next day once one was reviewed (APKG exports)")
+	cmd.Flags().BoolVar(&flags.ReverseSameDay, "reverse-same-day", flags.ReverseSameDay, "Introduce the reverse card of a new note on the same day as the forward card (APKG exports)")
+	cmd.Flags().StringVar(&flags.CardDirections, "card-directions", flags.CardDirections, "Cards of exported words: forward, reverse or both, plus type for a type-in-answer card (e.g. \"forward,type\")")
+	cmd.Flags().StringVar(&flags.PackageFormat, "apkg-format", flags.PackageFormat, "APKG package format: anki21b (Anki 2.1.50+) or legacy (all Anki versions)")
+	cmd.Flags().StringVar(&flags.Subdecks, "subdecks", flags.Subdecks, "Put exported cards into subdecks by card type, tag or batch file section: none, type, tag or section")
+	cmd.Flags().BoolVar(&flags.SinceLastExport, "since-last-export", false, "Export only cards that are new or changed since their last export to the same file or deck (--anki, --anki-connect)")
+	cmd.Flags().StringVar(&flags.ChangedSince, "changed-since", "", "Export only cards changed after this date: YYYY-MM-DD, \"YYYY-MM-DD HH:MM\" or RFC 3339")
+	cmd.Flags().StringVar(&flags.OnlyTag, "only-tag", "", "Export only cards with this Anki tag")
+	cmd.Flags().BoolVar(&flags.ListExports, "list-exports", false, "List the exports of the output directory with their files, filters and cards")
+	cmd.Flags().StringVar(&flags.InspectAPKG, "inspect-apkg", "", "Check an .apkg file (from totalrecall or Anki) for schema, note, card and media problems")
+	cmd.Flags().StringVar(&flags.ImportAPKG, "import-apkg", "", "Create card directories from the notes of an .apkg file, copying their audio and images")
+	cmd.Flags().StringVar(&flags.ImportFields, "import-fields", "", "Note fields for --import-apkg, e.g. \"bulgarian=Back,translation=Front,audio=Sound,image=Picture\" (detected by default)")
+	cmd.Flags().BoolVar(&flags.JSON, "json", false, "Print the --inspect-apkg report, the --import-apkg result or the --list-exports ledger as JSON")
+	cmd.Flags().StringVar(&flags.DumpTemplates, "dump-templates", "", "Write the built-in Anki card templates and CSS to this directory as a starting point for overrides")
+	cmd.Flags().StringSliceVar(&flags.Tags, "tag", nil, "Anki tag for the generated cards (repeatable or comma-separated, e.g. --tag lesson-3,food)")
 	cmd.Flags().BoolVar(&flags.ListModels, "list-models", false, "List available OpenAI and Gemini models for the configured API keys")
 	cmd.Flags().BoolVar(&flags.AllVoices, "all-voices", false, "Generate audio in all available voices (creates multiple files)")
 	cmd.Flags().BoolVar(&flags.NoAutoPlay, "no-auto-play", false, "Disable automatic audio playback in GUI mode (auto-play is enabled by default)")
-	cmd.Flags().BoolVar(&flags.Archive, "archive", false, "Archive existing cards directory with timestamp")
+	cmd.Flags().BoolVar(&flags.Archive, "archive", false, "Archive the cards in the output directory with timestamp")
+	cmd.Flags().StringVar(&flags.ArchiveFormat, "archive-format", flags.ArchiveFormat, "Archive format for --archive: dir, tar.gz or tar.zst")
+	cmd.Flags().BoolVar(&flags.ListArchives, "list-archives", false, "List archives of the output directory with their words")
+	cmd.Flags().StringVar(&flags.RestoreArchive, "restore-archive", "", "Restore the named archive (or \"latest\") into the output directory")
+	cmd.Flags().BoolVar(&flags.RestoreMerge, "restore-merge", false, "Merge a restored archive into existing cards, skipping words that already have a card")
+	cmd.Flags().IntVar(&flags.PruneArchives, "prune-archives", 0, "Delete all but the newest N archives")
+	cmd.Flags().BoolVar(&flags.MigrateCards, "migrate-cards", false, "Upgrade card directories in the output directory to the current card.json manifest format")
+	cmd.Flags().BoolVar(&flags.ListRevisions, "list-revisions", false, "List the stored audio and image revisions of the given word")
+	cmd.Flags().IntVar(&flags.RestoreRevision, "restore-revision", 0, "Restore revision N of an asset of the given word (see --list-revisions and --revision-asset)")
+	cmd.Flags().StringVar(&flags.RevisionAsset, "revision-asset", flags.RevisionAsset, "Asset restored by --restore-revision: image, audio, audio_front, audio_back, ...")
+	cmd.Flags().BoolVar(&flags.ListTrash, "list-trash", false, "List deleted cards in the trash bin of the output directory")
+	cmd.Flags().StringVar(&flags.RestoreTrash, "restore-trash", "", "Restore a deleted card from the trash bin, by entry name or word")
+	cmd.Flags().BoolVar(&flags.PurgeTrash, "purge-trash", false, "Permanently delete cards that have been in the trash bin longer than --trash-retention days")
+	cmd.Flags().IntVar(&flags.TrashRetentionDays, "trash-retention", flags.TrashRetentionDays, "Retention period in days for --purge-trash")
 
 	// OpenAI flags
 	cmd.Flags().StringVar(&flags.OpenAIModel, "openai-model", flags.OpenAIModel, "OpenAI TTS model: tts-1, tts-1-hd, gpt-4o-mini-tts")
@@ -122,10 +273,30 @@ func setupFlags(cmd *cobra.Command, flags *Flags) {
 	cmd.Flags().StringVar(&flags.OpenAIInstruction, "openai-instruction", "", "Voice instructions for gpt-4o-mini-tts model (e.g., 'speak slowly with a Bulgarian accent')")
 
 	// Gemini audio flags
-	cmd.Flags().StringVar(&flags.AudioProvider, "audio-provider", flags.AudioProvider, "Audio provider (gemini or openai; config file audio.provider also applies)")
+	cmd.Flags().StringVar(&flags.AudioProvider, "audio-provider", flags.AudioProvider, "Audio provider (gemini, openai or local for offline Piper/espeak-ng; config file audio.provider also applies)")
+	cmd.Flags().StringSliceVar(&flags.AudioFallback, "audio-fallback", nil, "Audio providers to fall back to, in order, when the audio provider hits its quota, is not authorized, has its circuit open or blocks the text (e.g. --audio-fallback openai,local)")
 	cmd.Flags().StringVar(&flags.GeminiTTSModel, "gemini-tts-model", flags.GeminiTTSModel, "Gemini TTS model (config file audio.gemini_tts_model also applies)")
 	cmd.Flags().StringVar(&flags.GeminiVoice, "gemini-voice", flags.GeminiVoice, geminiVoiceUsage())
 
+	// Local audio flags
+	cmd.Flags().StringVar(&flags.LocalTTSEngine, "local-tts-engine", "", "Offline TTS engine of --audio-provider local: piper or espeak-ng (default piper when a model is set)")
+	cmd.Flags().StringVar(&flags.LocalTTSBinary, "local-tts-binary", "", "Path of the piper or espeak-ng binary (default: looked up in PATH)")
+	cmd.Flags().StringVar(&flags.LocalTTSModel, "local-tts-model", "", "Piper voice model (.onnx file) for --audio-provider local")
+	cmd.Flags().StringVar(&flags.LocalTTSVoice, "local-tts-voice", "", "espeak-ng voice for --audio-provider local, e.g. bg or bg+f2 (random Bulgarian voice by default)")
+
+	// TTS cache flags
+	cmd.Flags().IntVar(&flags.AudioCacheSize, "audio-cache-size", flags.AudioCacheSize, "Size cap of the cache of generated audio in MB, which reuses identical clips instead of paying for them again (0 disables it)")
+	cmd.Flags().BoolVar(&flags.NoAudioCache, "no-audio-cache", false, "Generate fresh audio instead of reusing cached clips; the new takes replace the cached ones")
+
+	// Audio post-processing flags
+	cmd.Flags().Float64Var(&flags.LoudnessTarget, "loudness-target", flags.LoudnessTarget, "EBU R128 loudness target of generated audio in LUFS, applied with ffmpeg (0 disables normalization)")
+	cmd.Flags().Float64Var(&flags.SilenceThreshold, "silence-threshold", flags.SilenceThreshold, "Trim leading and trailing audio quieter than this many dB (0 disables trimming)")
+	cmd.Flags().IntVar(&flags.FadeMs, "fade-ms", 0, "Fade generated audio in and out over this many milliseconds (0 disables fading)")
+
+	// Slow audio flags
+	cmd.Flags().BoolVar(&flags.SlowAudio, "slow-audio", false, "Also generate a slow, syllable-clear variant of every audio clip (audio_slow.mp3 next to audio.mp3)")
+	cmd.Flags().Float64Var(&flags.SlowAudioSpeed, "slow-audio-speed", flags.SlowAudioSpeed, "Speed of the slow audio variants, between 0.25 and 1")
+
 	// OpenAI Image Generation flags
 	cmd.Flags().StringVar(&flags.OpenAIImageModel, "openai-image-model", flags.OpenAIImageModel, "OpenAI image model: dall-e-2 or dall-e-3")
 	cmd.Flags().StringVar(&flags.OpenAIImageSize, "openai-image-size", flags.OpenAIImageSize, "Image size: 256x256, 512x512, 1024x1024 (dall-e-3: also 1024x1792, 1792x1024)")
@@ -154,13 +325,34 @@ func bindFlagsToViper(cmd *cobra.Command) error {
 	bindings := map[string]string{
 		"audio.format":                "format",
 		"audio.provider":              "audio-provider",
+		"audio.fallback_providers":    "audio-fallback",
 		"audio.openai_model":          "openai-model",
 		"audio.openai_voice":          "openai-voice",
 		"audio.openai_speed":          "openai-speed",
 		"audio.openai_instruction":    "openai-instruction",
 		"audio.gemini_tts_model":      "gemini-tts-model",
 		"audio.gemini_voice":          "gemini-voice",
+		"audio.local_engine":          "local-tts-engine",
+		"audio.local_binary":          "local-tts-binary",
+		"audio.local_model":           "local-tts-model",
+		"audio.local_voice":           "local-tts-voice",
+		"audio.cache_size_mb":         "audio-cache-size",
+		"audio.loudness_target":       "loudness-target",
+		"audio.silence_threshold":     "silence-threshold",
+		"audio.fade_ms":               "fade-ms",
+		"audio.slow_variant":          "slow-audio",
+		"audio.slow_speed":            "slow-audio-speed",
 		"output.directory":            "output",
+		"archive.format":              "archive-format",
+		"anki.connect_url":            "anki-connect-url",
+		"anki.new_per_day":            "new-per-day",
+		"anki.reviews_per_day":        "reviews-per-day",
+		"anki.learning_steps":         "learning-steps",
+		"anki.bury_siblings":          "bury-siblings",
+		"anki.reverse_same_day":       "reverse-same-day",
+		"anki.card_directions":        "card-directions",
+		"anki.apkg_format":            "apkg-format",
+		"anki.subdecks":               "subdecks",
 		"image.provider":              "image-api",
 		"image.openai_model":          "openai-image-model",
 		"image.openai_size":           "openai-image-size",
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// clozeDeletion matches an Anki cloze deletion {{c1::answer}} or
+// {{c1::answer::hint}}.
+var clozeDeletion = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// clozeShorthand matches the [answer] shorthand of batch files.
+var clozeShorthand = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// HasCloze reports whether text contains a cloze deletion, either in Anki
+// syntax or as [answer] shorthand.
+func HasCloze(text string) bool {
+	return clozeDeletion.MatchString(text) || clozeShorthand.MatchString(text)
+}
+
+// ExpandClozeShorthand rewrites every [answer] in text to an Anki cloze
+// deletion. Each bracket becomes its own card, numbered after the deletions
+// text already has, so "[Аз] обичам [ябълки]." asks for both words on
+// separate cards.
+func ExpandClozeShorthand(text string) string {
+	next := slices.Max(append(ClozeNumbers(text), 0)) + 1
+	return clozeShorthand.ReplaceAllStringFunc(text, func(match string) string {
+		answer := strings.TrimSpace(match[1 : len(match)-1])
+		deletion := fmt.Sprintf("{{c%d::%s}}", next, answer)
+		next++
+		return deletion
+	})
+}
+
+// ClozeText returns the plain sentence of a cloze text: the deletions are
+// replaced by their answers and hints are dropped.
+func ClozeText(cloze string) string {
+	plain := clozeDeletion.ReplaceAllString(cloze, "$2")
+	return strings.Join(strings.Fields(plain), " ")
+}
+
+// ClozeNumbers returns the distinct cloze numbers of text in ascending
+// order. Anki creates one card per number.
+func ClozeNumbers(text string) []int {
+	var numbers []int
+	for _, match := range clozeDeletion.FindAllStringSubmatch(text, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 || slices.Contains(numbers, n) {
+			continue
+		}
+		numbers = append(numbers, n)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
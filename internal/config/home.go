@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 var userHomeDir = os.UserHomeDir
@@ -20,3 +21,27 @@ func HomeDir() (string, error) {
 
 	return homeDir, nil
 }
+
+// ConfigDir returns the directory for user-supplied files such as Anki
+// template overrides: $XDG_CONFIG_HOME/totalrecall, or ~/.config/totalrecall
+// when XDG_CONFIG_HOME is unset.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "totalrecall"), nil
+	}
+
+	homeDir, err := HomeDir()
+	return filepath.Join(homeDir, ".config", "totalrecall"), err
+}
+
+// StateDir returns the directory for data totalrecall keeps between runs,
+// such as the cards and the TTS cache: $XDG_STATE_HOME/totalrecall, or
+// ~/.local/state/totalrecall when XDG_STATE_HOME is unset.
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "totalrecall"), nil
+	}
+
+	homeDir, err := HomeDir()
+	return filepath.Join(homeDir, ".local", "state", "totalrecall"), err
+}
@@ -23,3 +23,41 @@ func TestHomeDirReturnsFallbackWhenResolutionFails(t *testing.T) {
 		t.Fatalf("HomeDir() homeDir = %q, want %q", homeDir, ".")
 	}
 }
+
+func TestConfigDirPrefersXDGConfigHome(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	if dir, err := ConfigDir(); err != nil || dir != "/tmp/xdg/totalrecall" {
+		t.Fatalf("ConfigDir() = %q, %v; want /tmp/xdg/totalrecall", dir, err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	oldUserHomeDir := userHomeDir
+	t.Cleanup(func() {
+		userHomeDir = oldUserHomeDir
+	})
+	userHomeDir = func() (string, error) {
+		return "/home/user", nil
+	}
+	if dir, err := ConfigDir(); err != nil || dir != "/home/user/.config/totalrecall" {
+		t.Fatalf("ConfigDir() = %q, %v; want /home/user/.config/totalrecall", dir, err)
+	}
+}
+
+func TestStateDirPrefersXDGStateHome(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/xdg-state")
+	if dir, err := StateDir(); err != nil || dir != "/tmp/xdg-state/totalrecall" {
+		t.Fatalf("StateDir() = %q, %v; want /tmp/xdg-state/totalrecall", dir, err)
+	}
+
+	t.Setenv("XDG_STATE_HOME", "")
+	oldUserHomeDir := userHomeDir
+	t.Cleanup(func() {
+		userHomeDir = oldUserHomeDir
+	})
+	userHomeDir = func() (string, error) {
+		return "/home/user", nil
+	}
+	if dir, err := StateDir(); err != nil || dir != "/home/user/.local/state/totalrecall" {
+		t.Fatalf("StateDir() = %q, %v; want /home/user/.local/state/totalrecall", dir, err)
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// CardDirections selects the cards a word note produces in Anki: the
+// forward card asks for the Bulgarian word, the reverse card shows it, and
+// the type-in card asks to type the Bulgarian word. The zero value means
+// "not chosen", which falls back to the export-wide setting.
+type CardDirections struct {
+	Forward bool
+	Reverse bool
+	TypeIn  bool
+}
+
+// DefaultCardDirections returns the forward and reverse cards every note had
+// before the directions became configurable.
+func DefaultCardDirections() CardDirections {
+	return CardDirections{Forward: true, Reverse: true}
+}
+
+// IsZero reports whether no direction is chosen.
+func (d CardDirections) IsZero() bool {
+	return d == CardDirections{}
+}
+
+// Or returns d, or fallback when d is zero.
+func (d CardDirections) Or(fallback CardDirections) CardDirections {
+	if d.IsZero() {
+		return fallback
+	}
+	return d
+}
+
+// String returns the directions in the syntax ParseCardDirections accepts,
+// e.g. "both", "forward" or "reverse,type". It is empty for the zero value.
+func (d CardDirections) String() string {
+	var parts []string
+	switch {
+	case d.Forward && d.Reverse:
+		parts = append(parts, "both")
+	case d.Forward:
+		parts = append(parts, "forward")
+	case d.Reverse:
+		parts = append(parts, "reverse")
+	}
+	if d.TypeIn {
+		parts = append(parts, "type")
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCardDirections parses a combination of "forward", "reverse", "both"
+// and "type", separated by commas, plus signs or spaces: "both,type" adds a
+// type-in card to the two default cards, "type" makes it the only card. An
+// empty string yields the zero value.
+func ParseCardDirections(s string) (CardDirections, error) {
+	var d CardDirections
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ',' || r == '+' || r == ' ' || r == '\t'
+	})
+	for _, field := range fields {
+		switch field {
+		case "forward":
+			d.Forward = true
+		case "reverse":
+			d.Reverse = true
+		case "both":
+			d.Forward, d.Reverse = true, true
+		case "type", "type-in":
+			d.TypeIn = true
+		default:
+			return CardDirections{}, fmt.Errorf("unknown card direction %q (want forward, reverse, both or type)", field)
+		}
+	}
+	return d, nil
+}
+
+// SaveCardDirections stores the directions chosen for a single card in its
+// manifest; the zero value removes the choice again.
+func SaveCardDirections(cardDir string, directions CardDirections) error {
+	return store.SaveDirections(cardDir, directions.String())
+}
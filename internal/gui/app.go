@@ -26,6 +26,7 @@ import (
 	appconfig "codeberg.org/snonux/totalrecall/internal/config"
 	"codeberg.org/snonux/totalrecall/internal/image"
 	"codeberg.org/snonux/totalrecall/internal/phonetic"
+	"codeberg.org/snonux/totalrecall/internal/store"
 	"codeberg.org/snonux/totalrecall/internal/translation"
 )
 
@@ -47,6 +48,8 @@ type Application struct {
 	imageDisplay     *ImageDisplay
 	audioPlayer      *AudioPlayer
 	translationEntry *CustomEntry
+	tagsEntry        *CustomEntry
+	exampleEntry     *CustomEntry
 	cardTypeSelect   *widget.Select
 	statusLabel      *widget.Label
 	queueStatusLabel *widget.Label
@@ -66,6 +69,12 @@ type Application struct {
 	regenerateAllBtn         *ttwidget.Button
 	deleteButton             *ttwidget.Button
 
+	// Asset revision controls
+	revisionSlotSelect *widget.Select
+	prevRevisionBtn    *ttwidget.Button
+	nextRevisionBtn    *ttwidget.Button
+	revisionLabel      *widget.Label
+
 	// State management
 	currentWord          string
 	currentAudioFile     string
@@ -126,10 +135,12 @@ type Application struct {
 	gen     *GenerationOrchestrator // audio, image, and phonetics generation
 
 	// Focused sub-handlers (SRP); initialized lazily via ensureHandlers.
-	nav      *NavigationHandler
-	export   *ExportHandler
-	queueMgr *QueueManager
-	keys     *KeyboardShortcuts
+	nav       *NavigationHandler
+	export    *ExportHandler
+	queueMgr  *QueueManager
+	keys      *KeyboardShortcuts
+	revisions *RevisionHandler
+	trash     *TrashHandler
 }
 
 // Config holds GUI application configuration
@@ -148,10 +159,44 @@ type Config struct {
 	// GeminiTTSModel selects the Gemini TTS model when Gemini audio is active.
 	GeminiTTSModel string
 	// GeminiVoice selects a specific Gemini voice; empty picks a random Gemini voice.
-	GeminiVoice         string
+	GeminiVoice string
+	// LocalTTSEngine, LocalTTSBinary, LocalTTSModel and LocalTTSVoice
+	// configure the offline Piper / espeak-ng engine of the "local" audio
+	// provider.
+	LocalTTSEngine string
+	LocalTTSBinary string
+	LocalTTSModel  string
+	LocalTTSVoice  string
+	// AudioFallback lists the providers tried in order after AudioProvider
+	// when it fails on quota, authentication, an open circuit or a safety
+	// block.
+	AudioFallback []string
+	// AudioCache reuses identical clips instead of generating them again;
+	// nil always generates.
+	AudioCache *audio.Cache
+	// AudioPostProcess normalizes, trims and fades generated clips.
+	AudioPostProcess audio.PostProcessConfig
+	// SlowAudioSpeed is the speed of the slow variant rendered next to
+	// every clip; 0 renders none.
+	SlowAudioSpeed      float64
 	TranslationProvider translation.Provider
 	PhoneticProvider    phonetic.Provider
 	AutoPlay            bool // Whether to automatically play audio when generated or navigated to
+	// AnkiConnectURL is the endpoint of the AnkiConnect export; empty uses
+	// ankiconnect.DefaultURL.
+	AnkiConnectURL string
+	// DeckOptions are the scheduling options of APKG exports; nil means
+	// anki.DefaultDeckOptions.
+	DeckOptions *anki.DeckOptions
+	// CardDirections are the card directions of exported cards that do not
+	// choose their own; zero means internal.DefaultCardDirections.
+	CardDirections internal.CardDirections
+	// PackageFormat is the layout of APKG exports; empty means
+	// anki.DefaultPackageFormat.
+	PackageFormat anki.PackageFormat
+	// DeckGrouping splits exports into subdecks; empty means
+	// anki.DeckGroupingNone.
+	DeckGrouping anki.DeckGrouping
 
 	// Injectable dependencies — when non-nil, New() uses them directly instead of
 	// constructing new instances from the provider/key fields above.
@@ -362,6 +407,12 @@ func audioConfigForApp(config *Config) *audio.Config {
 		GeminiTTSModel:    defaults.GeminiTTSModel,
 		GeminiVoice:       config.GeminiVoice,
 		GeminiSpeed:       defaults.GeminiSpeed,
+		LocalEngine:       config.LocalTTSEngine,
+		LocalBinary:       config.LocalTTSBinary,
+		LocalModel:        config.LocalTTSModel,
+		LocalVoice:        config.LocalTTSVoice,
+		LocalSpeed:        defaults.LocalSpeed,
+		PostProcess:       audio.AvailablePostProcess(config.AudioPostProcess),
 	}
 
 	if config.GeminiTTSModel != "" {
@@ -411,7 +462,7 @@ func (a *Application) setupUI() {
 
 	inputSection := a.buildInputSection()
 	displaySection := a.buildDisplaySection()
-	exportButton, archiveButton, helpButton, toolbar := a.buildToolbar()
+	exportButton, archiveButton, trashButton, helpButton, toolbar := a.buildToolbar()
 	statusSection := a.buildStatusSection()
 
 	// Combine all sections — toolbar and input at top, status at bottom.
@@ -456,6 +507,9 @@ func (a *Application) setupUI() {
 			if archiveButton != nil {
 				archiveButton.SetToolTip("Archive all cards (v)")
 			}
+			if trashButton != nil {
+				trashButton.SetToolTip("Trash bin (t)")
+			}
 			if helpButton != nil {
 				helpButton.SetToolTip("Show hotkeys (?)")
 			}
@@ -473,6 +527,8 @@ func (a *Application) setupUI() {
 func (a *Application) buildInputSection() fyne.CanvasObject {
 	a.buildWordInput()
 	a.buildTranslationInput()
+	a.buildTagsInput()
+	a.buildExampleInput()
 
 	a.cardTypeSelect = widget.NewSelect([]string{"English → Bulgarian", "Bulgarian → Bulgarian"}, func(selected string) {
 		if selected == "Bulgarian → Bulgarian" {
@@ -493,10 +549,10 @@ func (a *Application) buildInputSection() fyne.CanvasObject {
 	a.nextWordBtn = ttwidget.NewButton("", a.onNextWord)
 	a.nextWordBtn.Icon = theme.NavigateNextIcon()
 
-	inputGrid := container.New(layout.NewGridLayout(3),
-		a.wordInput, a.translationEntry, a.cardTypeSelect,
+	inputGrid := container.New(layout.NewGridLayout(4),
+		a.wordInput, a.translationEntry, a.tagsEntry, a.cardTypeSelect,
 	)
-	return container.NewBorder(nil, nil, nil, a.submitButton, inputGrid)
+	return container.NewBorder(nil, nil, nil, a.submitButton, container.NewVBox(inputGrid, a.exampleEntry))
 }
 
 // buildWordInput creates and wires the Bulgarian word entry field. The OnChanged
@@ -555,6 +611,33 @@ func (a *Application) buildTranslationInput() {
 	a.translationEntry.SetOnEscape(func() { a.window.Canvas().Unfocus() })
 }
 
+// buildTagsInput creates the tags entry. It shows the tags of the current
+// card and saves edits to it; new words are submitted with the tags it holds.
+func (a *Application) buildTagsInput() {
+	a.tagsEntry = NewCustomEntry()
+	a.tagsEntry.SetPlaceHolder("Tags (e.g. lesson-3 food)...")
+	a.tagsEntry.OnChanged = func(string) { a.saveTags() }
+	a.tagsEntry.OnSubmitted = func(string) {
+		a.onSubmit()
+		a.window.Canvas().Unfocus()
+	}
+	a.tagsEntry.SetOnEscape(func() { a.window.Canvas().Unfocus() })
+}
+
+// buildExampleInput creates the example sentence entry. It takes the same
+// "sentence = translation" form as batch files and saves edits to the
+// current card; new words are submitted with the example it holds.
+func (a *Application) buildExampleInput() {
+	a.exampleEntry = NewCustomEntry()
+	a.exampleEntry.SetPlaceHolder("Example sentence (optional) = its translation...")
+	a.exampleEntry.OnChanged = func(string) { a.saveExample() }
+	a.exampleEntry.OnSubmitted = func(string) {
+		a.onSubmit()
+		a.window.Canvas().Unfocus()
+	}
+	a.exampleEntry.SetOnEscape(func() { a.window.Canvas().Unfocus() })
+}
+
 // buildDisplaySection constructs and returns the image/prompt and log/audio
 // display area.
 func (a *Application) buildDisplaySection() fyne.CanvasObject {
@@ -589,9 +672,9 @@ func (a *Application) buildDisplaySection() fyne.CanvasObject {
 }
 
 // buildToolbar constructs action/navigation/utility buttons and the toolbar
-// container. Returns the three utility buttons (for late tooltip wiring) and
+// container. Returns the four utility buttons (for late tooltip wiring) and
 // the toolbar itself.
-func (a *Application) buildToolbar() (exportButton, archiveButton, helpButton *ttwidget.Button, toolbar fyne.CanvasObject) {
+func (a *Application) buildToolbar() (exportButton, archiveButton, trashButton, helpButton *ttwidget.Button, toolbar fyne.CanvasObject) {
 	a.keepButton = ttwidget.NewButtonWithIcon("", theme.DocumentCreateIcon(), a.onKeepAndContinue)
 	a.regenerateImageBtn = ttwidget.NewButtonWithIcon("", theme.ColorPaletteIcon(), a.onRegenerateImage)
 	a.regenerateRandomImageBtn = ttwidget.NewButtonWithIcon("", theme.ViewRefreshIcon(), a.onRegenerateRandomImage)
@@ -605,15 +688,20 @@ func (a *Application) buildToolbar() (exportButton, archiveButton, helpButton *t
 
 	exportButton = ttwidget.NewButtonWithIcon("", theme.UploadIcon(), a.onExportToAnki)
 	archiveButton = ttwidget.NewButtonWithIcon("", theme.FolderOpenIcon(), a.onArchive)
+	trashButton = ttwidget.NewButtonWithIcon("", theme.HistoryIcon(), a.onShowTrash)
 	helpButton = ttwidget.NewButtonWithIcon("", theme.HelpIcon(), a.onShowHotkeys)
 
+	a.ensureHandlers()
+	revisionControls := a.revisions.buildControls()
+
 	toolbar = container.NewHBox(
 		a.prevWordBtn, a.nextWordBtn, widget.NewSeparator(),
 		a.keepButton, a.deleteButton, widget.NewSeparator(),
 		a.regenerateImageBtn, a.regenerateRandomImageBtn, a.regenerateAudioBtn, a.regenerateAllBtn, widget.NewSeparator(),
-		exportButton, archiveButton, helpButton,
+		revisionControls, widget.NewSeparator(),
+		exportButton, archiveButton, trashButton, helpButton,
 	)
-	return exportButton, archiveButton, helpButton, toolbar
+	return exportButton, archiveButton, trashButton, helpButton, toolbar
 }
 
 // buildStatusSection constructs and returns the status bar at the bottom of
@@ -739,6 +827,8 @@ func (a *Application) onSubmit() {
 	job := a.queue.AddWordWithPrompt(inputs.wordToProcess, a.imagePromptEntry.Text)
 	job.NeedsTranslation = inputs.needsTranslation
 	job.CardType = a.currentCardType
+	job.Tags = store.ParseTags(a.tagsEntry.Text)
+	job.Example, job.ExampleTranslation = store.ParseExample(a.exampleEntry.Text)
 	if a.currentTranslation != "" {
 		job.Translation = a.currentTranslation
 	}
@@ -1054,6 +1144,8 @@ func (a *Application) clearCurrentWordState() {
 	a.currentImage = ""
 	a.currentPhonetic = ""
 	a.mu.Unlock()
+	// Cleared after the word, so the example of the previous card stays.
+	a.exampleEntry.SetText("")
 
 	a.hideProgress()
 	a.submitButton.Enable()
@@ -1127,6 +1219,7 @@ func (a *Application) reenableImageButtons() {
 	a.regenerateImageBtn.Enable()
 	a.regenerateRandomImageBtn.Enable()
 	a.regenerateAllBtn.Enable()
+	a.refreshRevisionControls()
 }
 
 // onRegenerateAudio regenerates front audio (or single audio for en-bg cards)
@@ -1230,6 +1323,7 @@ func (a *Application) reenableAudioButtons() {
 	a.hideProgress()
 	a.regenerateAudioBtn.Enable()
 	a.regenerateAllBtn.Enable()
+	a.refreshRevisionControls()
 }
 
 // onRegenerateBackAudio regenerates back audio for bg-bg cards
@@ -1323,8 +1417,9 @@ func (a *Application) onExportToAnki() {
 // onArchive shows a confirmation dialog and archives the current cards directory
 // on user confirmation. Keyboard shortcuts y/ъ confirm, n/н/c/ц/Esc cancel.
 func (a *Application) onArchive() {
-	confirmDialog := dialog.NewConfirm("Archive Cards",
-		"Are you sure you want to archive all existing cards?\n\nThis will move the cards directory to:\n~/.local/state/totalrecall/archive/cards-TIMESTAMP",
+	message := fmt.Sprintf("Are you sure you want to archive all existing cards?\n\nThis will move the cards directory to:\n%s",
+		filepath.Join(archive.ArchiveDir(a.config.OutputDir), "cards-TIMESTAMP"))
+	confirmDialog := dialog.NewConfirm("Archive Cards", message,
 		func(confirmed bool) {
 			if confirmed {
 				a.performArchive()
@@ -1335,16 +1430,10 @@ func (a *Application) onArchive() {
 	a.showArchiveConfirmDialog(confirmDialog)
 }
 
-// performArchive moves the cards directory to the archive location, clears in-memory
+// performArchive archives the configured output directory, clears in-memory
 // state, and refreshes the word list. Errors are shown via the error dialog.
 func (a *Application) performArchive() {
-	home, err := appconfig.HomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
-	}
-	cardsDir := filepath.Join(home, ".local", "state", "totalrecall", "cards")
-
-	if err := a.archiver.ArchiveCards(cardsDir); err != nil {
+	if err := a.archiver.ArchiveCards(a.config.OutputDir); err != nil {
 		dialog.ShowError(err, a.window)
 		return
 	}
@@ -1415,6 +1504,12 @@ func (a *Application) showArchiveConfirmDialog(confirmDialog *dialog.ConfirmDial
 	confirmDialog.Show()
 }
 
+// onShowTrash opens the Trash Bin dialog for restoring or purging deleted cards.
+func (a *Application) onShowTrash() {
+	a.ensureHandlers()
+	a.trash.onShowTrash()
+}
+
 // onShowHotkeys delegates to KeyboardShortcuts.
 func (a *Application) onShowHotkeys() {
 	a.ensureHandlers()
@@ -1657,6 +1752,10 @@ func (a *Application) setupTooltips() {
 			if a.deleteButton != nil {
 				a.deleteButton.SetToolTip("Delete word (d)")
 			}
+			if a.revisionSlotSelect != nil {
+				a.prevRevisionBtn.SetToolTip("Previous revision ([/ш)")
+				a.nextRevisionBtn.SetToolTip("Next revision (]/щ)")
+			}
 
 			// Export and help button tooltips are set in the main window setup
 			// goroutine (see setupUI) to avoid a double 500 ms wait here.
@@ -1668,6 +1767,12 @@ func (a *Application) setupTooltips() {
 			if a.audioPlayer != nil && a.audioPlayer.playBackButton != nil {
 				a.audioPlayer.playBackButton.SetToolTip("Play back audio (P)")
 			}
+			if a.audioPlayer != nil && a.audioPlayer.playSlowButton != nil {
+				a.audioPlayer.playSlowButton.SetToolTip("Play slow audio (s)")
+			}
+			if a.audioPlayer != nil && a.audioPlayer.playBackSlowButton != nil {
+				a.audioPlayer.playBackSlowButton.SetToolTip("Play slow back audio (S)")
+			}
 			if a.audioPlayer != nil && a.audioPlayer.stopButton != nil {
 				a.audioPlayer.stopButton.SetToolTip("Stop audio")
 			}
@@ -1675,7 +1780,8 @@ func (a *Application) setupTooltips() {
 	}()
 }
 
-// ensureHandlers lazily wires NavigationHandler, ExportHandler, QueueManager, and KeyboardShortcuts.
+// ensureHandlers lazily wires NavigationHandler, ExportHandler, QueueManager,
+// KeyboardShortcuts, RevisionHandler, and TrashHandler.
 func (a *Application) ensureHandlers() {
 	if a.nav == nil {
 		a.nav = &NavigationHandler{app: a}
@@ -1689,6 +1795,12 @@ func (a *Application) ensureHandlers() {
 	if a.keys == nil {
 		a.keys = &KeyboardShortcuts{app: a}
 	}
+	if a.revisions == nil {
+		a.revisions = &RevisionHandler{app: a}
+	}
+	if a.trash == nil {
+		a.trash = &TrashHandler{app: a}
+	}
 }
 
 func (a *Application) scanExistingWords() {
@@ -1706,6 +1818,23 @@ func (a *Application) loadExistingFiles(word string) {
 	a.nav.loadExistingFiles(word)
 }
 
+// refreshRevisionControls updates the revision control for the current card.
+// Must be called on the UI goroutine.
+func (a *Application) refreshRevisionControls() {
+	a.ensureHandlers()
+	a.revisions.refresh()
+}
+
+func (a *Application) onPrevRevision() {
+	a.ensureHandlers()
+	a.revisions.step(-1)
+}
+
+func (a *Application) onNextRevision() {
+	a.ensureHandlers()
+	a.revisions.step(1)
+}
+
 func (a *Application) onPrevWord() {
 	a.ensureHandlers()
 	a.nav.onPrevWord()
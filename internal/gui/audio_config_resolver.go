@@ -36,8 +36,39 @@ func (r *AudioConfigResolver) ProviderName() string {
 	return audio.DefaultProviderConfig().Provider
 }
 
+// FallbackProviders returns the providers tried after ProviderName when it
+// fails; see audio.RunWithProviderFailover.
+func (r *AudioConfigResolver) FallbackProviders() []string {
+	if r.guiConfig == nil {
+		return nil
+	}
+	return r.guiConfig.AudioFallback
+}
+
+// Cache returns the TTS cache of the GUI config, or nil when there is none.
+func (r *AudioConfigResolver) Cache() *audio.Cache {
+	if r.guiConfig == nil {
+		return nil
+	}
+	return r.guiConfig.AudioCache
+}
+
+// ForProvider returns a resolver for another provider of the failover chain
+// that shares the keys and settings of r.
+func (r *AudioConfigResolver) ForProvider(provider string) *AudioConfigResolver {
+	audioConfig := audio.Config{}
+	if r.audioConfig != nil {
+		audioConfig = *r.audioConfig
+	}
+	audioConfig.Provider = provider
+	return NewAudioConfigResolver(r.guiConfig, &audioConfig)
+}
+
 // Voices returns the configured provider's voice list.
 func (r *AudioConfigResolver) Voices() []string {
+	if r.audioConfig != nil && r.ProviderName() == audio.LocalProviderName {
+		return audio.VoicesForConfig(r.audioConfig)
+	}
 	return audio.VoicesFor(r.ProviderName())
 }
 
@@ -75,6 +106,9 @@ func (r *AudioConfigResolver) ConfigForGeneration(voice string, speed float64) a
 		if strings.TrimSpace(audioConfig.GeminiTTSModel) == "" {
 			audioConfig.GeminiTTSModel = audio.DefaultProviderConfig().GeminiTTSModel
 		}
+	case audio.LocalProviderName:
+		audioConfig.LocalVoice = voice
+		audioConfig.LocalSpeed = speed
 	default:
 		audioConfig.OpenAIVoice = voice
 		audioConfig.OpenAISpeed = speed
@@ -82,13 +116,3 @@ func (r *AudioConfigResolver) ConfigForGeneration(voice string, speed float64) a
 
 	return audioConfig
 }
-
-// BaseConfigForAttribution returns the configured audio.Config pointer, or the
-// package default when unset — matching how attribution sidecars resolve the
-// base parameters before per-run voice/speed overrides.
-func (r *AudioConfigResolver) BaseConfigForAttribution() *audio.Config {
-	if r.audioConfig != nil {
-		return r.audioConfig
-	}
-	return audio.DefaultProviderConfig()
-}
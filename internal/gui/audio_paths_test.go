@@ -34,6 +34,8 @@ func newGUIAudioTestApp(t *testing.T, tempDir string) *Application {
 		audioPlayer:              NewAudioPlayer(),
 		imageDisplay:             NewImageDisplay(),
 		translationEntry:         NewCustomEntry(),
+		tagsEntry:                NewCustomEntry(),
+		exampleEntry:             NewCustomEntry(),
 		cardTypeSelect:           widget.NewSelect([]string{"English → Bulgarian", "Bulgarian → Bulgarian"}, nil),
 		imagePromptEntry:         NewCustomMultiLineEntry(),
 		statusLabel:              widget.NewLabel(""),
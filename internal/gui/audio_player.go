@@ -16,6 +16,9 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+
+	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // AudioPlayer is a custom widget for playing audio files
@@ -39,6 +42,13 @@ type AudioPlayer struct {
 	voiceInfo       string          // Stores voice and speed info
 	autoPlayEnabled *bool           // Pointer to parent's auto-play state
 	ctx             context.Context // Application context; guards post-playback UI updates
+
+	// The slow variants of audioFile and audioFileBack and the buttons
+	// playing them; empty and hidden when the card has none.
+	audioFileSlow      string
+	audioFileBackSlow  string
+	playSlowButton     *ttwidget.Button
+	playBackSlowButton *ttwidget.Button
 }
 
 type audioCommandCandidate struct {
@@ -68,6 +78,12 @@ func NewAudioPlayer() *AudioPlayer {
 	p.playBackLabel = widget.NewLabel("")
 	p.playBackLabel.TextStyle = fyne.TextStyle{Bold: true}
 
+	p.playSlowButton = ttwidget.NewButton("Slow", p.onPlaySlow)
+	p.playSlowButton.Icon = theme.MediaPlayIcon()
+
+	p.playBackSlowButton = ttwidget.NewButton("Slow", p.onPlayBackSlow)
+	p.playBackSlowButton.Icon = theme.MediaPlayIcon()
+
 	p.stopButton = ttwidget.NewButton("", p.onStop)
 	p.stopButton.Icon = theme.MediaStopIcon()
 
@@ -86,14 +102,16 @@ func NewAudioPlayer() *AudioPlayer {
 	p.playBackButton.Disable()
 	p.playBackButton.Hide() // Only show for bg-bg cards
 	p.playBackLabel.Hide()
+	p.playSlowButton.Hide()
+	p.playBackSlowButton.Hide()
 	p.stopButton.Disable()
 
 	// Layout: buttons on the left, status on the right, phonetic fills the middle.
 	// Using NewBorder so the phonetic label expands horizontally rather than being
 	// squeezed to its minimum width in an HBox.
 	buttons := container.NewVBox(
-		container.NewHBox(p.playButton, p.playButtonLabel),
-		container.NewHBox(p.playBackButton, p.playBackLabel),
+		container.NewHBox(p.playButton, p.playButtonLabel, p.playSlowButton),
+		container.NewHBox(p.playBackButton, p.playBackLabel, p.playBackSlowButton),
 	)
 	leftControls := container.NewHBox(buttons, p.stopButton)
 	p.container = container.NewBorder(nil, nil, leftControls, p.statusLabel, p.phoneticLabel)
@@ -131,6 +149,8 @@ func (p *AudioPlayer) setAudioFileInternal(audioFile string, allowAutoPlay bool)
 	p.audioFile = audioFile
 	p.isPlaying = false
 
+	p.audioFileSlow = p.setSlowVariant(p.playSlowButton, audioFile)
+
 	if audioFile != "" {
 		p.playButton.Enable()
 
@@ -191,6 +211,7 @@ func (p *AudioPlayer) setAudioFileInternal(audioFile string, allowAutoPlay bool)
 // SetBackAudioFile sets the back audio file for bg-bg cards
 func (p *AudioPlayer) SetBackAudioFile(audioFile string) {
 	p.audioFileBack = audioFile
+	p.audioFileBackSlow = p.setSlowVariant(p.playBackSlowButton, audioFile)
 	if audioFile != "" {
 		p.isBgBg = true
 		p.playBackButton.Enable()
@@ -217,11 +238,38 @@ func (p *AudioPlayer) SetBackAudioFile(audioFile string) {
 	}
 }
 
+// setSlowVariant shows button when audioFile has a slow variant and returns
+// the variant's path, or hides it and returns "".
+func (p *AudioPlayer) setSlowVariant(button *ttwidget.Button, audioFile string) string {
+	slowFile := slowAudioFileOf(audioFile)
+	if slowFile == "" {
+		button.Hide()
+	} else {
+		button.Show()
+	}
+	if p.container != nil {
+		p.container.Refresh()
+	}
+	return slowFile
+}
+
+// slowAudioFileOf resolves the slow variant of audioFile (audio_slow.mp3 for
+// audio.mp3), or "" when there is none.
+func slowAudioFileOf(audioFile string) string {
+	if audioFile == "" {
+		return ""
+	}
+	baseName := store.AssetSlot(audioFile) + store.SlowAudioSuffix
+	return anki.ResolveAudioFile(filepath.Dir(audioFile), baseName, strings.TrimPrefix(filepath.Ext(audioFile), "."))
+}
+
 // Clear clears the audio player
 func (p *AudioPlayer) Clear() {
 	p.onStop()
 	p.audioFile = ""
 	p.audioFileBack = ""
+	p.audioFileSlow = ""
+	p.audioFileBackSlow = ""
 	p.isBgBg = false
 	p.isPlaying = false
 	p.voiceInfo = ""
@@ -231,6 +279,8 @@ func (p *AudioPlayer) Clear() {
 	p.playButtonLabel.SetText("")
 	p.playBackLabel.SetText("")
 	p.playBackLabel.Hide()
+	p.playSlowButton.Hide()
+	p.playBackSlowButton.Hide()
 	p.stopButton.Disable()
 	p.statusLabel.SetText("No audio loaded")
 	p.phoneticLabel.SetText("")
@@ -301,6 +351,37 @@ func (p *AudioPlayer) onPlayBack() {
 	p.statusLabel.SetText(fmt.Sprintf("Playing back audio: %s", filepath.Base(p.audioFileBack)))
 }
 
+// onPlaySlow handles the slow audio button of the front (or only) audio
+func (p *AudioPlayer) onPlaySlow() {
+	p.playSlowFile(p.audioFileSlow)
+}
+
+// onPlayBackSlow handles the slow audio button of the back audio
+func (p *AudioPlayer) onPlayBackSlow() {
+	p.playSlowFile(p.audioFileBackSlow)
+}
+
+// playSlowFile plays a slow variant. The slow buttons keep their play icon;
+// the stop button ends playback.
+func (p *AudioPlayer) playSlowFile(audioFile string) {
+	if audioFile == "" {
+		return
+	}
+
+	if p.isPlaying {
+		p.onStop()
+	}
+
+	if err := p.startPlaybackForFile(audioFile); err != nil {
+		p.statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+		return
+	}
+
+	p.isPlaying = true
+	p.stopButton.Enable()
+	p.statusLabel.SetText(fmt.Sprintf("Playing slow audio: %s", filepath.Base(audioFile)))
+}
+
 // onStop handles stop button click
 func (p *AudioPlayer) onStop() {
 	if p.playCmd != nil && p.playCmd.Process != nil {
@@ -339,6 +420,20 @@ func (p *AudioPlayer) PlayBack() {
 	}
 }
 
+// PlaySlow triggers playback of the slow variant of the front (or only) audio
+func (p *AudioPlayer) PlaySlow() {
+	if p.audioFileSlow != "" {
+		fyne.Do(p.onPlaySlow)
+	}
+}
+
+// PlayBackSlow triggers playback of the slow variant of the back audio
+func (p *AudioPlayer) PlayBackSlow() {
+	if p.audioFileBackSlow != "" {
+		fyne.Do(p.onPlayBackSlow)
+	}
+}
+
 // startPlayback starts audio playback using platform-specific commands
 // This plays the front audio file (p.audioFile)
 func (p *AudioPlayer) startPlayback() error {
@@ -361,6 +456,7 @@ func (p *AudioPlayer) startPlaybackForFile(audioFile string) error {
 	// ctx.Done() is checked before fyne.Do so we never write to freed widgets
 	// after the application window has been closed (Go Mistake #62).
 	isPlayingBack := audioFile == p.audioFileBack
+	isPlayingSlow := audioFile == p.audioFileSlow || audioFile == p.audioFileBackSlow
 	ctx := p.ctx
 	go func() {
 		err := cmd.Run()
@@ -372,6 +468,8 @@ func (p *AudioPlayer) startPlaybackForFile(audioFile string) error {
 				if isPlayingBack {
 					p.playBackButton.SetIcon(theme.MediaPlayIcon())
 					p.statusLabel.SetText(fmt.Sprintf("Finished: %s", filepath.Base(p.audioFileBack)))
+				} else if isPlayingSlow {
+					p.statusLabel.SetText(fmt.Sprintf("Finished: %s", filepath.Base(audioFile)))
 				} else {
 					p.playButton.SetIcon(theme.MediaPlayIcon())
 					p.statusLabel.SetText(fmt.Sprintf("Finished: %s%s", filepath.Base(p.audioFile), p.voiceInfo))
@@ -2,6 +2,7 @@ package gui
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -58,3 +59,22 @@ func TestLinuxAudioPlaybackCommandUsesFormatCompatiblePlayer(t *testing.T) {
 		t.Fatalf("command args = %#v, want final arg %q", cmd.Args, audioFile)
 	}
 }
+
+func TestSlowAudioFileOf(t *testing.T) {
+	cardDir := t.TempDir()
+	for _, name := range []string{"audio_front.mp3", "audio_front_slow.mp3", "audio_back.mp3"} {
+		if err := os.WriteFile(filepath.Join(cardDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	if got, want := slowAudioFileOf(filepath.Join(cardDir, "audio_front.mp3")), filepath.Join(cardDir, "audio_front_slow.mp3"); got != want {
+		t.Errorf("slowAudioFileOf(front) = %q, want %q", got, want)
+	}
+	if got := slowAudioFileOf(filepath.Join(cardDir, "audio_back.mp3")); got != "" {
+		t.Errorf("slowAudioFileOf(back) = %q, want no slow variant", got)
+	}
+	if got := slowAudioFileOf(""); got != "" {
+		t.Errorf("slowAudioFileOf(\"\") = %q, want \"\"", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -41,11 +42,12 @@ func (cs *CardService) FindCardDirectory(word string) string {
 
 // EnsureWordDirectoryAndMetadata creates a new card directory and writes word
 // metadata to word.txt inside it. Returns the directory path.
-// Uses store.FindOrCreateCardDirectory so the creation logic is not duplicated.
+// Uses store.CardStore.EnsureCardDirectory so the creation logic (including
+// the cross-process lock against duplicate directories) is not duplicated.
 func (cs *CardService) EnsureWordDirectoryAndMetadata(word string) (string, error) {
-	wordDir := cs.cardStore.FindOrCreateCardDirectory(word)
-	if wordDir == "" || wordDir == cs.config.OutputDir {
-		return "", fmt.Errorf("failed to create card directory for %q", word)
+	wordDir, err := cs.cardStore.EnsureCardDirectory(word)
+	if err != nil {
+		return "", fmt.Errorf("failed to create card directory for %q: %w", word, err)
 	}
 	return wordDir, nil
 }
@@ -71,7 +73,7 @@ func (cs *CardService) ScanExistingWords() []string {
 }
 
 // dirHasContent returns true if the card directory contains at least one audio
-// file, image file, or translation file.
+// file, image file, or a stored translation.
 func (cs *CardService) dirHasContent(wordDir string) bool {
 	// Reuse the Application audio-path helpers via package-level functions to
 	// avoid duplicating the metadata-parsing logic.
@@ -85,15 +87,11 @@ func (cs *CardService) dirHasContent(wordDir string) bool {
 		}
 	}
 
-	if _, err := os.Stat(filepath.Join(wordDir, "translation.txt")); err == nil {
-		return true
-	}
-
-	return false
+	return store.LoadManifest(wordDir).Translation != ""
 }
 
-// SaveTranslation persists the translation for the given word to disk.
-// It finds or creates the card directory as necessary.
+// SaveTranslation persists the translation for the given word in its card
+// manifest. It finds or creates the card directory as necessary.
 func (cs *CardService) SaveTranslation(word, translation string) error {
 	if word == "" || translation == "" {
 		return nil
@@ -104,16 +102,49 @@ func (cs *CardService) SaveTranslation(word, translation string) error {
 		return err
 	}
 
-	translationFile := filepath.Join(wordDir, "translation.txt")
-	content := fmt.Sprintf("%s = %s\n", word, translation)
-	if err := os.WriteFile(translationFile, []byte(content), 0644); err != nil {
+	if err := store.SaveTranslation(wordDir, word, translation); err != nil {
 		return fmt.Errorf("failed to save translation: %w", err)
 	}
 
 	return nil
 }
 
-// SavePhoneticInfo persists phonetic information for the given word to disk.
+// SaveTags replaces the tags of an existing card. Unchanged tags are not
+// written, so loading a card into the tags entry does not touch its manifest.
+func (cs *CardService) SaveTags(word string, tags []string) error {
+	wordDir := cs.FindCardDirectory(word)
+	if word == "" || wordDir == "" {
+		return nil
+	}
+	if slices.Equal(store.LoadManifest(wordDir).Tags, tags) {
+		return nil
+	}
+
+	if err := store.SaveTags(wordDir, tags); err != nil {
+		return fmt.Errorf("failed to save tags: %w", err)
+	}
+	return nil
+}
+
+// SaveExample replaces the example sentence of an existing card. Like tags,
+// an unchanged example is not written.
+func (cs *CardService) SaveExample(word, example, translation string) error {
+	wordDir := cs.FindCardDirectory(word)
+	if word == "" || wordDir == "" {
+		return nil
+	}
+	if manifest := store.LoadManifest(wordDir); manifest.Example == example && manifest.ExampleTranslation == translation {
+		return nil
+	}
+
+	if err := store.SaveExample(wordDir, example, translation); err != nil {
+		return fmt.Errorf("failed to save example: %w", err)
+	}
+	return nil
+}
+
+// SavePhoneticInfo persists phonetic information for the given word in its
+// card manifest.
 func (cs *CardService) SavePhoneticInfo(word, phoneticText string) error {
 	if word == "" || phoneticText == "" || phoneticText == "Failed to fetch phonetic information" {
 		return nil
@@ -124,29 +155,22 @@ func (cs *CardService) SavePhoneticInfo(word, phoneticText string) error {
 		return err
 	}
 
-	phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-	if err := os.WriteFile(phoneticFile, []byte(phoneticText), 0644); err != nil {
+	if err := store.SavePhonetic(wordDir, phoneticText); err != nil {
 		return fmt.Errorf("failed to save phonetic info: %w", err)
 	}
 
 	return nil
 }
 
-// LoadPhoneticInfo reads phonetic information from disk for the given word.
-// Returns empty string if not found.
+// LoadPhoneticInfo reads phonetic information from the card manifest for the
+// given word. Returns empty string if not found.
 func (cs *CardService) LoadPhoneticInfo(word string) string {
 	wordDir := cs.FindCardDirectory(word)
 	if wordDir == "" {
 		return ""
 	}
 
-	phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-	data, err := os.ReadFile(phoneticFile)
-	if err != nil {
-		return ""
-	}
-
-	return string(data)
+	return store.LoadManifest(wordDir).IPA
 }
 
 // CardFiles holds the paths to all files loaded for a single card.
@@ -159,9 +183,13 @@ type CardFiles struct {
 	PhoneticInfo string
 	ImagePrompt  string
 	CardType     internal.CardType
+	Tags         []string
+	Example      string // "sentence = translation" as shown in the example entry
 }
 
-// LoadCardFiles loads all available files for the given word from disk.
+// LoadCardFiles loads all available files for the given word. Metadata comes
+// from the card manifest; media paths from the assets it records, falling
+// back to a directory scan for cards written before assets were recorded.
 // Returns nil if no card directory exists for the word.
 func (cs *CardService) LoadCardFiles(word string) *CardFiles {
 	wordDir := cs.FindCardDirectory(word)
@@ -172,59 +200,23 @@ func (cs *CardService) LoadCardFiles(word string) *CardFiles {
 
 	fmt.Printf("Loading files from directory: %s\n", wordDir)
 
-	cf := &CardFiles{WordDir: wordDir}
-	cf.CardType = internal.LoadCardType(wordDir)
+	manifest := store.LoadManifest(wordDir)
+	cf := &CardFiles{
+		WordDir:      wordDir,
+		Translation:  manifest.Translation,
+		ImagePrompt:  manifest.ImagePrompt,
+		PhoneticInfo: manifest.IPA,
+		CardType:     internal.ParseCardType(manifest.CardType),
+		Tags:         manifest.Tags,
+		Example:      store.FormatExample(manifest.Example, manifest.ExampleTranslation),
+	}
 
-	cs.loadTranslation(wordDir, cf)
-	cs.loadImagePrompt(wordDir, cf)
-	cs.loadPhoneticFile(wordDir, cf)
 	cs.loadAudioFiles(wordDir, cf)
-	cs.loadImageFile(wordDir, cf)
+	cs.loadImageFile(wordDir, manifest, cf)
 
 	return cf
 }
 
-// loadTranslation reads translation.txt and stores the translation in cf.
-func (cs *CardService) loadTranslation(wordDir string, cf *CardFiles) {
-	translationFile := filepath.Join(wordDir, "translation.txt")
-	data, err := os.ReadFile(translationFile)
-	if err != nil {
-		return
-	}
-
-	content := string(data)
-	parts := strings.Split(content, "=")
-	if len(parts) >= 2 {
-		cf.Translation = strings.TrimSpace(parts[1])
-	}
-}
-
-// loadImagePrompt reads image_prompt.txt and stores the prompt in cf.
-func (cs *CardService) loadImagePrompt(wordDir string, cf *CardFiles) {
-	promptFile := filepath.Join(wordDir, "image_prompt.txt")
-	data, err := os.ReadFile(promptFile)
-	if err != nil {
-		fmt.Printf("No prompt file found at: %s\n", promptFile)
-		return
-	}
-
-	fmt.Printf("Loaded prompt from file: %s\n", promptFile)
-	cf.ImagePrompt = strings.TrimSpace(string(data))
-}
-
-// loadPhoneticFile reads phonetic.txt and stores the info in cf.
-func (cs *CardService) loadPhoneticFile(wordDir string, cf *CardFiles) {
-	phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-	data, err := os.ReadFile(phoneticFile)
-	if err != nil {
-		fmt.Printf("No phonetic file found at: %s (error: %v)\n", phoneticFile, err)
-		return
-	}
-
-	fmt.Printf("Loaded phonetic info from file: %s\n", phoneticFile)
-	cf.PhoneticInfo = string(data)
-}
-
 // loadAudioFiles resolves front and/or back audio paths depending on card type.
 func (cs *CardService) loadAudioFiles(wordDir string, cf *CardFiles) {
 	if cf.CardType.IsBgBg() {
@@ -234,17 +226,10 @@ func (cs *CardService) loadAudioFiles(wordDir string, cf *CardFiles) {
 	}
 }
 
-// loadImageFile finds the first matching image file in the card directory.
-func (cs *CardService) loadImageFile(wordDir string, cf *CardFiles) {
-	for _, pattern := range []string{"image.jpg", "image.png"} {
-		imagePath := filepath.Join(wordDir, pattern)
-		if _, err := os.Stat(imagePath); err == nil {
-			cf.ImageFile = imagePath
-			break
-		}
-	}
-
-	if cf.ImageFile == "" {
+// loadImageFile takes the newest image recorded in the manifest.
+func (cs *CardService) loadImageFile(wordDir string, manifest *store.Manifest, cf *CardFiles) {
+	cf.ImageFile = manifest.AssetPath(wordDir, store.AssetImage)
+	if cf.ImageFile == "" || cf.ImagePrompt != "" {
 		return
 	}
 
@@ -290,6 +275,7 @@ func (cs *CardService) CheckMissingFiles(word string, existingAudio, existingAud
 	}
 
 	result := &CardFiles{}
+	manifest := store.LoadManifest(wordDir)
 
 	// Check for missing audio.
 	if existingAudio == "" {
@@ -307,42 +293,18 @@ func (cs *CardService) CheckMissingFiles(word string, existingAudio, existingAud
 		result.AudioBack = back
 	}
 
-	// Check for missing image.
+	// Check for missing image, translation, prompt and phonetic info.
 	if existingImage == "" {
-		for _, pattern := range []string{"image.jpg", "image.png"} {
-			imagePath := filepath.Join(wordDir, pattern)
-			if _, err := os.Stat(imagePath); err == nil {
-				result.ImageFile = imagePath
-				break
-			}
-		}
+		result.ImageFile = manifest.AssetPath(wordDir, store.AssetImage)
 	}
-
-	// Check for missing translation.
 	if existingTranslation == "" {
-		translationFile := filepath.Join(wordDir, "translation.txt")
-		if data, err := os.ReadFile(translationFile); err == nil {
-			parts := strings.Split(string(data), "=")
-			if len(parts) >= 2 {
-				result.Translation = strings.TrimSpace(parts[1])
-			}
-		}
+		result.Translation = manifest.Translation
 	}
-
-	// Check for missing image prompt.
 	if existingPrompt == "" {
-		promptFile := filepath.Join(wordDir, "image_prompt.txt")
-		if data, err := os.ReadFile(promptFile); err == nil {
-			result.ImagePrompt = strings.TrimSpace(string(data))
-		}
+		result.ImagePrompt = manifest.ImagePrompt
 	}
-
-	// Check for missing phonetic info.
 	if existingPhonetic == "" {
-		phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-		if data, err := os.ReadFile(phoneticFile); err == nil {
-			result.PhoneticInfo = string(data)
-		}
+		result.PhoneticInfo = manifest.IPA
 	}
 
 	return result
@@ -356,15 +318,8 @@ func (cs *CardService) DeleteWord(word string, existingWords []string, savedCard
 		return existingWords, savedCards, fmt.Errorf("no card directory found for word %q", word)
 	}
 
-	trashDir := filepath.Join(cs.config.OutputDir, ".trashbin")
-	if err := os.MkdirAll(trashDir, 0755); err != nil {
-		return existingWords, savedCards, fmt.Errorf("failed to create trash directory: %w", err)
-	}
-
-	dirName := filepath.Base(wordDir)
-	trashWordDir := filepath.Join(trashDir, fmt.Sprintf("%s_%s", dirName, trashTimestamp()))
-	if err := os.Rename(wordDir, trashWordDir); err != nil {
-		return existingWords, savedCards, fmt.Errorf("failed to move files to trash: %w", err)
+	if err := cs.TrashCardDirectory(wordDir); err != nil {
+		return existingWords, savedCards, err
 	}
 
 	// Remove the word from the existingWords list.
@@ -386,21 +341,75 @@ func (cs *CardService) DeleteWord(word string, existingWords []string, savedCard
 	return newWords, newCards, nil
 }
 
-// LoadImagePromptForWord reads the image_prompt.txt file for the given word.
-// Returns empty string if not found.
+// TrashCardDirectory moves a card directory to the trash bin.
+func (cs *CardService) TrashCardDirectory(wordDir string) error {
+	_, err := cs.cardStore.MoveToTrash(wordDir)
+	return err
+}
+
+// ListTrash returns the trashed cards, most recently deleted first.
+func (cs *CardService) ListTrash() ([]store.TrashEntry, error) {
+	return cs.cardStore.ListTrash()
+}
+
+// RestoreFromTrash moves a trashed card back into the output directory and
+// returns its directory. The error wraps store.ErrTrashConflict when the word
+// has been created again in the meantime.
+func (cs *CardService) RestoreFromTrash(entry store.TrashEntry) (string, error) {
+	return cs.cardStore.RestoreFromTrash(entry)
+}
+
+// PurgeTrash permanently deletes trashed cards older than retention and
+// returns how many were removed.
+func (cs *CardService) PurgeTrash(retention time.Duration) (int, error) {
+	purged, err := cs.cardStore.PurgeTrash(time.Now().Add(-retention))
+	return len(purged), err
+}
+
+// FilterExportCards drops the cards of gen that filter does not keep for an
+// export in format to destination, comparing them with the export ledger of
+// the output directory.
+func (cs *CardService) FilterExportCards(gen *anki.Generator, filter anki.ExportFilter, format, destination string) error {
+	return gen.FilterCards(filter, cs.cardStore, format, destination)
+}
+
+// RecordExport adds an export of cards to the export ledger of the output
+// directory.
+func (cs *CardService) RecordExport(export store.Export, cards []anki.Card) error {
+	return anki.RecordExport(cs.cardStore, export, cards)
+}
+
+// LoadImagePromptForWord reads the image prompt from the card manifest for the
+// given word. Returns empty string if not found.
 func (cs *CardService) LoadImagePromptForWord(word string) string {
 	wordDir := cs.FindCardDirectory(word)
 	if wordDir == "" {
 		return ""
 	}
 
-	promptFile := filepath.Join(wordDir, "image_prompt.txt")
-	data, err := os.ReadFile(promptFile)
-	if err != nil {
+	return store.LoadManifest(wordDir).ImagePrompt
+}
+
+// LoadTagsForWord returns the tags of the card for word, or nil.
+func (cs *CardService) LoadTagsForWord(word string) []string {
+	wordDir := cs.FindCardDirectory(word)
+	if wordDir == "" {
+		return nil
+	}
+
+	return store.LoadManifest(wordDir).Tags
+}
+
+// LoadExampleForWord returns the example sentence of the card for word in
+// the form of the example entry, or "".
+func (cs *CardService) LoadExampleForWord(word string) string {
+	wordDir := cs.FindCardDirectory(word)
+	if wordDir == "" {
 		return ""
 	}
 
-	return strings.TrimSpace(string(data))
+	manifest := store.LoadManifest(wordDir)
+	return store.FormatExample(manifest.Example, manifest.ExampleTranslation)
 }
 
 // hasAnyAudioFileInDir is a package-level helper so CardService can check for
@@ -414,32 +423,14 @@ func hasAnyAudioFileInDir(wordDir string) bool {
 	return front != "" || back != ""
 }
 
-// resolveSingleAudioFileInDir resolves the single en-bg audio file from a card dir.
+// resolveSingleAudioFileInDir resolves the single en-bg audio file from a card
+// dir. The newest clip recorded in the card manifest wins; anki.ResolveAudioFile
+// falls back to scanning the directory for unrecorded audio.
 func resolveSingleAudioFileInDir(wordDir string) string {
-	if audioFile := resolveAudioFileFromMetadata(wordDir, "audio_file"); audioFile != "" {
-		return audioFile
-	}
-
 	return anki.ResolveAudioFile(wordDir, "audio", "")
 }
 
 // resolveBgBgAudioFilesInDir resolves front+back audio files for a bg-bg card dir.
 func resolveBgBgAudioFilesInDir(wordDir string) (string, string) {
-	front := resolveAudioFileFromMetadata(wordDir, "audio_file")
-	if front == "" {
-		front = anki.ResolveAudioFile(wordDir, "audio_front", "")
-	}
-
-	back := resolveAudioFileFromMetadata(wordDir, "audio_file_back")
-	if back == "" {
-		back = anki.ResolveAudioFile(wordDir, "audio_back", "")
-	}
-
-	return front, back
-}
-
-// trashTimestamp returns a formatted timestamp string for use in trash
-// directory names, ensuring uniqueness across multiple deletions.
-func trashTimestamp() string {
-	return time.Now().Format("20060102_150405")
+	return anki.ResolveAudioFile(wordDir, "audio_front", ""), anki.ResolveAudioFile(wordDir, "audio_back", "")
 }
@@ -14,10 +14,20 @@ import (
 
 	"codeberg.org/snonux/totalrecall/internal"
 	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/ankiconnect"
 	appconfig "codeberg.org/snonux/totalrecall/internal/config"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
-// ExportHandler owns the Export to Anki dialog and APKG/CSV export paths (SRP).
+// Export formats offered by the Export to Anki dialog.
+const (
+	exportFormatAPKG        = "APKG (Recommended)"
+	exportFormatCSV         = "CSV (Legacy)"
+	exportFormatAnkiConnect = "AnkiConnect (running Anki)"
+)
+
+// ExportHandler owns the Export to Anki dialog and APKG/CSV/AnkiConnect export
+// paths (SRP).
 type ExportHandler struct {
 	app *Application
 }
@@ -31,7 +41,7 @@ func (e *ExportHandler) onExportToAnki() {
 		return
 	}
 
-	formatOptions := []string{"APKG (Recommended)", "CSV (Legacy)"}
+	formatOptions := []string{exportFormatAPKG, exportFormatCSV, exportFormatAnkiConnect}
 	formatSelect := widget.NewSelect(formatOptions, nil)
 	formatSelect.SetSelected(formatOptions[0])
 	deckNameEntry := widget.NewEntry()
@@ -43,11 +53,47 @@ func (e *ExportHandler) onExportToAnki() {
 		e.browseExportDir(&selectedDir, dirLabel)
 	})
 
-	content := e.buildExportDialogContent(formatSelect, deckNameEntry, dirLabel, dirButton)
-	e.showExportDialog(content, formatOptions, formatSelect, deckNameEntry, &selectedDir)
+	filter := newExportFilterWidgets()
+	content := e.buildExportDialogContent(formatSelect, deckNameEntry, dirLabel, dirButton, filter)
+	e.showExportDialog(content, formatSelect, deckNameEntry, &selectedDir, filter)
+}
+
+// exportFilterWidgets are the card selection controls of the export dialog.
+type exportFilterWidgets struct {
+	sinceLastExport *widget.Check
+	changedSince    *widget.Entry
+	tag             *widget.Entry
 }
 
-func (e *ExportHandler) buildExportDialogContent(formatSelect *widget.Select, deckNameEntry *widget.Entry, dirLabel *widget.Label, dirButton *widget.Button) fyne.CanvasObject {
+func newExportFilterWidgets() *exportFilterWidgets {
+	changedSince := widget.NewEntry()
+	changedSince.SetPlaceHolder("Changed since YYYY-MM-DD (optional)")
+	tag := widget.NewEntry()
+	tag.SetPlaceHolder("Only tag (optional)")
+	return &exportFilterWidgets{
+		sinceLastExport: widget.NewCheck("Only new or changed since last export", nil),
+		changedSince:    changedSince,
+		tag:             tag,
+	}
+}
+
+// filter returns the export filter the controls describe.
+func (f *exportFilterWidgets) filter() (anki.ExportFilter, error) {
+	filter := anki.ExportFilter{
+		SinceLastExport: f.sinceLastExport.Checked,
+		Tag:             strings.TrimSpace(f.tag.Text),
+	}
+	if strings.TrimSpace(f.changedSince.Text) != "" {
+		since, err := anki.ParseExportDate(f.changedSince.Text)
+		if err != nil {
+			return anki.ExportFilter{}, err
+		}
+		filter.ChangedSince = since
+	}
+	return filter, nil
+}
+
+func (e *ExportHandler) buildExportDialogContent(formatSelect *widget.Select, deckNameEntry *widget.Entry, dirLabel *widget.Label, dirButton *widget.Button, filter *exportFilterWidgets) fyne.CanvasObject {
 	return container.NewVBox(
 		widget.NewLabel("Export Format:"),
 		formatSelect,
@@ -57,12 +103,17 @@ func (e *ExportHandler) buildExportDialogContent(formatSelect *widget.Select, de
 		widget.NewSeparator(),
 		widget.NewLabel("Export Directory:"),
 		container.NewBorder(nil, nil, nil, dirButton, dirLabel),
+		widget.NewSeparator(),
+		widget.NewLabel("Cards:"),
+		filter.sinceLastExport,
+		filter.changedSince,
+		filter.tag,
 		widget.NewLabel(""),
-		widget.NewRichTextFromMarkdown("**APKG**: Complete package with media files included\n**CSV**: Text only, requires manual media copy"),
+		widget.NewRichTextFromMarkdown("**APKG**: Complete package with media files included\n**CSV**: Text only, requires manual media copy\n**AnkiConnect**: Adds the cards to the running Anki (needs the AnkiConnect add-on)"),
 	)
 }
 
-func (e *ExportHandler) showExportDialog(content fyne.CanvasObject, formatOptions []string, formatSelect *widget.Select, deckNameEntry *widget.Entry, selectedDir *string) {
+func (e *ExportHandler) showExportDialog(content fyne.CanvasObject, formatSelect *widget.Select, deckNameEntry *widget.Entry, selectedDir *string, filter *exportFilterWidgets) {
 	a := e.app
 	exportDialogOpen := true
 
@@ -75,11 +126,16 @@ func (e *ExportHandler) showExportDialog(content fyne.CanvasObject, formatOption
 		if deckName == "" {
 			deckName = "Bulgarian Vocabulary"
 		}
-		e.performExport(formatSelect.Selected == formatOptions[0], deckName, *selectedDir)
+		exportFilter, err := filter.filter()
+		if err != nil {
+			dialog.ShowError(err, a.window)
+			return
+		}
+		e.performExport(formatSelect.Selected, deckName, *selectedDir, exportFilter)
 	}, a.window)
 
 	e.wireExportDialogKeys(customDialog, &exportDialogOpen)
-	customDialog.Resize(fyne.NewSize(400, 300))
+	customDialog.Resize(fyne.NewSize(400, 420))
 	customDialog.Show()
 }
 
@@ -163,50 +219,167 @@ func (e *ExportHandler) browseExportDir(dir *string, label *widget.Label) {
 	folderDialog.Show()
 }
 
-func (e *ExportHandler) performExport(isAPKG bool, deckName, outputDir string) {
-	if isAPKG {
-		e.exportAPKG(deckName, outputDir)
-	} else {
-		e.exportCSV(outputDir)
+func (e *ExportHandler) performExport(format, deckName, outputDir string, filter anki.ExportFilter) {
+	switch format {
+	case exportFormatCSV:
+		e.exportCSV(deckName, outputDir, filter)
+	case exportFormatAnkiConnect:
+		e.exportAnkiConnect(deckName, filter)
+	default:
+		e.exportAPKG(deckName, outputDir, filter)
+	}
+}
+
+// loadExportCards fills gen with the cards of the output directory that
+// filter keeps for an export in format to destination. It returns false after
+// telling the user when there is nothing to export.
+func (e *ExportHandler) loadExportCards(gen *anki.Generator, filter anki.ExportFilter, format, destination string) bool {
+	a := e.app
+	if err := gen.GenerateFromDirectory(a.config.OutputDir); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to load cards: %w", err), a.window)
+		return false
+	}
+	if err := a.getCardService().FilterExportCards(gen, filter, format, destination); err != nil {
+		dialog.ShowError(fmt.Errorf("failed to select cards: %w", err), a.window)
+		return false
+	}
+	if len(gen.GetCards()) == 0 {
+		dialog.ShowInformation("Nothing to Export", fmt.Sprintf("No cards match the selection (%s).", filter), a.window)
+		return false
+	}
+	return true
+}
+
+// recordExport adds a finished export to the export ledger. file is the
+// written file, empty for AnkiConnect. The export is done at this point, so a
+// ledger that cannot be written is only reported.
+func (e *ExportHandler) recordExport(format, destination, file, deckName string, filter anki.ExportFilter, cards []anki.Card) {
+	export := store.Export{File: file, Format: format, Destination: destination, Deck: deckName, Filter: filter.String()}
+	if err := e.app.getCardService().RecordExport(export, cards); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 	}
 }
 
-func (e *ExportHandler) exportAPKG(deckName, outputDir string) {
+func (e *ExportHandler) exportAPKG(deckName, outputDir string, filter anki.ExportFilter) {
 	a := e.app
 	filename := fmt.Sprintf("%s.apkg", internal.SanitizeFilename(deckName))
 	outputPath := filepath.Join(outputDir, filename)
 
-	gen := anki.NewGenerator(nil)
-	if err := gen.GenerateFromDirectory(a.config.OutputDir); err != nil {
-		dialog.ShowError(fmt.Errorf("failed to load cards: %w", err), a.window)
+	options := anki.DefaultGeneratorOptions()
+	options.DeckOptions = a.config.DeckOptions
+	options.CardDirections = a.config.CardDirections
+	options.PackageFormat = a.config.PackageFormat
+	options.DeckGrouping = a.config.DeckGrouping
+	gen := anki.NewGenerator(options)
+	if !e.loadExportCards(gen, filter, anki.ExportFormatAPKG, outputPath) {
 		return
 	}
 	if err := gen.GenerateAPKG(outputPath, deckName); err != nil {
 		dialog.ShowError(fmt.Errorf("failed to generate APKG: %w", err), a.window)
 		return
 	}
+	e.recordExport(anki.ExportFormatAPKG, outputPath, gen.OutputFile(), deckName, filter, gen.GetCards())
 	total, withAudio, withImages := gen.Stats()
 	a.updateStatus(fmt.Sprintf("Exported %d cards to %s (%d with audio, %d with images)", total, outputDir, withAudio, withImages))
 }
 
-func (e *ExportHandler) exportCSV(outputDir string) {
+func (e *ExportHandler) exportCSV(deckName, outputDir string, filter anki.ExportFilter) {
 	a := e.app
-	outputPath := filepath.Join(outputDir, "anki_import.csv")
+	outputPath := filepath.Join(outputDir, anki.CSVBundleDir, anki.CSVFileName)
 
 	gen := anki.NewGenerator(&anki.GeneratorOptions{
 		OutputPath:     outputPath,
 		MediaFolder:    a.config.OutputDir,
 		IncludeHeaders: true,
 		AudioFormat:    a.config.AudioFormat,
+		CardDirections: a.config.CardDirections,
+		DeckGrouping:   a.config.DeckGrouping,
 	})
-	if err := gen.GenerateFromDirectory(a.config.OutputDir); err != nil {
-		dialog.ShowError(fmt.Errorf("failed to load cards: %w", err), a.window)
+	if !e.loadExportCards(gen, filter, anki.ExportFormatCSV, outputPath) {
 		return
 	}
-	if err := gen.GenerateCSV(); err != nil {
+	if err := gen.GenerateCSV(deckName); err != nil {
 		dialog.ShowError(fmt.Errorf("failed to generate CSV: %w", err), a.window)
 		return
 	}
+	e.recordExport(anki.ExportFormatCSV, outputPath, gen.OutputFile(), deckName, filter, gen.GetCards())
 	total, withAudio, withImages := gen.Stats()
-	a.updateStatus(fmt.Sprintf("Exported %d cards to %s (%d with audio, %d with images)", total, outputDir, withAudio, withImages))
+	a.updateStatus(fmt.Sprintf("Exported %d cards to %s (%d with audio, %d with images)", total, filepath.Dir(outputPath), withAudio, withImages))
+}
+
+// exportAnkiConnect adds the cards to deckName in the running Anki. The
+// requests run in a tracked goroutine because media uploads can take a while;
+// the per-card results are shown in a dialog afterwards.
+func (e *ExportHandler) exportAnkiConnect(deckName string, filter anki.ExportFilter) {
+	a := e.app
+	gen := anki.NewGenerator(&anki.GeneratorOptions{AudioFormat: a.config.AudioFormat})
+	if !e.loadExportCards(gen, filter, anki.ExportFormatAnkiConnect, deckName) {
+		return
+	}
+	client := ankiconnect.NewClient(a.config.AnkiConnectURL)
+	a.updateStatus(fmt.Sprintf("Adding cards to Anki via AnkiConnect at %s...", client.URL()))
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		results, err := e.pushToAnkiConnect(client, deckName, gen.GetCards())
+		if err == nil {
+			// Results come in card order; failed cards stay due for the
+			// next incremental export.
+			var exported []anki.Card
+			for i, result := range results {
+				if result.Err == nil {
+					exported = append(exported, gen.GetCards()[i])
+				}
+			}
+			if len(exported) > 0 {
+				e.recordExport(anki.ExportFormatAnkiConnect, deckName, "", deckName, filter, exported)
+			}
+		}
+		if a.ctx.Err() != nil {
+			return
+		}
+		fyne.Do(func() {
+			if err != nil {
+				a.updateStatus("AnkiConnect export failed")
+				dialog.ShowError(err, a.window)
+				return
+			}
+			summary := ankiconnect.Summary(results)
+			a.updateStatus("AnkiConnect: " + summary)
+			e.showAnkiConnectResults(summary, results)
+		})
+	}()
+}
+
+func (e *ExportHandler) pushToAnkiConnect(client *ankiconnect.Client, deckName string, cards []anki.Card) ([]ankiconnect.Result, error) {
+	a := e.app
+	exporter, err := ankiconnect.NewExporter(client, deckName)
+	if err != nil {
+		return nil, err
+	}
+	exporter.SetCardDirections(a.config.CardDirections)
+	exporter.SetDeckGrouping(a.config.DeckGrouping)
+	return exporter.Export(a.ctx, cards)
+}
+
+// showAnkiConnectResults lists the outcome of every card, failures first.
+func (e *ExportHandler) showAnkiConnectResults(summary string, results []ankiconnect.Result) {
+	var failed, done []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s: %v", result.Status, result.Word, result.Err))
+			continue
+		}
+		done = append(done, fmt.Sprintf("%s %s", result.Status, result.Word))
+	}
+
+	label := widget.NewLabel(strings.Join(append(failed, done...), "\n"))
+	label.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(label)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+
+	content := container.NewBorder(widget.NewLabel(summary), nil, nil, nil, scroll)
+	dialog.ShowCustom("AnkiConnect Export", "Close", content, e.app.window)
 }
@@ -6,11 +6,14 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"codeberg.org/snonux/totalrecall/internal/anki"
 	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/image"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 type fakePromptAwareImageClient struct {
@@ -248,7 +251,7 @@ func TestGenerateAudioUsesSharedOpenAIVoices(t *testing.T) {
 		t.Fatalf("expected attribution file %q: %v", attrPath, err)
 	}
 	attribution := string(attributionData)
-	if !strings.Contains(attribution, "Processed text sent to TTS: ябълка...") {
+	if !strings.Contains(attribution, "Processed text sent to TTS: ябълка\n") {
 		t.Fatalf("openai attribution missing processed text: %q", attribution)
 	}
 }
@@ -325,7 +328,7 @@ func TestGenerateAudioUsesRandomGeminiVoiceAndAttribution(t *testing.T) {
 	if !strings.Contains(attribution, "sentinel-gemini-voice") {
 		t.Fatalf("gemini attribution should use the selected random Gemini voice: %q", attribution)
 	}
-	if !strings.Contains(attribution, "Processed text sent to TTS: ябълка...") {
+	if !strings.Contains(attribution, "Processed text sent to TTS: ябълка\n") {
 		t.Fatalf("gemini attribution missing processed text: %q", attribution)
 	}
 
@@ -482,8 +485,8 @@ func TestGenerateAudioBgBgUsesSharedOpenAIVoices(t *testing.T) {
 		audioPath string
 		wantText  string
 	}{
-		{audioPath: frontPath, wantText: "ябълка..."},
-		{audioPath: backPath, wantText: "круша..."},
+		{audioPath: frontPath, wantText: "ябълка\n"},
+		{audioPath: backPath, wantText: "круша\n"},
 	} {
 		attrPath := audio.AttributionPath(tc.audioPath)
 		attributionData, err := os.ReadFile(attrPath)
@@ -681,3 +684,228 @@ func TestGenerateAudioProviderFactoryError(t *testing.T) {
 		t.Fatalf("generateAudioFront() error = %q, want it to contain %q", err.Error(), "provider factory failed")
 	}
 }
+
+func TestGenerateAudioBgBgFailsOverToTheNextProvider(t *testing.T) {
+	originalVoices := append([]string(nil), audio.OpenAIVoices...)
+	t.Cleanup(func() {
+		audio.OpenAIVoices = originalVoices
+	})
+
+	audio.OpenAIVoices = []string{"sentinel-fallback-voice"}
+
+	fakeProvider := &fakeAudioProvider{}
+	tempDir := t.TempDir()
+	cardDir := filepath.Join(tempDir, "card")
+	if err := os.MkdirAll(cardDir, 0755); err != nil {
+		t.Fatalf("failed to create card dir: %v", err)
+	}
+
+	app := &Application{
+		config: &Config{
+			OutputDir:     tempDir,
+			AudioFormat:   "mp3",
+			AudioFallback: []string{"openai"},
+		},
+		audioConfig: &audio.Config{
+			Provider:       "gemini",
+			OutputDir:      tempDir,
+			GeminiVoice:    "Kore",
+			OpenAIModel:    "gpt-4o-mini-tts",
+			GeminiTTSModel: "gemini-2.5-flash-preview-tts",
+		},
+	}
+	var providers []string
+	app.newAudioProvider = func(config *audio.Config) (audio.Provider, error) {
+		providers = append(providers, config.Provider)
+		if config.Provider == "gemini" {
+			return nil, errors.New("gemini API error: Error 429, Message: Resource has been exhausted (e.g. check quota)., Status: RESOURCE_EXHAUSTED")
+		}
+		return fakeProvider, nil
+	}
+
+	frontPath, _, err := app.generateAudioBgBg(context.Background(), "ябълка", "круша", cardDir)
+	if err != nil {
+		t.Fatalf("generateAudioBgBg() unexpected error: %v", err)
+	}
+	if strings.Join(providers, ",") != "gemini,openai,openai" {
+		t.Fatalf("providers = %v, want gemini then openai for both sides", providers)
+	}
+	if fakeProvider.generateCalls != 2 {
+		t.Fatalf("GenerateAudio() calls = %d, want %d", fakeProvider.generateCalls, 2)
+	}
+
+	metadataData, err := os.ReadFile(filepath.Join(cardDir, "audio_metadata.txt"))
+	if err != nil {
+		t.Fatalf("expected metadata file: %v", err)
+	}
+	metadata := string(metadataData)
+	for _, want := range []string{"provider=openai", "failover_from=gemini (quota exceeded)", "voice=sentinel-fallback-voice"} {
+		if !strings.Contains(metadata, want) {
+			t.Fatalf("metadata = %q, missing %q", metadata, want)
+		}
+	}
+	attributionData, err := os.ReadFile(audio.AttributionPath(frontPath))
+	if err != nil {
+		t.Fatalf("expected attribution file: %v", err)
+	}
+	if !strings.Contains(string(attributionData), "Fallback after: gemini (quota exceeded)") {
+		t.Fatalf("attribution = %q, want the failed provider", attributionData)
+	}
+}
+
+func TestGenerateAudioBgBgReusesCachedClips(t *testing.T) {
+	tempDir := t.TempDir()
+	fakeProvider := &fakeAudioProvider{generateFunc: func(text, outputFile string) error {
+		return os.WriteFile(outputFile, []byte(text), 0644)
+	}}
+	app := &Application{
+		config: &Config{
+			OutputDir:   tempDir,
+			AudioFormat: "mp3",
+			AudioCache:  audio.NewCache(filepath.Join(tempDir, "tts-cache"), 1<<20),
+		},
+		audioConfig: &audio.Config{
+			Provider:       "gemini",
+			OutputDir:      tempDir,
+			GeminiVoice:    "Kore",
+			GeminiTTSModel: "gemini-2.5-flash-preview-tts",
+		},
+		newAudioProvider: func(*audio.Config) (audio.Provider, error) {
+			return fakeProvider, nil
+		},
+	}
+	generate := func(card, front, back string) string {
+		t.Helper()
+		cardDir := filepath.Join(tempDir, card)
+		if err := os.MkdirAll(cardDir, 0755); err != nil {
+			t.Fatalf("failed to create card dir: %v", err)
+		}
+		if _, _, err := app.generateAudioBgBg(context.Background(), front, back, cardDir); err != nil {
+			t.Fatalf("generateAudioBgBg() unexpected error: %v", err)
+		}
+		metadata, err := os.ReadFile(filepath.Join(cardDir, "audio_metadata.txt"))
+		if err != nil {
+			t.Fatalf("expected metadata file: %v", err)
+		}
+		return string(metadata)
+	}
+
+	if metadata := generate("first", "ябълка", "круша"); fakeProvider.generateCalls != 2 || strings.Contains(metadata, "cache=hit") {
+		t.Fatalf("first card: %d generate calls, metadata %q; want fresh takes", fakeProvider.generateCalls, metadata)
+	}
+	if metadata := generate("second", "ябълка", "круша"); fakeProvider.generateCalls != 2 || !strings.Contains(metadata, "cache=hit") {
+		t.Fatalf("second card: %d generate calls, metadata %q; want the cached clips", fakeProvider.generateCalls, metadata)
+	}
+	if data, err := os.ReadFile(filepath.Join(tempDir, "second", "audio_back.mp3")); err != nil || string(data) != "круша" {
+		t.Fatalf("cached back audio = %q, %v", data, err)
+	}
+	// Only one cached side is not a cache hit of the card.
+	if metadata := generate("third", "ябълка", "слива"); fakeProvider.generateCalls != 3 || strings.Contains(metadata, "cache=hit") {
+		t.Fatalf("third card: %d generate calls, metadata %q; want a fresh back", fakeProvider.generateCalls, metadata)
+	}
+}
+
+func TestGenerateAudioRendersSlowVariant(t *testing.T) {
+	tempDir := t.TempDir()
+	speeds := make(map[string]float64)
+	app := &Application{
+		config: &Config{
+			OutputDir:      tempDir,
+			AudioFormat:    "mp3",
+			SlowAudioSpeed: 0.7,
+		},
+		audioConfig: &audio.Config{
+			Provider:       "gemini",
+			OutputDir:      tempDir,
+			GeminiVoice:    "Kore",
+			GeminiSpeed:    1.0,
+			GeminiTTSModel: "gemini-2.5-flash-preview-tts",
+		},
+		newAudioProvider: func(config *audio.Config) (audio.Provider, error) {
+			return &fakeAudioProvider{generateFunc: func(text, outputFile string) error {
+				speeds[filepath.Base(outputFile)] = config.GeminiSpeed
+				return os.WriteFile(outputFile, []byte(text), 0644)
+			}}, nil
+		},
+	}
+
+	audioFile, err := app.generateAudio(context.Background(), "ябълка", tempDir)
+	if err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+	if want := map[string]float64{"audio.mp3": 1.0, "audio_slow.mp3": 0.7}; !reflect.DeepEqual(speeds, want) {
+		t.Fatalf("generated clips at speeds %v; want %v", speeds, want)
+	}
+
+	slowFile := anki.ResolveAudioFile(tempDir, "audio_slow", "mp3")
+	if slowFile != audio.SlowAudioPath(audioFile) {
+		t.Fatalf("resolved slow audio = %q; want %q", slowFile, audio.SlowAudioPath(audioFile))
+	}
+	attribution, err := os.ReadFile(audio.AttributionPath(slowFile))
+	if err != nil || !strings.Contains(string(attribution), "syllable by syllable") {
+		t.Fatalf("slow attribution = %q, %v; want the slow Gemini prompt", attribution, err)
+	}
+	metadata, err := os.ReadFile(filepath.Join(tempDir, "audio_metadata.txt"))
+	if err != nil || !strings.Contains(string(metadata), "speed=1.00") {
+		t.Fatalf("metadata = %q, %v; want the normal-speed clip", metadata, err)
+	}
+}
+
+func TestGenerateAudioRecordsCachedSlowVariant(t *testing.T) {
+	tempDir := t.TempDir()
+	fakeProvider := &fakeAudioProvider{generateFunc: func(text, outputFile string) error {
+		return os.WriteFile(outputFile, []byte(text), 0644)
+	}}
+	app := &Application{
+		config: &Config{
+			OutputDir:      tempDir,
+			AudioFormat:    "mp3",
+			SlowAudioSpeed: 0.7,
+			AudioCache:     audio.NewCache(filepath.Join(tempDir, "tts-cache"), 1<<20),
+		},
+		audioConfig: &audio.Config{
+			Provider:       "gemini",
+			OutputDir:      tempDir,
+			GeminiVoice:    "Kore",
+			GeminiSpeed:    1.0,
+			GeminiTTSModel: "gemini-2.5-flash-preview-tts",
+		},
+		newAudioProvider: func(*audio.Config) (audio.Provider, error) {
+			return fakeProvider, nil
+		},
+	}
+	// generate renders the audio of a new card and returns the manifest
+	// entry and the attribution of its slow clip.
+	generate := func(card string) (store.Asset, string) {
+		t.Helper()
+		cardDir := filepath.Join(tempDir, card)
+		if err := os.MkdirAll(cardDir, 0755); err != nil {
+			t.Fatalf("failed to create card dir: %v", err)
+		}
+		audioFile, err := app.generateAudio(context.Background(), "ябълка", cardDir)
+		if err != nil {
+			t.Fatalf("generateAudio() unexpected error: %v", err)
+		}
+		slowFile := audio.SlowAudioPath(audioFile)
+		attribution, err := os.ReadFile(audio.AttributionPath(slowFile))
+		if err != nil {
+			t.Fatalf("expected slow attribution: %v", err)
+		}
+		for _, asset := range store.LoadManifest(cardDir).Assets {
+			if asset.File == filepath.Base(slowFile) {
+				return asset, string(attribution)
+			}
+		}
+		t.Fatalf("slow clip %s missing from the card manifest", filepath.Base(slowFile))
+		return store.Asset{}, ""
+	}
+
+	asset, attribution := generate("first")
+	if fakeProvider.generateCalls != 2 || asset.Cached || strings.Contains(attribution, "TTS cache") {
+		t.Fatalf("first card: %d generate calls, slow clip %+v, attribution %q; want fresh clips", fakeProvider.generateCalls, asset, attribution)
+	}
+	asset, attribution = generate("second")
+	if fakeProvider.generateCalls != 2 || !asset.Cached || !strings.Contains(attribution, "Copied from the TTS cache") {
+		t.Fatalf("second card: %d generate calls, slow clip %+v, attribution %q; want the cached slow clip", fakeProvider.generateCalls, asset, attribution)
+	}
+}
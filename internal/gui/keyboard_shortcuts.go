@@ -26,6 +26,7 @@ const hotkeysMarkdown = `[Project Page: https://codeberg.org/snonux/totalrecall]
 ## Focus Fields
 **b/б** Focus Bulgarian input
 **e/е** Focus English input
+**k/к** Focus tags
 **o/о** Focus image prompt
 
 ## Word Processing
@@ -40,14 +41,21 @@ const hotkeysMarkdown = `[Project Page: https://codeberg.org/snonux/totalrecall]
 **A/А** Regenerate back audio (bg-bg only)
 **r/р** Regenerate all
 
+## Revisions
+**[/ш** Restore previous revision
+**]/щ** Restore next revision
+
 ## Playback
 **p/п** Play front audio (or audio for en-bg)
 **P/П** Play back audio (bg-bg only)
+**s/с** Play slow front audio (or slow audio for en-bg)
+**S/С** Play slow back audio (bg-bg only)
 **u/у** Toggle auto-play
 
 ## Export & Archive
 **x/ж** Export to Anki
 **v/в** Archive all cards
+**t/т** Trash bin (restore or purge deleted cards)
 
 ## Help
 **?** Show hotkeys
@@ -131,7 +139,7 @@ func (ks *KeyboardShortcuts) setupKeyboardShortcuts() {
 func (ks *KeyboardShortcuts) handleTypedRune(r rune) {
 	a := ks.app
 	focused := a.window.Canvas().Focused()
-	isInputFocused := focused == a.wordInput || focused == a.imagePromptEntry || focused == a.translationEntry
+	isInputFocused := focused == a.wordInput || focused == a.imagePromptEntry || focused == a.translationEntry || focused == a.tagsEntry || focused == a.exampleEntry
 	if isInputFocused || a.deleteConfirming || a.quitConfirming {
 		return
 	}
@@ -141,6 +149,8 @@ func (ks *KeyboardShortcuts) handleTypedRune(r rune) {
 		a.window.Canvas().Focus(a.wordInput)
 	case 'e', 'E', 'е', 'Е':
 		a.window.Canvas().Focus(a.translationEntry)
+	case 'k', 'K', 'к', 'К':
+		a.window.Canvas().Focus(a.tagsEntry)
 	case 'o', 'O', 'о', 'О':
 		a.window.Canvas().Focus(a.imagePromptEntry)
 	case 'г', 'Г':
@@ -183,10 +193,20 @@ func (ks *KeyboardShortcuts) handleTypedRune(r rune) {
 		if a.currentAudioFileBack != "" {
 			a.audioPlayer.PlayBack()
 		}
+	case 's', 'с':
+		if a.currentAudioFile != "" {
+			a.audioPlayer.PlaySlow()
+		}
+	case 'S', 'С':
+		if a.currentAudioFileBack != "" {
+			a.audioPlayer.PlayBackSlow()
+		}
 	case 'ж', 'Ж':
 		a.export.onExportToAnki()
 	case 'в', 'В':
 		a.onArchive()
+	case 'т', 'Т':
+		a.onShowTrash()
 	case '?':
 		ks.onShowHotkeys()
 	case 'h', 'H', 'х', 'Х':
@@ -201,6 +221,14 @@ func (ks *KeyboardShortcuts) handleTypedRune(r rune) {
 		a.onQuitConfirm()
 	case 'u', 'U', 'у', 'У':
 		a.toggleAutoPlay()
+	case '[', 'ш', 'Ш':
+		if !a.prevRevisionBtn.Disabled() {
+			a.onPrevRevision()
+		}
+	case ']', 'щ', 'Щ':
+		if !a.nextRevisionBtn.Disabled() {
+			a.onNextRevision()
+		}
 	}
 }
 
@@ -210,7 +238,7 @@ func (ks *KeyboardShortcuts) handleTypedRune(r rune) {
 func (ks *KeyboardShortcuts) handleTypedKey(ev *fyne.KeyEvent) {
 	a := ks.app
 	focused := a.window.Canvas().Focused()
-	isInputFocused := focused == a.wordInput || focused == a.imagePromptEntry || focused == a.translationEntry
+	isInputFocused := focused == a.wordInput || focused == a.imagePromptEntry || focused == a.translationEntry || focused == a.tagsEntry || focused == a.exampleEntry
 
 	if ev.Name == fyne.KeyEscape {
 		a.window.Canvas().Unfocus()
@@ -228,7 +256,7 @@ func (ks *KeyboardShortcuts) handleTypedKey(ev *fyne.KeyEvent) {
 		return
 	}
 
-	if ev.Name == fyne.KeyB || ev.Name == fyne.KeyE || ev.Name == fyne.KeyO {
+	if ev.Name == fyne.KeyB || ev.Name == fyne.KeyE || ev.Name == fyne.KeyK || ev.Name == fyne.KeyO {
 		return
 	}
 
@@ -244,6 +272,10 @@ func (ks *KeyboardShortcuts) handleTabNavigation() {
 	case a.wordInput:
 		a.window.Canvas().Focus(a.translationEntry)
 	case a.translationEntry:
+		a.window.Canvas().Focus(a.tagsEntry)
+	case a.tagsEntry:
+		a.window.Canvas().Focus(a.exampleEntry)
+	case a.exampleEntry:
 		a.window.Canvas().Focus(a.imagePromptEntry)
 	case a.imagePromptEntry:
 		a.window.Canvas().Focus(a.wordInput)
@@ -316,6 +348,9 @@ func (ks *KeyboardShortcuts) handleShortcutKey(key fyne.KeyName) {
 	case fyne.KeyV:
 		a.onArchive()
 
+	case fyne.KeyT:
+		a.onShowTrash()
+
 	case fyne.KeyQ:
 		a.onQuitConfirm()
 	}
@@ -2,9 +2,8 @@ package gui
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -217,6 +216,8 @@ func (n *NavigationHandler) applyQueueJobToState(job *WordJob) {
 		if prompt := a.getCardService().LoadImagePromptForWord(job.Word); prompt != "" {
 			a.imagePromptEntry.SetText(prompt)
 		}
+		a.tagsEntry.SetText(strings.Join(a.getCardService().LoadTagsForWord(job.Word), " "))
+		a.exampleEntry.SetText(a.getCardService().LoadExampleForWord(job.Word))
 
 		a.updateStatus(fmt.Sprintf("Loaded from queue: %s", job.Word))
 	})
@@ -284,10 +285,13 @@ func (n *NavigationHandler) loadExistingFiles(word string) {
 		if cf.ImagePrompt != "" {
 			a.imagePromptEntry.SetText(cf.ImagePrompt)
 		}
+		a.tagsEntry.SetText(strings.Join(cf.Tags, " "))
+		a.exampleEntry.SetText(cf.Example)
 		if cf.PhoneticInfo != "" {
 			a.audioPlayer.SetPhonetic(cf.PhoneticInfo)
 		}
 		a.updateStatus(fmt.Sprintf("Loaded: %s", word))
+		a.refreshRevisionControls()
 	})
 }
 
@@ -495,8 +499,6 @@ func (n *NavigationHandler) deleteCurrentWord() {
 		return
 	}
 
-	trashDir := filepath.Join(a.config.OutputDir, ".trashbin")
-
 	a.mu.Lock()
 	a.savedCards = newCards
 	a.mu.Unlock()
@@ -548,9 +550,7 @@ func (n *NavigationHandler) deleteCurrentWord() {
 
 		recreatedDir := n.findCardDirectory(deletedWord)
 		if recreatedDir != "" {
-			ts := time.Now().Format("20060102_150405")
-			dest := filepath.Join(trashDir, fmt.Sprintf("%s_%s_cleanup", filepath.Base(recreatedDir), ts))
-			if err := os.Rename(recreatedDir, dest); err == nil {
+			if err := a.getCardService().TrashCardDirectory(recreatedDir); err == nil {
 				fmt.Printf("Cleanup: moved recreated directory for '%s' to trash\n", deletedWord)
 			}
 		}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"fyne.io/fyne/v2"
 
@@ -14,6 +13,7 @@ import (
 	"codeberg.org/snonux/totalrecall/internal/image"
 	"codeberg.org/snonux/totalrecall/internal/phonetic"
 	"codeberg.org/snonux/totalrecall/internal/registry"
+	"codeberg.org/snonux/totalrecall/internal/store"
 	"codeberg.org/snonux/totalrecall/internal/translation"
 )
 
@@ -93,17 +93,40 @@ func (o *GenerationOrchestrator) audioOutputFormat() string {
 	return o.audioResolver.OutputFormat()
 }
 
+// audioVoice is the provider settings, voice and speed an audio clip is
+// generated with. failoverFrom lists the providers of the failover chain that
+// failed before; it is empty for the configured provider. cacheHit records
+// that the clips were copied from the TTS cache.
+type audioVoice struct {
+	resolver     *AudioConfigResolver
+	voice        string
+	speed        float64
+	failoverFrom string
+	cacheHit     bool
+}
+
+// config returns the audio.Config of the clip.
+func (v audioVoice) config() audio.Config {
+	audioConfig := v.resolver.ConfigForGeneration(v.voice, v.speed)
+	audioConfig.FailoverFrom = v.failoverFrom
+	audioConfig.CacheHit = v.cacheHit
+	return audioConfig
+}
+
 // generateAudioFile generates a single audio file for text using the given
-// voice and speed. It is the lowest-level generation call.
+// voice and speed of the configured provider.
 func (o *GenerationOrchestrator) generateAudioFile(ctx context.Context, text, outputFile, voice string, speed float64) error {
-	audioConfig := o.audioResolver.ConfigForGeneration(voice, speed)
-
-	provider, err := o.newAudioProvider(&audioConfig)
-	if err != nil {
-		return err
-	}
+	_, err := o.renderClip(ctx, text, outputFile, audioVoice{resolver: o.audioResolver, voice: voice, speed: speed}, 0)
+	return err
+}
 
-	return provider.GenerateAudio(ctx, text, outputFile)
+// renderClip renders text to outputFile with the provider, voice and speed
+// of v through audio.RenderClip, followed by the slow variant when slowSpeed
+// is above 0, and reports whether the clip came from the TTS cache. It is the
+// lowest-level generation call.
+func (o *GenerationOrchestrator) renderClip(ctx context.Context, text, outputFile string, v audioVoice, slowSpeed float64) (bool, error) {
+	audioConfig := v.config()
+	return audio.RenderClip(ctx, v.resolver.Cache(), o.newAudioProvider, &audioConfig, text, outputFile, slowSpeed)
 }
 
 // --- Audio generation public methods ---
@@ -122,26 +145,21 @@ func (o *GenerationOrchestrator) GenerateAudio(ctx context.Context, word, cardDi
 		isRegeneration = true
 	}
 
-	voice, speed := o.voiceSelector.VoiceAndSpeed()
-
-	if isRegeneration {
-		fmt.Printf("Regenerating audio for '%s' with voice: %s, speed: %.2f\n", word, voice, speed)
-	} else {
-		fmt.Printf("Generating audio for '%s' with voice: %s, speed: %.2f\n", word, voice, speed)
-	}
-
-	finalVoice, err := o.runAudioWithFallbacks(ctx, word, audioFile, voice, speed)
+	used, err := o.runAudioWithFailover(func(v *audioVoice) error {
+		if isRegeneration {
+			fmt.Printf("Regenerating audio for '%s' with voice: %s, speed: %.2f\n", word, v.voice, v.speed)
+		} else {
+			fmt.Printf("Generating audio for '%s' with voice: %s, speed: %.2f\n", word, v.voice, v.speed)
+		}
+		var err error
+		v.cacheHit, err = o.renderClip(ctx, word, audioFile, *v, o.config.SlowAudioSpeed)
+		return err
+	})
 	if err != nil {
 		return "", err
 	}
 
-	audioCfg := o.audioResolver.ConfigForGeneration(finalVoice, speed)
-
-	if err := o.saveAudioAttribution(word, audioFile, finalVoice, speed); err != nil {
-		fmt.Printf("Warning: Failed to save audio attribution: %v\n", err)
-	}
-
-	if err := o.saveAudioMetadata(cardDir, audioCfg, finalVoice, speed, "en-bg", audioFile, ""); err != nil {
+	if err := o.saveAudioMetadata(cardDir, used.config(), used.voice, used.speed, "en-bg", audioFile, ""); err != nil {
 		fmt.Printf("Warning: Failed to save audio metadata: %v\n", err)
 	}
 
@@ -154,24 +172,21 @@ func (o *GenerationOrchestrator) GenerateAudioFront(ctx context.Context, word, c
 		return "", fmt.Errorf("card directory not provided")
 	}
 
-	voice, speed := o.voiceSelector.VoiceAndSpeed()
-	fmt.Printf("Generating front audio for '%s' with voice: %s, speed: %.2f\n", word, voice, speed)
 	frontFile := filepath.Join(cardDir, fmt.Sprintf("audio_front.%s", o.audioOutputFormat()))
 
-	finalVoice, err := o.runAudioWithFallbacks(ctx, word, frontFile, voice, speed)
+	used, err := o.runAudioWithFailover(func(v *audioVoice) error {
+		fmt.Printf("Generating front audio for '%s' with voice: %s, speed: %.2f\n", word, v.voice, v.speed)
+		var err error
+		v.cacheHit, err = o.renderClip(ctx, word, frontFile, *v, o.config.SlowAudioSpeed)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate front audio: %w", err)
 	}
 
-	audioCfg := o.audioResolver.ConfigForGeneration(finalVoice, speed)
-
-	if err := o.saveAudioAttribution(word, frontFile, finalVoice, speed); err != nil {
-		fmt.Printf("Warning: Failed to save audio attribution: %v\n", err)
-	}
-
 	// Resolve the existing back audio path to keep the metadata complete.
 	_, existingBack := resolveBgBgAudioFilesInDir(cardDir)
-	if err := o.saveAudioMetadata(cardDir, audioCfg, finalVoice, speed, "bg-bg", frontFile, existingBack); err != nil {
+	if err := o.saveAudioMetadata(cardDir, used.config(), used.voice, used.speed, "bg-bg", frontFile, existingBack); err != nil {
 		fmt.Printf("Warning: Failed to save audio metadata: %v\n", err)
 	}
 
@@ -184,24 +199,21 @@ func (o *GenerationOrchestrator) GenerateAudioBack(ctx context.Context, text, ca
 		return "", fmt.Errorf("card directory not provided")
 	}
 
-	voice, speed := o.voiceSelector.VoiceAndSpeed()
-	fmt.Printf("Generating back audio for '%s' with voice: %s, speed: %.2f\n", text, voice, speed)
 	backFile := filepath.Join(cardDir, fmt.Sprintf("audio_back.%s", o.audioOutputFormat()))
 
-	finalVoice, err := o.runAudioWithFallbacks(ctx, text, backFile, voice, speed)
+	used, err := o.runAudioWithFailover(func(v *audioVoice) error {
+		fmt.Printf("Generating back audio for '%s' with voice: %s, speed: %.2f\n", text, v.voice, v.speed)
+		var err error
+		v.cacheHit, err = o.renderClip(ctx, text, backFile, *v, o.config.SlowAudioSpeed)
+		return err
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate back audio: %w", err)
 	}
 
-	audioCfg := o.audioResolver.ConfigForGeneration(finalVoice, speed)
-
-	if err := o.saveAudioAttribution(text, backFile, finalVoice, speed); err != nil {
-		fmt.Printf("Warning: Failed to save audio attribution: %v\n", err)
-	}
-
 	// Resolve the existing front audio path to keep the metadata complete.
 	existingFront, _ := resolveBgBgAudioFilesInDir(cardDir)
-	if err := o.saveAudioMetadata(cardDir, audioCfg, finalVoice, speed, "bg-bg", existingFront, backFile); err != nil {
+	if err := o.saveAudioMetadata(cardDir, used.config(), used.voice, used.speed, "bg-bg", existingFront, backFile); err != nil {
 		fmt.Printf("Warning: Failed to save audio metadata: %v\n", err)
 	}
 
@@ -215,101 +227,74 @@ func (o *GenerationOrchestrator) GenerateAudioBgBg(ctx context.Context, front, b
 		return "", "", fmt.Errorf("card directory not provided")
 	}
 
-	voice, speed := o.voiceSelector.VoiceAndSpeed()
-
-	fmt.Printf("Generating front audio for '%s' with voice: %s, speed: %.2f\n", front, voice, speed)
 	frontFile := filepath.Join(cardDir, fmt.Sprintf("audio_front.%s", o.audioOutputFormat()))
 	backFile := filepath.Join(cardDir, fmt.Sprintf("audio_back.%s", o.audioOutputFormat()))
 
-	// runPair generates both files using the given candidate voice.
-	runPair := func(candidate string) error {
-		if err := o.generateAudioFile(ctx, front, frontFile, candidate, speed); err != nil {
+	used, err := o.runAudioWithFailover(func(v *audioVoice) error {
+		fmt.Printf("Generating front audio for '%s' with voice: %s, speed: %.2f\n", front, v.voice, v.speed)
+		frontCached, err := o.renderClip(ctx, front, frontFile, *v, o.config.SlowAudioSpeed)
+		if err != nil {
 			return fmt.Errorf("failed to generate front audio: %w", err)
 		}
-		fmt.Printf("Generating back audio for '%s' with voice: %s, speed: %.2f\n", back, candidate, speed)
-		if err := o.generateAudioFile(ctx, back, backFile, candidate, speed); err != nil {
+		fmt.Printf("Generating back audio for '%s' with voice: %s, speed: %.2f\n", back, v.voice, v.speed)
+		backCached, err := o.renderClip(ctx, back, backFile, *v, o.config.SlowAudioSpeed)
+		if err != nil {
 			return fmt.Errorf("failed to generate back audio: %w", err)
 		}
+		v.cacheHit = frontCached && backCached
 		return nil
-	}
-
-	finalVoice, err := o.runPairWithFallbacks(voice, runPair)
+	})
 	if err != nil {
 		return "", "", err
 	}
 
-	audioCfg := o.audioResolver.ConfigForGeneration(finalVoice, speed)
-
-	if err := o.saveAudioAttribution(front, frontFile, finalVoice, speed); err != nil {
-		fmt.Printf("Warning: Failed to save audio attribution: %v\n", err)
-	}
-	if err := o.saveAudioAttribution(back, backFile, finalVoice, speed); err != nil {
-		fmt.Printf("Warning: Failed to save audio attribution: %v\n", err)
-	}
-
-	if err := o.saveAudioMetadata(cardDir, audioCfg, finalVoice, speed, "bg-bg", frontFile, backFile); err != nil {
+	if err := o.saveAudioMetadata(cardDir, used.config(), used.voice, used.speed, "bg-bg", frontFile, backFile); err != nil {
 		fmt.Printf("Warning: Failed to save audio metadata: %v\n", err)
 	}
 
 	return frontFile, backFile, nil
 }
 
-// runAudioWithFallbacks runs a single-file audio generation with Gemini voice
-// fallback support. Returns the voice that was ultimately used.
-func (o *GenerationOrchestrator) runAudioWithFallbacks(ctx context.Context, text, outputFile, voice string, speed float64) (string, error) {
-	if o.audioResolver.ProviderName() == "gemini" && !o.voiceSelector.GeminiVoicePinned() {
-		return audio.RunWithVoiceFallbacks(voice, func(candidate string) error {
-			if candidate != voice {
-				fmt.Printf("Retrying Gemini audio with voice: %s\n", candidate)
-			}
-			return o.generateAudioFile(ctx, text, outputFile, candidate, speed)
-		}, nil)
+// runAudioWithFailover calls generate with a voice of each provider of the
+// failover chain until one succeeds, and returns the voice that was used,
+// including the cache hit generate records on it.
+// Gemini without a pinned voice first retries its other voices when it
+// returns no audio.
+func (o *GenerationOrchestrator) runAudioWithFailover(generate func(v *audioVoice) error) (audioVoice, error) {
+	chain, err := audio.ProviderChain(o.audioResolver.ProviderName(), o.audioResolver.FallbackProviders())
+	if err != nil {
+		return audioVoice{}, err
 	}
 
-	return voice, o.generateAudioFile(ctx, text, outputFile, voice, speed)
-}
+	var used audioVoice
+	_, err = audio.RunWithProviderFailover(chain, func(provider, failoverFrom string) error {
+		resolver, selector := o.audioResolver, o.voiceSelector
+		if provider != resolver.ProviderName() {
+			resolver = resolver.ForProvider(provider)
+			selector = NewVoiceSelector(resolver)
+			fmt.Printf("Falling back to %s audio\n", provider)
+		}
+		voice, speed := selector.VoiceAndSpeed()
+		used = audioVoice{resolver: resolver, voice: voice, speed: speed, failoverFrom: failoverFrom}
 
-// runPairWithFallbacks runs a pair-generation function with Gemini voice
-// fallback support. Returns the voice that was ultimately used.
-func (o *GenerationOrchestrator) runPairWithFallbacks(voice string, runPair func(string) error) (string, error) {
-	if o.audioResolver.ProviderName() == "gemini" && !o.voiceSelector.GeminiVoicePinned() {
-		return audio.RunWithVoiceFallbacks(voice, func(candidate string) error {
+		if provider != "gemini" || selector.GeminiVoicePinned() {
+			return generate(&used)
+		}
+		_, err := audio.RunWithVoiceFallbacks(voice, func(candidate string) error {
 			if candidate != voice {
 				fmt.Printf("Retrying Gemini audio with voice: %s\n", candidate)
 			}
-			return runPair(candidate)
+			used.voice = candidate
+			return generate(&used)
 		}, nil)
+		return err
+	}, func(provider, reason string, err error) {
+		fmt.Printf("Warning: %s audio failed (%s): %v\n", provider, reason, err)
+	})
+	if err != nil {
+		return audioVoice{}, err
 	}
-
-	return voice, runPair(voice)
-}
-
-// saveAudioAttribution saves attribution metadata for a generated audio file.
-// Uses BuildAttributionFor so no switch on provider name is needed here.
-func (o *GenerationOrchestrator) saveAudioAttribution(word, audioFile, voice string, speed float64) error {
-	processedText := audio.ProcessedTextForWord(word)
-	providerName := o.audioResolver.ProviderName()
-
-	cfg := o.audioResolver.BaseConfigForAttribution()
-
-	// Override voice and speed with the values used for this specific generation.
-	cfgCopy := *cfg
-	cfgCopy.Provider = providerName
-	cfgCopy.GeminiVoice = voice
-	cfgCopy.GeminiSpeed = speed
-	cfgCopy.OpenAIVoice = voice
-	cfgCopy.OpenAISpeed = speed
-
-	instruction := audio.InstructionForProvider(providerName, &cfgCopy)
-	params := audio.AttributionParamsFrom(&cfgCopy, word, instruction, processedText, time.Now())
-	attribution := audio.BuildAttributionFor(providerName, params)
-
-	attrPath := audio.AttributionPath(audioFile)
-	if err := os.WriteFile(attrPath, []byte(attribution), 0644); err != nil {
-		return fmt.Errorf("failed to write audio attribution file: %w", err)
-	}
-
-	return nil
+	return used, nil
 }
 
 // saveAudioMetadata writes a sidecar metadata file alongside the audio file.
@@ -338,9 +323,16 @@ func (o *GenerationOrchestrator) saveAudioMetadata(cardDir string, audioCfg audi
 		GeminiTTSModel:    audioCfg.GeminiTTSModel,
 		GeminiVoice:       voice,
 		GeminiSpeed:       speed,
+		LocalEngine:       audioCfg.LocalEngine,
+		LocalModel:        audioCfg.LocalModel,
+		LocalVoice:        voice,
+		LocalSpeed:        speed,
+		FailoverFrom:      audioCfg.FailoverFrom,
+		CacheHit:          audioCfg.CacheHit,
+		PostProcess:       audioCfg.PostProcess,
 	})
 
-	if err := os.WriteFile(metadataFile, []byte(metadata), 0644); err != nil {
+	if err := store.WriteFileAtomic(metadataFile, []byte(metadata)); err != nil {
 		return fmt.Errorf("failed to write audio metadata file: %w", err)
 	}
 
@@ -361,6 +353,12 @@ func (o *GenerationOrchestrator) GenerateImagesWithPrompt(ctx context.Context, w
 		return "", fmt.Errorf("card directory not provided")
 	}
 
+	// Keep the image being replaced (and its prompt) as a revision before the
+	// prompt callback and the download overwrite them.
+	if err := store.SnapshotAssets(cardDir); err != nil {
+		return "", fmt.Errorf("failed to preserve previous image: %w", err)
+	}
+
 	downloadOpts := &image.DownloadOptions{
 		OutputDir:         cardDir,
 		OverwriteExisting: true,
@@ -389,7 +387,7 @@ func (o *GenerationOrchestrator) GenerateImagesWithPrompt(ctx context.Context, w
 	}
 
 	// The prompt has already been saved and UI updated via the callback.
-	return path, nil
+	return path, o.recordImageAsset(cardDir, path, searcher)
 }
 
 // imagePromptCallback returns a closure that saves the image prompt to disk
@@ -398,13 +396,37 @@ func (o *GenerationOrchestrator) GenerateImagesWithPrompt(ctx context.Context, w
 // dependency on Application.
 func (o *GenerationOrchestrator) imagePromptCallback(cardDir, word string) func(prompt string) {
 	return func(prompt string) {
-		promptFile := filepath.Join(cardDir, "image_prompt.txt")
-		if err := os.WriteFile(promptFile, []byte(prompt), 0644); err != nil {
+		if err := store.SaveImagePrompt(cardDir, prompt); err != nil {
 			fmt.Printf("Warning: Failed to save prompt for '%s': %v\n", word, err)
 		}
 	}
 }
 
+// recordImageAsset records a downloaded image and its provider in the card
+// manifest. The prompt is taken from the manifest, where the prompt callback
+// stored it before the download started.
+func (o *GenerationOrchestrator) recordImageAsset(cardDir, path string, searcher image.PromptAwareClient) error {
+	model := o.config.NanoBananaModel
+	if strings.ToLower(strings.TrimSpace(o.config.ImageProvider)) == image.ImageProviderOpenAI {
+		model = guiOpenAIImageModel
+	}
+
+	err := store.RecordAsset(cardDir, store.Asset{
+		Kind:     store.AssetImage,
+		File:     path,
+		Provider: searcher.Name(),
+		Model:    model,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record image in card manifest: %w", err)
+	}
+	return nil
+}
+
+// guiOpenAIImageModel is the OpenAI image model used by the GUI; DALL-E 2
+// supports the 512×512 size the card view is laid out for.
+const guiOpenAIImageModel = "dall-e-2"
+
 // guiImageClientFactories maps provider name to image client builder. Add new
 // providers by registering here instead of extending a switch in newImageSearcher.
 var guiImageClientFactories = func() *registry.Registry[string, func(*GenerationOrchestrator) (image.PromptAwareClient, error)] {
@@ -421,7 +443,7 @@ func (o *GenerationOrchestrator) buildOpenAIImageClient() (image.PromptAwareClie
 
 	openaiConfig := &image.OpenAIConfig{
 		APIKey:  o.config.OpenAIKey,
-		Model:   "dall-e-2", // DALL-E 2 supports 512×512
+		Model:   guiOpenAIImageModel,
 		Size:    "512x512",
 		Quality: "standard",
 		Style:   "natural",
@@ -514,6 +536,12 @@ func (o *GenerationOrchestrator) generateImagesWithPromptAndNotify(
 		return "", fmt.Errorf("card directory not provided")
 	}
 
+	// Keep the image being replaced (and its prompt) as a revision before the
+	// prompt callback and the download overwrite them.
+	if err := store.SnapshotAssets(cardDir); err != nil {
+		return "", fmt.Errorf("failed to preserve previous image: %w", err)
+	}
+
 	downloadOpts := &image.DownloadOptions{
 		OutputDir:         cardDir,
 		OverwriteExisting: true,
@@ -526,8 +554,7 @@ func (o *GenerationOrchestrator) generateImagesWithPromptAndNotify(
 
 	// Wrap the prompt callback so the UI is notified in addition to the file save.
 	searcher.SetPromptCallback(func(prompt string) {
-		promptFile := filepath.Join(cardDir, "image_prompt.txt")
-		if err := os.WriteFile(promptFile, []byte(prompt), 0644); err != nil {
+		if err := store.SaveImagePrompt(cardDir, prompt); err != nil {
 			fmt.Printf("Warning: Failed to save prompt for '%s': %v\n", word, err)
 		}
 		if promptUI != nil {
@@ -546,5 +573,8 @@ func (o *GenerationOrchestrator) generateImagesWithPromptAndNotify(
 	}
 
 	_, path, err := downloader.DownloadBestMatchWithOptions(ctx, searchOpts)
-	return path, err
+	if err != nil {
+		return path, err
+	}
+	return path, o.recordImageAsset(cardDir, path, searcher)
 }
@@ -3,8 +3,8 @@ package gui
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // GenerateResult holds the outcome of a parallel generation run.
@@ -116,14 +116,14 @@ func (ParallelRunner) GenerateMaterials(
 	}, nil
 }
 
-// savePhoneticIfValid saves phonetic info to disk when the info is valid.
+// savePhoneticIfValid saves phonetic info to the card manifest when the info
+// is valid.
 func savePhoneticIfValid(phoneticInfo, cardDir, word string) {
 	if phoneticInfo == "" || phoneticInfo == "Failed to fetch phonetic information" {
 		return
 	}
 
-	phoneticFile := filepath.Join(cardDir, "phonetic.txt")
-	if err := os.WriteFile(phoneticFile, []byte(phoneticInfo), 0644); err != nil {
+	if err := store.SavePhonetic(cardDir, phoneticInfo); err != nil {
 		fmt.Printf("Warning: Failed to save phonetic info for '%s': %v\n", word, err)
 	}
 }
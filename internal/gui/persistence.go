@@ -2,6 +2,8 @@ package gui
 
 import (
 	"fmt"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // ensureCardDirectory ensures a card directory exists for the given word.
@@ -18,6 +20,23 @@ func (a *Application) saveTranslation() {
 	}
 }
 
+// saveTags saves the tags entry to the current card. Cards that do not exist
+// yet get their tags when they are submitted.
+func (a *Application) saveTags() {
+	if err := a.getCardService().SaveTags(a.currentWord, store.ParseTags(a.tagsEntry.Text)); err != nil {
+		a.showError(err)
+	}
+}
+
+// saveExample saves the example entry to the current card. Cards that do not
+// exist yet get their example when they are submitted.
+func (a *Application) saveExample() {
+	example, translation := store.ParseExample(a.exampleEntry.Text)
+	if err := a.getCardService().SaveExample(a.currentWord, example, translation); err != nil {
+		a.showError(err)
+	}
+}
+
 // saveImagePrompt is retained for compatibility but is currently a no-op.
 // The image prompt is saved by the image generation callback when the image
 // is generated, so there is no need to save it separately here.
@@ -19,9 +19,13 @@ type WordJob struct {
 	Error            error
 	StartedAt        time.Time
 	CompletedAt      time.Time
-	CustomPrompt     string // Custom prompt for image generation
-	NeedsTranslation bool   // Whether translation is needed
-	CardType         string // Card type: "en-bg" or "bg-bg"
+	CustomPrompt     string   // Custom prompt for image generation
+	NeedsTranslation bool     // Whether translation is needed
+	CardType         string   // Card type: "en-bg" or "bg-bg"
+	Tags             []string // Anki tags from the tags entry
+	// Example and ExampleTranslation come from the example entry
+	Example            string
+	ExampleTranslation string
 }
 
 // JobStatus represents the current state of a job
@@ -7,6 +7,7 @@ import (
 	"fyne.io/fyne/v2"
 
 	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // QueueManager owns background word-job processing: card contexts, active
@@ -168,6 +169,15 @@ func (qm *QueueManager) prepareJobDirectory(job *WordJob) (string, bool, bool) {
 		return "", false, false
 	}
 
+	if err := store.AddTags(cardDir, job.Tags); err != nil {
+		fmt.Printf("Warning: failed to save tags for '%s': %v\n", job.Word, err)
+	}
+	if job.Example != "" {
+		if err := store.SaveExample(cardDir, job.Example, job.ExampleTranslation); err != nil {
+			fmt.Printf("Warning: failed to save example for '%s': %v\n", job.Word, err)
+		}
+	}
+
 	return cardDir, isBgBg, true
 }
 
@@ -0,0 +1,184 @@
+package gui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	ttwidget "github.com/dweymouth/fyne-tooltip/widget"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// defaultRevisionSlot is the asset slot shown first when a card has stored
+// image revisions, since images are what users regenerate most often.
+const defaultRevisionSlot = "image"
+
+// RevisionHandler owns the previous/next revision control that rolls an asset
+// of the current card back to an earlier generation (SRP).
+type RevisionHandler struct {
+	app *Application
+}
+
+// buildControls creates the slot selector, the previous/next buttons and the
+// "N / M" label. The controls start disabled until a card with stored
+// revisions is loaded.
+func (r *RevisionHandler) buildControls() fyne.CanvasObject {
+	a := r.app
+	a.revisionSlotSelect = widget.NewSelect(nil, func(string) { r.refresh() })
+	a.revisionSlotSelect.PlaceHolder = "Revisions"
+	a.prevRevisionBtn = ttwidget.NewButtonWithIcon("", theme.MediaSkipPreviousIcon(), func() { r.step(-1) })
+	a.nextRevisionBtn = ttwidget.NewButtonWithIcon("", theme.MediaSkipNextIcon(), func() { r.step(1) })
+	a.revisionLabel = widget.NewLabel("")
+
+	r.refresh()
+	return container.NewHBox(a.revisionSlotSelect, a.prevRevisionBtn, a.revisionLabel, a.nextRevisionBtn)
+}
+
+// refresh re-reads the revisions of the current card and updates the controls.
+// Must be called on the UI goroutine.
+func (r *RevisionHandler) refresh() {
+	a := r.app
+	if a.revisionSlotSelect == nil {
+		return
+	}
+
+	m, _ := r.currentManifest()
+	slots := []string(nil)
+	if m != nil {
+		slots = m.RevisionSlots()
+	}
+
+	a.revisionSlotSelect.Options = slots
+	slot := r.pickSlot(slots)
+	// Assign directly: SetSelected would call back into refresh.
+	a.revisionSlotSelect.Selected = slot
+	a.revisionSlotSelect.Refresh()
+
+	if slot == "" {
+		a.revisionSlotSelect.Disable()
+		a.prevRevisionBtn.Disable()
+		a.nextRevisionBtn.Disable()
+		a.revisionLabel.SetText("")
+		return
+	}
+	a.revisionSlotSelect.Enable()
+
+	revisions := m.RevisionsOf(slot)
+	pos := revisionPosition(revisions, m.SelectedRevision(slot))
+	if pos < 0 {
+		a.revisionLabel.SetText(fmt.Sprintf("- / %d", len(revisions)))
+	} else {
+		a.revisionLabel.SetText(fmt.Sprintf("%d / %d", pos+1, len(revisions)))
+	}
+
+	// Without a selected revision the current file is newer than every
+	// stored one, so only stepping back makes sense.
+	setEnabled(a.prevRevisionBtn, pos != 0 && len(revisions) > 0)
+	setEnabled(a.nextRevisionBtn, pos >= 0 && pos < len(revisions)-1)
+}
+
+// step restores the revision delta positions away from the selected one and
+// reloads the card. Ignored while the card is still being generated, since
+// the restored file would be overwritten straight away.
+func (r *RevisionHandler) step(delta int) {
+	a := r.app
+	word := a.currentWord
+	if word == "" || a.queueMgr.hasActiveOperations(word) {
+		return
+	}
+
+	m, cardDir := r.currentManifest()
+	if m == nil {
+		return
+	}
+	slot := a.revisionSlotSelect.Selected
+	revisions := m.RevisionsOf(slot)
+	if len(revisions) == 0 {
+		return
+	}
+
+	target := revisionPosition(revisions, m.SelectedRevision(slot))
+	if target < 0 {
+		target = len(revisions)
+	}
+	target += delta
+	if target < 0 || target >= len(revisions) {
+		return
+	}
+	number := revisions[target].Number
+
+	a.prevRevisionBtn.Disable()
+	a.nextRevisionBtn.Disable()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		rev, err := store.RestoreRevision(cardDir, slot, number)
+		if err != nil {
+			fyne.Do(func() {
+				a.showError(fmt.Errorf("failed to restore revision: %w", err))
+				r.refresh()
+			})
+			return
+		}
+
+		a.loadExistingFiles(word)
+		fyne.Do(func() {
+			r.refresh()
+			a.updateStatus(fmt.Sprintf("Restored %s revision %d of %s", slot, rev.Number, word))
+		})
+	}()
+}
+
+// currentManifest loads the manifest of the current word's card directory.
+func (r *RevisionHandler) currentManifest() (*store.Manifest, string) {
+	a := r.app
+	if a.currentWord == "" {
+		return nil, ""
+	}
+	cardDir := a.getCardService().FindCardDirectory(a.currentWord)
+	if cardDir == "" {
+		return nil, ""
+	}
+	return store.LoadManifest(cardDir), cardDir
+}
+
+// pickSlot keeps the user's slot choice when it is still available and
+// otherwise falls back to the image slot or the first slot with revisions.
+func (r *RevisionHandler) pickSlot(slots []string) string {
+	if len(slots) == 0 {
+		return ""
+	}
+	preferred := []string{r.app.revisionSlotSelect.Selected, defaultRevisionSlot}
+	for _, want := range preferred {
+		for _, slot := range slots {
+			if slot == want {
+				return slot
+			}
+		}
+	}
+	return slots[0]
+}
+
+// revisionPosition returns the index of the revision with the given number,
+// or -1 when none matches.
+func revisionPosition(revisions []store.Revision, number int) int {
+	for i, rev := range revisions {
+		if rev.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+func setEnabled(button *ttwidget.Button, enabled bool) {
+	if enabled {
+		button.Enable()
+	} else {
+		button.Disable()
+	}
+}
@@ -0,0 +1,166 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// TrashHandler owns the Trash Bin dialog, which lists deleted cards and
+// restores or purges them (SRP).
+type TrashHandler struct {
+	app *Application
+
+	entries  []store.TrashEntry
+	selected int
+}
+
+// onShowTrash opens the Trash Bin dialog.
+func (t *TrashHandler) onShowTrash() {
+	a := t.app
+	if err := t.reload(); err != nil {
+		a.showError(err)
+		return
+	}
+	if len(t.entries) == 0 {
+		dialog.ShowInformation("Trash Bin", "The trash bin is empty.", a.window)
+		return
+	}
+
+	list := widget.NewList(
+		func() int { return len(t.entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(trashEntryLabel(t.entries[id]))
+		},
+	)
+
+	restoreButton := widget.NewButton("Restore (r)", nil)
+	restoreButton.Disable()
+	list.OnSelected = func(id widget.ListItemID) {
+		t.selected = id
+		restoreButton.Enable()
+	}
+	restoreButton.OnTapped = func() {
+		t.restoreSelected(list, restoreButton)
+	}
+
+	daysEntry := widget.NewEntry()
+	daysEntry.SetText(strconv.Itoa(int(store.DefaultTrashRetention / (24 * time.Hour))))
+	purgeButton := widget.NewButton("Purge", func() {
+		t.purge(daysEntry.Text, list, restoreButton)
+	})
+
+	purgeRow := container.NewHBox(widget.NewLabel("Purge cards deleted more than"), daysEntry, widget.NewLabel("days ago"), purgeButton)
+	content := container.NewBorder(nil, container.NewVBox(widget.NewSeparator(), restoreButton, purgeRow), nil, nil, list)
+
+	trashDialog := dialog.NewCustom("Trash Bin", "Close (c/Esc)", content, a.window)
+	t.wireTrashDialogKeys(trashDialog, list, restoreButton)
+	trashDialog.Resize(fyne.NewSize(620, 420))
+	trashDialog.Show()
+}
+
+// reload re-reads the trash bin and clears the selection.
+func (t *TrashHandler) reload() error {
+	entries, err := t.app.getCardService().ListTrash()
+	if err != nil {
+		return err
+	}
+	t.entries = entries
+	t.selected = -1
+	return nil
+}
+
+// restoreSelected moves the selected card back into the output directory. A
+// word that has been generated again since it was deleted is reported as a
+// conflict and left in the trash bin.
+func (t *TrashHandler) restoreSelected(list *widget.List, restoreButton *widget.Button) {
+	a := t.app
+	if t.selected < 0 || t.selected >= len(t.entries) {
+		return
+	}
+	entry := t.entries[t.selected]
+
+	if _, err := a.getCardService().RestoreFromTrash(entry); err != nil {
+		if errors.Is(err, store.ErrTrashConflict) {
+			err = fmt.Errorf("%q already has a card again; delete that card first to restore the trashed one", entry.Word)
+		}
+		a.showError(err)
+		return
+	}
+
+	a.scanExistingWords()
+	a.updateStatus(fmt.Sprintf("Restored '%s' from trash", entry.Word))
+	t.refreshList(list, restoreButton)
+}
+
+// purge removes trashed cards older than the number of days in daysText.
+func (t *TrashHandler) purge(daysText string, list *widget.List, restoreButton *widget.Button) {
+	a := t.app
+	days, err := strconv.Atoi(strings.TrimSpace(daysText))
+	if err != nil || days < 0 {
+		a.showError(fmt.Errorf("invalid retention %q: enter a number of days", daysText))
+		return
+	}
+
+	purged, err := a.getCardService().PurgeTrash(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		a.showError(err)
+	}
+	a.updateStatus(fmt.Sprintf("Purged %d card(s) from trash", purged))
+	t.refreshList(list, restoreButton)
+}
+
+func (t *TrashHandler) refreshList(list *widget.List, restoreButton *widget.Button) {
+	if err := t.reload(); err != nil {
+		t.app.showError(err)
+	}
+	list.UnselectAll()
+	list.Refresh()
+	restoreButton.Disable()
+}
+
+// wireTrashDialogKeys attaches keyboard shortcuts to the trash dialog: r/р
+// restores the selected card, c/ц and Esc close it. Original handlers are
+// restored on close.
+func (t *TrashHandler) wireTrashDialogKeys(trashDialog *dialog.CustomDialog, list *widget.List, restoreButton *widget.Button) {
+	a := t.app
+	origRune := a.window.Canvas().OnTypedRune()
+	origKey := a.window.Canvas().OnTypedKey()
+
+	a.window.Canvas().SetOnTypedRune(func(r rune) {
+		switch r {
+		case 'r', 'R', 'р', 'Р':
+			t.restoreSelected(list, restoreButton)
+		case 'c', 'C', 'ц', 'Ц':
+			trashDialog.Hide()
+		}
+	})
+	a.window.Canvas().SetOnTypedKey(func(ev *fyne.KeyEvent) {
+		if ev.Name == fyne.KeyEscape {
+			trashDialog.Hide()
+		}
+	})
+	trashDialog.SetOnClosed(func() {
+		a.window.Canvas().SetOnTypedRune(origRune)
+		a.window.Canvas().SetOnTypedKey(origKey)
+	})
+}
+
+// trashEntryLabel renders one trash bin entry for the list.
+func trashEntryLabel(entry store.TrashEntry) string {
+	word := entry.Word
+	if word == "" {
+		word = "(unknown word)"
+	}
+	return fmt.Sprintf("%s  —  deleted %s  (%s)", word, entry.DeletedAt.Format("2006-01-02 15:04"), entry.Name)
+}
@@ -28,6 +28,17 @@ func (v *VoiceSelector) VoiceAndSpeed() (string, float64) {
 			}
 		}
 		return randomVoice(v.resolver.Voices()), v.GeminiSpeed()
+	case audio.LocalProviderName:
+		speed := audio.DefaultProviderConfig().LocalSpeed
+		if v.resolver.audioConfig != nil {
+			if v.resolver.audioConfig.LocalSpeed > 0 {
+				speed = v.resolver.audioConfig.LocalSpeed
+			}
+			if voice := strings.TrimSpace(v.resolver.audioConfig.LocalVoice); voice != "" {
+				return voice, speed
+			}
+		}
+		return randomVoice(v.resolver.Voices()), speed
 	default:
 		return randomVoice(v.resolver.Voices()), randomOpenAISpeed()
 	}
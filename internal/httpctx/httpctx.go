@@ -31,6 +31,11 @@ const (
 	// Search including scene + generation) when the caller did not set a deadline.
 	OperationTimeoutDefault = 15 * time.Minute
 
+	// AnkiConnectHTTPTimeout bounds each AnkiConnect request. Anki answers
+	// from the local machine, but storeMediaFile uploads whole audio clips
+	// and Anki may be busy syncing.
+	AnkiConnectHTTPTimeout = 2 * time.Minute
+
 	// ListModelsTimeout bounds model-listing CLI calls.
 	ListModelsTimeout = 3 * time.Minute
 
@@ -55,6 +60,11 @@ func ImageDownloadHTTPClient() *http.Client {
 	return &http.Client{Timeout: ImageDownloadTimeout}
 }
 
+// AnkiConnectHTTPClient returns a client for the local AnkiConnect API.
+func AnkiConnectHTTPClient() *http.Client {
+	return &http.Client{Timeout: AnkiConnectHTTPTimeout}
+}
+
 // NewOpenAIClient creates a go-openai client whose HTTP transport has a deadline.
 func NewOpenAIClient(token string) *openai.Client {
 	cfg := openai.DefaultConfig(token)
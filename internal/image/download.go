@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // DownloadOptions configures image download behavior
@@ -74,23 +76,17 @@ func (d *Downloader) DownloadImage(ctx context.Context, result *SearchResult, ou
 		_ = reader.Close()
 	}()
 
-	// Create output file
-	file, err := os.Create(outputPath)
+	// Write to a temporary file so readers never see a partial image.
+	file, err := store.CreateAtomic(outputPath)
 	if err != nil {
 		return fmt.Errorf("create output file %q: %w", outputPath, err)
 	}
-	defer func() {
-		if closeErr := file.Close(); err == nil && closeErr != nil {
-			err = fmt.Errorf("close output file %q: %w", outputPath, closeErr)
-		}
-	}()
+	defer file.Abort()
 
 	// Copy with size limit if specified
-	var written int64
 	if d.options.MaxSizeBytes > 0 {
-		written, err = io.CopyN(file, reader, d.options.MaxSizeBytes)
+		written, err := io.CopyN(file, reader, d.options.MaxSizeBytes)
 		if err != nil && err != io.EOF {
-			_ = os.Remove(outputPath) // Clean up on error
 			return fmt.Errorf("write output file %q: %w", outputPath, err)
 		}
 
@@ -98,32 +94,24 @@ func (d *Downloader) DownloadImage(ctx context.Context, result *SearchResult, ou
 		if written == d.options.MaxSizeBytes {
 			// Try to read one more byte to see if file is larger
 			if _, err := reader.Read(make([]byte, 1)); err != io.EOF {
-				_ = os.Remove(outputPath) // Clean up
 				return fmt.Errorf("image exceeds max size %d bytes", d.options.MaxSizeBytes)
 			}
 		}
-
-		// Ensure the file is fully written to disk before returning
-		if err := file.Sync(); err != nil {
-			return fmt.Errorf("sync output file %q: %w", outputPath, err)
-		}
 	} else {
-		_, err = io.Copy(file, reader)
-		if err != nil {
-			_ = os.Remove(outputPath) // Clean up on error
+		if _, err := io.Copy(file, reader); err != nil {
 			return fmt.Errorf("write output file %q: %w", outputPath, err)
 		}
 	}
 
-	// Ensure the file is fully written to disk before returning
-	if err := file.Sync(); err != nil {
+	// Commit syncs the file to disk before renaming it into place.
+	if err := file.Commit(); err != nil {
 		return fmt.Errorf("sync output file %q: %w", outputPath, err)
 	}
 
 	// Save attribution if required
 	if attribution := d.searcher.GetAttribution(result); attribution != "" {
 		attrPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_attribution.txt"
-		if err := os.WriteFile(attrPath, []byte(attribution), 0644); err != nil {
+		if err := store.WriteFileAtomic(attrPath, []byte(attribution)); err != nil {
 			// Non-fatal error - log but don't fail the download
 			fmt.Fprintf(os.Stderr, "Warning: failed to save attribution: %v\n", err)
 		}
@@ -291,7 +291,7 @@ func (c *NanoBananaClient) resolvePrompt(ctx context.Context, opts *SearchOption
 		return customPrompt, nil
 	}
 
-	return c.createEducationalPrompt(ctx, opts.Query, translatedWord), nil
+	return c.createEducationalPrompt(ctx, opts.Query, translatedWord, opts.Sentence), nil
 }
 
 func (c *NanoBananaClient) buildPrompt(ctx context.Context, opts *SearchOptions) (string, string, error) {
@@ -330,10 +330,10 @@ func (c *NanoBananaClient) buildPrompt(ctx context.Context, opts *SearchOptions)
 // Scene generation and style selection are handled here; the shared
 // buildEducationalPrompt helper assembles the actual prompt text so that the
 // same policy is used by both NanoBananaClient and OpenAIClient.
-func (c *NanoBananaClient) createEducationalPrompt(ctx context.Context, bulgarianWord, englishTranslation string) string {
+func (c *NanoBananaClient) createEducationalPrompt(ctx context.Context, bulgarianWord, englishTranslation string, sentence bool) string {
 	subject := promptSubject(englishTranslation, bulgarianWord)
 
-	scene, err := c.generateSceneDescription(ctx, bulgarianWord, englishTranslation)
+	scene, err := c.generateSceneDescription(ctx, bulgarianWord, englishTranslation, sentence)
 	if err != nil {
 		fmt.Printf("  Failed to generate scene: %v, using basic prompt\n", err)
 		scene = ""
@@ -357,15 +357,16 @@ func (c *NanoBananaClient) createEducationalPrompt(ctx context.Context, bulgaria
 	return buildEducationalPrompt(selectedStyle, scene, subject)
 }
 
-func (c *NanoBananaClient) generateSceneDescription(ctx context.Context, bulgarianWord, englishTranslation string) (string, error) {
+func (c *NanoBananaClient) generateSceneDescription(ctx context.Context, bulgarianWord, englishTranslation string, sentence bool) (string, error) {
 	fmt.Printf("Nano Banana Scene Generation: Creating scene for '%s' (%s)\n", bulgarianWord, englishTranslation)
 
+	systemPrompt, userPrompt := scenePrompts(englishTranslation, sentence)
 	scene, err := nanoBananaGenerateText(
 		ctx,
 		c,
 		c.textModelName(),
-		"You are helping create educational flashcards for language learning. Generate a brief, vivid scene description that incorporates the given English word in a memorable, contextual way. The scene should be visually interesting and help with memory retention. Keep it to 1-2 sentences, focusing on visual elements that can be illustrated. The subject (the English word) should be the clear focal point of the image, prominent and centered.",
-		fmt.Sprintf("Create a scene description for the English word '%s' that would make a memorable flashcard image. Make sure '%s' is the main focus and most prominent element in the scene.", englishTranslation, englishTranslation),
+		systemPrompt,
+		userPrompt,
 		0.7,
 		100,
 	)
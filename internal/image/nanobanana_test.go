@@ -318,6 +318,40 @@ func TestNanoBananaClient_Search_TrivialSceneFallsBackToSubjectPrompt(t *testing
 	}
 }
 
+func TestNanoBananaClient_Search_SentenceIllustratesMeaning(t *testing.T) {
+	originalText := nanoBananaGenerateText
+	originalImage := nanoBananaGenerateImage
+	t.Cleanup(func() {
+		nanoBananaGenerateText = originalText
+		nanoBananaGenerateImage = originalImage
+	})
+
+	var gotUserPrompt string
+	nanoBananaGenerateText = func(_ context.Context, _ *NanoBananaClient, _, systemPrompt, userPrompt string, _ float32, _ int32) (string, error) {
+		if !strings.Contains(systemPrompt, "situation described by the given English sentence") {
+			t.Fatalf("system prompt = %q, want the sentence scene prompt", systemPrompt)
+		}
+		gotUserPrompt = userPrompt
+		return "A traveller with a suitcase asks a passer-by for directions in a busy street.", nil
+	}
+	nanoBananaGenerateImage = func(_ context.Context, _ *NanoBananaClient, _, _ string) ([]byte, string, error) {
+		return mustPNGBytes(t), "image/png", nil
+	}
+
+	client := NewNanoBananaClient(&NanoBananaConfig{APIKey: "test-key"})
+	_, err := client.Search(context.Background(), &SearchOptions{
+		Query:       "Къде е гарата?",
+		Translation: "Where is the station?",
+		Sentence:    true,
+	})
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %v", err)
+	}
+	if !strings.Contains(gotUserPrompt, "English sentence 'Where is the station?'") {
+		t.Fatalf("scene prompt = %q, want the translated sentence", gotUserPrompt)
+	}
+}
+
 func TestNanoBananaClient_Search_ImageGenerationError(t *testing.T) {
 	originalText := nanoBananaGenerateText
 	originalImage := nanoBananaGenerateImage
@@ -118,7 +118,7 @@ func (c *OpenAIClient) Search(ctx context.Context, opts *SearchOptions) ([]Searc
 		}
 		fmt.Printf("Using custom prompt: %s\n", prompt)
 	} else {
-		prompt = c.createEducationalPrompt(ctx, opts.Query, translatedWord)
+		prompt = c.createEducationalPrompt(ctx, opts.Query, translatedWord, opts.Sentence)
 		if prompt == "" {
 			return nil, &SearchError{
 				Provider: "openai",
@@ -250,10 +250,10 @@ func (c *OpenAIClient) SetPromptCallback(callback func(prompt string)) {
 // Scene generation and style selection are handled here; the shared
 // buildEducationalPrompt helper assembles the actual prompt text so that the
 // same policy is used by both OpenAIClient and NanoBananaClient.
-func (c *OpenAIClient) createEducationalPrompt(ctx context.Context, bulgarianWord, englishTranslation string) string {
+func (c *OpenAIClient) createEducationalPrompt(ctx context.Context, bulgarianWord, englishTranslation string, sentence bool) string {
 	subject := promptSubject(englishTranslation, bulgarianWord)
 
-	scene, err := c.generateSceneDescription(ctx, bulgarianWord, englishTranslation)
+	scene, err := c.generateSceneDescription(ctx, bulgarianWord, englishTranslation, sentence)
 	if err != nil {
 		fmt.Printf("  Failed to generate scene: %v, using basic prompt\n", err)
 		scene = ""
@@ -278,20 +278,22 @@ func (c *OpenAIClient) createEducationalPrompt(ctx context.Context, bulgarianWor
 }
 
 // generateSceneDescription generates a contextual scene description for the word
-func (c *OpenAIClient) generateSceneDescription(ctx context.Context, bulgarianWord, englishTranslation string) (string, error) {
+// or sentence
+func (c *OpenAIClient) generateSceneDescription(ctx context.Context, bulgarianWord, englishTranslation string, sentence bool) (string, error) {
 	// Use OpenAI to generate a scene description
 	fmt.Printf("OpenAI Scene Generation: Creating scene for '%s' (%s)\n", bulgarianWord, englishTranslation)
+	systemPrompt, userPrompt := scenePrompts(englishTranslation, sentence)
 
 	req := openai.ChatCompletionRequest{
 		Model: openai.GPT4oMini,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are helping create educational flashcards for language learning. Generate a brief, vivid scene description that incorporates the given English word in a memorable, contextual way. The scene should be visually interesting and help with memory retention. Keep it to 1-2 sentences, focusing on visual elements that can be illustrated. The subject (the English word) should be the clear focal point of the image, prominent and centered.",
+				Content: systemPrompt,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
-				Content: fmt.Sprintf("Create a scene description for the English word '%s' that would make a memorable flashcard image. Make sure '%s' is the main focus and most prominent element in the scene.", englishTranslation, englishTranslation),
+				Content: userPrompt,
 			},
 		},
 		Temperature: 0.7, // Balanced temperature for creativity with consistency
@@ -21,6 +21,22 @@ func promptSubject(englishTranslation, fallback string) string {
 	return "the requested term"
 }
 
+// Scene description prompts shared by the OpenAI and Nano Banana clients.
+const (
+	wordSceneSystemPrompt     = "You are helping create educational flashcards for language learning. Generate a brief, vivid scene description that incorporates the given English word in a memorable, contextual way. The scene should be visually interesting and help with memory retention. Keep it to 1-2 sentences, focusing on visual elements that can be illustrated. The subject (the English word) should be the clear focal point of the image, prominent and centered."
+	sentenceSceneSystemPrompt = "You are helping create educational flashcards for language learning. Generate a brief, vivid scene description that shows the situation described by the given English sentence, so that a learner can guess the meaning of the sentence from the image alone. Keep it to 1-2 sentences, focusing on the people, actions and objects that can be illustrated."
+)
+
+// scenePrompts returns the system and user prompt of the scene description
+// request. A sentence is illustrated by its meaning as a whole; a word by
+// the thing it names.
+func scenePrompts(englishTranslation string, sentence bool) (system, user string) {
+	if sentence {
+		return sentenceSceneSystemPrompt, fmt.Sprintf("Create a scene description for the English sentence '%s' that would make a memorable flashcard image. Every part of its meaning should be visible in the scene.", englishTranslation)
+	}
+	return wordSceneSystemPrompt, fmt.Sprintf("Create a scene description for the English word '%s' that would make a memorable flashcard image. Make sure '%s' is the main focus and most prominent element in the scene.", englishTranslation, englishTranslation)
+}
+
 func normalizePromptText(text string) string {
 	text = trimMarkdownFence(text)
 	text = strings.TrimSpace(text)
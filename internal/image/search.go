@@ -29,6 +29,10 @@ type SearchOptions struct {
 	Orientation  string // Orientation: "horizontal", "vertical", "all"
 	CustomPrompt string // Custom prompt for AI image generation
 	AspectRatio  string // Override aspect ratio (e.g. "9:16"); empty = provider default
+	// Sentence marks Query and Translation as a whole sentence. The image
+	// then illustrates the situation the sentence describes rather than a
+	// single object.
+	Sentence bool
 	// ReferenceImages holds raw PNG bytes of previously generated images.
 	// When non-empty, the NanoBanana client sends them as multimodal content
 	// alongside the text prompt so the model can match character appearance
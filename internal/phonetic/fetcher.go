@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -15,6 +13,7 @@ import (
 
 	appconfig "codeberg.org/snonux/totalrecall/internal/config"
 	"codeberg.org/snonux/totalrecall/internal/httpctx"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 const (
@@ -148,12 +147,7 @@ func (f *Fetcher) FetchAndSave(word, wordDir string) error {
 		return err
 	}
 
-	phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-	if err := os.WriteFile(phoneticFile, []byte(phoneticInfo), 0644); err != nil {
-		return fmt.Errorf("failed to write phonetic file: %w", err)
-	}
-
-	return nil
+	return store.SavePhonetic(wordDir, phoneticInfo)
 }
 
 // Fetch fetches phonetic information for a word.
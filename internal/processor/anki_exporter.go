@@ -1,18 +1,26 @@
 package processor
 
 // AnkiExporter builds Anki import artifacts (CSV or APKG) from the in-memory
-// translation cache and on-disk card directories.
+// translation cache and on-disk card directories, or pushes the same cards
+// into a running Anki through AnkiConnect.
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"codeberg.org/snonux/totalrecall/internal"
 	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/ankiconnect"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
+// ErrNothingToExport is returned by exports whose filter leaves no cards, so
+// no empty package is written.
+var ErrNothingToExport = errors.New("no cards match the export filter")
+
 // AnkiExporter generates Anki deck output using Processor state (flags, cache,
 // card directory lookups).
 type AnkiExporter struct {
@@ -31,17 +39,105 @@ func (e *AnkiExporter) GenerateAnkiFile() (string, error) {
 
 	audioFormat := p.EffectiveAudioFormat()
 	gen := anki.NewGenerator(&anki.GeneratorOptions{
-		OutputPath:     filepath.Join(outputDir, "anki_import.csv"),
+		OutputPath:     filepath.Join(outputDir, anki.CSVBundleDir, anki.CSVFileName),
 		MediaFolder:    p.Flags.OutputDir,
 		IncludeHeaders: true,
 		AudioFormat:    audioFormat,
+		DeckOptions:    p.Config.DeckOptions,
+		CardDirections: p.Config.CardDirections,
+		PackageFormat:  p.Config.PackageFormat,
+		DeckGrouping:   p.Config.DeckGrouping,
 	})
 
 	if err := e.populateAnkiGenerator(gen, audioFormat); err != nil {
 		return "", err
 	}
+	format, destination := e.ankiOutputTarget(outputDir)
+	if err := e.filterAnkiCards(gen, format, destination); err != nil {
+		return "", err
+	}
 
-	return e.writeAnkiOutput(gen, outputDir)
+	return e.writeAnkiOutput(gen, destination)
+}
+
+// PushToAnkiConnect adds the cards of this run (or, without a run, all cards
+// in the output directory) to the --deck-name deck of a running Anki.
+func (e *AnkiExporter) PushToAnkiConnect(ctx context.Context) ([]ankiconnect.Result, error) {
+	p := e.p
+	audioFormat := p.EffectiveAudioFormat()
+	gen := anki.NewGenerator(&anki.GeneratorOptions{
+		MediaFolder: p.Flags.OutputDir,
+		AudioFormat: audioFormat,
+	})
+	if err := e.populateAnkiGenerator(gen, audioFormat); err != nil {
+		return nil, err
+	}
+	if err := e.filterAnkiCards(gen, anki.ExportFormatAnkiConnect, p.Flags.DeckName); err != nil {
+		return nil, err
+	}
+
+	exporter, err := ankiconnect.NewExporter(ankiconnect.NewClient(p.Config.AnkiConnectURL), p.Flags.DeckName)
+	if err != nil {
+		return nil, err
+	}
+	exporter.SetCardDirections(p.Config.CardDirections)
+	exporter.SetDeckGrouping(p.Config.DeckGrouping)
+	cards := gen.GetCards()
+	results, err := exporter.Export(ctx, cards)
+	if err != nil {
+		return nil, err
+	}
+
+	// Results come in card order; failed cards stay due for the next
+	// incremental export.
+	var exported []anki.Card
+	for i, result := range results {
+		if result.Err == nil {
+			exported = append(exported, cards[i])
+		}
+	}
+	if len(exported) > 0 {
+		e.recordExport(anki.ExportFormatAnkiConnect, p.Flags.DeckName, "", exported)
+	}
+	return results, nil
+}
+
+// filterAnkiCards applies the export filter of the run to the generator's
+// cards, for an export in format to destination. It returns
+// ErrNothingToExport when the filter leaves none.
+func (e *AnkiExporter) filterAnkiCards(gen *anki.Generator, format, destination string) error {
+	filter := e.p.Config.ExportFilter
+	if filter.IsZero() {
+		return nil
+	}
+	total := len(gen.GetCards())
+	if err := gen.FilterCards(filter, e.p.cardStore, format, destination); err != nil {
+		return err
+	}
+	kept := len(gen.GetCards())
+	fmt.Printf("  Exporting %d of %d cards (%s)\n", kept, total, filter)
+	if kept == 0 {
+		return ErrNothingToExport
+	}
+	return nil
+}
+
+// recordExport adds the export to the export ledger of the output directory.
+// file is the written file, empty for AnkiConnect. The export itself
+// succeeded at this point, so a ledger that cannot be written is only
+// reported.
+func (e *AnkiExporter) recordExport(format, destination, file string, cards []anki.Card) {
+	p := e.p
+	export := store.Export{
+		File:        file,
+		Format:      format,
+		Destination: destination,
+		Deck:        p.Flags.DeckName,
+		Filter:      p.Config.ExportFilter.String(),
+	}
+	if err := anki.RecordExport(p.cardStore, export, cards); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 }
 
 // resolveAnkiOutputDir returns the directory where the Anki file should be
@@ -81,7 +177,8 @@ func (e *AnkiExporter) populateAnkiGenerator(gen *anki.Generator, audioFormat st
 }
 
 // buildAnkiCard constructs an anki.Card for a word, resolving all associated
-// media files (audio, image, phonetic) from the word's card directory.
+// media files (audio, image, phonetic) from the word's card manifest. The
+// translation from the current batch run wins over the stored one.
 func (e *AnkiExporter) buildAnkiCard(bulgarian, english, audioFormat string) anki.Card {
 	p := e.p
 	card := anki.Card{
@@ -94,47 +191,48 @@ func (e *AnkiExporter) buildAnkiCard(bulgarian, english, audioFormat string) ank
 		return card
 	}
 
-	cardType := internal.LoadCardType(wordDir)
-	if cardType.IsBgBg() {
-		card.AudioFile = anki.ResolveAudioFile(wordDir, "audio_front", audioFormat)
-		card.AudioFileBack = anki.ResolveAudioFile(wordDir, "audio_back", audioFormat)
-	} else {
-		card.AudioFile = anki.ResolveAudioFile(wordDir, "audio", audioFormat)
+	stored, ok := anki.CardFromDirectory(wordDir, audioFormat)
+	if !ok {
+		return card
 	}
 
-	imageFile := filepath.Join(wordDir, "image.jpg")
-	if _, err := os.Stat(imageFile); err == nil {
-		card.ImageFile = imageFile
-	}
+	stored.Bulgarian = bulgarian
+	stored.Translation = english
+	return stored
+}
 
-	phoneticFile := filepath.Join(wordDir, "phonetic.txt")
-	if data, err := os.ReadFile(phoneticFile); err == nil {
-		notes := strings.TrimSpace(string(data))
-		card.Notes = strings.ReplaceAll(notes, "\n", "<br>")
+// ankiOutputTarget returns the format and the file of the Anki output in
+// outputDir: the TSV file of a CSV bundle with --anki-csv, the APKG file
+// otherwise.
+func (e *AnkiExporter) ankiOutputTarget(outputDir string) (string, string) {
+	p := e.p
+	if p.Flags.AnkiCSV {
+		return anki.ExportFormatCSV, filepath.Join(outputDir, anki.CSVBundleDir, anki.CSVFileName)
 	}
-
-	return card
+	return anki.ExportFormatAPKG, filepath.Join(outputDir, fmt.Sprintf("%s.apkg", internal.SanitizeFilename(p.Flags.DeckName)))
 }
 
-// writeAnkiOutput generates either a CSV or APKG file depending on the
-// --anki-csv flag and returns the output path.
-func (e *AnkiExporter) writeAnkiOutput(gen *anki.Generator, outputDir string) (string, error) {
+// writeAnkiOutput generates either a CSV bundle or the APKG file at
+// outputPath depending on the --anki-csv flag, records it in the export
+// ledger and returns the written file: the TSV file of the bundle or the
+// APKG file.
+func (e *AnkiExporter) writeAnkiOutput(gen *anki.Generator, outputPath string) (string, error) {
 	p := e.p
 	if p.Flags.AnkiCSV {
-		outputPath := filepath.Join(outputDir, "anki_import.csv")
-		if err := gen.GenerateCSV(); err != nil {
+		if err := gen.GenerateCSV(p.Flags.DeckName); err != nil {
 			return "", fmt.Errorf("failed to generate CSV: %w", err)
 		}
 		e.printAnkiStats(gen)
-		return outputPath, nil
+		e.recordExport(anki.ExportFormatCSV, outputPath, gen.OutputFile(), gen.GetCards())
+		return gen.OutputFile(), nil
 	}
 
-	outputPath := filepath.Join(outputDir, fmt.Sprintf("%s.apkg", internal.SanitizeFilename(p.Flags.DeckName)))
 	if err := gen.GenerateAPKG(outputPath, p.Flags.DeckName); err != nil {
 		return "", fmt.Errorf("failed to generate APKG: %w", err)
 	}
 	e.printAnkiStats(gen)
-	return outputPath, nil
+	e.recordExport(anki.ExportFormatAPKG, outputPath, gen.OutputFile(), gen.GetCards())
+	return gen.OutputFile(), nil
 }
 
 // printAnkiStats logs the card generation statistics to stdout.
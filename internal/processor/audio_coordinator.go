@@ -19,28 +19,70 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/cli"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
+// audioVoice is the provider and voice one audio clip is generated with.
+// FailoverFrom lists the providers of the failover chain that failed before
+// Provider took over; it is empty for the configured provider.
+type audioVoice struct {
+	Provider     string
+	Voice        string
+	FailoverFrom string
+}
+
 // audioVoicesForProvider returns all available voices for the configured provider
 // without requiring a Provider instance (uses the package-level VoicesFor helper).
 func (p *Processor) audioVoicesForProvider() []string {
-	return audio.VoicesFor(p.AudioProviderName())
+	return p.audioVoicesFor(p.AudioProviderName())
+}
+
+// audioVoicesFor returns all voices of the named provider. The local voices
+// depend on the engine and model.
+func (p *Processor) audioVoicesFor(provider string) []string {
+	if provider == audio.LocalProviderName {
+		return audio.LocalVoices(p.localAudioConfig())
+	}
+	return audio.VoicesFor(provider)
+}
+
+// localAudioConfig returns the offline TTS settings of the run.
+func (p *Processor) localAudioConfig() audio.LocalAudioConfig {
+	return audio.LocalAudioConfig{
+		Engine: p.Config.LocalTTSEngine,
+		Binary: p.Config.LocalTTSBinary,
+		Model:  p.Config.LocalTTSModel,
+		Voice:  p.Config.LocalTTSVoice,
+	}
 }
 
 // audioVoiceForProvider selects a single voice for the configured provider.
-// If a specific voice is configured, it is returned; otherwise a random voice
-// from the provider's list is chosen using the injected randomIntn function.
 func (p *Processor) audioVoiceForProvider() string {
-	switch p.AudioProviderName() {
+	return p.audioVoiceFor(p.AudioProviderName())
+}
+
+// audioVoiceFor selects a single voice for the named provider. If a specific
+// voice is configured, it is returned; otherwise a random voice from the
+// provider's list is chosen using the injected randomIntn function.
+func (p *Processor) audioVoiceFor(provider string) string {
+	switch provider {
 	case "gemini":
 		if voice := p.GeminiVoice(); voice != "" {
 			return voice
 		}
-		voices := p.audioVoicesForProvider()
+		voices := p.audioVoicesFor(provider)
+		if p.randomIntn != nil {
+			return voices[p.randomIntn(len(voices))]
+		}
+		return voices[rand.Intn(len(voices))]
+	case audio.LocalProviderName:
+		if voice := p.Config.LocalTTSVoice; voice != "" {
+			return voice
+		}
+		voices := p.audioVoicesFor(provider)
 		if p.randomIntn != nil {
 			return voices[p.randomIntn(len(voices))]
 		}
@@ -49,7 +91,7 @@ func (p *Processor) audioVoiceForProvider() string {
 		if voice := p.OpenAIVoice(); voice != "" {
 			return voice
 		}
-		voices := p.audioVoicesForProvider()
+		voices := p.audioVoicesFor(provider)
 		if p.randomIntn != nil {
 			return voices[p.randomIntn(len(voices))]
 		}
@@ -67,6 +109,12 @@ func (p *Processor) logSelectedAudioVoice(provider, voice string) {
 		} else {
 			fmt.Printf("  Using random Gemini voice: %s\n", voice)
 		}
+	case audio.LocalProviderName:
+		if p.Config.LocalTTSVoice != "" {
+			fmt.Printf("  Using specified local voice: %s\n", voice)
+		} else {
+			fmt.Printf("  Using local voice: %s\n", voice)
+		}
 	default:
 		if p.OpenAIVoice() != "" {
 			fmt.Printf("  Using specified voice: %s\n", voice)
@@ -78,32 +126,51 @@ func (p *Processor) logSelectedAudioVoice(provider, voice string) {
 
 // generateAudio generates audio files for a word using the configured provider.
 // When AllVoices is set all provider voices are generated; otherwise a single
-// voice is selected (with Gemini fallback retry on empty audio).
+// voice is selected and the provider failover chain applies.
 // ctx is threaded down to provider.GenerateAudio so the caller's deadline applies.
 func (p *Processor) generateAudio(ctx context.Context, word string) error {
-	provider := p.AudioProviderName()
-
 	if p.Flags.AllVoices {
 		return p.generateAudioForAllVoices(ctx, word)
 	}
 
-	voice := p.audioVoiceForProvider()
-	p.logSelectedAudioVoice(provider, voice)
+	wordDir := p.findOrCreateWordDirectory(word)
+	return p.runAudioWithFailover(func(voice audioVoice) error {
+		return p.generateVoiceAudioInDir(ctx, word, voice, "audio", wordDir)
+	})
+}
 
-	// For Gemini with no explicit voice, use automatic fallback through all voices.
-	if provider == "gemini" && p.GeminiVoice() == "" {
+// runAudioWithFailover calls generate with a voice of each provider of the
+// failover chain until one succeeds. Gemini without a pinned voice first
+// retries its other voices when it returns no audio.
+func (p *Processor) runAudioWithFailover(generate func(voice audioVoice) error) error {
+	chain, err := audio.ProviderChain(p.AudioProviderName(), p.Config.AudioFallback)
+	if err != nil {
+		return err
+	}
+
+	_, err = audio.RunWithProviderFailover(chain, func(provider, failoverFrom string) error {
+		voice := p.audioVoiceFor(provider)
+		if failoverFrom != "" {
+			fmt.Printf("  Falling back to %s audio\n", provider)
+		}
+		p.logSelectedAudioVoice(provider, voice)
+
+		if provider != "gemini" || p.GeminiVoice() != "" {
+			return generate(audioVoice{Provider: provider, Voice: voice, FailoverFrom: failoverFrom})
+		}
 		_, err := audio.RunWithVoiceFallbacks(voice, func(candidate string) error {
 			if candidate != voice {
 				fmt.Printf("  Retrying Gemini audio with voice: %s\n", candidate)
 			}
-			return p.generateAudioWithVoice(ctx, word, candidate)
+			return generate(audioVoice{Provider: provider, Voice: candidate, FailoverFrom: failoverFrom})
 		}, func(candidate string) {
 			fmt.Printf("  Warning: Gemini returned no audio for voice %s\n", candidate)
 		})
 		return err
-	}
-
-	return p.generateAudioWithVoice(ctx, word, voice)
+	}, func(provider, reason string, err error) {
+		fmt.Printf("  Warning: %s audio failed (%s): %v\n", provider, reason, err)
+	})
+	return err
 }
 
 // generateAudioForAllVoices iterates over every voice for the configured
@@ -120,46 +187,27 @@ func (p *Processor) generateAudioForAllVoices(ctx context.Context, word string)
 }
 
 // generateAudioBgBg generates audio files for both sides of a bg-bg card.
-// Both audio files are saved to the same directory as the front-word card.
+// Both audio files are saved to the same directory as the front-word card,
+// and both sides use the same provider and voice.
 // ctx is threaded down to provider.GenerateAudio so the caller's deadline applies.
 func (p *Processor) generateAudioBgBg(ctx context.Context, front, back string) error {
-	provider := p.AudioProviderName()
-
-	voice := p.audioVoiceForProvider()
-	p.logSelectedAudioVoice(provider, voice)
-
 	// Find or create the word directory ONCE (for the front word).
 	// Both audio files will be saved to this same directory.
 	wordDir := p.findOrCreateWordDirectory(front)
 
-	generatePair := func(candidate string) error {
+	return p.runAudioWithFailover(func(voice audioVoice) error {
 		fmt.Printf("  Generating front audio for '%s'...\n", front)
-		if err := p.generateAudioWithVoiceAndFilenameInDir(ctx, front, candidate, "audio_front", wordDir); err != nil {
+		if err := p.generateVoiceAudioInDir(ctx, front, voice, "audio_front", wordDir); err != nil {
 			return fmt.Errorf("failed to generate front audio: %w", err)
 		}
 
 		fmt.Printf("  Generating back audio for '%s'...\n", back)
-		if err := p.generateAudioWithVoiceAndFilenameInDir(ctx, back, candidate, "audio_back", wordDir); err != nil {
+		if err := p.generateVoiceAudioInDir(ctx, back, voice, "audio_back", wordDir); err != nil {
 			return fmt.Errorf("failed to generate back audio: %w", err)
 		}
 
 		return nil
-	}
-
-	// Use automatic fallback through all Gemini voices when no voice is pinned.
-	if provider == "gemini" && p.GeminiVoice() == "" {
-		_, err := audio.RunWithVoiceFallbacks(voice, func(candidate string) error {
-			if candidate != voice {
-				fmt.Printf("  Retrying Gemini audio with voice: %s\n", candidate)
-			}
-			return generatePair(candidate)
-		}, func(candidate string) {
-			fmt.Printf("  Warning: Gemini returned no audio for voice %s\n", candidate)
-		})
-		return err
-	}
-
-	return generatePair(voice)
+	})
 }
 
 // generateAudioWithVoice generates audio for a word with a specific voice,
@@ -175,36 +223,40 @@ func (p *Processor) generateAudioWithVoiceAndFilename(ctx context.Context, word,
 	return p.generateAudioWithVoiceAndFilenameInDir(ctx, word, voice, filenameBase, wordDir)
 }
 
-// generateAudioWithVoiceAndFilenameInDir is the core audio generation method.
-// It assembles the provider config, creates the provider, runs TTS, and writes
-// the audio file plus its attribution/metadata sidecars to wordDir.
-// ctx is passed directly to provider.GenerateAudio so the caller's deadline applies.
+// generateAudioWithVoiceAndFilenameInDir generates audio with a voice of the
+// configured provider and saves it using filenameBase inside wordDir.
 func (p *Processor) generateAudioWithVoiceAndFilenameInDir(ctx context.Context, word, voice, filenameBase, wordDir string) error {
-	providerConfig := p.buildAudioProviderConfig(voice)
+	return p.generateVoiceAudioInDir(ctx, word, audioVoice{Provider: p.AudioProviderName(), Voice: voice}, filenameBase, wordDir)
+}
 
-	provider, err := p.newAudioProvider(providerConfig)
-	if err != nil {
-		return err
-	}
+// generateVoiceAudioInDir is the core audio generation method.
+// It assembles the provider config, renders the clip and its slow variant with
+// audio.RenderClip, and writes the metadata sidecar to wordDir.
+// ctx is passed directly to provider.GenerateAudio so the caller's deadline applies.
+func (p *Processor) generateVoiceAudioInDir(ctx context.Context, word string, voice audioVoice, filenameBase, wordDir string) error {
+	providerConfig := p.buildAudioProviderConfig(voice.Provider, voice.Voice)
+	providerConfig.FailoverFrom = voice.FailoverFrom
 
-	outputFile := p.buildAudioOutputPath(wordDir, filenameBase, voice, providerConfig.OutputFormat)
+	outputFile := p.buildAudioOutputPath(wordDir, filenameBase, voice.Voice, providerConfig.OutputFormat)
 
-	if err := provider.GenerateAudio(ctx, word, outputFile); err != nil {
-		return err
+	// The voice-specific files of --all-voices get no slow variants.
+	slowSpeed := p.Config.SlowAudioSpeed
+	if p.Flags.AllVoices {
+		slowSpeed = 0
 	}
-
-	// Write attribution and metadata sidecars next to the audio file.
-	if err := p.saveAudioAttribution(word, outputFile, providerConfig); err != nil {
-		return fmt.Errorf("failed to save audio attribution: %w", err)
+	cached, err := audio.RenderClip(ctx, p.audioCache, p.newAudioProvider, providerConfig, word, outputFile, slowSpeed)
+	if err != nil {
+		return err
 	}
+	providerConfig.CacheHit = cached
 
-	return nil
+	return p.saveAudioMetadata(providerConfig, outputFile)
 }
 
-// buildAudioProviderConfig assembles an audio.Config from CLI flags and the
-// resolved processor Config. The voice argument is the already-resolved voice string for this call.
-func (p *Processor) buildAudioProviderConfig(voice string) *audio.Config {
-	audioProvider := p.AudioProviderName()
+// buildAudioProviderConfig assembles an audio.Config for the named provider
+// from CLI flags and the resolved processor Config. The voice argument is the
+// already-resolved voice string for this call.
+func (p *Processor) buildAudioProviderConfig(audioProvider, voice string) *audio.Config {
 	audioFormat := p.EffectiveAudioFormat()
 
 	// Generate random speed between 0.90 and 1.00 if not explicitly set.
@@ -218,6 +270,7 @@ func (p *Processor) buildAudioProviderConfig(voice string) *audio.Config {
 	providerConfig.OutputDir = p.Flags.OutputDir
 	providerConfig.OpenAIKey = cli.GetOpenAIKey()
 	providerConfig.GoogleAPIKey = cli.GetGoogleAPIKey()
+	providerConfig.PostProcess = audio.AvailablePostProcess(p.Config.AudioPostProcess)
 
 	switch audioProvider {
 	case "gemini":
@@ -229,6 +282,13 @@ func (p *Processor) buildAudioProviderConfig(voice string) *audio.Config {
 			providerConfig.GeminiVoice = p.GeminiVoice()
 		}
 		providerConfig.GeminiSpeed = 1.0
+	case audio.LocalProviderName:
+		local := p.localAudioConfig()
+		providerConfig.OutputFormat = audioFormat
+		providerConfig.LocalEngine = local.Engine
+		providerConfig.LocalBinary = local.Binary
+		providerConfig.LocalModel = local.Model
+		providerConfig.LocalVoice = voice
 	default:
 		p.applyOpenAIAudioConfig(providerConfig, voice, speed, audioFormat)
 	}
@@ -267,29 +327,14 @@ func (p *Processor) buildAudioOutputPath(wordDir, filenameBase, voice, outputFor
 	return filepath.Join(wordDir, fmt.Sprintf("%s.%s", filenameBase, outputFormat))
 }
 
-// saveAudioAttribution writes two sidecar files next to the audio file:
-//   - <audioFile>.attribution.txt — human-readable attribution for the clip
-//   - audio_metadata.txt          — machine-readable metadata for the GUI
-func (p *Processor) saveAudioAttribution(word, audioFile string, config *audio.Config) error {
-	processedText := audio.ProcessedTextForProvider(config.Provider, word)
-	instruction := audio.InstructionForProvider(config.Provider, config)
-
-	params := audio.AttributionParamsFrom(config, word, instruction, processedText, time.Now())
-	attribution := audio.BuildAttributionFor(config.Provider, params)
-
-	attrPath := audio.AttributionPath(audioFile)
-	if err := os.WriteFile(attrPath, []byte(attribution), 0644); err != nil {
-		return fmt.Errorf("failed to write audio attribution file: %w", err)
-	}
-
-	// Also save metadata for GUI display.
-	wordDir := filepath.Dir(audioFile)
-	metadataFile := filepath.Join(wordDir, "audio_metadata.txt")
+// saveAudioMetadata writes audio_metadata.txt, the machine-readable metadata
+// of the card's audio for older releases.
+func (p *Processor) saveAudioMetadata(config *audio.Config, audioFile string) error {
+	metadataFile := filepath.Join(filepath.Dir(audioFile), "audio_metadata.txt")
 	metadata := p.buildAudioMetadata(config, audioFile)
-	if err := os.WriteFile(metadataFile, []byte(metadata), 0644); err != nil {
+	if err := store.WriteFileAtomic(metadataFile, []byte(metadata)); err != nil {
 		return fmt.Errorf("failed to save audio metadata: %w", err)
 	}
-
 	return nil
 }
 
@@ -309,6 +354,13 @@ func (p *Processor) buildAudioMetadata(config *audio.Config, audioFile string) s
 		GeminiTTSModel:    config.GeminiTTSModel,
 		GeminiVoice:       config.GeminiVoice,
 		GeminiSpeed:       config.GeminiSpeed,
+		LocalEngine:       config.LocalEngine,
+		LocalModel:        config.LocalModel,
+		LocalVoice:        config.LocalVoice,
+		LocalSpeed:        config.LocalSpeed,
+		FailoverFrom:      config.FailoverFrom,
+		CacheHit:          config.CacheHit,
+		PostProcess:       config.PostProcess,
 	})
 }
 
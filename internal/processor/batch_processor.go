@@ -99,13 +99,28 @@ func (b *BatchProcessor) processBatchEntries(entries []batch.WordEntry) (skipped
 		if p.isWordFullyProcessed(entry.Bulgarian) {
 			wordDir := p.findCardDirectory(entry.Bulgarian)
 			fmt.Printf("  ✓ Skipping '%s' - already fully processed in %s\n", entry.Bulgarian, filepath.Base(wordDir))
+			// Tags added to the batch file still reach finished cards.
+			if err := p.saveTags(wordDir, entry.Tags); err != nil {
+				fmt.Fprintf(os.Stderr, "Error tagging '%s': %v\n", entry.Bulgarian, err)
+			}
+			if err := b.saveEntryChoices(wordDir, entry); err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing '%s': %v\n", entry.Bulgarian, err)
+			}
 			skipped++
 			continue
 		}
 
 		wordCtx, wordCancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		err := p.ProcessWordWithTranslationAndType(wordCtx, entry.Bulgarian, entry.Translation, entry.CardType)
+		var err error
+		if entry.CardType.IsSentence() {
+			err = p.ProcessSentence(wordCtx, entry.Cloze, entry.Translation, entry.Tags)
+		} else {
+			err = p.ProcessWordWithTranslationAndType(wordCtx, entry.Bulgarian, entry.Translation, entry.CardType, entry.Tags)
+		}
 		wordCancel()
+		if err == nil {
+			err = b.saveEntryChoices(p.findCardDirectory(entry.Bulgarian), entry)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing '%s': %v\n", entry.Bulgarian, err)
 			errCount++
@@ -116,6 +131,18 @@ func (b *BatchProcessor) processBatchEntries(entries []batch.WordEntry) (skipped
 	return
 }
 
+// saveEntryChoices stores the card directions, the section and the example
+// sentence the batch line chose for its card.
+func (b *BatchProcessor) saveEntryChoices(wordDir string, entry batch.WordEntry) error {
+	if err := b.p.saveDirections(wordDir, entry.Directions); err != nil {
+		return err
+	}
+	if err := b.p.saveExample(wordDir, entry.Example, entry.ExampleTranslation); err != nil {
+		return err
+	}
+	return b.p.saveSection(wordDir, entry.Section)
+}
+
 // printBatchSummary prints a human-readable summary of the batch run.
 func (b *BatchProcessor) printBatchSummary(total, processed, skipped, errCount int) {
 	fmt.Printf("\n=== Batch Processing Summary ===\n")
@@ -27,6 +27,13 @@ func (p *Processor) findOrCreateWordDirectory(word string) string {
 	return p.cardStore.FindOrCreateCardDirectory(word)
 }
 
+// ensureWordDirectory is findOrCreateWordDirectory with error reporting. It
+// is used where a card is started so that a lock held by another totalrecall
+// process aborts the word with a clear error instead of a warning.
+func (p *Processor) ensureWordDirectory(word string) (string, error) {
+	return p.cardStore.EnsureCardDirectory(word)
+}
+
 // findCardDirectory searches the configured output directory for an existing
 // card directory that contains the given word. Returns an empty string when
 // no matching directory is found. Delegates to the shared CardStore.
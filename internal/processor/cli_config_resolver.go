@@ -6,6 +6,8 @@ package processor
 // those stay on Processor.
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"codeberg.org/snonux/totalrecall/internal/audio"
@@ -95,6 +97,28 @@ func (r *CLIConfigResolver) OpenAIVoice() string {
 	return ""
 }
 
+// AudioCache returns the TTS cache configured by audio.cache_dir and
+// audio.cache_size_mb, or nil when it is disabled. With --no-audio-cache it
+// generates fresh takes, which replace the cached clips.
+func (r *CLIConfigResolver) AudioCache() *audio.Cache {
+	if r.Config.AudioCacheSizeMB <= 0 {
+		return nil
+	}
+
+	dir := r.Config.AudioCacheDir
+	if dir == "" {
+		var err error
+		if dir, err = audio.DefaultCacheDir(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: TTS cache disabled: %v\n", err)
+			return nil
+		}
+	}
+
+	cache := audio.NewCache(dir, int64(r.Config.AudioCacheSizeMB)<<20)
+	cache.Refresh = r.Flags.NoAudioCache
+	return cache
+}
+
 // GUIConfig returns a gui.Config populated from flags and config.
 // Callers (typically cmd/main.go) use this to construct the GUI application
 // so that gui.New() lives outside the processor package and the processor→gui
@@ -131,11 +155,24 @@ func (r *CLIConfigResolver) GUIConfig() *gui.Config {
 		NanoBananaTextModel: r.NanoBananaTextModelForRunMode(),
 		GeminiTTSModel:      r.GeminiTTSModel(),
 		GeminiVoice:         r.GeminiVoice(),
+		LocalTTSEngine:      r.Config.LocalTTSEngine,
+		LocalTTSBinary:      r.Config.LocalTTSBinary,
+		LocalTTSModel:       r.Config.LocalTTSModel,
+		LocalTTSVoice:       r.Config.LocalTTSVoice,
+		AudioFallback:       r.Config.AudioFallback,
+		AudioCache:          r.AudioCache(),
+		AudioPostProcess:    r.Config.AudioPostProcess,
+		SlowAudioSpeed:      r.Config.SlowAudioSpeed,
 		TranslationProvider: translationProvider,
 		PhoneticProvider:    phoneticProvider,
 		AutoPlay:            !r.Flags.NoAutoPlay, // Invert the flag (--no-auto-play disables auto-play)
 		PhoneticFetcher:     phoneticFetcher,
 		Translator:          translator,
+		AnkiConnectURL:      r.Config.AnkiConnectURL,
+		DeckOptions:         r.Config.DeckOptions,
+		CardDirections:      r.Config.CardDirections,
+		PackageFormat:       r.Config.PackageFormat,
+		DeckGrouping:        r.Config.DeckGrouping,
 	}
 }
 
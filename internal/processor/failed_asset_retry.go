@@ -94,11 +94,12 @@ func (p *Processor) scanFailedAssetPlans() ([]failedAssetPlan, error) {
 }
 
 func (p *Processor) buildFailedAssetPlan(card store.CardDirectory) failedAssetPlan {
+	manifest := store.LoadManifest(card.Path)
 	plan := failedAssetPlan{
 		Card:        card,
-		CardType:    internal.LoadCardType(card.Path),
-		Translation: readStoredTranslation(card.Path),
-		ImagePrompt: readStoredImagePrompt(card.Path),
+		CardType:    internal.ParseCardType(manifest.CardType),
+		Translation: manifest.Translation,
+		ImagePrompt: usableImagePrompt(manifest.ImagePrompt),
 	}
 
 	if !p.Flags.SkipAudio {
@@ -119,13 +120,25 @@ func (p *Processor) buildFailedAssetPlan(card store.CardDirectory) failedAssetPl
 		}
 	}
 
-	if !p.Flags.SkipImages && !imageAssetReady(card.Path) {
+	if !p.Flags.SkipImages && !imageAssetReady(card.Path, plan.ImagePrompt) && !importedImageReady(card.Path, manifest) {
 		plan.Assets = append(plan.Assets, failedAssetImage)
 	}
 
 	return plan
 }
 
+// importedImageReady reports whether the card has an image copied from an
+// APKG file. Such images have no prompt, but they are what the user studied
+// with, so they must not be replaced by a generated one.
+func importedImageReady(wordDir string, manifest *store.Manifest) bool {
+	for _, asset := range manifest.AssetsOfKind(store.AssetImage) {
+		if asset.Provider == anki.ImportProvider && fileExistsAndNonEmpty(filepath.Join(wordDir, asset.File)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Processor) regenerateFailedAsset(ctx context.Context, plan failedAssetPlan, asset failedAssetKind) error {
 	switch asset {
 	case failedAssetAudio:
@@ -150,26 +163,10 @@ func (p *Processor) regenerateFailedAsset(ctx context.Context, plan failedAssetP
 }
 
 func (p *Processor) generateCardAudioSideInDir(ctx context.Context, text, wordDir, filenameBase, label string) error {
-	provider := p.AudioProviderName()
-	voice := p.audioVoiceForProvider()
-	p.logSelectedAudioVoice(provider, voice)
-
-	run := func(candidate string) error {
-		if candidate != voice {
-			fmt.Printf("  Retrying Gemini audio with voice: %s\n", candidate)
-		}
+	return p.runAudioWithFailover(func(voice audioVoice) error {
 		fmt.Printf("  Generating %s for '%s'...\n", label, text)
-		return p.generateAudioWithVoiceAndFilenameInDir(ctx, text, candidate, filenameBase, wordDir)
-	}
-
-	if provider == "gemini" && p.GeminiVoice() == "" {
-		_, err := audio.RunWithVoiceFallbacks(voice, run, func(candidate string) {
-			fmt.Printf("  Warning: Gemini returned no audio for voice %s\n", candidate)
-		})
-		return err
-	}
-
-	return run(voice)
+		return p.generateVoiceAudioInDir(ctx, text, voice, filenameBase, wordDir)
+	})
 }
 
 func audioAssetReady(wordDir, baseName, preferredFormat string) bool {
@@ -187,14 +184,14 @@ func audioAssetReady(wordDir, baseName, preferredFormat string) bool {
 	return fileExistsAndNonEmpty(filepath.Join(wordDir, "audio_metadata.txt"))
 }
 
-func imageAssetReady(wordDir string) bool {
+func imageAssetReady(wordDir, imagePrompt string) bool {
 	if firstUsableImagePath(wordDir) == "" {
 		return false
 	}
 	if !fileExistsAndNonEmpty(filepath.Join(wordDir, "image_attribution.txt")) {
 		return false
 	}
-	return readStoredImagePrompt(wordDir) != ""
+	return imagePrompt != ""
 }
 
 func firstUsableImagePath(wordDir string) string {
@@ -227,27 +224,10 @@ func firstUsableImagePath(wordDir string) string {
 	return ""
 }
 
-func readStoredTranslation(wordDir string) string {
-	data, err := os.ReadFile(filepath.Join(wordDir, "translation.txt"))
-	if err != nil {
-		return ""
-	}
-
-	parts := strings.SplitN(string(data), "=", 2)
-	if len(parts) != 2 {
-		return strings.TrimSpace(string(data))
-	}
-
-	return strings.TrimSpace(parts[1])
-}
-
-func readStoredImagePrompt(wordDir string) string {
-	data, err := os.ReadFile(filepath.Join(wordDir, "image_prompt.txt"))
-	if err != nil {
-		return ""
-	}
-
-	prompt := strings.TrimSpace(string(data))
+// usableImagePrompt returns the stored image prompt, or "" when it is empty
+// or records a provider failure instead of a real prompt.
+func usableImagePrompt(prompt string) string {
+	prompt = strings.TrimSpace(prompt)
 	if prompt == "" || looksLikeFailedPrompt(prompt) {
 		return ""
 	}
@@ -10,13 +10,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"codeberg.org/snonux/totalrecall/internal"
 	"codeberg.org/snonux/totalrecall/internal/cli"
 	"codeberg.org/snonux/totalrecall/internal/image"
 	"codeberg.org/snonux/totalrecall/internal/registry"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 // downloadImagesWithTranslation downloads images for a word into its card
@@ -38,6 +38,12 @@ func (p *Processor) downloadImagesWithPrompt(ctx context.Context, word, translat
 
 	wordDir := p.findOrCreateWordDirectory(word)
 
+	// Keep the image being replaced (and its prompt) as a revision before the
+	// prompt callback and the download overwrite them.
+	if err := store.SnapshotAssets(wordDir); err != nil {
+		return fmt.Errorf("failed to preserve previous image: %w", err)
+	}
+
 	downloader := image.NewDownloader(searcher, &image.DownloadOptions{
 		OutputDir:         wordDir,
 		OverwriteExisting: true,
@@ -53,6 +59,7 @@ func (p *Processor) downloadImagesWithPrompt(ctx context.Context, word, translat
 	if strings.TrimSpace(customPrompt) != "" {
 		searchOpts.CustomPrompt = strings.TrimSpace(customPrompt)
 	}
+	searchOpts.Sentence = internal.LoadCardType(wordDir).IsSentence()
 
 	// Register a prompt callback so the AI-generated prompt is persisted
 	// to disk before the download completes (used by the GUI and for debugging).
@@ -75,6 +82,16 @@ func (p *Processor) downloadImagesWithPrompt(ctx context.Context, word, translat
 		return err
 	}
 
+	err = store.RecordAsset(wordDir, store.Asset{
+		Kind:     store.AssetImage,
+		File:     path,
+		Provider: searcher.Name(),
+		Model:    p.imageModelForRunMode(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record image in card manifest: %w", err)
+	}
+
 	return nil
 }
 
@@ -85,14 +102,13 @@ func (p *Processor) downloadImagesWithPrompt(ctx context.Context, word, translat
 // promptErr accumulates write failures so downloadImagesWithTranslation can
 // return them to the caller instead of only logging.
 func (p *Processor) registerPromptCallback(searcher image.PromptAwareClient, wordDir string, promptErr *error) {
-	promptFile := filepath.Join(wordDir, "image_prompt.txt")
 	searcher.SetPromptCallback(func(prompt string) {
 		if prompt == "" {
 			return
 		}
-		if err := os.WriteFile(promptFile, []byte(prompt), 0644); err != nil {
+		if err := store.SaveImagePrompt(wordDir, prompt); err != nil {
 			if promptErr != nil {
-				*promptErr = errors.Join(*promptErr, fmt.Errorf("failed to save image prompt: %w", err))
+				*promptErr = errors.Join(*promptErr, err)
 			}
 		}
 	})
@@ -117,11 +133,7 @@ func (p *Processor) saveImagePrompt(wordDir string, searcher image.PromptAwareCl
 		return nil
 	}
 
-	promptFile := filepath.Join(wordDir, "image_prompt.txt")
-	if err := os.WriteFile(promptFile, []byte(usedPrompt), 0644); err != nil {
-		return fmt.Errorf("failed to save image prompt: %w", err)
-	}
-	return nil
+	return store.SaveImagePrompt(wordDir, usedPrompt)
 }
 
 // processorImageClientFactories maps run-mode image provider name to builder.
@@ -158,6 +170,19 @@ func (p *Processor) imageProviderForRunMode() string {
 	return strings.ToLower(strings.TrimSpace(p.Flags.ImageAPI))
 }
 
+// imageModelForRunMode returns the image model recorded as provenance in the
+// card manifest, applying the same flag-wins-over-config rules as the
+// searcher constructors below.
+func (p *Processor) imageModelForRunMode() string {
+	if p.imageProviderForRunMode() == image.ImageProviderOpenAI {
+		if p.Flags.OpenAIImageModel == "dall-e-2" && p.Config.ImageOpenAIModelSet {
+			return p.Config.ImageOpenAIModel
+		}
+		return p.Flags.OpenAIImageModel
+	}
+	return p.NanoBananaModelForRunMode()
+}
+
 // newOpenAIImageSearcher builds an OpenAI PromptAwareClient from CLI flags and
 // the resolved processor Config. Config-file overrides are applied only when
 // the flag still holds its default value so explicit CLI flags always win.
@@ -6,8 +6,11 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
+	"codeberg.org/snonux/totalrecall/internal/ankiconnect"
 	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/cli"
 	"codeberg.org/snonux/totalrecall/internal/httpctx"
@@ -41,6 +44,24 @@ type Config struct {
 	OpenAISpeedSet       bool
 	OpenAIInstruction    string
 	OpenAIInstructionSet bool
+	// Local (offline) TTS settings of the "local" audio provider.
+	LocalTTSEngine string
+	LocalTTSBinary string
+	LocalTTSModel  string
+	LocalTTSVoice  string
+	// AudioFallback lists the providers tried in order after AudioProvider
+	// when it fails on quota, authentication, an open circuit or a safety
+	// block.
+	AudioFallback []string
+	// AudioCacheDir holds the TTS cache; empty uses the state directory.
+	// AudioCacheSizeMB caps its size; 0 disables the cache.
+	AudioCacheDir    string
+	AudioCacheSizeMB int
+	// AudioPostProcess normalizes, trims and fades generated clips.
+	AudioPostProcess audio.PostProcessConfig
+	// SlowAudioSpeed is the speed of the slow variant rendered next to
+	// every clip; 0 renders none.
+	SlowAudioSpeed float64
 
 	// Image settings
 	ImageProvider               string
@@ -56,6 +77,24 @@ type Config struct {
 	ImageNanoBananaModelSet     bool
 	ImageNanoBananaTextModel    string
 	ImageNanoBananaTextModelSet bool
+
+	// AnkiConnectURL is the AnkiConnect endpoint; empty means the default.
+	AnkiConnectURL string
+	// DeckOptions are the scheduling options of APKG exports; nil means
+	// anki.DefaultDeckOptions.
+	DeckOptions *anki.DeckOptions
+	// CardDirections are the card directions of exported cards that do not
+	// choose their own; zero means internal.DefaultCardDirections.
+	CardDirections internal.CardDirections
+	// PackageFormat is the layout of APKG exports; empty means
+	// anki.DefaultPackageFormat.
+	PackageFormat anki.PackageFormat
+	// DeckGrouping splits exports into subdecks; empty means
+	// anki.DeckGroupingNone.
+	DeckGrouping anki.DeckGrouping
+	// ExportFilter narrows Anki exports to new, changed or tagged cards;
+	// zero exports all cards.
+	ExportFilter anki.ExportFilter
 }
 
 // Processor handles the main word processing logic.
@@ -86,6 +125,9 @@ type Processor struct {
 	// Production code uses audio.NewProvider; tests replace it with a fake.
 	newAudioProvider audio.ProviderFactory
 
+	// audioCache reuses identical clips; nil when the TTS cache is disabled.
+	audioCache *audio.Cache
+
 	batchProcessor *BatchProcessor
 	ankiExporter   *AnkiExporter
 }
@@ -110,6 +152,7 @@ func NewProcessor(flags *cli.Flags, cfg *Config) *Processor {
 		imageFactories:   image.DefaultClientFactories(),
 		newAudioProvider: audio.NewProvider,
 	}
+	p.audioCache = p.AudioCache()
 	p.batchProcessor = &BatchProcessor{p: p}
 	p.ankiExporter = &AnkiExporter{p: p}
 	return p
@@ -131,31 +174,64 @@ func (p *Processor) ProcessSingleWord(word string) error {
 	}
 
 	fmt.Printf("\nProcessing: %s\n", word)
+	if internal.HasCloze(word) {
+		ctx, cancel := context.WithTimeout(context.Background(), httpctx.SingleWordProcessTimeout)
+		defer cancel()
+		return p.ProcessSentence(ctx, internal.ExpandClozeShorthand(word), "", nil)
+	}
 	return p.ProcessWordWithTranslation(word, "")
 }
 
+// ProcessSentence creates a sentence card from a cloze text such as
+// "{{c1::Къде}} е гарата?". The card directory is named after the plain
+// sentence, which is also what the audio and the translation are made of.
+func (p *Processor) ProcessSentence(ctx context.Context, cloze, providedTranslation string, tags []string) error {
+	sentence := internal.ClozeText(cloze)
+	if len(internal.ClozeNumbers(cloze)) == 0 {
+		return fmt.Errorf("sentence '%s' has no cloze deletion", sentence)
+	}
+
+	wordDir, err := p.ensureWordDirectory(sentence)
+	if err != nil {
+		return fmt.Errorf("failed to create card directory: %w", err)
+	}
+	if err := store.SaveCloze(wordDir, cloze); err != nil {
+		return fmt.Errorf("failed to save cloze: %w", err)
+	}
+
+	return p.ProcessWordWithTranslationAndType(ctx, sentence, providedTranslation, internal.CardTypeSentence, tags)
+}
+
 // ProcessWordWithTranslation processes a word with an optional provided English
 // translation, using the default en-bg card type.
 func (p *Processor) ProcessWordWithTranslation(word, providedTranslation string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), httpctx.SingleWordProcessTimeout)
 	defer cancel()
-	return p.ProcessWordWithTranslationAndType(ctx, word, providedTranslation, internal.CardTypeEnBg)
+	return p.ProcessWordWithTranslationAndType(ctx, word, providedTranslation, internal.CardTypeEnBg, nil)
 }
 
 // ProcessWordWithTranslationAndType processes a word with optional provided
-// translation and card type. ctx is used for all downstream API calls (audio
+// translation and card type. tags are added to the card together with the
+// --tag flags. ctx is used for all downstream API calls (audio
 // TTS, image generation) so the caller can cancel or time-out the operation.
 // ProcessBatch passes a per-word deadline; callers without a deadline may pass
 // context.Background().
-func (p *Processor) ProcessWordWithTranslationAndType(ctx context.Context, word, providedTranslation string, cardType internal.CardType) error {
+func (p *Processor) ProcessWordWithTranslationAndType(ctx context.Context, word, providedTranslation string, cardType internal.CardType, tags []string) error {
 	translationText := p.resolveTranslation(ctx, word, providedTranslation, cardType)
 
-	wordDir := p.findOrCreateWordDirectory(word)
+	wordDir, err := p.ensureWordDirectory(word)
+	if err != nil {
+		return fmt.Errorf("failed to create card directory: %w", err)
+	}
 
 	if err := internal.SaveCardType(wordDir, cardType); err != nil {
 		return fmt.Errorf("failed to save card type: %w", err)
 	}
 
+	if err := p.saveTags(wordDir, tags); err != nil {
+		return err
+	}
+
 	if err := p.saveTranslationIfNeeded(word, translationText, wordDir); err != nil {
 		return fmt.Errorf("failed to save translation: %w", err)
 	}
@@ -184,8 +260,8 @@ func (p *Processor) ProcessWordWithTranslationAndType(ctx context.Context, word,
 }
 
 // resolveTranslation determines the effective translation text for the word.
-// For bg-bg cards it uses the provided definition; for en-bg cards it fetches
-// an English translation when none was provided.
+// For bg-bg cards it uses the provided definition; for en-bg and sentence
+// cards it fetches an English translation when none was provided.
 func (p *Processor) resolveTranslation(_ context.Context, word, providedTranslation string, cardType internal.CardType) string {
 	if providedTranslation != "" {
 		if cardType.IsBgBg() {
@@ -201,7 +277,11 @@ func (p *Processor) resolveTranslation(_ context.Context, word, providedTranslat
 	}
 
 	fmt.Printf("  Translating to English...\n")
-	translationText, err := p.translator.TranslateWord(word)
+	translate := p.translator.TranslateWord
+	if cardType.IsSentence() {
+		translate = p.translator.TranslateSentence
+	}
+	translationText, err := translate(word)
 	if err != nil {
 		fmt.Printf("  Warning: Translation failed: %v\n", err)
 		return ""
@@ -228,6 +308,50 @@ func (p *Processor) saveTranslationIfNeeded(word, translationText, wordDir strin
 	return nil
 }
 
+// saveTags adds the --tag flags and the given per-word tags to the card.
+func (p *Processor) saveTags(wordDir string, tags []string) error {
+	if err := store.AddTags(wordDir, slices.Concat(p.Flags.Tags, tags)); err != nil {
+		return fmt.Errorf("failed to save tags: %w", err)
+	}
+	return nil
+}
+
+// saveDirections records the card directions a batch line chose for the
+// card. Without a choice the card keeps the directions it has.
+func (p *Processor) saveDirections(wordDir string, directions internal.CardDirections) error {
+	if directions.IsZero() {
+		return nil
+	}
+	if err := internal.SaveCardDirections(wordDir, directions); err != nil {
+		return fmt.Errorf("failed to save card directions: %w", err)
+	}
+	return nil
+}
+
+// saveSection records the batch file section the card is listed under.
+// Lines outside of sections leave the section of the card alone.
+func (p *Processor) saveSection(wordDir, section string) error {
+	if section == "" {
+		return nil
+	}
+	if err := store.SaveSection(wordDir, section); err != nil {
+		return fmt.Errorf("failed to save section: %w", err)
+	}
+	return nil
+}
+
+// saveExample records the example sentence a batch line gave for the card.
+// Lines without an example leave the example of the card alone.
+func (p *Processor) saveExample(wordDir, example, translation string) error {
+	if example == "" {
+		return nil
+	}
+	if err := store.SaveExample(wordDir, example, translation); err != nil {
+		return fmt.Errorf("failed to save example: %w", err)
+	}
+	return nil
+}
+
 // generateAudioForCard dispatches audio generation to the appropriate helper
 // based on card type. bg-bg cards need audio for both front and back sides.
 func (p *Processor) generateAudioForCard(ctx context.Context, word, translationText string, cardType internal.CardType) error {
@@ -241,3 +365,8 @@ func (p *Processor) generateAudioForCard(ctx context.Context, word, translationT
 func (p *Processor) GenerateAnkiFile() (string, error) {
 	return p.ankiExporter.GenerateAnkiFile()
 }
+
+// PushToAnkiConnect adds the cards to a running Anki through AnkiConnect.
+func (p *Processor) PushToAnkiConnect(ctx context.Context) ([]ankiconnect.Result, error) {
+	return p.ankiExporter.PushToAnkiConnect(ctx)
+}
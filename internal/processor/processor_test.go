@@ -8,15 +8,18 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"codeberg.org/snonux/totalrecall/internal"
+	"codeberg.org/snonux/totalrecall/internal/anki"
 	"codeberg.org/snonux/totalrecall/internal/audio"
 	"codeberg.org/snonux/totalrecall/internal/cli"
 	"codeberg.org/snonux/totalrecall/internal/gui"
 	"codeberg.org/snonux/totalrecall/internal/image"
 	"codeberg.org/snonux/totalrecall/internal/phonetic"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 type stubImageSearcher struct {
@@ -364,6 +367,206 @@ func TestGenerateAudioUsesSharedOpenAIVoices(t *testing.T) {
 	}
 }
 
+func TestGenerateAudioFailsOverToTheNextProvider(t *testing.T) {
+	flags := cli.NewFlags()
+	flags.OutputDir = t.TempDir()
+	flags.AudioFormat = "mp3"
+
+	cfg := &Config{
+		AudioProvider: "openai",
+		AudioFallback: []string{"gemini,local"},
+		LocalTTSVoice: "bg+f2",
+	}
+	p := NewProcessor(flags, cfg)
+	var providers []string
+	p.newAudioProvider = func(config *audio.Config) (audio.Provider, error) {
+		providers = append(providers, config.Provider)
+		switch config.Provider {
+		case "openai":
+			return nil, errors.New("OpenAI API key is required")
+		case "gemini":
+			return &fakeAudioProvider{generateFunc: func(string, string) error {
+				return fmt.Errorf("%w: SAFETY", audio.ErrGeminiAudioBlocked)
+			}}, nil
+		}
+		return &fakeAudioProvider{}, nil
+	}
+
+	if err := p.generateAudio(context.Background(), "ябълка"); err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+	if want := []string{"openai", "gemini", "local"}; strings.Join(providers, ",") != strings.Join(want, ",") {
+		t.Fatalf("providers = %v, want %v", providers, want)
+	}
+
+	wordDir := p.findCardDirectory("ябълка")
+	metadata, err := os.ReadFile(filepath.Join(wordDir, "audio_metadata.txt"))
+	if err != nil {
+		t.Fatalf("expected metadata file: %v", err)
+	}
+	for _, want := range []string{
+		"provider=local",
+		"failover_from=openai (authentication failed), gemini (blocked by safety filter)",
+		"voice=bg+f2",
+	} {
+		if !strings.Contains(string(metadata), want) {
+			t.Fatalf("metadata = %q, missing %q", metadata, want)
+		}
+	}
+	attribution, err := os.ReadFile(audio.AttributionPath(filepath.Join(wordDir, "audio.mp3")))
+	if err != nil {
+		t.Fatalf("expected attribution file: %v", err)
+	}
+	if !strings.Contains(string(attribution), "Audio generated offline by local TTS") ||
+		!strings.Contains(string(attribution), "Fallback after: openai (authentication failed), gemini (blocked by safety filter)") {
+		t.Fatalf("attribution = %q, want the local provider and the failed providers", attribution)
+	}
+}
+
+func TestGenerateAudioReusesCachedClips(t *testing.T) {
+	cacheDir := t.TempDir()
+	newProcessor := func(noCache bool) (*Processor, *fakeAudioProvider) {
+		flags := cli.NewFlags()
+		flags.OutputDir = t.TempDir()
+		flags.AudioFormat = "mp3"
+		flags.NoAudioCache = noCache
+		p := NewProcessor(flags, &Config{
+			AudioProvider:    audio.LocalProviderName,
+			LocalTTSVoice:    "bg+f2",
+			AudioCacheDir:    cacheDir,
+			AudioCacheSizeMB: 1,
+		})
+		provider := &fakeAudioProvider{generateFunc: func(_, outputFile string) error {
+			return os.WriteFile(outputFile, []byte("take"), 0644)
+		}}
+		p.newAudioProvider = func(*audio.Config) (audio.Provider, error) {
+			return provider, nil
+		}
+		return p, provider
+	}
+	metadata := func(p *Processor) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(p.findCardDirectory("ябълка"), "audio_metadata.txt"))
+		if err != nil {
+			t.Fatalf("expected metadata file: %v", err)
+		}
+		return string(data)
+	}
+
+	first, provider := newProcessor(false)
+	if err := first.generateAudio(context.Background(), "ябълка"); err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+	if provider.generateCalls != 1 || strings.Contains(metadata(first), "cache=hit") {
+		t.Fatalf("first run: %d generate calls, metadata %q; want a fresh take", provider.generateCalls, metadata(first))
+	}
+
+	second, provider := newProcessor(false)
+	if err := second.generateAudio(context.Background(), "ябълка"); err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+	if provider.generateCalls != 0 || !strings.Contains(metadata(second), "cache=hit") {
+		t.Fatalf("second run: %d generate calls, metadata %q; want the cached clip", provider.generateCalls, metadata(second))
+	}
+	if data, err := os.ReadFile(filepath.Join(second.findCardDirectory("ябълка"), "audio.mp3")); err != nil || string(data) != "take" {
+		t.Fatalf("cached audio = %q, %v", data, err)
+	}
+
+	fresh, provider := newProcessor(true)
+	if err := fresh.generateAudio(context.Background(), "ябълка"); err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+	if provider.generateCalls != 1 || strings.Contains(metadata(fresh), "cache=hit") {
+		t.Fatalf("--no-audio-cache run: %d generate calls; want a fresh take", provider.generateCalls)
+	}
+}
+
+func TestGenerateAudioPostProcessesClips(t *testing.T) {
+	// A fake ffmpeg in PATH writes the filter chain it was given as the audio.
+	binDir := t.TempDir()
+	script := "#!/bin/sh\nfilters=\"\"\nprev=\"\"\nout=\"\"\nfor arg in \"$@\"; do if [ \"$prev\" = \"-af\" ]; then filters=\"$arg\"; fi; prev=\"$arg\"; out=\"$arg\"; done\nprintf '%s' \"$filters\" > \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(binDir, "ffmpeg"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake ffmpeg script: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+
+	flags := cli.NewFlags()
+	flags.OutputDir = t.TempDir()
+	flags.AudioFormat = "mp3"
+	p := NewProcessor(flags, &Config{
+		AudioProvider:    audio.LocalProviderName,
+		LocalTTSVoice:    "bg+f2",
+		AudioPostProcess: audio.PostProcessConfig{LoudnessLUFS: -16, FadeMs: 20},
+	})
+	p.newAudioProvider = func(*audio.Config) (audio.Provider, error) {
+		return &fakeAudioProvider{generateFunc: func(_, outputFile string) error {
+			return os.WriteFile(outputFile, []byte("raw"), 0644)
+		}}, nil
+	}
+
+	if err := p.generateAudio(context.Background(), "ябълка"); err != nil {
+		t.Fatalf("generateAudio() unexpected error: %v", err)
+	}
+
+	wordDir := p.findCardDirectory("ябълка")
+	data, err := os.ReadFile(filepath.Join(wordDir, "audio.mp3"))
+	if err != nil || !strings.Contains(string(data), "loudnorm=I=-16") || !strings.Contains(string(data), "afade") {
+		t.Fatalf("audio = %q, %v; want the post-processed clip", data, err)
+	}
+	metadata, err := os.ReadFile(filepath.Join(wordDir, "audio_metadata.txt"))
+	if err != nil {
+		t.Fatalf("expected metadata file: %v", err)
+	}
+	if !strings.Contains(string(metadata), "loudness_lufs=-16.0\nfade_ms=20\n") || strings.Contains(string(metadata), "silence_trim_db") {
+		t.Fatalf("metadata = %q, want the post-processing settings", metadata)
+	}
+}
+
+func TestGenerateAudioBgBgRendersSlowVariants(t *testing.T) {
+	flags := cli.NewFlags()
+	flags.OutputDir = t.TempDir()
+	flags.AudioFormat = "mp3"
+	p := NewProcessor(flags, &Config{
+		AudioProvider:  audio.LocalProviderName,
+		LocalTTSVoice:  "bg+f2",
+		SlowAudioSpeed: 0.6,
+	})
+	speeds := make(map[string]float64)
+	p.newAudioProvider = func(config *audio.Config) (audio.Provider, error) {
+		return &fakeAudioProvider{generateFunc: func(text, outputFile string) error {
+			speeds[filepath.Base(outputFile)] = config.LocalSpeed
+			return os.WriteFile(outputFile, []byte(text), 0644)
+		}}, nil
+	}
+
+	if err := p.generateAudioBgBg(context.Background(), "ябълка", "плод"); err != nil {
+		t.Fatalf("generateAudioBgBg() unexpected error: %v", err)
+	}
+
+	wordDir := p.findCardDirectory("ябълка")
+	want := map[string]float64{"audio_front.mp3": 1.0, "audio_front_slow.mp3": 0.6, "audio_back.mp3": 1.0, "audio_back_slow.mp3": 0.6}
+	if !reflect.DeepEqual(speeds, want) {
+		t.Fatalf("generated clips at speeds %v; want %v", speeds, want)
+	}
+	for baseName, text := range map[string]string{"audio_front_slow": "ябълка", "audio_back_slow": "плод"} {
+		file := anki.ResolveAudioFile(wordDir, baseName, "mp3")
+		if data, err := os.ReadFile(file); err != nil || string(data) != text {
+			t.Errorf("%s = %q, %v; want the slow clip of %q", baseName, data, err, text)
+		}
+		if _, err := os.Stat(audio.AttributionPath(file)); err != nil {
+			t.Errorf("%s has no attribution: %v", baseName, err)
+		}
+	}
+
+	metadata, err := os.ReadFile(filepath.Join(wordDir, "audio_metadata.txt"))
+	if err != nil {
+		t.Fatalf("expected metadata file: %v", err)
+	}
+	if !strings.Contains(string(metadata), "audio_file_back=audio_back.mp3\n") || !strings.Contains(string(metadata), "speed=1.00") {
+		t.Fatalf("metadata = %q, want the normal-speed clips", metadata)
+	}
+}
+
 func TestGenerateAudioBgBgUsesSharedOpenAIVoices(t *testing.T) {
 	originalVoices := append([]string(nil), audio.OpenAIVoices...)
 	t.Cleanup(func() {
@@ -997,7 +1200,7 @@ func TestGenerateAnkiFileUsesEffectiveAudioFormatForGemini(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GenerateAnkiFile() unexpected error: %v", err)
 	}
-	if !strings.HasSuffix(outputPath, "anki_import.csv") {
+	if outputPath != filepath.Join(tempDir, anki.CSVBundleDir, anki.CSVFileName) {
 		t.Fatalf("GenerateAnkiFile() output = %q, want CSV output", outputPath)
 	}
 
@@ -1009,6 +1212,9 @@ func TestGenerateAnkiFileUsesEffectiveAudioFormatForGemini(t *testing.T) {
 	if !strings.Contains(string(csvData), fmt.Sprintf("[sound:%s_audio_alpha.wav]", cardID)) {
 		t.Fatalf("generated CSV did not reference the resolved multi-voice wav audio for card %q: %s", cardID, csvData)
 	}
+	if _, err := os.Stat(filepath.Join(tempDir, anki.CSVBundleDir, anki.CSVMediaDir, cardID+"_audio_alpha.wav")); err != nil {
+		t.Fatalf("audio was not copied into the CSV bundle: %v", err)
+	}
 }
 
 func TestIsWordFullyProcessedUsesMultiVoiceAttributionFiles(t *testing.T) {
@@ -1481,6 +1687,34 @@ func TestRetryFailedAssets_RegeneratesOnlyMissingBgBgBackAudio(t *testing.T) {
 	}
 }
 
+func TestRetryFailedAssets_KeepsImportedImage(t *testing.T) {
+	flags := cli.NewFlags()
+	flags.OutputDir = t.TempDir()
+	flags.SkipAudio = true
+	p := NewProcessor(flags, &Config{})
+
+	cardDir := p.findOrCreateWordDirectory("ябълка")
+	imagePath := filepath.Join(cardDir, "image.jpg")
+	if err := os.WriteFile(imagePath, []byte("imported image"), 0644); err != nil {
+		t.Fatalf("setup image.jpg: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cardDir, "image_attribution.txt"), []byte("Imported from deck.apkg"), 0644); err != nil {
+		t.Fatalf("setup image attribution: %v", err)
+	}
+	if err := store.RecordAsset(cardDir, store.Asset{Kind: store.AssetImage, File: imagePath, Provider: anki.ImportProvider}); err != nil {
+		t.Fatalf("setup image asset: %v", err)
+	}
+
+	output := captureStdout(t, func() {
+		if err := p.RetryFailedAssets(); err != nil {
+			t.Fatalf("RetryFailedAssets() unexpected error: %v", err)
+		}
+	})
+	if !strings.Contains(output, "No failed assets found") {
+		t.Fatalf("imported image without a prompt was retried: %q", output)
+	}
+}
+
 func TestProcessWordWithTranslation_ProvidedTranslation(t *testing.T) {
 	flags := cli.NewFlags()
 	flags.OutputDir = t.TempDir()
@@ -1559,6 +1793,23 @@ func TestFindCardDirectory(t *testing.T) {
 	}
 }
 
+func TestSaveTagsCombinesFlagAndWordTags(t *testing.T) {
+	flags := cli.NewFlags()
+	flags.OutputDir = t.TempDir()
+	flags.Tags = []string{"lesson-3"}
+	p := NewProcessor(flags, &Config{})
+
+	wordDir := p.findOrCreateWordDirectory("ябълка")
+	if err := p.saveTags(wordDir, []string{"food"}); err != nil {
+		t.Fatalf("saveTags() error = %v", err)
+	}
+
+	got := store.LoadManifest(wordDir).Tags
+	if len(got) != 2 || got[0] != "food" || got[1] != "lesson-3" {
+		t.Errorf("Tags = %v; want [food lesson-3]", got)
+	}
+}
+
 func TestGenerateAnkiFile(t *testing.T) {
 	flags := cli.NewFlags()
 	flags.OutputDir = t.TempDir()
@@ -1579,14 +1830,14 @@ func TestGenerateAnkiFile(t *testing.T) {
 		t.Errorf("GenerateAnkiFile failed: %v", err)
 	}
 
-	// Check CSV file was created in home directory
+	// Check CSV bundle was created in home directory
 	homeDir, _ := os.UserHomeDir()
-	csvFile := filepath.Join(homeDir, "anki_import.csv")
-	if _, err := os.Stat(csvFile); os.IsNotExist(err) {
+	bundleDir := filepath.Join(homeDir, anki.CSVBundleDir)
+	if _, err := os.Stat(filepath.Join(bundleDir, anki.CSVFileName)); os.IsNotExist(err) {
 		t.Error("CSV file was not created in home directory")
 	}
-	if err := os.Remove(csvFile); err != nil && !os.IsNotExist(err) {
-		t.Errorf("Failed to remove CSV file: %v", err)
+	if err := os.RemoveAll(bundleDir); err != nil {
+		t.Errorf("Failed to remove CSV bundle: %v", err)
 	}
 }
 
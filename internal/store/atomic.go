@@ -0,0 +1,119 @@
+package store
+
+// atomic.go provides the write helpers used for every file inside a card
+// directory. Each file is first written under a hidden temporary name in the
+// same directory and then renamed over the destination, so a crash or a
+// concurrent reader (GUI preview, exporter, another totalrecall process)
+// never observes a half-written audio clip, image or sidecar.
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AtomicFile is a file being written under a temporary name. Call Commit to
+// move it into place or Abort to discard it; Abort after Commit is a no-op,
+// so `defer f.Abort()` is the idiomatic cleanup.
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomic creates a temporary file next to path. Nothing is visible at
+// path until Commit succeeds.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	tmp, err := createTempFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{File: tmp, path: path}, nil
+}
+
+// Commit flushes the temporary file to disk and renames it to its final path.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	syncErr := f.Sync()
+	closeErr := f.Close()
+	if err := errors.Join(syncErr, closeErr); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return renameIntoPlace(f.Name(), f.path)
+}
+
+// Abort closes and removes the temporary file unless it was committed.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// WriteFileAtomic writes data to path via a temporary file and rename.
+func WriteFileAtomic(path string, data []byte) error {
+	f, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer f.Abort()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// WriteAtomically lets write produce the file at a temporary path and renames
+// it to path once write succeeds. It is meant for external tools such as
+// ffmpeg that insist on opening the output file themselves; the temporary
+// name keeps the extension of path so such tools still detect the format.
+func WriteAtomically(path string, write func(tmpPath string) error) error {
+	tmp, err := createTempFor(path)
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_ = tmp.Close()
+
+	if err := write(tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return renameIntoPlace(tmpPath, path)
+}
+
+// createTempFor creates a hidden temporary file in the directory of path,
+// named after path so stray leftovers are easy to attribute. The leading dot
+// keeps it out of the audio and image discovery globs.
+func createTempFor(path string) (*os.File, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	pattern := "." + strings.TrimSuffix(base, ext) + ".*" + ext
+	return os.CreateTemp(filepath.Dir(path), pattern)
+}
+
+func renameIntoPlace(tmpPath, path string) error {
+	// CreateTemp uses 0600; card files have always been world-readable.
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package store
+
+import "strings"
+
+// ParseExample splits example input such as "Ям ябълка. = I eat an apple."
+// into the sentence and its translation. The translation is optional.
+func ParseExample(text string) (example, translation string) {
+	example, translation, _ = strings.Cut(text, "=")
+	return strings.TrimSpace(example), strings.TrimSpace(translation)
+}
+
+// FormatExample is the inverse of ParseExample.
+func FormatExample(example, translation string) string {
+	if translation == "" {
+		return example
+	}
+	return example + " = " + translation
+}
+
+// SaveExample stores the example sentence of a card and its translation. An
+// empty example removes both.
+func SaveExample(cardDir, example, translation string) error {
+	example = strings.TrimSpace(example)
+	translation = strings.TrimSpace(translation)
+	if example == "" {
+		translation = ""
+	}
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Example = example
+		m.ExampleTranslation = translation
+	})
+}
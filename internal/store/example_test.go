@@ -0,0 +1,47 @@
+package store_test
+
+import (
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+func TestParseExample(t *testing.T) {
+	tests := []struct {
+		input, example, translation string
+	}{
+		{"", "", ""},
+		{"Ям ябълка.", "Ям ябълка.", ""},
+		{" Ям ябълка. = I eat an apple. ", "Ям ябълка.", "I eat an apple."},
+	}
+
+	for _, tt := range tests {
+		example, translation := store.ParseExample(tt.input)
+		if example != tt.example || translation != tt.translation {
+			t.Errorf("ParseExample(%q) = %q, %q; want %q, %q", tt.input, example, translation, tt.example, tt.translation)
+		}
+		if again, _ := store.ParseExample(store.FormatExample(example, translation)); again != example {
+			t.Errorf("ParseExample(FormatExample(%q, %q)) = %q", example, translation, again)
+		}
+	}
+}
+
+// TestSaveExample stores the example in the manifest and clears it again.
+func TestSaveExample(t *testing.T) {
+	cardDir := store.New(t.TempDir()).FindOrCreateCardDirectory("ябълка")
+
+	if err := store.SaveExample(cardDir, " Ям ябълка. ", "I eat an apple."); err != nil {
+		t.Fatalf("SaveExample() error = %v", err)
+	}
+	m := store.LoadManifest(cardDir)
+	if m.Example != "Ям ябълка." || m.ExampleTranslation != "I eat an apple." {
+		t.Errorf("manifest example = %q, %q", m.Example, m.ExampleTranslation)
+	}
+
+	if err := store.SaveExample(cardDir, "", "I eat an apple."); err != nil {
+		t.Fatalf("SaveExample() error = %v", err)
+	}
+	if m := store.LoadManifest(cardDir); m.Example != "" || m.ExampleTranslation != "" {
+		t.Errorf("manifest example after clearing = %q, %q", m.Example, m.ExampleTranslation)
+	}
+}
@@ -0,0 +1,437 @@
+package store
+
+// index.go keeps a persistent word → card directory index so lookups do not
+// have to open every card directory. The index is a cache, never the source
+// of truth: card.json (or the legacy word.txt) still decides which word a
+// directory holds, and every index hit is verified against it.
+//
+// Staleness is detected cheaply on each access:
+//   - The modification time of the output directory changes whenever a card
+//     directory is created, deleted, renamed or moved to the trash bin. A
+//     changed mtime triggers an incremental refresh that only reads the new
+//     directories.
+//   - The modification time of the index file changes when another process
+//     (e.g. the CLI while the GUI is open) saved it, so the in-memory copy is
+//     reloaded from disk.
+//   - A verified hit that no longer matches its directory forces a full
+//     rebuild.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// IndexDirName is the hidden directory inside the output directory that
+	// holds the card index. The index lives in a subdirectory rather than next
+	// to the cards because saving it must not touch the output directory's
+	// mtime, which is what signals that cards were added or removed.
+	IndexDirName = ".index"
+	// IndexFileName is the card index file inside IndexDirName.
+	IndexFileName = "cards.json"
+
+	indexVersion = 1
+
+	// mtimeGranularity guards against file systems with coarse timestamps: a
+	// directory modified within this window of the last scan may have changed
+	// again without its mtime moving, so it is rescanned.
+	mtimeGranularity = time.Second
+)
+
+// indexFile is the on-disk representation of the card index.
+type indexFile struct {
+	Version    int             `json:"version"`
+	DirModTime time.Time       `json:"dir_mod_time"`
+	ScannedAt  time.Time       `json:"scanned_at"`
+	Cards      []CardDirectory `json:"cards"`
+	// Pending lists directories that had no word yet when they were scanned,
+	// typically because another process was still creating them. They are
+	// re-read on every access until they either gain a word or disappear.
+	Pending []string `json:"pending,omitempty"`
+}
+
+// cardIndex is the in-memory view of one output directory's index. It is
+// shared by every CardStore in the process rooted at the same directory.
+type cardIndex struct {
+	mu          sync.Mutex
+	outputDir   string
+	data        indexFile
+	byID        map[string]CardDirectory
+	byWord      map[string]string
+	loaded      bool
+	fileModTime time.Time
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = make(map[string]*cardIndex)
+)
+
+// indexFor returns the process-wide index for outputDir.
+func indexFor(outputDir string) *cardIndex {
+	key := filepath.Clean(outputDir)
+	if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	ix, ok := indexes[key]
+	if !ok {
+		ix = &cardIndex{outputDir: outputDir}
+		indexes[key] = ix
+	}
+	return ix
+}
+
+// FindByWord returns the card directory holding word. The hit is verified
+// against the directory's metadata; a mismatch rebuilds the index.
+func (cs *CardStore) FindByWord(word string) (CardDirectory, bool) {
+	ix := indexFor(cs.outputDir)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.sync()
+	card, ok := ix.verify(ix.byWord[word])
+	if ok {
+		return card, true
+	}
+	if _, indexed := ix.byWord[word]; !indexed {
+		return CardDirectory{}, false
+	}
+
+	ix.rebuild()
+	card, ok = ix.byID[ix.byWord[word]]
+	return card, ok
+}
+
+// FindByID returns the card directory with the given card ID (the directory
+// name, e.g. "1700000000000_abcdef12").
+func (cs *CardStore) FindByID(id string) (CardDirectory, bool) {
+	ix := indexFor(cs.outputDir)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.sync()
+	if card, ok := ix.verify(id); ok {
+		return card, true
+	}
+	if _, indexed := ix.byID[id]; !indexed {
+		return CardDirectory{}, false
+	}
+
+	ix.rebuild()
+	card, ok := ix.byID[id]
+	return card, ok
+}
+
+// FindByPrefix returns all cards whose word starts with prefix, sorted by
+// word. Results come straight from the index and are not verified one by one.
+func (cs *CardStore) FindByPrefix(prefix string) []CardDirectory {
+	var cards []CardDirectory
+	for _, card := range cs.Cards() {
+		if strings.HasPrefix(card.Word, prefix) {
+			cards = append(cards, card)
+		}
+	}
+
+	sort.SliceStable(cards, func(i, j int) bool {
+		return cards[i].Word < cards[j].Word
+	})
+	return cards
+}
+
+// Cards returns every indexed card sorted by card ID, which is also creation
+// order because IDs start with the creation timestamp.
+func (cs *CardStore) Cards() []CardDirectory {
+	ix := indexFor(cs.outputDir)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.sync()
+	cards := make([]CardDirectory, len(ix.data.Cards))
+	copy(cards, ix.data.Cards)
+	return cards
+}
+
+// RebuildIndex discards the card index and rebuilds it from the card
+// directories on disk.
+func (cs *CardStore) RebuildIndex() error {
+	ix := indexFor(cs.outputDir)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.loaded = true
+	return ix.rebuild()
+}
+
+// indexCard records a created or updated card directory in its parent's index
+// if that index is in use. It is called after card.json has been written so
+// word and card type changes show up without a rescan.
+func indexCard(cardDir string, m *Manifest) {
+	outputDir := filepath.Dir(cardDir)
+	ix := indexFor(outputDir)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if !ix.loaded && !fileExists(indexPath(outputDir)) {
+		// No index for this directory yet; the next lookup builds one.
+		return
+	}
+
+	ix.sync()
+	card := CardDirectory{
+		ID:       filepath.Base(cardDir),
+		Path:     cardDir,
+		Word:     strings.TrimSpace(m.Word),
+		CardType: m.CardType,
+	}
+	if card.Word == "" || ix.byID[card.ID] == card {
+		return
+	}
+
+	ix.put(card)
+	ix.save()
+}
+
+// verify returns the indexed card with the given ID after re-reading its
+// directory, failing when the directory vanished or now holds another word.
+// The entry's card type is refreshed on the way since reading the manifest
+// costs nothing extra.
+func (ix *cardIndex) verify(id string) (CardDirectory, bool) {
+	indexed, ok := ix.byID[id]
+	if !ok {
+		return CardDirectory{}, false
+	}
+
+	current, ok := readCardDirectory(indexed.Path)
+	if !ok || current.Word != indexed.Word {
+		return CardDirectory{}, false
+	}
+
+	if current != indexed {
+		ix.put(current)
+		ix.save()
+	}
+	return current, true
+}
+
+// sync brings the in-memory index up to date with the disk, reloading the
+// index file when another process changed it and rescanning the output
+// directory when cards were added or removed. Callers must hold ix.mu.
+func (ix *cardIndex) sync() {
+	dirInfo, err := os.Stat(ix.outputDir)
+	if err != nil {
+		// Output directory does not exist (yet); nothing is indexed.
+		ix.reset(indexFile{Version: indexVersion})
+		ix.loaded = true
+		return
+	}
+
+	indexInfo, err := os.Stat(ix.indexPath())
+	switch {
+	case err != nil && ix.loaded && !ix.fileModTime.IsZero():
+		// The index file was removed behind our back; start over.
+		ix.reset(indexFile{Version: indexVersion})
+		ix.fileModTime = time.Time{}
+	case err == nil && (!ix.loaded || !indexInfo.ModTime().Equal(ix.fileModTime)):
+		ix.load()
+	case !ix.loaded:
+		ix.reset(indexFile{Version: indexVersion})
+	}
+	ix.loaded = true
+
+	dirModTime := dirInfo.ModTime()
+	racy := !ix.data.ScannedAt.After(dirModTime.Add(mtimeGranularity))
+	if ix.data.DirModTime.Equal(dirModTime) && !racy {
+		ix.resolvePending()
+		return
+	}
+
+	ix.refresh(dirModTime)
+	ix.save()
+}
+
+// load replaces the in-memory index with the index file. Unreadable or
+// outdated files yield an empty index, which the following refresh fills.
+func (ix *cardIndex) load() {
+	data, err := os.ReadFile(ix.indexPath())
+	if err != nil {
+		ix.reset(indexFile{Version: indexVersion})
+		return
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != indexVersion {
+		ix.reset(indexFile{Version: indexVersion})
+		return
+	}
+
+	for i := range file.Cards {
+		file.Cards[i].Path = filepath.Join(ix.outputDir, file.Cards[i].ID)
+	}
+	ix.reset(file)
+	if info, err := os.Stat(ix.indexPath()); err == nil {
+		ix.fileModTime = info.ModTime()
+	}
+}
+
+// refresh reconciles the index with the directory listing. Known directories
+// are kept as they are; only new and pending ones are read.
+func (ix *cardIndex) refresh(dirModTime time.Time) {
+	scannedAt := time.Now()
+	entries, err := os.ReadDir(ix.outputDir)
+	if err != nil {
+		ix.reset(indexFile{Version: indexVersion})
+		return
+	}
+
+	file := indexFile{Version: indexVersion, DirModTime: dirModTime, ScannedAt: scannedAt}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		if card, ok := ix.byID[entry.Name()]; ok {
+			file.Cards = append(file.Cards, card)
+			continue
+		}
+
+		card, ok := readCardDirectory(filepath.Join(ix.outputDir, entry.Name()))
+		if !ok {
+			file.Pending = append(file.Pending, entry.Name())
+			continue
+		}
+		file.Cards = append(file.Cards, card)
+	}
+
+	ix.reset(file)
+}
+
+// resolvePending re-reads directories that had no word when they were last
+// scanned and indexes those that have one now.
+func (ix *cardIndex) resolvePending() {
+	changed := false
+	for _, name := range ix.data.Pending {
+		if card, ok := readCardDirectory(filepath.Join(ix.outputDir, name)); ok {
+			ix.put(card)
+			changed = true
+		}
+	}
+	if changed {
+		ix.save()
+	}
+}
+
+// rebuild rereads every card directory and saves the result.
+func (ix *cardIndex) rebuild() error {
+	ix.reset(indexFile{Version: indexVersion})
+
+	dirInfo, err := os.Stat(ix.outputDir)
+	if err != nil {
+		return nil
+	}
+	ix.refresh(dirInfo.ModTime())
+	return ix.save()
+}
+
+// put inserts or replaces card and keeps the entries sorted by ID.
+func (ix *cardIndex) put(card CardDirectory) {
+	cards := ix.data.Cards[:0:0]
+	for _, existing := range ix.data.Cards {
+		if existing.ID != card.ID {
+			cards = append(cards, existing)
+		}
+	}
+	cards = append(cards, card)
+
+	pending := ix.data.Pending[:0:0]
+	for _, name := range ix.data.Pending {
+		if name != card.ID {
+			pending = append(pending, name)
+		}
+	}
+
+	file := ix.data
+	file.Cards = cards
+	file.Pending = pending
+	ix.reset(file)
+}
+
+// reset installs file as the current index and rebuilds the lookup maps.
+// When several directories hold the same word the oldest one wins, matching
+// the order in which the directory scan used to find them.
+func (ix *cardIndex) reset(file indexFile) {
+	sort.Slice(file.Cards, func(i, j int) bool {
+		return file.Cards[i].ID < file.Cards[j].ID
+	})
+
+	ix.data = file
+	ix.byID = make(map[string]CardDirectory, len(file.Cards))
+	ix.byWord = make(map[string]string, len(file.Cards))
+	for _, card := range file.Cards {
+		ix.byID[card.ID] = card
+		if _, exists := ix.byWord[card.Word]; !exists {
+			ix.byWord[card.Word] = card.ID
+		}
+	}
+}
+
+// save writes the index atomically. Failing to save only costs a rescan on
+// the next start, so callers may ignore the error.
+func (ix *cardIndex) save() error {
+	data, err := json.MarshalIndent(ix.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode card index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ix.indexPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create card index directory: %w", err)
+	}
+	if err := WriteFileAtomic(ix.indexPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to save card index: %w", err)
+	}
+
+	if info, err := os.Stat(ix.indexPath()); err == nil {
+		ix.fileModTime = info.ModTime()
+	}
+	return nil
+}
+
+func (ix *cardIndex) indexPath() string {
+	return indexPath(ix.outputDir)
+}
+
+func indexPath(outputDir string) string {
+	return filepath.Join(outputDir, IndexDirName, IndexFileName)
+}
+
+// readCardDirectory reads the word and card type of a card directory from
+// card.json, falling back to the legacy sidecars. It does not go through
+// LoadManifest so a lookup never reads more than the files it needs.
+func readCardDirectory(cardDir string) (CardDirectory, bool) {
+	card := CardDirectory{ID: filepath.Base(cardDir), Path: cardDir}
+
+	if m, err := ReadManifest(cardDir); err == nil {
+		card.Word = strings.TrimSpace(m.Word)
+		card.CardType = m.CardType
+	}
+	if card.Word == "" {
+		card.Word = readLegacyWord(cardDir)
+	}
+	if card.Word == "" {
+		return CardDirectory{}, false
+	}
+	if card.CardType == "" {
+		card.CardType = readTrimmedFile(filepath.Join(cardDir, CardTypeFileName))
+	}
+
+	return card, true
+}
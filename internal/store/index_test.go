@@ -0,0 +1,115 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// TestCardIndexLookups verifies lookup by word, card ID and prefix, and that
+// the index is persisted in the output directory.
+func TestCardIndexLookups(t *testing.T) {
+	outputDir := t.TempDir()
+	cs := store.New(outputDir)
+
+	apple := cs.FindOrCreateCardDirectory("ябълка")
+	cat := cs.FindOrCreateCardDirectory("котка")
+	cs.FindOrCreateCardDirectory("кораб")
+	if err := store.SaveCardType(cat, "bg-bg"); err != nil {
+		t.Fatalf("SaveCardType() error = %v", err)
+	}
+
+	card, ok := cs.FindByWord("котка")
+	if !ok || card.Path != cat || card.CardType != "bg-bg" {
+		t.Errorf("FindByWord() = %+v, %v; want %s with bg-bg", card, ok, cat)
+	}
+
+	card, ok = cs.FindByID(filepath.Base(apple))
+	if !ok || card.Word != "ябълка" {
+		t.Errorf("FindByID() = %+v, %v", card, ok)
+	}
+	if _, ok := cs.FindByID("missing"); ok {
+		t.Error("FindByID(missing) found a card")
+	}
+
+	matches := cs.FindByPrefix("ко")
+	if len(matches) != 2 || matches[0].Word != "кораб" || matches[1].Word != "котка" {
+		t.Errorf("FindByPrefix() = %+v; want кораб, котка", matches)
+	}
+
+	if _, err := os.Stat(filepath.Join(outputDir, store.IndexDirName, store.IndexFileName)); err != nil {
+		t.Errorf("index file not written: %v", err)
+	}
+	if words := cs.ScanWords(nil); len(words) != 3 {
+		t.Errorf("ScanWords() = %v; index directory must not count as a card", words)
+	}
+}
+
+// TestCardIndexPicksUpExternalChanges simulates another process adding,
+// deleting and editing card directories behind the store's back.
+func TestCardIndexPicksUpExternalChanges(t *testing.T) {
+	outputDir := t.TempDir()
+	cs := store.New(outputDir)
+
+	bread := cs.FindOrCreateCardDirectory("хляб")
+	if got := cs.FindCardDirectory("вода"); got != "" {
+		t.Fatalf("FindCardDirectory(вода) = %q before it exists", got)
+	}
+
+	// A card created without going through this store is found.
+	water := writeCardFiles(t, outputDir, "external", map[string]string{"word.txt": "вода"})
+	if got := cs.FindCardDirectory("вода"); got != water {
+		t.Errorf("FindCardDirectory(вода) = %q; want %q", got, water)
+	}
+
+	// A deleted card disappears.
+	if err := os.RemoveAll(water); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if got := cs.FindCardDirectory("вода"); got != "" {
+		t.Errorf("FindCardDirectory(вода) = %q after deletion", got)
+	}
+
+	// Editing the word of an indexed card is detected on the next hit.
+	if err := store.UpdateManifest(bread, func(m *store.Manifest) { m.Word = "мляко" }); err != nil {
+		t.Fatalf("UpdateManifest() error = %v", err)
+	}
+	if got := cs.FindCardDirectory("хляб"); got != "" {
+		t.Errorf("FindCardDirectory(хляб) = %q after rename", got)
+	}
+	if got := cs.FindCardDirectory("мляко"); got != bread {
+		t.Errorf("FindCardDirectory(мляко) = %q; want %q", got, bread)
+	}
+}
+
+// TestCardIndexRebuildsWhenCorrupt checks that a damaged index file is
+// replaced by a fresh scan instead of breaking lookups.
+func TestCardIndexRebuildsWhenCorrupt(t *testing.T) {
+	outputDir := t.TempDir()
+	dog := writeCardFiles(t, outputDir, "card1", map[string]string{"word.txt": "куче"})
+
+	indexPath := filepath.Join(outputDir, store.IndexDirName, store.IndexFileName)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := os.WriteFile(indexPath, []byte("{broken"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	cs := store.New(outputDir)
+	if got := cs.FindCardDirectory("куче"); got != dog {
+		t.Errorf("FindCardDirectory() = %q; want %q", got, dog)
+	}
+
+	if err := os.Remove(indexPath); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := cs.RebuildIndex(); err != nil {
+		t.Fatalf("RebuildIndex() error = %v", err)
+	}
+	if cards := cs.Cards(); len(cards) != 1 || cards[0].ID != "card1" {
+		t.Errorf("Cards() after rebuild = %+v", cards)
+	}
+}
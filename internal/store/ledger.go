@@ -0,0 +1,179 @@
+package store
+
+// ledger.go keeps the export ledger of an output directory: which cards, in
+// which state, went into which export file or deck and when. Incremental
+// exports compare the cards with their last ledger entry for the same target
+// to ship only what is new or changed since then.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	// LedgerDirName is the hidden directory inside the output directory that
+	// holds the export ledger. Like the card index it lives in a
+	// subdirectory, so that recording an export does not touch the output
+	// directory's mtime.
+	LedgerDirName = ".exports"
+	// LedgerFileName is the export ledger file inside LedgerDirName.
+	LedgerFileName = "ledger.json"
+
+	ledgerVersion = 1
+)
+
+// ExportedAsset is the state of one asset of an exported card: the file and
+// the revision it was at.
+type ExportedAsset struct {
+	Kind     AssetKind `json:"kind"`
+	File     string    `json:"file"`
+	Revision int       `json:"revision,omitempty"`
+}
+
+// ExportedCard is the state of a card when it was exported.
+type ExportedCard struct {
+	ID   string `json:"id"`
+	Word string `json:"word"`
+	// UpdatedAt is the manifest's UpdatedAt; any change to the card, its
+	// text or its assets moves it.
+	UpdatedAt time.Time       `json:"updated_at"`
+	Assets    []ExportedAsset `json:"assets,omitempty"`
+}
+
+// Export is one export in the ledger.
+type Export struct {
+	// File is the written file; empty for exports to a running Anki.
+	File   string `json:"file,omitempty"`
+	Format string `json:"format"`
+	// Destination is where the cards went: the file for APKG and CSV
+	// exports, the deck for exports to a running Anki. With Format it
+	// identifies the target of the export.
+	Destination string `json:"destination,omitempty"`
+	Deck        string `json:"deck,omitempty"`
+	// Filter describes the card selection, e.g. "since last export"; empty
+	// for exports of all cards.
+	Filter     string         `json:"filter,omitempty"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Cards      []ExportedCard `json:"cards"`
+}
+
+// Target returns the destination of the export. Ledgers written before
+// exports had a destination kept it in File.
+func (e Export) Target() string {
+	if e.Destination != "" {
+		return e.Destination
+	}
+	return e.File
+}
+
+// ExportLedger lists the exports of an output directory, oldest first.
+type ExportLedger struct {
+	Version int      `json:"version"`
+	Exports []Export `json:"exports"`
+}
+
+// ExportedCardState returns the current state of the card in cardDir, as it
+// is recorded in the ledger.
+func ExportedCardState(cardDir string) ExportedCard {
+	m := LoadManifest(cardDir)
+	card := ExportedCard{ID: filepath.Base(cardDir), Word: m.Word, UpdatedAt: m.UpdatedAt}
+	for _, asset := range m.Assets {
+		card.Assets = append(card.Assets, ExportedAsset{Kind: asset.Kind, File: asset.File, Revision: asset.Revision})
+	}
+	return card
+}
+
+// ExportsTo returns a ledger with only the exports in format to
+// destination, so that exports to one target do not count for another.
+func (l *ExportLedger) ExportsTo(format, destination string) *ExportLedger {
+	filtered := &ExportLedger{Version: l.Version}
+	for _, export := range l.Exports {
+		if export.Format == format && export.Target() == destination {
+			filtered.Exports = append(filtered.Exports, export)
+		}
+	}
+	return filtered
+}
+
+// LastExport returns the newest export of the card with the given ID and
+// the state the card had in it.
+func (l *ExportLedger) LastExport(cardID string) (Export, ExportedCard, bool) {
+	for i := len(l.Exports) - 1; i >= 0; i-- {
+		for _, card := range l.Exports[i].Cards {
+			if card.ID == cardID {
+				return l.Exports[i], card, true
+			}
+		}
+	}
+	return Export{}, ExportedCard{}, false
+}
+
+// ChangedSinceExport reports whether the card in cardDir was never exported
+// or changed after its last export.
+func (l *ExportLedger) ChangedSinceExport(cardDir string) bool {
+	current := ExportedCardState(cardDir)
+	_, last, ok := l.LastExport(current.ID)
+	if !ok {
+		return true
+	}
+	return current.UpdatedAt.After(last.UpdatedAt) || !slices.Equal(current.Assets, last.Assets)
+}
+
+// ExportLedger reads the export ledger of the store. A store that was never
+// exported has an empty ledger.
+func (cs *CardStore) ExportLedger() (*ExportLedger, error) {
+	data, err := os.ReadFile(cs.ledgerPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return &ExportLedger{Version: ledgerVersion}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export ledger: %w", err)
+	}
+
+	var ledger ExportLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("failed to decode export ledger: %w", err)
+	}
+	return &ledger, nil
+}
+
+// RecordExport appends export to the ledger. The card states should be taken
+// with ExportedCardState right after the export was written.
+func (cs *CardStore) RecordExport(export Export) error {
+	lock, err := LockOutputDirectory(cs.outputDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	ledger, err := cs.ExportLedger()
+	if err != nil {
+		return err
+	}
+	if export.ExportedAt.IsZero() {
+		export.ExportedAt = time.Now()
+	}
+	ledger.Version = ledgerVersion
+	ledger.Exports = append(ledger.Exports, export)
+
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export ledger: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cs.ledgerPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create export ledger directory: %w", err)
+	}
+	if err := WriteFileAtomic(cs.ledgerPath(), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write export ledger: %w", err)
+	}
+	return nil
+}
+
+func (cs *CardStore) ledgerPath() string {
+	return filepath.Join(cs.outputDir, LedgerDirName, LedgerFileName)
+}
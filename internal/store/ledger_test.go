@@ -0,0 +1,85 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// TestExportLedgerTracksChanges records an export and checks which cards
+// count as changed afterwards.
+func TestExportLedgerTracksChanges(t *testing.T) {
+	outputDir := t.TempDir()
+	cs := store.New(outputDir)
+	apple := cs.FindOrCreateCardDirectory("ябълка")
+	cat := cs.FindOrCreateCardDirectory("котка")
+	dog := cs.FindOrCreateCardDirectory("куче")
+	if err := os.WriteFile(filepath.Join(apple, "image.jpg"), []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RecordAsset(apple, store.Asset{Kind: store.AssetImage, File: "image.jpg", Provider: "openai"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err := cs.ExportLedger()
+	if err != nil || len(ledger.Exports) != 0 {
+		t.Fatalf("ExportLedger() of a new store = %+v, %v", ledger, err)
+	}
+
+	export := store.Export{File: "deck.apkg", Format: "apkg", Destination: "deck.apkg", Deck: "Deck"}
+	for _, dir := range []string{apple, cat} {
+		export.Cards = append(export.Cards, store.ExportedCardState(dir))
+	}
+	if err := cs.RecordExport(export); err != nil {
+		t.Fatalf("RecordExport() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, store.LedgerDirName, store.LedgerFileName)); err != nil {
+		t.Fatalf("ledger file: %v", err)
+	}
+
+	// A changed translation and a card that was never exported are due.
+	if err := store.UpdateManifest(cat, func(m *store.Manifest) { m.Translation = "cat" }); err != nil {
+		t.Fatal(err)
+	}
+	ledger, err = cs.ExportLedger()
+	if err != nil {
+		t.Fatalf("ExportLedger() error = %v", err)
+	}
+	if ledger.ChangedSinceExport(apple) {
+		t.Error("unchanged card counts as changed")
+	}
+	if !ledger.ChangedSinceExport(cat) {
+		t.Error("changed card does not count as changed")
+	}
+	if !ledger.ChangedSinceExport(dog) {
+		t.Error("card that was never exported does not count as changed")
+	}
+
+	last, card, ok := ledger.LastExport(filepath.Base(apple))
+	if !ok || last.Target() != "deck.apkg" || card.Word != "ябълка" || len(card.Assets) != 1 || last.ExportedAt.IsZero() {
+		t.Errorf("LastExport() = %+v, %+v, %v", last, card, ok)
+	}
+
+	// A second export supersedes the first for its cards.
+	if err := cs.RecordExport(store.Export{Format: "ankiconnect", Destination: "Deck", Cards: []store.ExportedCard{store.ExportedCardState(cat)}}); err != nil {
+		t.Fatalf("RecordExport() error = %v", err)
+	}
+	ledger, err = cs.ExportLedger()
+	if err != nil || len(ledger.Exports) != 2 {
+		t.Fatalf("ExportLedger() = %+v, %v", ledger, err)
+	}
+	if ledger.ChangedSinceExport(cat) {
+		t.Error("card counts as changed after its re-export")
+	}
+
+	// Exports to another target do not count.
+	apkg := ledger.ExportsTo("apkg", "deck.apkg")
+	if len(apkg.Exports) != 1 || !apkg.ChangedSinceExport(cat) || apkg.ChangedSinceExport(apple) {
+		t.Errorf("ExportsTo(apkg, deck.apkg) = %+v", apkg)
+	}
+	if other := ledger.ExportsTo("apkg", "other.apkg"); len(other.Exports) != 0 || !other.ChangedSinceExport(apple) {
+		t.Errorf("ExportsTo(apkg, other.apkg) = %+v", other)
+	}
+}
@@ -0,0 +1,144 @@
+package store
+
+// lock.go implements advisory locks that serialise writers across goroutines
+// and across totalrecall processes. The output directory lock guards card
+// creation (check for the word, then create its directory) so that a GUI and
+// a CLI batch working on the same collection cannot create two directories
+// for one word. The per-card lock guards read-modify-write cycles on
+// card.json and its sidecars.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// cardLockFileName is the lock file inside every card directory.
+	cardLockFileName = ".lock"
+	// outputLockFileName is the lock file for card creation, kept inside the
+	// index directory so taking the lock never changes the output directory.
+	outputLockFileName = "output.lock"
+
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+// lockTimeout bounds how long a writer waits for another process. Locks are
+// only held for a handful of small file writes, so a lock that stays taken
+// this long belongs to a stuck or very busy process.
+var lockTimeout = 10 * time.Second
+
+// ErrLocked reports that another totalrecall process held a lock for longer
+// than the lock timeout.
+var ErrLocked = errors.New("locked by another totalrecall process")
+
+// errWouldBlock is returned by tryLockFile when the lock is taken.
+var errWouldBlock = errors.New("lock is held")
+
+// Lock is a held advisory lock. Release it with Unlock.
+type Lock struct {
+	file *os.File
+	mu   *sync.Mutex
+}
+
+var (
+	processLocksMu sync.Mutex
+	processLocks   = make(map[string]*sync.Mutex)
+)
+
+// LockOutputDirectory takes the card-creation lock of outputDir, creating
+// the directory when needed.
+func LockOutputDirectory(outputDir string) (*Lock, error) {
+	lockDir := filepath.Join(outputDir, IndexDirName)
+	if err := os.MkdirAll(lockDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	return acquireLock(filepath.Join(lockDir, outputLockFileName), "output directory "+outputDir)
+}
+
+// LockCardDirectory takes the write lock of an existing card directory.
+func LockCardDirectory(cardDir string) (*Lock, error) {
+	return acquireLock(filepath.Join(cardDir, cardLockFileName), "card directory "+cardDir)
+}
+
+// Unlock releases the lock. It is safe to call on a nil Lock.
+func (l *Lock) Unlock() {
+	if l == nil {
+		return
+	}
+	_ = unlockFile(l.file)
+	_ = l.file.Close()
+	l.mu.Unlock()
+}
+
+// acquireLock serialises goroutines of this process with a mutex first and
+// only then competes with other processes for the file lock, so waiting in
+// process never runs into the timeout.
+func acquireLock(path, what string) (*Lock, error) {
+	mu := processLock(path)
+	mu.Lock()
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to open lock file for %s: %w", what, err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := tryLockFile(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errWouldBlock) {
+			_ = file.Close()
+			mu.Unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", what, err)
+		}
+		if time.Now().After(deadline) {
+			holder := lockHolder(path)
+			_ = file.Close()
+			mu.Unlock()
+			return nil, fmt.Errorf("%s is %w%s", what, ErrLocked, holder)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	// Record the owner so a competing process can name it in its error.
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	return &Lock{file: file, mu: mu}, nil
+}
+
+func processLock(path string) *sync.Mutex {
+	key := filepath.Clean(path)
+	if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+
+	processLocksMu.Lock()
+	defer processLocksMu.Unlock()
+
+	mu, ok := processLocks[key]
+	if !ok {
+		mu = &sync.Mutex{}
+		processLocks[key] = mu
+	}
+	return mu
+}
+
+// lockHolder returns " (pid N)" for the process recorded in the lock file, or
+// "" when it is unknown.
+func lockHolder(path string) string {
+	pid := readTrimmedFile(path)
+	if _, err := strconv.Atoi(pid); err != nil {
+		return ""
+	}
+	return " (pid " + pid + ")"
+}
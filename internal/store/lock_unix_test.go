@@ -0,0 +1,60 @@
+//go:build unix
+
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestLockReportsOtherProcess holds a card lock through a separate open file
+// description, which flock treats exactly like another process, and checks
+// that writers give up with ErrLocked naming the holder.
+func TestLockReportsOtherProcess(t *testing.T) {
+	cardDir := t.TempDir()
+	lockPath := filepath.Join(cardDir, cardLockFileName)
+
+	holder, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	defer func() { _ = holder.Close() }()
+	if err := syscall.Flock(int(holder.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatalf("setup flock: %v", err)
+	}
+	if _, err := holder.WriteString("4242\n"); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	previous := lockTimeout
+	lockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { lockTimeout = previous })
+
+	err = SaveTranslation(cardDir, "ябълка", "apple")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("SaveTranslation() error = %v; want ErrLocked", err)
+	}
+	if !strings.Contains(err.Error(), "pid 4242") {
+		t.Errorf("error %q does not name the lock holder", err)
+	}
+	if fileExists(filepath.Join(cardDir, TranslationFileName)) {
+		t.Error("translation.txt written without holding the lock")
+	}
+
+	// Once the other process lets go, writes succeed and record our pid.
+	if err := syscall.Flock(int(holder.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := SaveTranslation(cardDir, "ябълка", "apple"); err != nil {
+		t.Fatalf("SaveTranslation() after unlock error = %v", err)
+	}
+	if got := readTrimmedFile(lockPath); got != strconv.Itoa(os.Getpid()) {
+		t.Errorf("lock file pid = %q; want %d", got, os.Getpid())
+	}
+}
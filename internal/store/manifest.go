@@ -0,0 +1,365 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestFileName is the per-card metadata file stored in every card
+	// directory. It replaces the scattered text sidecars as the single source
+	// of truth for card metadata.
+	ManifestFileName = "card.json"
+
+	// ManifestVersion is the schema version written by this release. Readers
+	// upgrade older manifests in memory; MigrateManifests persists the upgrade.
+	ManifestVersion = 1
+)
+
+// AssetKind identifies the role a generated file plays on a card. The audio
+// kinds deliberately match the base names used on disk (audio, audio_front,
+// audio_back) so callers can map between them without a lookup table.
+type AssetKind string
+
+const (
+	// AssetAudio is the single audio clip of an en-bg card.
+	AssetAudio AssetKind = "audio"
+	// AssetAudioFront is the front-side audio clip of a bg-bg card.
+	AssetAudioFront AssetKind = "audio_front"
+	// AssetAudioBack is the back-side audio clip of a bg-bg card.
+	AssetAudioBack AssetKind = "audio_back"
+	// AssetAudioSlow, AssetAudioFrontSlow and AssetAudioBackSlow are the
+	// slow renditions of the clips above, stored as <base>_slow.<ext>.
+	AssetAudioSlow      AssetKind = "audio_slow"
+	AssetAudioFrontSlow AssetKind = "audio_front_slow"
+	AssetAudioBackSlow  AssetKind = "audio_back_slow"
+	// AssetImage is the card illustration.
+	AssetImage AssetKind = "image"
+)
+
+// SlowAudioSuffix ends the base name of a slow audio clip.
+const SlowAudioSuffix = "_slow"
+
+// Asset records one generated file together with the provenance needed to
+// explain or regenerate it. File and Attribution are relative to the card
+// directory so the whole directory can be moved or archived.
+type Asset struct {
+	Kind        AssetKind `json:"kind"`
+	File        string    `json:"file"`
+	Provider    string    `json:"provider,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	Voice       string    `json:"voice,omitempty"`
+	Format      string    `json:"format,omitempty"`
+	Prompt      string    `json:"prompt,omitempty"`
+	Attribution string    `json:"attribution,omitempty"`
+	// Cached records that the file was copied from a cache of earlier
+	// generations instead of generated for this card.
+	Cached bool `json:"cached,omitempty"`
+	// Revision is the stored revision this file corresponds to (see
+	// revisions.go); 0 for files recorded before revision history existed.
+	Revision  int       `json:"revision,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manifest is the versioned, structured description of one card directory.
+// CardType holds the raw card type string ("en-bg", "bg-bg", "sentence") because store
+// sits below the internal package in the dependency graph.
+type Manifest struct {
+	Version     int    `json:"version"`
+	Word        string `json:"word"`
+	Translation string `json:"translation,omitempty"`
+	CardType    string `json:"card_type,omitempty"`
+	IPA         string `json:"ipa,omitempty"`
+	ImagePrompt string `json:"image_prompt,omitempty"`
+	// Example is a Bulgarian example sentence using the word, exported with
+	// its translation to the Example fields of the Anki note.
+	Example            string `json:"example,omitempty"`
+	ExampleTranslation string `json:"example_translation,omitempty"`
+	// Cloze is the sentence of a sentence card with its cloze deletions
+	// ({{c1::...}}); Word holds the same sentence without them.
+	Cloze string `json:"cloze,omitempty"`
+	// Directions overrides the card directions of the export for this
+	// card, e.g. "forward" or "both,type" (see internal.CardDirections).
+	Directions string `json:"directions,omitempty"`
+	// Section is the batch file section the card was listed under, e.g.
+	// "Lesson 3::Food"; exports may turn it into a subdeck.
+	Section string `json:"section,omitempty"`
+	// Tags are exported as Anki note tags (see tags.go).
+	Tags   []string `json:"tags,omitempty"`
+	Assets []Asset  `json:"assets,omitempty"`
+	// Revisions holds the bounded history of earlier asset versions.
+	Revisions []Revision `json:"revisions,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// NewManifest returns an empty manifest for word stamped with the current
+// schema version and creation time.
+func NewManifest(word string) *Manifest {
+	now := time.Now()
+	return &Manifest{
+		Version:   ManifestVersion,
+		Word:      word,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// ReadManifest reads card.json from cardDir without consulting any legacy
+// sidecar files. The returned error wraps os.ErrNotExist when the directory
+// has not been migrated yet.
+func ReadManifest(cardDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(cardDir, ManifestFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestFileName, err)
+	}
+
+	return &m, nil
+}
+
+// LoadManifest returns the manifest for cardDir and never returns nil. When
+// card.json is missing or incomplete, the gaps are filled from the legacy
+// sidecar files (word.txt, translation.txt, phonetic.txt, ...) so directories
+// written by older releases keep working until they are migrated.
+func LoadManifest(cardDir string) *Manifest {
+	m, err := ReadManifest(cardDir)
+	if err != nil {
+		return legacyManifest(cardDir)
+	}
+
+	if !m.complete() {
+		m.fillFrom(legacyManifest(cardDir))
+	}
+
+	return m
+}
+
+// SaveManifest writes m to cardDir/card.json, stamping the current schema
+// version and modification time. The file is written to a temporary name and
+// renamed into place so readers never observe a partially written manifest.
+func SaveManifest(cardDir string, m *Manifest) error {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return saveManifestLocked(cardDir, m)
+}
+
+// UpdateManifest loads the manifest for cardDir (migrating legacy sidecars on
+// the fly), applies mutate and saves the result atomically. The card lock is
+// held throughout: the GUI generates audio, image and phonetics for one card
+// in parallel goroutines, and a CLI run may touch the same card, all of which
+// record their results in the same manifest.
+func UpdateManifest(cardDir string, mutate func(m *Manifest)) error {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return updateManifestLocked(cardDir, mutate)
+}
+
+// SaveCloze stores the cloze text of a sentence card. It only lives in
+// card.json; older releases treat sentence cards as en-bg cards.
+func SaveCloze(cardDir, cloze string) error {
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Cloze = strings.TrimSpace(cloze)
+	})
+}
+
+// SaveDirections stores the card directions chosen for a single card; an
+// empty string leaves the choice to the export.
+func SaveDirections(cardDir, directions string) error {
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Directions = strings.TrimSpace(directions)
+	})
+}
+
+// SaveSection stores the batch file section of a card; an empty string
+// removes it.
+func SaveSection(cardDir, section string) error {
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Section = strings.TrimSpace(section)
+	})
+}
+
+// updateManifestLocked leaves card.json alone when mutate changed nothing:
+// UpdatedAt is exported as the Anki modification time, so re-saving an
+// unchanged translation (the GUI does so whenever a card is displayed) must
+// not make the card look edited.
+func updateManifestLocked(cardDir string, mutate func(m *Manifest)) error {
+	m := LoadManifest(cardDir)
+	mutate(m)
+
+	if onDisk, err := ReadManifest(cardDir); err == nil && sameManifest(onDisk, m) {
+		return nil
+	}
+	return saveManifestLocked(cardDir, m)
+}
+
+func sameManifest(a, b *Manifest) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+func saveManifestLocked(cardDir string, m *Manifest) error {
+	now := time.Now()
+	m.Version = ManifestVersion
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	m.UpdatedAt = now
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", ManifestFileName, err)
+	}
+	data = append(data, '\n')
+
+	if err := WriteFileAtomic(filepath.Join(cardDir, ManifestFileName), data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", ManifestFileName, err)
+	}
+
+	indexCard(cardDir, m)
+	return nil
+}
+
+// PutAsset records asset in the manifest, replacing any earlier entry for the
+// same file so regenerating an asset updates its provenance in place.
+func (m *Manifest) PutAsset(asset Asset) {
+	asset.File = filepath.Base(asset.File)
+	if asset.Attribution != "" {
+		asset.Attribution = filepath.Base(asset.Attribution)
+	}
+	if asset.Kind == "" {
+		asset.Kind = AssetKindForFile(asset.File)
+	}
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = time.Now()
+	}
+
+	for i := range m.Assets {
+		if m.Assets[i].File == asset.File {
+			m.Assets[i] = asset
+			return
+		}
+	}
+	m.Assets = append(m.Assets, asset)
+}
+
+// AssetsOfKind returns the recorded assets of the given kind, newest first.
+func (m *Manifest) AssetsOfKind(kind AssetKind) []Asset {
+	var assets []Asset
+	for _, asset := range m.Assets {
+		if asset.Kind == kind {
+			assets = append(assets, asset)
+		}
+	}
+
+	sort.SliceStable(assets, func(i, j int) bool {
+		if !assets[i].CreatedAt.Equal(assets[j].CreatedAt) {
+			return assets[i].CreatedAt.After(assets[j].CreatedAt)
+		}
+		return assets[i].File < assets[j].File
+	})
+	return assets
+}
+
+// AssetPaths returns absolute paths of the recorded assets of the given kind
+// that still exist inside cardDir, newest first. Entries whose files were
+// removed by hand are skipped rather than reported as errors.
+func (m *Manifest) AssetPaths(cardDir string, kind AssetKind) []string {
+	var paths []string
+	for _, asset := range m.AssetsOfKind(kind) {
+		path := filepath.Join(cardDir, asset.File)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// AssetPath returns the newest existing asset of the given kind, or "".
+func (m *Manifest) AssetPath(cardDir string, kind AssetKind) string {
+	paths := m.AssetPaths(cardDir, kind)
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[0]
+}
+
+// AssetKindForFile infers the asset kind from a card file name such as
+// audio_front.mp3, audio_alloy.wav, audio_slow.mp3 or image.png.
+func AssetKindForFile(name string) AssetKind {
+	base := filepath.Base(name)
+	var kind AssetKind
+	switch {
+	case strings.HasPrefix(base, "audio_front"):
+		kind = AssetAudioFront
+	case strings.HasPrefix(base, "audio_back"):
+		kind = AssetAudioBack
+	case strings.HasPrefix(base, "audio"):
+		kind = AssetAudio
+	default:
+		return AssetImage
+	}
+	if strings.HasSuffix(strings.TrimSuffix(base, filepath.Ext(base)), SlowAudioSuffix) {
+		kind += SlowAudioSuffix
+	}
+	return kind
+}
+
+// complete reports whether every field that can be backfilled from legacy
+// sidecars is already populated, letting LoadManifest skip the extra reads.
+func (m *Manifest) complete() bool {
+	return m.Word != "" && m.Translation != "" && m.CardType != "" &&
+		m.IPA != "" && m.ImagePrompt != "" && len(m.Assets) > 0
+}
+
+// fillFrom copies fields that are empty in m from legacy. Assets are only
+// taken for kinds the manifest does not know about yet.
+func (m *Manifest) fillFrom(legacy *Manifest) {
+	if m.Word == "" {
+		m.Word = legacy.Word
+	}
+	if m.Translation == "" {
+		m.Translation = legacy.Translation
+	}
+	if m.CardType == "" {
+		m.CardType = legacy.CardType
+	}
+	if m.IPA == "" {
+		m.IPA = legacy.IPA
+	}
+	if m.ImagePrompt == "" {
+		m.ImagePrompt = legacy.ImagePrompt
+	}
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = legacy.CreatedAt
+	}
+
+	known := make(map[AssetKind]bool, len(m.Assets))
+	for _, asset := range m.Assets {
+		known[asset.Kind] = true
+	}
+	for _, asset := range legacy.Assets {
+		if !known[asset.Kind] {
+			m.Assets = append(m.Assets, asset)
+		}
+	}
+}
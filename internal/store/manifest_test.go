@@ -0,0 +1,258 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// writeCardFiles creates a card directory under root and fills it with the
+// given files.
+func writeCardFiles(t *testing.T, root, name string, files map[string]string) string {
+	t.Helper()
+
+	cardDir := filepath.Join(root, name)
+	if err := os.MkdirAll(cardDir, 0755); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(cardDir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("setup %s: %v", file, err)
+		}
+	}
+	return cardDir
+}
+
+// TestLoadManifestFromLegacySidecars verifies that cards written before
+// card.json existed are described by LoadManifest via their text sidecars.
+func TestLoadManifestFromLegacySidecars(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "1700000000000_abcdef12", map[string]string{
+		"word.txt":              "ябълка",
+		"translation.txt":       "ябълка = apple\n",
+		"phonetic.txt":          "[ˈjabəlkə]\n",
+		"cardtype.txt":          "en-bg",
+		"image_prompt.txt":      "a red apple\n",
+		"audio.mp3":             "audio",
+		"audio_attribution.txt": "attr",
+		"audio_metadata.txt":    "provider=gemini\nmodel=tts-model\nvoice=Kore\nformat=mp3\naudio_file=audio.mp3\n",
+		"image.png":             "png",
+	})
+
+	m := store.LoadManifest(cardDir)
+
+	if m.Word != "ябълка" || m.Translation != "apple" || m.IPA != "[ˈjabəlkə]" ||
+		m.CardType != "en-bg" || m.ImagePrompt != "a red apple" {
+		t.Fatalf("LoadManifest() fields = %+v", m)
+	}
+	if want := time.UnixMilli(1700000000000); !m.CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v; want %v", m.CreatedAt, want)
+	}
+
+	audio := m.AssetsOfKind(store.AssetAudio)
+	if len(audio) != 1 {
+		t.Fatalf("audio assets = %+v; want one", audio)
+	}
+	if audio[0].File != "audio.mp3" || audio[0].Provider != "gemini" || audio[0].Voice != "Kore" ||
+		audio[0].Model != "tts-model" || audio[0].Attribution != "audio_attribution.txt" {
+		t.Errorf("audio asset = %+v", audio[0])
+	}
+
+	if got := m.AssetPath(cardDir, store.AssetImage); got != filepath.Join(cardDir, "image.png") {
+		t.Errorf("image path = %q", got)
+	}
+}
+
+// TestManifestIsSourceOfTruth checks that values saved through the store
+// helpers land in card.json and win over stale sidecar content.
+func TestManifestIsSourceOfTruth(t *testing.T) {
+	outputDir := t.TempDir()
+	cardDir := store.FindOrCreateCardDirectory(outputDir, "котка")
+
+	if _, err := store.ReadManifest(cardDir); err != nil {
+		t.Fatalf("new card directory has no manifest: %v", err)
+	}
+	if err := store.SaveTranslation(cardDir, "котка", "cat"); err != nil {
+		t.Fatalf("SaveTranslation() error = %v", err)
+	}
+	if err := store.SaveCardType(cardDir, "bg-bg"); err != nil {
+		t.Fatalf("SaveCardType() error = %v", err)
+	}
+
+	// A hand-edited sidecar must not override the manifest.
+	if err := os.WriteFile(filepath.Join(cardDir, "translation.txt"), []byte("котка = dog\n"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	m, err := store.ReadManifest(cardDir)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if m.Version != store.ManifestVersion || m.Word != "котка" || m.CardType != "bg-bg" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if got := store.LoadManifest(cardDir).Translation; got != "cat" {
+		t.Errorf("Translation = %q; want %q", got, "cat")
+	}
+
+	data, err := os.ReadFile(filepath.Join(cardDir, "translation.txt"))
+	if err != nil || !strings.Contains(string(data), "котка") {
+		t.Errorf("translation.txt mirror missing: %q, %v", data, err)
+	}
+}
+
+// TestUnchangedUpdateKeepsUpdatedAt re-saves an identical translation, which
+// must not count as an edit of the card.
+func TestUnchangedUpdateKeepsUpdatedAt(t *testing.T) {
+	cardDir := store.FindOrCreateCardDirectory(t.TempDir(), "котка")
+	if err := store.SaveTranslation(cardDir, "котка", "cat"); err != nil {
+		t.Fatalf("SaveTranslation() error = %v", err)
+	}
+	saved := store.LoadManifest(cardDir).UpdatedAt
+
+	time.Sleep(10 * time.Millisecond)
+	if err := store.SaveTranslation(cardDir, "котка", "cat"); err != nil {
+		t.Fatalf("SaveTranslation() error = %v", err)
+	}
+	if got := store.LoadManifest(cardDir).UpdatedAt; !got.Equal(saved) {
+		t.Errorf("UpdatedAt = %v after an unchanged save; want %v", got, saved)
+	}
+
+	if err := store.SaveTranslation(cardDir, "котка", "kitty"); err != nil {
+		t.Fatalf("SaveTranslation() error = %v", err)
+	}
+	if got := store.LoadManifest(cardDir).UpdatedAt; !got.After(saved) {
+		t.Errorf("UpdatedAt = %v after a change; want later than %v", got, saved)
+	}
+}
+
+// TestRecordAssetReplacesAndSkipsMissingFiles verifies asset bookkeeping:
+// re-recording a file replaces its entry, attribution sidecars are detected,
+// and entries whose files disappeared are not returned.
+func TestRecordAssetReplacesAndSkipsMissingFiles(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "card", map[string]string{
+		"word.txt":                    "куче",
+		"audio_alloy.mp3":             "a",
+		"audio_alloy_attribution.txt": "attr",
+		"audio_nova.mp3":              "b",
+	})
+
+	older := time.Now().Add(-time.Hour)
+	for _, asset := range []store.Asset{
+		{File: "audio_alloy.mp3", Provider: "openai", Voice: "alloy", CreatedAt: older},
+		{File: "audio_nova.mp3", Provider: "openai", Voice: "nova"},
+		{File: "audio_alloy.mp3", Provider: "openai", Voice: "alloy", Model: "tts-1", CreatedAt: older},
+		{File: "audio_gone.mp3", Provider: "openai", Voice: "echo"},
+	} {
+		if err := store.RecordAsset(cardDir, asset); err != nil {
+			t.Fatalf("RecordAsset(%s) error = %v", asset.File, err)
+		}
+	}
+
+	m := store.LoadManifest(cardDir)
+	assets := m.AssetsOfKind(store.AssetAudio)
+	if len(assets) != 3 {
+		t.Fatalf("audio assets = %+v; want 3 entries", assets)
+	}
+
+	paths := m.AssetPaths(cardDir, store.AssetAudio)
+	want := []string{filepath.Join(cardDir, "audio_nova.mp3"), filepath.Join(cardDir, "audio_alloy.mp3")}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("AssetPaths() = %v; want %v", paths, want)
+	}
+
+	for _, asset := range assets {
+		if asset.File == "audio_alloy.mp3" {
+			if asset.Model != "tts-1" || asset.Attribution != "audio_alloy_attribution.txt" || asset.Format != "mp3" {
+				t.Errorf("replaced asset = %+v", asset)
+			}
+		}
+	}
+}
+
+// TestAssetKindForFile verifies that slow clips get kinds of their own, so
+// they never stand in for the normal-speed clip of their side.
+func TestAssetKindForFile(t *testing.T) {
+	tests := map[string]store.AssetKind{
+		"audio.mp3":            store.AssetAudio,
+		"audio_alloy.wav":      store.AssetAudio,
+		"audio_slow.mp3":       store.AssetAudioSlow,
+		"audio_front.wav":      store.AssetAudioFront,
+		"audio_front_slow.wav": store.AssetAudioFrontSlow,
+		"audio_back_slow.mp3":  store.AssetAudioBackSlow,
+		"image.png":            store.AssetImage,
+	}
+	for name, want := range tests {
+		if got := store.AssetKindForFile(name); got != want {
+			t.Errorf("AssetKindForFile(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestMigrateManifests verifies that migration writes card.json for legacy
+// cards, skips up-to-date cards on a rerun and leaves corrupt manifests alone.
+func TestMigrateManifests(t *testing.T) {
+	outputDir := t.TempDir()
+	legacyDir := writeCardFiles(t, outputDir, "legacy", map[string]string{
+		"_word.txt":       "хляб",
+		"translation.txt": "хляб = bread\n",
+	})
+	corruptDir := writeCardFiles(t, outputDir, "corrupt", map[string]string{
+		"word.txt":  "мляко",
+		"card.json": "{not json",
+	})
+	writeCardFiles(t, outputDir, ".trashbin", map[string]string{"word.txt": "стар"})
+
+	cs := store.New(outputDir)
+	report := cs.MigrateManifests()
+	if report.Migrated != 1 || report.Current != 0 || report.Failed != 1 || len(report.Errors) != 1 {
+		t.Fatalf("first MigrateManifests() = %+v", report)
+	}
+
+	m, err := store.ReadManifest(legacyDir)
+	if err != nil {
+		t.Fatalf("ReadManifest() after migration error = %v", err)
+	}
+	if m.Word != "хляб" || m.Translation != "bread" {
+		t.Errorf("migrated manifest = %+v", m)
+	}
+
+	data, err := os.ReadFile(filepath.Join(corruptDir, "card.json"))
+	if err != nil || string(data) != "{not json" {
+		t.Errorf("corrupt manifest was modified: %q, %v", data, err)
+	}
+
+	report = cs.MigrateManifests()
+	if report.Migrated != 0 || report.Current != 1 || report.Failed != 1 {
+		t.Errorf("second MigrateManifests() = %+v", report)
+	}
+}
+
+// TestMigrateCardDirectoryBumpsOlderVersion verifies that a manifest written
+// by an older schema is rewritten with the current version exactly once.
+func TestMigrateCardDirectoryBumpsOlderVersion(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "old", map[string]string{
+		"card.json": `{"version": 0, "word": "хляб", "translation": "bread", "card_type": "en-bg"}`,
+	})
+
+	migrated, err := store.MigrateCardDirectory(cardDir)
+	if err != nil || !migrated {
+		t.Fatalf("MigrateCardDirectory() = %v, %v; want a migration", migrated, err)
+	}
+
+	m, err := store.ReadManifest(cardDir)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if m.Version != store.ManifestVersion || m.Word != "хляб" || m.Translation != "bread" {
+		t.Errorf("migrated manifest = %+v, want version %d with the card kept", m, store.ManifestVersion)
+	}
+
+	migrated, err = store.MigrateCardDirectory(cardDir)
+	if err != nil || migrated {
+		t.Errorf("second MigrateCardDirectory() = %v, %v; want no work", migrated, err)
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MigrationReport summarises a MigrateManifests run.
+type MigrationReport struct {
+	// Migrated counts directories that received a new or upgraded card.json.
+	Migrated int
+	// Current counts directories whose card.json was already up to date.
+	Current int
+	// Failed counts directories that could not be migrated; see Errors.
+	Failed int
+	// Errors holds one entry per failed directory.
+	Errors []error
+}
+
+// MigrateCardDirectory upgrades a single card directory in place. It builds
+// card.json from the legacy sidecars when the manifest is missing, and
+// rewrites manifests whose schema version is older than ManifestVersion.
+// It reports whether anything was written. A card.json that cannot be
+// parsed is left untouched and reported as an error rather than overwritten.
+func MigrateCardDirectory(cardDir string) (bool, error) {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return false, err
+	}
+	defer lock.Unlock()
+
+	existing, err := ReadManifest(cardDir)
+	switch {
+	case err == nil && existing.Version >= ManifestVersion:
+		return false, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return false, err
+	}
+
+	// Save directly: updateManifestLocked would skip the write because the
+	// upgrade changes nothing but the version.
+	if err := saveManifestLocked(cardDir, LoadManifest(cardDir)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// MigrateManifests upgrades every card directory under the output directory
+// to the current card.json schema. Failures are collected in the report so
+// one damaged card does not block the rest of the collection.
+func (cs *CardStore) MigrateManifests() MigrationReport {
+	var report MigrationReport
+
+	for _, card := range cs.ListCardDirectories(nil) {
+		migrated, err := MigrateCardDirectory(card.Path)
+		switch {
+		case err != nil:
+			report.Failed++
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", card.Path, err))
+		case migrated:
+			report.Migrated++
+		default:
+			report.Current++
+		}
+	}
+
+	return report
+}
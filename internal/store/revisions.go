@@ -0,0 +1,353 @@
+package store
+
+// revisions.go keeps a bounded history of every generated audio clip and
+// image of a card. Each time RecordAsset stores a new asset, a copy is kept
+// in the card's .history directory together with its provenance and
+// attribution. The file under its normal name (image.jpg, audio.mp3, ...) is
+// always the selected revision, so everything that reads cards — the GUI,
+// the exporters, the retry planner — automatically uses whatever revision
+// the user picked.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// HistoryDirName is the hidden directory inside a card directory that
+	// holds earlier asset revisions.
+	HistoryDirName = ".history"
+
+	// MaxAssetRevisions bounds the number of revisions kept per asset slot.
+	// The oldest revisions are dropped first; the selected one never is.
+	MaxAssetRevisions = 5
+)
+
+// Revision is one stored version of an asset slot.
+type Revision struct {
+	// Number increases by one for every new revision of the slot.
+	Number int `json:"number"`
+	// Slot is the asset file name without extension ("image", "audio",
+	// "audio_front", "audio_alloy", ...). Revisions of a slot may differ in
+	// extension, e.g. when the image provider switched from jpg to png.
+	Slot string `json:"slot"`
+	// Path is the stored copy, relative to the card directory.
+	Path string `json:"path"`
+	// AttributionPath is the stored copy of the attribution sidecar, if any.
+	AttributionPath string `json:"attribution_path,omitempty"`
+	// Asset is the provenance the revision had when it was generated.
+	Asset Asset `json:"asset"`
+}
+
+// AssetSlot returns the revision slot of an asset file name.
+func AssetSlot(file string) string {
+	base := filepath.Base(file)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// RevisionsOf returns the stored revisions of slot, oldest first.
+func (m *Manifest) RevisionsOf(slot string) []Revision {
+	var revisions []Revision
+	for _, rev := range m.Revisions {
+		if rev.Slot == slot {
+			revisions = append(revisions, rev)
+		}
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Number < revisions[j].Number
+	})
+	return revisions
+}
+
+// RevisionSlots returns the slots that have stored revisions, sorted by name.
+func (m *Manifest) RevisionSlots() []string {
+	seen := make(map[string]bool)
+	var slots []string
+	for _, rev := range m.Revisions {
+		if !seen[rev.Slot] {
+			seen[rev.Slot] = true
+			slots = append(slots, rev.Slot)
+		}
+	}
+	sort.Strings(slots)
+	return slots
+}
+
+// SelectedRevision returns the revision number currently in use for slot, or
+// 0 when the current file has no stored revision.
+func (m *Manifest) SelectedRevision(slot string) int {
+	for _, asset := range m.Assets {
+		if AssetSlot(asset.File) == slot && asset.Revision > 0 {
+			return asset.Revision
+		}
+	}
+	return 0
+}
+
+// SnapshotAssets stores a revision of every current asset of the card that
+// does not have one yet. Call it before regenerating assets of a card written
+// by an older release, so the first regeneration does not lose the original.
+func SnapshotAssets(cardDir string) error {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	m := LoadManifest(cardDir)
+	changed := false
+	for i := range m.Assets {
+		if m.Assets[i].Revision > 0 {
+			continue
+		}
+		if err := m.snapshotRevision(cardDir, &m.Assets[i]); err != nil {
+			return fmt.Errorf("failed to store revision of %s: %w", m.Assets[i].File, err)
+		}
+		changed = changed || m.Assets[i].Revision > 0
+	}
+
+	if !changed {
+		return nil
+	}
+	return saveManifestLocked(cardDir, m)
+}
+
+// RestoreRevision makes revision number of slot the selected one: its stored
+// copy and attribution replace the current file, its provenance replaces the
+// manifest entry, and for images its prompt becomes the card's image prompt.
+// The entry is stamped with the restore time, so that the restored file ranks
+// as the newest of its kind ahead of other files such as the voice-specific
+// clips of --all-voices. Other files of the same slot (e.g. image.png when restoring an image.jpg
+// revision) are removed; they remain available as revisions.
+func RestoreRevision(cardDir, slot string, number int) (Revision, error) {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return Revision{}, err
+	}
+	defer lock.Unlock()
+
+	m := LoadManifest(cardDir)
+	rev, ok := m.revision(slot, number)
+	if !ok {
+		return Revision{}, fmt.Errorf("revision %d of %s not found", number, slot)
+	}
+
+	target := filepath.Join(cardDir, rev.Asset.File)
+	if err := copyFileAtomic(filepath.Join(cardDir, rev.Path), target); err != nil {
+		return Revision{}, fmt.Errorf("failed to restore %s: %w", rev.Asset.File, err)
+	}
+
+	asset := rev.Asset
+	asset.Revision = rev.Number
+	asset.CreatedAt = time.Now()
+	if rev.AttributionPath != "" && asset.Attribution != "" {
+		if err := copyFileAtomic(filepath.Join(cardDir, rev.AttributionPath), filepath.Join(cardDir, asset.Attribution)); err != nil {
+			return Revision{}, fmt.Errorf("failed to restore %s: %w", asset.Attribution, err)
+		}
+	} else {
+		// The revision had no attribution; whatever sidecar is on disk
+		// belongs to a newer revision.
+		asset.Attribution = ""
+	}
+
+	m.dropOtherSlotFiles(cardDir, slot, asset.File, asset.Attribution)
+	m.PutAsset(asset)
+
+	if asset.Kind == AssetImage && asset.Prompt != "" {
+		m.ImagePrompt = asset.Prompt
+		if err := WriteFileAtomic(filepath.Join(cardDir, ImagePromptFileName), []byte(asset.Prompt)); err != nil {
+			return Revision{}, fmt.Errorf("failed to save image prompt: %w", err)
+		}
+	}
+
+	if err := saveManifestLocked(cardDir, m); err != nil {
+		return Revision{}, err
+	}
+	return rev, nil
+}
+
+// snapshotRevision stores a copy of asset as the next revision of its slot
+// and sets asset.Revision. A file identical to the newest stored revision
+// reuses that revision instead of adding a duplicate. Missing files are
+// ignored. Callers must hold the card lock.
+func (m *Manifest) snapshotRevision(cardDir string, asset *Asset) error {
+	source := filepath.Join(cardDir, asset.File)
+	if info, err := os.Stat(source); err != nil || info.IsDir() {
+		return nil
+	}
+
+	slot := AssetSlot(asset.File)
+	revisions := m.RevisionsOf(slot)
+	if len(revisions) > 0 {
+		latest := revisions[len(revisions)-1]
+		if sameFileContent(source, filepath.Join(cardDir, latest.Path)) {
+			// The attribution may have been written after the asset itself.
+			if err := latest.storeAttribution(cardDir, asset.Attribution); err != nil {
+				return err
+			}
+			asset.Revision = latest.Number
+			m.putRevision(latest.withAsset(*asset))
+			return nil
+		}
+	}
+
+	next := 1
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Number + 1
+	}
+	if err := os.MkdirAll(filepath.Join(cardDir, HistoryDirName), 0755); err != nil {
+		return err
+	}
+
+	rev := Revision{
+		Number: next,
+		Slot:   slot,
+		Path:   filepath.Join(HistoryDirName, fmt.Sprintf("%s.%d%s", slot, next, filepath.Ext(asset.File))),
+	}
+	if err := copyFileAtomic(source, filepath.Join(cardDir, rev.Path)); err != nil {
+		return err
+	}
+	if err := rev.storeAttribution(cardDir, asset.Attribution); err != nil {
+		return err
+	}
+
+	asset.Revision = next
+	m.putRevision(rev.withAsset(*asset))
+	m.pruneRevisions(cardDir, slot, next)
+	return nil
+}
+
+// storeAttribution copies the attribution sidecar into the history and sets
+// rev.AttributionPath. A missing sidecar is ignored.
+func (rev *Revision) storeAttribution(cardDir, attribution string) error {
+	if attribution == "" || !fileExists(filepath.Join(cardDir, attribution)) {
+		return nil
+	}
+
+	path := filepath.Join(HistoryDirName, fmt.Sprintf("%s.%d_attribution.txt", rev.Slot, rev.Number))
+	if err := copyFileAtomic(filepath.Join(cardDir, attribution), filepath.Join(cardDir, path)); err != nil {
+		return err
+	}
+	rev.AttributionPath = path
+	return nil
+}
+
+func (rev Revision) withAsset(asset Asset) Revision {
+	rev.Asset = asset
+	rev.Asset.Revision = rev.Number
+	return rev
+}
+
+func (m *Manifest) revision(slot string, number int) (Revision, bool) {
+	for _, rev := range m.Revisions {
+		if rev.Slot == slot && rev.Number == number {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+func (m *Manifest) putRevision(rev Revision) {
+	for i := range m.Revisions {
+		if m.Revisions[i].Slot == rev.Slot && m.Revisions[i].Number == rev.Number {
+			m.Revisions[i] = rev
+			return
+		}
+	}
+	m.Revisions = append(m.Revisions, rev)
+}
+
+// pruneRevisions drops the oldest revisions of slot beyond MaxAssetRevisions,
+// never the selected one, and deletes their stored files.
+func (m *Manifest) pruneRevisions(cardDir, slot string, selected int) {
+	revisions := m.RevisionsOf(slot)
+	excess := len(revisions) - MaxAssetRevisions
+	if excess <= 0 {
+		return
+	}
+
+	drop := make(map[int]bool, excess)
+	for _, rev := range revisions {
+		if len(drop) == excess {
+			break
+		}
+		if rev.Number == selected {
+			continue
+		}
+		drop[rev.Number] = true
+		_ = os.Remove(filepath.Join(cardDir, rev.Path))
+		if rev.AttributionPath != "" {
+			_ = os.Remove(filepath.Join(cardDir, rev.AttributionPath))
+		}
+	}
+
+	kept := m.Revisions[:0]
+	for _, rev := range m.Revisions {
+		if rev.Slot != slot || !drop[rev.Number] {
+			kept = append(kept, rev)
+		}
+	}
+	m.Revisions = kept
+}
+
+// dropOtherSlotFiles removes the manifest entries of slot together with their
+// files (except keep) and attribution sidecars (except keepAttribution), so
+// a restored revision replaces every current file of the slot.
+func (m *Manifest) dropOtherSlotFiles(cardDir, slot, keep, keepAttribution string) {
+	kept := m.Assets[:0]
+	for _, asset := range m.Assets {
+		if AssetSlot(asset.File) != slot {
+			kept = append(kept, asset)
+			continue
+		}
+		if asset.File != keep {
+			_ = os.Remove(filepath.Join(cardDir, asset.File))
+		}
+		// Attribution names are derived from the slot, so the kept file
+		// usually shares the sidecar that was just restored.
+		if asset.Attribution != "" && asset.Attribution != keepAttribution {
+			_ = os.Remove(filepath.Join(cardDir, asset.Attribution))
+		}
+	}
+	m.Assets = kept
+}
+
+func copyFileAtomic(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := CreateAtomic(target)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Commit()
+}
+
+// sameFileContent reports whether both files exist and hold the same bytes.
+// Assets are at most a few megabytes, so comparing them in memory is fine.
+func sameFileContent(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil || infoA.Size() != infoB.Size() {
+		return false
+	}
+
+	dataA, errA := os.ReadFile(a)
+	dataB, errB := os.ReadFile(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
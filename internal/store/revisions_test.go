@@ -0,0 +1,173 @@
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// generateImage simulates one image generation: the file is overwritten in
+// place and then recorded, just like the processor and the GUI do.
+func generateImage(t *testing.T, cardDir, file, content, prompt string) {
+	t.Helper()
+
+	if err := store.SnapshotAssets(cardDir); err != nil {
+		t.Fatalf("SnapshotAssets() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(cardDir, file), []byte(content), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := store.RecordAsset(cardDir, store.Asset{Kind: store.AssetImage, File: file, Provider: "nanobanana", Prompt: prompt}); err != nil {
+		t.Fatalf("RecordAsset() error = %v", err)
+	}
+}
+
+// TestRevisionHistoryIsBounded checks that every generation becomes a
+// revision and that only the newest MaxAssetRevisions are kept.
+func TestRevisionHistoryIsBounded(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "card", map[string]string{"word.txt": "котка"})
+
+	total := store.MaxAssetRevisions + 2
+	for i := 1; i <= total; i++ {
+		generateImage(t, cardDir, "image.jpg", "jpeg "+strconv.Itoa(i), "prompt "+strconv.Itoa(i))
+	}
+	// Recording the same file again must not add a duplicate revision.
+	generateImage(t, cardDir, "image.jpg", "jpeg "+strconv.Itoa(total), "prompt "+strconv.Itoa(total))
+
+	m := store.LoadManifest(cardDir)
+	revisions := m.RevisionsOf("image")
+	if len(revisions) != store.MaxAssetRevisions {
+		t.Fatalf("RevisionsOf(image) has %d revisions; want %d", len(revisions), store.MaxAssetRevisions)
+	}
+	if first := revisions[0].Number; first != total-store.MaxAssetRevisions+1 {
+		t.Errorf("oldest kept revision = %d; want %d", first, total-store.MaxAssetRevisions+1)
+	}
+	if got := m.SelectedRevision("image"); got != total {
+		t.Errorf("SelectedRevision(image) = %d; want %d", got, total)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(cardDir, store.HistoryDirName))
+	if err != nil {
+		t.Fatalf("ReadDir(history) error = %v", err)
+	}
+	if len(entries) != store.MaxAssetRevisions {
+		t.Errorf("history directory holds %d files; want %d", len(entries), store.MaxAssetRevisions)
+	}
+}
+
+// TestRestoreRevision rolls an image back to an earlier revision stored with
+// a different extension and checks file, prompt and manifest.
+func TestRestoreRevision(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "card", map[string]string{"word.txt": "куче"})
+
+	generateImage(t, cardDir, "image.jpg", "good jpeg", "a friendly dog")
+	if err := os.WriteFile(filepath.Join(cardDir, "image_attribution.txt"), []byte("first attribution"), 0644); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := store.RecordAsset(cardDir, store.Asset{Kind: store.AssetImage, File: "image.jpg", Prompt: "a friendly dog"}); err != nil {
+		t.Fatalf("RecordAsset() error = %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(cardDir, "image.jpg")); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	generateImage(t, cardDir, "image.png", "worse png", "a scary dog")
+
+	rev, err := store.RestoreRevision(cardDir, "image", 1)
+	if err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if rev.Asset.File != "image.jpg" {
+		t.Errorf("restored file = %q; want image.jpg", rev.Asset.File)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(cardDir, "image.jpg")); err != nil || string(data) != "good jpeg" {
+		t.Errorf("image.jpg = %q, %v; want restored content", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(cardDir, "image_attribution.txt")); err != nil || string(data) != "first attribution" {
+		t.Errorf("image_attribution.txt = %q, %v; want restored attribution", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(cardDir, "image.png")); !os.IsNotExist(err) {
+		t.Errorf("image.png still exists after restoring the jpg revision (err = %v)", err)
+	}
+
+	m := store.LoadManifest(cardDir)
+	if m.ImagePrompt != "a friendly dog" {
+		t.Errorf("ImagePrompt = %q; want the restored revision's prompt", m.ImagePrompt)
+	}
+	if got := m.SelectedRevision("image"); got != 1 {
+		t.Errorf("SelectedRevision(image) = %d; want 1", got)
+	}
+	if len(m.RevisionsOf("image")) != 2 {
+		t.Errorf("RevisionsOf(image) = %+v; the newer revision must be kept", m.RevisionsOf("image"))
+	}
+
+	if _, err := store.RestoreRevision(cardDir, "image", 9); err == nil {
+		t.Error("RestoreRevision(9) succeeded for a missing revision")
+	}
+}
+
+// TestRestoreRevisionRanksFirst restores an older clip on a card with a
+// second audio file and checks that the restored clip is the card's audio.
+func TestRestoreRevisionRanksFirst(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "card", map[string]string{"word.txt": "хляб"})
+
+	generateAudio := func(file, content string, createdAt time.Time) {
+		t.Helper()
+		if err := store.SnapshotAssets(cardDir); err != nil {
+			t.Fatalf("SnapshotAssets() error = %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(cardDir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+		if err := store.RecordAsset(cardDir, store.Asset{Kind: store.AssetAudio, File: file, CreatedAt: createdAt}); err != nil {
+			t.Fatalf("RecordAsset() error = %v", err)
+		}
+	}
+	now := time.Now()
+	generateAudio("audio.mp3", "first take", now.Add(-3*time.Hour))
+	generateAudio("audio.mp3", "second take", now.Add(-2*time.Hour))
+	generateAudio("audio_alloy.mp3", "alloy take", now.Add(-time.Hour))
+
+	if _, err := store.RestoreRevision(cardDir, "audio", 1); err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+
+	path := store.LoadManifest(cardDir).AssetPath(cardDir, store.AssetAudio)
+	if filepath.Base(path) != "audio.mp3" {
+		t.Fatalf("AssetPath(audio) = %q; want the restored audio.mp3", path)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "first take" {
+		t.Errorf("audio.mp3 = %q, %v; want the restored take", data, err)
+	}
+}
+
+// TestSnapshotAssetsKeepsLegacyFiles checks that assets recorded before
+// revision history existed are preserved before their first regeneration.
+func TestSnapshotAssetsKeepsLegacyFiles(t *testing.T) {
+	cardDir := writeCardFiles(t, t.TempDir(), "card", map[string]string{
+		"word.txt":  "хляб",
+		"audio.mp3": "old audio",
+	})
+	if err := store.UpdateManifest(cardDir, func(m *store.Manifest) {
+		m.PutAsset(store.Asset{Kind: store.AssetAudio, File: "audio.mp3", Voice: "alloy"})
+	}); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if err := store.SnapshotAssets(cardDir); err != nil {
+		t.Fatalf("SnapshotAssets() error = %v", err)
+	}
+
+	revisions := store.LoadManifest(cardDir).RevisionsOf("audio")
+	if len(revisions) != 1 || revisions[0].Asset.Voice != "alloy" {
+		t.Fatalf("RevisionsOf(audio) = %+v; want the legacy file as revision 1", revisions)
+	}
+	if data, err := os.ReadFile(filepath.Join(cardDir, revisions[0].Path)); err != nil || string(data) != "old audio" {
+		t.Errorf("stored revision = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,281 @@
+package store
+
+// sidecars.go owns the legacy per-card text files (word.txt, translation.txt,
+// phonetic.txt, cardtype.txt, image_prompt.txt, audio_metadata.txt and the
+// *_attribution.txt files). Readers never parse these directly any more: they
+// go through LoadManifest, which only falls back to the sidecars for cards
+// that have not been migrated to card.json. The Save* helpers below still
+// mirror each value into its sidecar so that older releases can read cards
+// written by this one.
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// WordFileName holds the Bulgarian word of a card.
+	WordFileName = "word.txt"
+	// TranslationFileName holds the translation in "word = translation" form.
+	TranslationFileName = "translation.txt"
+	// PhoneticFileName holds the IPA transcription.
+	PhoneticFileName = "phonetic.txt"
+	// CardTypeFileName holds the card type ("en-bg", "bg-bg" or "sentence").
+	CardTypeFileName = "cardtype.txt"
+	// ImagePromptFileName holds the prompt used to generate the image.
+	ImagePromptFileName = "image_prompt.txt"
+	// AudioMetadataFileName holds key=value provenance of the latest audio run.
+	AudioMetadataFileName = "audio_metadata.txt"
+
+	legacyWordFileName = "_word.txt"
+)
+
+// imageFileNames lists the image names a card directory may contain, in
+// lookup order.
+var imageFileNames = []string{"image.jpg", "image.png", "image.webp"}
+
+// SaveTranslation stores the translation of word in the card manifest and
+// mirrors it to translation.txt.
+func SaveTranslation(cardDir, word, translation string) error {
+	content := fmt.Sprintf("%s = %s\n", word, translation)
+	return saveField(cardDir, TranslationFileName, content, "failed to write translation file", func(m *Manifest) {
+		if m.Word == "" {
+			m.Word = word
+		}
+		m.Translation = strings.TrimSpace(translation)
+	})
+}
+
+// SavePhonetic stores the IPA transcription in the card manifest and mirrors
+// it to phonetic.txt.
+func SavePhonetic(cardDir, ipa string) error {
+	return saveField(cardDir, PhoneticFileName, ipa, "failed to write phonetic file", func(m *Manifest) {
+		m.IPA = strings.TrimSpace(ipa)
+	})
+}
+
+// SaveCardType stores the card type in the card manifest and mirrors it to
+// cardtype.txt.
+func SaveCardType(cardDir, cardType string) error {
+	return saveField(cardDir, CardTypeFileName, cardType, "failed to write card type file", func(m *Manifest) {
+		m.CardType = strings.TrimSpace(cardType)
+	})
+}
+
+// SaveImagePrompt stores the image prompt in the card manifest and mirrors it
+// to image_prompt.txt. Image assets recorded afterwards inherit the prompt.
+func SaveImagePrompt(cardDir, prompt string) error {
+	return saveField(cardDir, ImagePromptFileName, prompt, "failed to save image prompt", func(m *Manifest) {
+		m.ImagePrompt = strings.TrimSpace(prompt)
+	})
+}
+
+// saveField writes a sidecar mirror and applies mutate to the manifest while
+// holding the card lock, so the sidecar and card.json never disagree for long.
+func saveField(cardDir, sidecar, content, errPrefix string, mutate func(m *Manifest)) error {
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := WriteFileAtomic(filepath.Join(cardDir, sidecar), []byte(content)); err != nil {
+		return fmt.Errorf("%s: %w", errPrefix, err)
+	}
+
+	return updateManifestLocked(cardDir, mutate)
+}
+
+// RecordAsset adds or replaces a generated asset in the card manifest and
+// keeps a copy of it as the newest revision of its slot. The asset file itself
+// (and any attribution sidecar) must already be written; the format and the
+// <name>_attribution.txt sidecar are filled in when the caller leaves them
+// empty.
+func RecordAsset(cardDir string, asset Asset) error {
+	name := filepath.Base(asset.File)
+	asset.File = name
+	if asset.Format == "" {
+		asset.Format = strings.TrimPrefix(filepath.Ext(name), ".")
+	}
+	if attribution := strings.TrimSuffix(name, filepath.Ext(name)) + "_attribution.txt"; asset.Attribution == "" && fileExists(filepath.Join(cardDir, attribution)) {
+		asset.Attribution = attribution
+	}
+
+	lock, err := LockCardDirectory(cardDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if asset.Kind == "" {
+		asset.Kind = AssetKindForFile(name)
+	}
+	if asset.CreatedAt.IsZero() {
+		asset.CreatedAt = time.Now()
+	}
+
+	m := LoadManifest(cardDir)
+	if asset.Kind == AssetImage && asset.Prompt == "" {
+		asset.Prompt = m.ImagePrompt
+	}
+	if err := m.snapshotRevision(cardDir, &asset); err != nil {
+		return fmt.Errorf("failed to store revision of %s: %w", name, err)
+	}
+	m.PutAsset(asset)
+
+	return saveManifestLocked(cardDir, m)
+}
+
+// legacyManifest builds a manifest purely from the sidecar files of a card
+// directory written before card.json existed.
+func legacyManifest(cardDir string) *Manifest {
+	m := &Manifest{
+		Version:     ManifestVersion,
+		Word:        readLegacyWord(cardDir),
+		Translation: readLegacyTranslation(cardDir),
+		CardType:    readTrimmedFile(filepath.Join(cardDir, CardTypeFileName)),
+		IPA:         readTrimmedFile(filepath.Join(cardDir, PhoneticFileName)),
+		ImagePrompt: readTrimmedFile(filepath.Join(cardDir, ImagePromptFileName)),
+		CreatedAt:   legacyCreatedAt(cardDir),
+	}
+	m.UpdatedAt = m.CreatedAt
+
+	m.Assets = append(m.Assets, legacyAudioAssets(cardDir, m.CardType)...)
+	if imageAsset, ok := legacyImageAsset(cardDir, m.ImagePrompt); ok {
+		m.Assets = append(m.Assets, imageAsset)
+	}
+
+	return m
+}
+
+func readLegacyWord(cardDir string) string {
+	if word := readTrimmedFile(filepath.Join(cardDir, WordFileName)); word != "" {
+		return word
+	}
+	// Backward-compatible fallback: old format used _word.txt.
+	return readTrimmedFile(filepath.Join(cardDir, legacyWordFileName))
+}
+
+func readLegacyTranslation(cardDir string) string {
+	content := readTrimmedFile(filepath.Join(cardDir, TranslationFileName))
+	if _, translation, found := strings.Cut(content, "="); found {
+		return strings.TrimSpace(translation)
+	}
+	return content
+}
+
+// legacyAudioAssets derives audio assets from the audio_file hints in
+// audio_metadata.txt. Cards without hints get no audio assets here; readers
+// then fall back to scanning the directory for audio files.
+func legacyAudioAssets(cardDir, cardType string) []Asset {
+	metadata := readKeyValueFile(filepath.Join(cardDir, AudioMetadataFileName))
+
+	var assets []Asset
+	for _, key := range []string{"audio_file", "audio_file_back"} {
+		name := filepath.Base(strings.TrimSpace(metadata[key]))
+		if name == "" || name == "." {
+			continue
+		}
+
+		path := filepath.Join(cardDir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		kind := AssetKindForFile(name)
+		if kind == AssetAudio && cardType == "bg-bg" {
+			kind = AssetAudioFront
+		}
+
+		asset := Asset{
+			Kind:      kind,
+			File:      name,
+			Provider:  metadata["provider"],
+			Model:     metadata["model"],
+			Voice:     metadata["voice"],
+			Format:    metadata["format"],
+			CreatedAt: info.ModTime(),
+		}
+		if attribution := strings.TrimSuffix(name, filepath.Ext(name)) + "_attribution.txt"; fileExists(filepath.Join(cardDir, attribution)) {
+			asset.Attribution = attribution
+		}
+		assets = append(assets, asset)
+	}
+
+	return assets
+}
+
+func legacyImageAsset(cardDir, prompt string) (Asset, bool) {
+	for _, name := range imageFileNames {
+		info, err := os.Stat(filepath.Join(cardDir, name))
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		asset := Asset{
+			Kind:      AssetImage,
+			File:      name,
+			Format:    strings.TrimPrefix(filepath.Ext(name), "."),
+			Prompt:    prompt,
+			CreatedAt: info.ModTime(),
+		}
+		if fileExists(filepath.Join(cardDir, "image_attribution.txt")) {
+			asset.Attribution = "image_attribution.txt"
+		}
+		return asset, true
+	}
+
+	return Asset{}, false
+}
+
+// legacyCreatedAt recovers the creation time from the epochMillis prefix of
+// the card ID, falling back to the directory modification time.
+func legacyCreatedAt(cardDir string) time.Time {
+	prefix, _, _ := strings.Cut(filepath.Base(cardDir), "_")
+	if millis, err := strconv.ParseInt(prefix, 10, 64); err == nil && millis > 0 {
+		return time.UnixMilli(millis)
+	}
+
+	if info, err := os.Stat(cardDir); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+func readTrimmedFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// readKeyValueFile parses a key=value file into a map. Missing files yield an
+// empty map.
+func readKeyValueFile(path string) map[string]string {
+	values := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return values
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
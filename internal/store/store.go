@@ -14,22 +14,26 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strings"
 	"time"
 )
 
 // CardStore manages the on-disk layout of word card directories under a single
-// output directory. It is safe to create multiple instances pointing at the
-// same directory; all operations are stateless file-system reads/writes.
+// output directory. Lookups go through a persistent card index (see index.go)
+// that is shared by all instances rooted at the same directory, so it is safe
+// and cheap to create multiple instances pointing at the same directory.
 type CardStore struct {
 	outputDir string
 }
 
-// CardDirectory describes one discovered on-disk card directory and the word
-// stored inside it.
+// CardDirectory describes one discovered on-disk card directory, the word
+// stored inside it and its card type ("en-bg", "bg-bg", "sentence"; empty when
+// unknown).
+// ID is the directory name.
 type CardDirectory struct {
-	Path string
-	Word string
+	ID       string `json:"id"`
+	Path     string `json:"-"`
+	Word     string `json:"word"`
+	CardType string `json:"card_type,omitempty"`
 }
 
 // New constructs a CardStore rooted at outputDir.
@@ -42,11 +46,15 @@ func (cs *CardStore) OutputDir() string {
 	return cs.outputDir
 }
 
-// FindCardDirectory searches the output directory for a subdirectory whose
-// word.txt (or legacy _word.txt) matches word. Returns the directory path or
-// an empty string when no matching directory is found.
+// FindCardDirectory looks up the card directory whose word matches word.
+// Returns the directory path or an empty string when no matching directory is
+// found.
 func (cs *CardStore) FindCardDirectory(word string) string {
-	return FindCardDirectory(cs.outputDir, word)
+	card, ok := cs.FindByWord(word)
+	if !ok {
+		return ""
+	}
+	return card.Path
 }
 
 // FindOrCreateCardDirectory returns the existing card directory for word, or
@@ -56,6 +64,49 @@ func (cs *CardStore) FindOrCreateCardDirectory(word string) string {
 	return FindOrCreateCardDirectory(cs.outputDir, word)
 }
 
+// EnsureCardDirectory is FindOrCreateCardDirectory with error reporting. The
+// lookup is repeated under the output directory lock before creating
+// anything, so concurrent GUI jobs and CLI runs on the same collection end up
+// sharing one directory per word. An error wrapping ErrLocked means another
+// totalrecall process kept the lock for too long.
+func (cs *CardStore) EnsureCardDirectory(word string) (string, error) {
+	if dir := cs.FindCardDirectory(word); dir != "" {
+		return dir, nil
+	}
+	dir, _, err := cs.CreateCardDirectory(word)
+	return dir, err
+}
+
+// CreateCardDirectory is EnsureCardDirectory that also reports whether it
+// created the directory. The lookup and the creation happen under the output
+// directory lock, so created is false whenever the word had a card directory
+// before, even one another job created a moment ago.
+func (cs *CardStore) CreateCardDirectory(word string) (dir string, created bool, err error) {
+	lock, err := LockOutputDirectory(cs.outputDir)
+	if err != nil {
+		return "", false, err
+	}
+	defer lock.Unlock()
+
+	if dir := cs.FindCardDirectory(word); dir != "" {
+		return dir, false, nil
+	}
+
+	wordDir := filepath.Join(cs.outputDir, GenerateCardID(word))
+	if err := os.MkdirAll(wordDir, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create word directory: %w", err)
+	}
+
+	if err := WriteFileAtomic(filepath.Join(wordDir, WordFileName), []byte(word)); err != nil {
+		return "", false, fmt.Errorf("failed to save word metadata: %w", err)
+	}
+	if err := SaveManifest(wordDir, NewManifest(word)); err != nil {
+		return "", false, fmt.Errorf("failed to save card manifest: %w", err)
+	}
+
+	return wordDir, true, nil
+}
+
 // ScanWords scans the output directory for subdirectories that contain at
 // least one content file (word.txt or legacy _word.txt) and passes a basic
 // content check provided by the caller. It returns a sorted list of Bulgarian
@@ -72,32 +123,16 @@ func (cs *CardStore) ScanWords(hasContent func(wordDir string) bool) []string {
 	return words
 }
 
-// ListCardDirectories scans the output directory for non-hidden card
-// subdirectories that contain word metadata. The result is sorted by directory
+// ListCardDirectories returns the non-hidden card subdirectories of the output
+// directory that contain word metadata, as recorded in the card index. The result is sorted by directory
 // path so callers can process cards in deterministic creation order.
 func (cs *CardStore) ListCardDirectories(hasContent func(wordDir string) bool) []CardDirectory {
-	entries, err := os.ReadDir(cs.outputDir)
-	if err != nil {
-		// Output directory does not exist yet; return empty list silently.
-		return []CardDirectory{}
-	}
-
-	cards := make([]CardDirectory, 0, len(entries))
+	indexed := cs.Cards()
+	cards := make([]CardDirectory, 0, len(indexed))
 
-	for _, entry := range entries {
-		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
-			continue
-		}
-
-		wordDir := filepath.Join(cs.outputDir, entry.Name())
-
-		word, ok := readWordFromDir(wordDir)
-		if !ok {
-			continue
-		}
-
-		if hasContent == nil || hasContent(wordDir) {
-			cards = append(cards, CardDirectory{Path: wordDir, Word: word})
+	for _, card := range indexed {
+		if hasContent == nil || hasContent(card.Path) {
+			cards = append(cards, card)
 		}
 	}
 
@@ -118,76 +153,22 @@ func GenerateCardID(bulgarianWord string) string {
 }
 
 // FindCardDirectory is the package-level (non-method) version of the directory
-// search. It searches outputDir for a subdirectory whose word.txt (or legacy
-// _word.txt) content matches word and returns its path, or "" if not found.
+// lookup. It returns the path of the subdirectory of outputDir whose word
+// matches word, or "" if not found.
 func FindCardDirectory(outputDir, word string) string {
-	entries, err := os.ReadDir(outputDir)
-	if err != nil {
-		return ""
-	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
-			continue
-		}
-
-		dirPath := filepath.Join(outputDir, entry.Name())
-		if found := readWordMatch(dirPath, word); found {
-			return dirPath
-		}
-	}
-
-	return ""
+	return New(outputDir).FindCardDirectory(word)
 }
 
 // FindOrCreateCardDirectory returns the existing card directory for word inside
 // outputDir, or creates a new one with a generated card ID. It also writes
-// word.txt so subsequent calls can find the directory.
+// card.json (and the word.txt mirror) so subsequent calls can find the
+// directory. Failures are printed as warnings and yield outputDir itself; use
+// CardStore.EnsureCardDirectory to handle them instead.
 func FindOrCreateCardDirectory(outputDir, word string) string {
-	if dir := FindCardDirectory(outputDir, word); dir != "" {
-		return dir
-	}
-
-	cardID := GenerateCardID(word)
-	wordDir := filepath.Join(outputDir, cardID)
-
-	if err := os.MkdirAll(wordDir, 0755); err != nil {
-		fmt.Printf("Warning: failed to create word directory: %v\n", err)
+	wordDir, err := New(outputDir).EnsureCardDirectory(word)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
 		return outputDir
 	}
-
-	if err := os.WriteFile(filepath.Join(wordDir, "word.txt"), []byte(word), 0644); err != nil {
-		fmt.Printf("Warning: failed to save word metadata: %v\n", err)
-	}
-
 	return wordDir
 }
-
-// readWordFromDir reads the Bulgarian word stored in word.txt (or the legacy
-// _word.txt) inside a card directory. Returns the word and true on success.
-func readWordFromDir(wordDir string) (string, bool) {
-	wordFile := filepath.Join(wordDir, "word.txt")
-	data, err := os.ReadFile(wordFile)
-	if err != nil {
-		// Backward-compatible fallback: old format used _word.txt.
-		wordFile = filepath.Join(wordDir, "_word.txt")
-		data, err = os.ReadFile(wordFile)
-		if err != nil {
-			return "", false
-		}
-	}
-
-	word := strings.TrimSpace(string(data))
-	if word == "" {
-		return "", false
-	}
-
-	return word, true
-}
-
-// readWordMatch returns true when the card directory at dirPath contains a
-// word file whose trimmed content equals word.
-func readWordMatch(dirPath, word string) bool {
-	found, ok := readWordFromDir(dirPath)
-	return ok && found == word
-}
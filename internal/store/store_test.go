@@ -3,6 +3,7 @@ package store_test
 import (
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	"codeberg.org/snonux/totalrecall/internal/store"
@@ -168,3 +169,118 @@ func TestCardStoreListCardDirectories(t *testing.T) {
 		t.Fatalf("ListCardDirectories()[0].Path = %q, want base %q", cards[0].Path, "card1")
 	}
 }
+
+// TestEnsureCardDirectoryConcurrent checks that parallel creators of the same
+// word share a single card directory instead of racing to create duplicates.
+func TestEnsureCardDirectoryConcurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	const workers = 8
+	dirs := make(chan string, workers)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate CardStore instances mimic independent GUI jobs.
+			dir, err := store.New(tmpDir).EnsureCardDirectory("мляко")
+			dirs <- dir
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(dirs)
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("EnsureCardDirectory() error = %v", err)
+		}
+	}
+	first := <-dirs
+	for dir := range dirs {
+		if dir != first {
+			t.Errorf("EnsureCardDirectory() = %q; want %q for every caller", dir, first)
+		}
+	}
+
+	if cards := store.New(tmpDir).ListCardDirectories(nil); len(cards) != 1 {
+		t.Errorf("card directories = %+v; want exactly one", cards)
+	}
+}
+
+// TestCreateCardDirectoryReportsCreation checks that only the caller that
+// created a card directory is told so, also when callers race.
+func TestCreateCardDirectoryReportsCreation(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	const workers = 8
+	created := make(chan bool, workers)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, err := store.New(tmpDir).CreateCardDirectory("мляко")
+			created <- ok
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(created)
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("CreateCardDirectory() error = %v", err)
+		}
+	}
+	creators := 0
+	for ok := range created {
+		if ok {
+			creators++
+		}
+	}
+	if creators != 1 {
+		t.Errorf("%d callers created the card directory; want exactly one", creators)
+	}
+
+	existing := store.New(tmpDir).FindCardDirectory("мляко")
+	if dir, ok, err := store.New(tmpDir).CreateCardDirectory("мляко"); err != nil || ok || dir != existing {
+		t.Errorf("CreateCardDirectory() of an existing word = %q, %v, %v; want %q, false", dir, ok, err, existing)
+	}
+}
+
+// TestWriteAtomicallyLeavesNothingOnFailure verifies that a failed write
+// neither creates the destination nor leaves temporary files behind.
+func TestWriteAtomicallyLeavesNothingOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "audio.mp3")
+
+	err := store.WriteAtomically(target, func(tmpPath string) error {
+		if filepath.Ext(tmpPath) != ".mp3" {
+			t.Errorf("temporary path %q lost the .mp3 extension", tmpPath)
+		}
+		if err := os.WriteFile(tmpPath, []byte("partial"), 0644); err != nil {
+			return err
+		}
+		return os.ErrInvalid
+	})
+	if err == nil {
+		t.Fatal("WriteAtomically() error = nil; want failure")
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 0 {
+		t.Errorf("directory not empty after failed write: %v", entries)
+	}
+
+	if err := store.WriteFileAtomic(target, []byte("mp3")); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "mp3" {
+		t.Errorf("target = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ParseTags splits free-form tag input such as "lesson-3, food verbs" or
+// "#food #lesson-3" into normalized tags. Commas and whitespace both separate
+// tags because Anki tags cannot contain spaces.
+func ParseTags(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return NormalizeTags(fields...)
+}
+
+// NormalizeTags returns tags the way Anki stores them: without a leading '#',
+// free of whitespace, de-duplicated case-insensitively (Anki treats "Food" and
+// "food" as the same tag, the first spelling wins) and sorted. It returns nil
+// when no tag remains.
+func NormalizeTags(tags ...string) []string {
+	seen := make(map[string]bool, len(tags))
+	var normalized []string
+	for _, tag := range tags {
+		for _, field := range strings.Fields(tag) {
+			field = strings.TrimLeft(field, "#")
+			if field == "" {
+				continue
+			}
+			key := strings.ToLower(field)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			normalized = append(normalized, field)
+		}
+	}
+
+	sort.SliceStable(normalized, func(i, j int) bool {
+		return strings.ToLower(normalized[i]) < strings.ToLower(normalized[j])
+	})
+	return normalized
+}
+
+// SaveTags replaces the tags of the card in cardDir. Tags have no legacy
+// sidecar: they only live in card.json.
+func SaveTags(cardDir string, tags []string) error {
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Tags = NormalizeTags(tags...)
+	})
+}
+
+// AddTags adds tags to the card in cardDir, keeping the tags it already has.
+// Reprocessing a word with another lesson's tag therefore files it under both
+// lessons.
+func AddTags(cardDir string, tags []string) error {
+	tags = NormalizeTags(tags...)
+	if len(tags) == 0 {
+		return nil
+	}
+	return UpdateManifest(cardDir, func(m *Manifest) {
+		m.Tags = NormalizeTags(append(m.Tags, tags...)...)
+	})
+}
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"lesson-3, food", []string{"food", "lesson-3"}},
+		{"#food #Lesson::3 food", []string{"food", "Lesson::3"}},
+		{" ,, ", nil},
+	}
+
+	for _, tt := range tests {
+		if got := store.ParseTags(tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %#v; want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestAddTagsKeepsExistingTags merges new tags into the manifest while
+// SaveTags replaces them.
+func TestAddTagsKeepsExistingTags(t *testing.T) {
+	cardDir := store.New(t.TempDir()).FindOrCreateCardDirectory("ябълка")
+
+	if err := store.SaveTags(cardDir, []string{"food", "lesson-1"}); err != nil {
+		t.Fatalf("SaveTags() error = %v", err)
+	}
+	if err := store.AddTags(cardDir, []string{"Food", "lesson-2"}); err != nil {
+		t.Fatalf("AddTags() error = %v", err)
+	}
+	if got, want := store.LoadManifest(cardDir).Tags, []string{"food", "lesson-1", "lesson-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags after AddTags() = %v; want %v", got, want)
+	}
+
+	if err := store.SaveTags(cardDir, nil); err != nil {
+		t.Fatalf("SaveTags(nil) error = %v", err)
+	}
+	if got := store.LoadManifest(cardDir).Tags; got != nil {
+		t.Errorf("Tags after SaveTags(nil) = %v; want none", got)
+	}
+}
@@ -0,0 +1,220 @@
+package store
+
+// trash.go manages the trash bin of deleted cards. Deleting a card moves its
+// directory to <output>/.trashbin/<card id>_<timestamp>, where it can be
+// listed, moved back into the store or purged once it is older than the
+// retention period.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	// TrashDirName is the hidden directory inside the output directory that
+	// holds deleted card directories.
+	TrashDirName = ".trashbin"
+
+	// DefaultTrashRetention is how long trashed cards are kept by default
+	// before a purge removes them.
+	DefaultTrashRetention = 30 * 24 * time.Hour
+
+	// trashTimeLayout is the deletion timestamp appended to trashed
+	// directory names. It is local time, as it always has been.
+	trashTimeLayout = "20060102_150405"
+)
+
+// ErrTrashConflict reports that a trashed card cannot be restored because
+// its word has been created again since it was deleted.
+var ErrTrashConflict = errors.New("word already exists in the card store")
+
+// trashNamePattern splits a trash entry name into card ID and deletion time.
+// Older GUI releases appended "_cleanup" to directories they trashed after a
+// deletion raced with a running generation.
+var trashNamePattern = regexp.MustCompile(`^(.+)_(\d{8}_\d{6})(?:_cleanup)?(?:_\d+)?$`)
+
+// TrashEntry is one deleted card in the trash bin.
+type TrashEntry struct {
+	// Name is the directory name inside the trash bin.
+	Name string
+	// Path is the full path of the trashed directory.
+	Path string
+	// CardID is the card directory name before deletion.
+	CardID string
+	// Word is the card's word; empty when the directory has no metadata.
+	Word string
+	// DeletedAt is when the card was moved to the trash bin.
+	DeletedAt time.Time
+}
+
+// TrashDir returns the trash bin directory of the store.
+func (cs *CardStore) TrashDir() string {
+	return filepath.Join(cs.outputDir, TrashDirName)
+}
+
+// MoveToTrash moves cardDir into the trash bin and returns its entry. It
+// takes the output directory lock so the move cannot interleave with another
+// process creating or restoring the same word.
+func (cs *CardStore) MoveToTrash(cardDir string) (TrashEntry, error) {
+	lock, err := LockOutputDirectory(cs.outputDir)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	defer lock.Unlock()
+
+	if err := os.MkdirAll(cs.TrashDir(), 0755); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	card, _ := readCardDirectory(cardDir)
+	now := time.Now()
+	base := fmt.Sprintf("%s_%s", filepath.Base(cardDir), now.Format(trashTimeLayout))
+	name := base
+	for i := 2; fileExists(filepath.Join(cs.TrashDir(), name)); i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	entry := TrashEntry{
+		Name:      name,
+		Path:      filepath.Join(cs.TrashDir(), name),
+		CardID:    filepath.Base(cardDir),
+		Word:      card.Word,
+		DeletedAt: now.Truncate(time.Second),
+	}
+	if err := os.Rename(cardDir, entry.Path); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to move card to trash: %w", err)
+	}
+	return entry, nil
+}
+
+// ListTrash returns the trashed cards, most recently deleted first. A missing
+// trash bin yields no entries.
+func (cs *CardStore) ListTrash() ([]TrashEntry, error) {
+	dirEntries, err := os.ReadDir(cs.TrashDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	var entries []TrashEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		entries = append(entries, cs.trashEntry(dirEntry))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+			return entries[i].DeletedAt.After(entries[j].DeletedAt)
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// FindTrashEntry resolves ref, either an entry name or a word, to a trash
+// entry. For a word the most recently deleted card wins.
+func (cs *CardStore) FindTrashEntry(ref string) (TrashEntry, error) {
+	entries, err := cs.ListTrash()
+	if err != nil {
+		return TrashEntry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.Name == ref {
+			return entry, nil
+		}
+	}
+	for _, entry := range entries {
+		if entry.Word == ref {
+			return entry, nil
+		}
+	}
+	return TrashEntry{}, fmt.Errorf("no trashed card named or holding %q", ref)
+}
+
+// RestoreFromTrash moves a trashed card back into the store under its
+// original card ID and returns the restored directory. It fails with an error
+// wrapping ErrTrashConflict when the word has a card again.
+func (cs *CardStore) RestoreFromTrash(entry TrashEntry) (string, error) {
+	lock, err := LockOutputDirectory(cs.outputDir)
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+
+	if entry.Word == "" {
+		return "", fmt.Errorf("trashed card %s has no word metadata", entry.Name)
+	}
+	if existing := cs.FindCardDirectory(entry.Word); existing != "" {
+		return "", fmt.Errorf("cannot restore %s: %w (%q is in %s)", entry.Name, ErrTrashConflict, entry.Word, filepath.Base(existing))
+	}
+
+	target := filepath.Join(cs.outputDir, entry.CardID)
+	if fileExists(target) {
+		target = filepath.Join(cs.outputDir, GenerateCardID(entry.Word))
+	}
+	if err := os.Rename(entry.Path, target); err != nil {
+		return "", fmt.Errorf("failed to restore card from trash: %w", err)
+	}
+	return target, nil
+}
+
+// PurgeTrash permanently deletes trashed cards deleted before cutoff and
+// returns the removed entries.
+func (cs *CardStore) PurgeTrash(cutoff time.Time) ([]TrashEntry, error) {
+	entries, err := cs.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []TrashEntry
+	var errs []error
+	for _, entry := range entries {
+		if !entry.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(entry.Path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge %s: %w", entry.Name, err))
+			continue
+		}
+		purged = append(purged, entry)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// trashEntry describes a trash bin directory. The deletion time comes from
+// the name; directories that do not follow the naming scheme fall back to
+// their modification time.
+func (cs *CardStore) trashEntry(dirEntry os.DirEntry) TrashEntry {
+	entry := TrashEntry{
+		Name:   dirEntry.Name(),
+		Path:   filepath.Join(cs.TrashDir(), dirEntry.Name()),
+		CardID: dirEntry.Name(),
+	}
+
+	if match := trashNamePattern.FindStringSubmatch(entry.Name); match != nil {
+		if deletedAt, err := time.ParseInLocation(trashTimeLayout, match[2], time.Local); err == nil {
+			entry.CardID = match[1]
+			entry.DeletedAt = deletedAt
+		}
+	}
+	if entry.DeletedAt.IsZero() {
+		if info, err := dirEntry.Info(); err == nil {
+			entry.DeletedAt = info.ModTime()
+		}
+	}
+
+	if card, ok := readCardDirectory(entry.Path); ok {
+		entry.Word = card.Word
+	}
+	return entry
+}
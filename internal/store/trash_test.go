@@ -0,0 +1,105 @@
+package store_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/snonux/totalrecall/internal/store"
+)
+
+// TestTrashRestoreRoundTrip deletes a card, lists it and restores it under
+// its original card ID.
+func TestTrashRestoreRoundTrip(t *testing.T) {
+	outputDir := t.TempDir()
+	cs := store.New(outputDir)
+	cardDir := cs.FindOrCreateCardDirectory("ябълка")
+
+	entry, err := cs.MoveToTrash(cardDir)
+	if err != nil {
+		t.Fatalf("MoveToTrash() error = %v", err)
+	}
+	if got := cs.FindCardDirectory("ябълка"); got != "" {
+		t.Errorf("FindCardDirectory() = %q after trashing", got)
+	}
+
+	entries, err := cs.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Word != "ябълка" || entries[0].CardID != filepath.Base(cardDir) {
+		t.Fatalf("ListTrash() = %+v", entries)
+	}
+	if !entries[0].DeletedAt.Equal(entry.DeletedAt) {
+		t.Errorf("DeletedAt = %v; want %v", entries[0].DeletedAt, entry.DeletedAt)
+	}
+
+	found, err := cs.FindTrashEntry("ябълка")
+	if err != nil {
+		t.Fatalf("FindTrashEntry() error = %v", err)
+	}
+	restored, err := cs.RestoreFromTrash(found)
+	if err != nil {
+		t.Fatalf("RestoreFromTrash() error = %v", err)
+	}
+	if restored != cardDir {
+		t.Errorf("RestoreFromTrash() = %q; want %q", restored, cardDir)
+	}
+	if got := cs.FindCardDirectory("ябълка"); got != cardDir {
+		t.Errorf("FindCardDirectory() = %q after restore; want %q", got, cardDir)
+	}
+}
+
+// TestTrashRestoreDetectsConflict refuses to restore a card whose word has
+// been created again since it was deleted.
+func TestTrashRestoreDetectsConflict(t *testing.T) {
+	cs := store.New(t.TempDir())
+	entry, err := cs.MoveToTrash(cs.FindOrCreateCardDirectory("котка"))
+	if err != nil {
+		t.Fatalf("MoveToTrash() error = %v", err)
+	}
+	recreated := cs.FindOrCreateCardDirectory("котка")
+
+	if _, err := cs.RestoreFromTrash(entry); !errors.Is(err, store.ErrTrashConflict) {
+		t.Fatalf("RestoreFromTrash() error = %v; want ErrTrashConflict", err)
+	}
+	if _, err := os.Stat(entry.Path); err != nil {
+		t.Errorf("trashed card was touched by the failed restore: %v", err)
+	}
+	if got := cs.FindCardDirectory("котка"); got != recreated {
+		t.Errorf("FindCardDirectory() = %q; want the recreated card %q", got, recreated)
+	}
+}
+
+// TestPurgeTrashHonoursRetention only removes entries deleted before the
+// cutoff, including legacy "_cleanup" entries.
+func TestPurgeTrashHonoursRetention(t *testing.T) {
+	outputDir := t.TempDir()
+	cs := store.New(outputDir)
+
+	old := time.Now().Add(-40 * 24 * time.Hour).Format("20060102_150405")
+	trashDir := filepath.Join(outputDir, store.TrashDirName)
+	writeCardFiles(t, trashDir, "1700000000000_abcdef12_"+old, map[string]string{"word.txt": "стар"})
+	writeCardFiles(t, trashDir, "1700000000001_abcdef13_"+old+"_cleanup", map[string]string{"word.txt": "стар"})
+	if _, err := cs.MoveToTrash(cs.FindOrCreateCardDirectory("нов")); err != nil {
+		t.Fatalf("MoveToTrash() error = %v", err)
+	}
+
+	purged, err := cs.PurgeTrash(time.Now().Add(-store.DefaultTrashRetention))
+	if err != nil {
+		t.Fatalf("PurgeTrash() error = %v", err)
+	}
+	if len(purged) != 2 {
+		t.Errorf("PurgeTrash() removed %+v; want the two old entries", purged)
+	}
+
+	entries, err := cs.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Word != "нов" {
+		t.Errorf("ListTrash() after purge = %+v; want only нов", entries)
+	}
+}
@@ -3,8 +3,6 @@ package translation
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -13,6 +11,7 @@ import (
 
 	appconfig "codeberg.org/snonux/totalrecall/internal/config"
 	"codeberg.org/snonux/totalrecall/internal/httpctx"
+	"codeberg.org/snonux/totalrecall/internal/store"
 )
 
 const (
@@ -115,6 +114,15 @@ func (t *Translator) TranslateWord(word string) (string, error) {
 	))
 }
 
+// TranslateSentence translates a Bulgarian sentence to English. Unlike a
+// word, the sentence is translated as a whole, keeping its punctuation.
+func (t *Translator) TranslateSentence(sentence string) (string, error) {
+	return t.translate(fmt.Sprintf(
+		"Translate the Bulgarian sentence '%s' to English. Respond with only the English translation, nothing else.",
+		sentence,
+	))
+}
+
 // TranslateEnglishToBulgarian translates an English word to Bulgarian.
 func (t *Translator) TranslateEnglishToBulgarian(word string) (string, error) {
 	return t.translate(fmt.Sprintf(
@@ -206,16 +214,9 @@ func (t *Translator) translateWithGemini(prompt string) (string, error) {
 	return translation, nil
 }
 
-// SaveTranslation saves the translation to a file in the word directory.
+// SaveTranslation saves the translation to the card manifest in the word directory.
 func SaveTranslation(wordDir, word, translation string) error {
-	outputFile := filepath.Join(wordDir, "translation.txt")
-	content := fmt.Sprintf("%s = %s\n", word, translation)
-
-	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("failed to write translation file: %w", err)
-	}
-
-	return nil
+	return store.SaveTranslation(wordDir, word, translation)
 }
 
 // TranslationCache stores translations in memory for batch operations.
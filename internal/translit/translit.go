@@ -0,0 +1,62 @@
+// Package translit transliterates Bulgarian Cyrillic into Latin script using
+// the Streamlined System, Bulgaria's official transliteration since 2009
+// (the one on road signs and in passports).
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+var latin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n",
+	'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "h", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "sht", 'ъ': "a", 'ь': "y",
+	'ю': "yu", 'я': "ya",
+}
+
+// Bulgarian returns text with every Bulgarian letter replaced by its Latin
+// equivalent; other characters are kept. Following the Streamlined System,
+// "ия" at the end of a word becomes "ia" (София → Sofia). Capitals
+// stay capitals: a multi-letter equivalent is fully upper case inside an
+// upper-case word (ЖАБА → ZHABA) and capitalized otherwise (Жаба → Zhaba).
+func Bulgarian(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	b.Grow(len(text))
+
+	for i, r := range runes {
+		lower := unicode.ToLower(r)
+		out, ok := latin[lower]
+		if !ok {
+			b.WriteRune(r)
+			continue
+		}
+		if lower == 'я' && i > 0 && unicode.ToLower(runes[i-1]) == 'и' && endsWord(runes, i+1) {
+			out = "a"
+		}
+		if unicode.IsUpper(r) {
+			if upperWord(runes, i) {
+				out = strings.ToUpper(out)
+			} else {
+				out = strings.ToUpper(out[:1]) + out[1:]
+			}
+		}
+		b.WriteString(out)
+	}
+	return b.String()
+}
+
+func endsWord(runes []rune, i int) bool {
+	return i >= len(runes) || !unicode.IsLetter(runes[i])
+}
+
+// upperWord reports whether the capital at i belongs to an all-capitals
+// word, judged by its neighbouring letters.
+func upperWord(runes []rune, i int) bool {
+	if i+1 < len(runes) && unicode.IsLetter(runes[i+1]) {
+		return unicode.IsUpper(runes[i+1])
+	}
+	return i > 0 && unicode.IsLetter(runes[i-1]) && unicode.IsUpper(runes[i-1])
+}
@@ -0,0 +1,27 @@
+package translit
+
+import "testing"
+
+func TestBulgarian(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"ябълка", "yabalka"},
+		{"щастие", "shtastie"},
+		{"България", "Balgaria"},
+		{"история на София", "istoria na Sofia"},
+		{"Жаба", "Zhaba"},
+		{"ЖАБА", "ZHABA"},
+		{"Ще дойда утре, нали?", "Shte doyda utre, nali?"},
+		{"Юлия", "Yulia"},
+		{"ияк", "iyak"},
+		{"hello", "hello"},
+	}
+
+	for _, tt := range tests {
+		if got := Bulgarian(tt.input); got != tt.want {
+			t.Errorf("Bulgarian(%q) = %q; want %q", tt.input, got, tt.want)
+		}
+	}
+}
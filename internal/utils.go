@@ -13,9 +13,9 @@ func GenerateCardID(bulgarianWord string) string {
 	return store.GenerateCardID(bulgarianWord)
 }
 
-// FindCardDirectory searches outputDir for a subdirectory whose word.txt
-// (or legacy _word.txt) matches the given word. Returns the directory path
-// or an empty string if not found.
+// FindCardDirectory looks up the subdirectory of outputDir whose word matches
+// the given word using the card index. Returns the directory path or an empty
+// string if not found.
 // Delegates to store.FindCardDirectory which is the single source of truth.
 func FindCardDirectory(outputDir, word string) string {
 	return store.FindCardDirectory(outputDir, word)